@@ -9,11 +9,20 @@ import (
 	"syscall"
 	"time"
 
+	"agentXmap/internal/catalog"
+	"agentXmap/internal/connector"
+	"agentXmap/internal/handler"
+	"agentXmap/internal/mail"
+	"agentXmap/internal/repository"
+	"agentXmap/internal/secrets"
+	"agentXmap/internal/service"
 	"agentXmap/pkg/config"
 	"agentXmap/pkg/logger"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // @title agentXmap API
@@ -21,6 +30,12 @@ import (
 // @description Backend API for agentXmap project.
 // @host localhost:8080
 // @BasePath /api/v1
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 func main() {
 	// 1. Load Config
 	cfg, err := config.LoadConfig()
@@ -37,15 +52,128 @@ func main() {
 	defer logger.Sync()
 	logger.Log.Info("Starting agentXmap API", zap.String("version", cfg.App.Version), zap.String("env", cfg.Server.Mode))
 
-	// 3. Setup Gin
+	// 3. Init Database
+	db, err := repository.InitDB(*cfg)
+	if err != nil {
+		logger.Log.Fatal("Failed to init database", zap.Error(err))
+	}
+	checkSchema(db, cfg.Database.SchemaCheck)
+
+	// 4. Wire repositories -> services -> handlers
+	userRepo := repository.NewUserRepository(db)
+	orgRepo := repository.NewOrganizationRepository(db)
+	invitationRepo := repository.NewInvitationRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	resetRepo := repository.NewPasswordResetTokenRepository(db)
+	outboxRepo := repository.NewOutboundEmailRepository(db)
+	agentRepo := repository.NewAgentRepository(db)
+	appRepo := repository.NewApplicationRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	resourceRepo := repository.NewResourceRepository(db)
+	resourceTypeRepo := repository.NewResourceTypeRepository(db)
+	leaseRepo := repository.NewCredentialLeaseRepository(db)
+	txManager := repository.NewTxManager(db)
+
+	sessionService, err := service.NewSessionService(refreshTokenRepo, userRepo, txManager, service.SessionConfig{
+		Secret:          []byte(cfg.Auth.JWTSecret),
+		Issuer:          cfg.Auth.Issuer,
+		AccessTokenTTL:  cfg.Auth.AccessTokenTTL,
+		RefreshTokenTTL: cfg.Auth.RefreshTokenTTL,
+	})
+	if err != nil {
+		logger.Log.Fatal("Failed to init session service", zap.Error(err))
+	}
+	keyProvider, err := secrets.NewKeyProvider(secrets.ProviderConfig{
+		Provider:          cfg.Secrets.KeyProvider,
+		MasterKeys:        cfg.Secrets.MasterKeys,
+		MasterKeyFile:     cfg.Secrets.MasterKeyFile,
+		CurrentKeyVersion: cfg.Secrets.CurrentKeyVersion,
+	})
+	if err != nil {
+		logger.Log.Fatal("Failed to init key provider", zap.Error(err))
+	}
+	mailer, err := mail.NewMailer(mail.Config{
+		Backend:      cfg.Mail.Backend,
+		From:         cfg.Mail.From,
+		SMTPHost:     cfg.Mail.SMTPHost,
+		SMTPPort:     cfg.Mail.SMTPPort,
+		SMTPUsername: cfg.Mail.SMTPUsername,
+		SMTPPassword: cfg.Mail.SMTPPassword,
+		OutboxDir:    cfg.Mail.OutboxDir,
+	})
+	if err != nil {
+		logger.Log.Fatal("Failed to init mailer", zap.Error(err))
+	}
+	mailTemplates, err := mail.LoadTemplates()
+	if err != nil {
+		logger.Log.Fatal("Failed to load mail templates", zap.Error(err))
+	}
+	mailService := service.NewMailService(outboxRepo, mailer, mailTemplates, service.MailConfig{
+		BaseURL:      cfg.Mail.BaseURL,
+		MaxAttempts:  cfg.Mail.MaxAttempts,
+		RetryBackoff: cfg.Mail.RetryBackoff,
+	})
+	auditService := service.NewAuditService(auditRepo)
+	identityService := service.NewIdentityService(userRepo, orgRepo, invitationRepo, resetRepo, txManager, auditService, sessionService, mailService)
+	agentService := service.NewAgentService(agentRepo, txManager, auditService)
+	applicationService := service.NewApplicationService(appRepo, txManager, auditService)
+	cipher := secrets.NewCipher(keyProvider)
+	drivers := connector.NewDefaultRegistry(connector.Options{
+		AllowPrivateNetworks: cfg.Connectors.AllowPrivateNetworks,
+	})
+	resourceService := service.NewResourceService(resourceRepo, agentRepo, txManager, auditService, cipher, drivers)
+	var operatorOrg uuid.UUID
+	if cfg.Catalog.OperatorOrganizationID != "" {
+		if operatorOrg, err = uuid.Parse(cfg.Catalog.OperatorOrganizationID); err != nil {
+			logger.Log.Fatal("Invalid catalog operator organization", zap.Error(err))
+		}
+	}
+	resourceTypeService := service.NewResourceTypeService(resourceTypeRepo, txManager, auditService, service.CatalogConfig{
+		OperatorOrganizationID: operatorOrg,
+	})
+	leaseService := service.NewCredentialLeaseService(leaseRepo, resourceRepo, agentRepo, appRepo, txManager, auditService, cipher, drivers, service.LeaseConfig{
+		ReadOnlyTTL:  cfg.Leases.ReadOnlyTTL,
+		ReadWriteTTL: cfg.Leases.ReadWriteTTL,
+	})
+
+	builtinTypes, err := catalog.BuiltinTypes()
+	if err != nil {
+		logger.Log.Fatal("Failed to load built-in resource types", zap.Error(err))
+	}
+	// The sync completes before the server starts listening, so no request sees
+	// a catalog missing a built-in type or still carrying an outdated schema.
+	syncCtx, cancelSync := context.WithTimeout(context.Background(), 30*time.Second)
+	syncReport, err := resourceTypeService.SyncBuiltinTypes(syncCtx, builtinTypes)
+	cancelSync()
+	if err != nil {
+		logger.Log.Fatal("Failed to sync built-in resource types", zap.Error(err))
+	}
+	logger.Log.Info("Built-in resource types synced",
+		zap.Strings("created", syncReport.Created),
+		zap.Strings("updated", syncReport.Updated),
+		zap.Int("unchanged", len(syncReport.Unchanged)))
+
+	authHandler := handler.NewAuthHandler(identityService, sessionService)
+	invitationHandler := handler.NewInvitationHandler(identityService)
+	agentHandler := handler.NewAgentHandler(agentService)
+	applicationHandler := handler.NewApplicationHandler(applicationService)
+	resourceHandler := handler.NewResourceHandler(resourceService)
+	resourceTypeHandler := handler.NewResourceTypeHandler(resourceTypeService)
+	leaseHandler := handler.NewCredentialLeaseHandler(leaseService)
+	auditHandler := handler.NewAuditHandler(auditService)
+
+	// 5. Setup Gin
 	if cfg.Server.Mode == "release" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 	r := gin.New()
-	r.Use(gin.Recovery())
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		logger.Log.Fatal("Invalid trusted proxies", zap.Error(err))
+	}
+	r.Use(gin.Recovery(), handler.CaptureClientIP())
 	// TODO: Add custom logger middleware
 
-	// 4. Routes
+	// 6. Routes
 	api := r.Group("/api/v1")
 	{
 		api.GET("/health", func(c *gin.Context) {
@@ -55,9 +183,31 @@ func main() {
 				"timestamp": time.Now().Unix(),
 			})
 		})
+
+		protected := api.Group("", handler.RequireAuth(sessionService))
+		authHandler.RegisterRoutes(api, protected)
+		invitationHandler.RegisterRoutes(api, protected)
+		agentHandler.RegisterRoutes(protected)
+		resourceHandler.RegisterRoutes(protected)
+		resourceTypeHandler.RegisterRoutes(protected)
+		auditHandler.RegisterRoutes(protected)
+
+		appAuthenticated := api.Group("", handler.RequireAPIKey(applicationService))
+		applicationHandler.RegisterAppRoutes(appAuthenticated)
+		leaseHandler.RegisterAppRoutes(appAuthenticated)
 	}
 
-	// 5. Start Server
+	// 7. Background jobs
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go expireLeases(jobsCtx, leaseService, cfg.Leases.ExpiryInterval)
+	go deliverEmails(jobsCtx, mailService, cfg.Mail.DeliveryInterval)
+	go expireInvitations(jobsCtx, identityService, cfg.Invitations.ExpiryInterval)
+	go identityService.ProcessPasswordResets(jobsCtx, func(err error) {
+		logger.Log.Error("Failed to process password reset request", zap.Error(err))
+	})
+
+	// 8. Start Server
 	srv := &http.Server{
 		Addr:    ":" + cfg.Server.Port,
 		Handler: r,
@@ -70,11 +220,12 @@ func main() {
 	}()
 	logger.Log.Info("Server listening", zap.String("port", cfg.Server.Port))
 
-	// 6. Graceful Shutdown
+	// 9. Graceful Shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	logger.Log.Info("Shutting down server...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -82,5 +233,100 @@ func main() {
 		logger.Log.Fatal("Server forced to shutdown:", zap.Error(err))
 	}
 
+	if sqlDB, err := db.DB(); err == nil {
+		_ = sqlDB.Close()
+	}
+
 	logger.Log.Info("Server exiting")
 }
+
+// expireLeases records the expiry of credential leases every interval until ctx is done.
+func expireLeases(ctx context.Context, leases service.CredentialLeaseService, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			expired, err := leases.ExpireLeases(ctx, now.UTC(), 100)
+			if err != nil {
+				logger.Log.Error("Failed to expire credential leases", zap.Error(err))
+			}
+			if expired > 0 {
+				logger.Log.Info("Credential leases expired", zap.Int("count", expired))
+			}
+		}
+	}
+}
+
+// expireInvitations marks overdue invitations expired every interval until ctx is done.
+func expireInvitations(ctx context.Context, identity service.IdentityService, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			expired, err := identity.ExpireInvitations(ctx, now.UTC())
+			if err != nil {
+				logger.Log.Error("Failed to expire invitations", zap.Error(err))
+			}
+			if expired > 0 {
+				logger.Log.Info("Invitations expired", zap.Int("count", expired))
+			}
+		}
+	}
+}
+
+// deliverEmails sends the queued emails every interval until ctx is done.
+func deliverEmails(ctx context.Context, mails service.MailService, interval time.Duration) {
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			sent, err := mails.DeliverPending(ctx, now.UTC(), 100)
+			if err != nil {
+				logger.Log.Error("Failed to deliver queued emails", zap.Error(err))
+			}
+			if sent > 0 {
+				logger.Log.Info("Queued emails delivered", zap.Int("count", sent))
+			}
+		}
+	}
+}
+
+// checkSchema logs every difference between the models and the database, and
+// aborts on any in "fail" mode.
+func checkSchema(db *gorm.DB, mode string) {
+	if mode == "off" {
+		return
+	}
+	drift, err := repository.CheckSchema(context.Background(), db)
+	if err != nil {
+		logger.Log.Fatal("Failed to check database schema", zap.Error(err))
+	}
+	for _, d := range drift {
+		logger.Log.Error("Database schema drift",
+			zap.String("table", d.Table),
+			zap.String("column", d.Column),
+			zap.String("enum", d.Enum),
+			zap.String("problem", d.Problem))
+	}
+	if len(drift) > 0 && mode == "fail" {
+		logger.Log.Fatal("Database schema does not match the models", zap.Int("differences", len(drift)))
+	}
+}
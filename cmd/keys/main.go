@@ -0,0 +1,139 @@
+// Command keys manages the master keys that wrap resource credentials.
+//
+//	keys generate -version v2   print a new keyring entry to append to secrets.master_keys
+//	keys status                 count secrets per master key version
+//	keys rotate [-batch 100]    rewrap every secret onto the current master key
+//
+// Rotating a master key is done without downtime: append the new entry to the
+// keyring and make it current, restart the API so new secrets use it, then run
+// "keys rotate". Secrets keep decrypting with their old version until rewrapped;
+// retire the old entry only once "keys status" reports nothing pending.
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"agentXmap/internal/repository"
+	"agentXmap/internal/secrets"
+	"agentXmap/internal/service"
+	"agentXmap/pkg/config"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "generate":
+		err = generate(os.Args[2:])
+	case "status":
+		err = withRotationService(func(ctx context.Context, svc service.KeyRotationService) error {
+			return status(ctx, svc)
+		})
+	case "rotate":
+		err = rotate(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: keys generate -version <name> | status | rotate [-batch n]")
+	os.Exit(2)
+}
+
+func generate(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	version := fs.String("version", "", "name of the new key version, e.g. v2")
+	_ = fs.Parse(args)
+	if *version == "" {
+		return fmt.Errorf("-version is required")
+	}
+
+	key, err := secrets.GenerateMasterKey()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s:%s\n", *version, base64.StdEncoding.EncodeToString(key))
+	return nil
+}
+
+func status(ctx context.Context, svc service.KeyRotationService) error {
+	st, err := svc.Status(ctx)
+	if err != nil {
+		return err
+	}
+	return printJSON(st)
+}
+
+func rotate(args []string) error {
+	fs := flag.NewFlagSet("rotate", flag.ExitOnError)
+	batch := fs.Int("batch", service.DefaultRotationBatchSize, "secrets rewrapped per batch")
+	_ = fs.Parse(args)
+
+	return withRotationService(func(ctx context.Context, svc service.KeyRotationService) error {
+		progress, err := svc.RotateSecrets(ctx, *batch, func(p service.KeyRotationProgress) {
+			fmt.Fprintf(os.Stderr, "batch %d: %d/%d rewrapped to %s, %d skipped, %d failed\n",
+				p.Batches, p.Rewrapped, p.Total, p.TargetKeyVersion, p.Skipped, len(p.Failures))
+		})
+		if progress != nil {
+			if perr := printJSON(progress); perr != nil && err == nil {
+				err = perr
+			}
+		}
+		if err == nil && progress != nil && len(progress.Failures) > 0 {
+			err = fmt.Errorf("%d secrets could not be rewrapped", len(progress.Failures))
+		}
+		return err
+	})
+}
+
+// withRotationService connects to the database configured for the API and runs
+// fn until it returns or the process is interrupted.
+func withRotationService(fn func(ctx context.Context, svc service.KeyRotationService) error) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	keyProvider, err := secrets.NewKeyProvider(secrets.ProviderConfig{
+		Provider:          cfg.Secrets.KeyProvider,
+		MasterKeys:        cfg.Secrets.MasterKeys,
+		MasterKeyFile:     cfg.Secrets.MasterKeyFile,
+		CurrentKeyVersion: cfg.Secrets.CurrentKeyVersion,
+	})
+	if err != nil {
+		return fmt.Errorf("init key provider: %w", err)
+	}
+	db, err := repository.InitDB(*cfg)
+	if err != nil {
+		return err
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	svc := service.NewKeyRotationService(repository.NewResourceRepository(db), secrets.NewCipher(keyProvider))
+	return fn(ctx, svc)
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,76 @@
+// Command schema compares the GORM models with the live database.
+//
+//	schema check   report missing tables and columns, type and enum mismatches
+//
+// check prints the differences as JSON and exits non-zero if there are any,
+// so it can gate a deployment after database/schemas/01_schema.sql changed.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"agentXmap/internal/repository"
+	"agentXmap/pkg/config"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "check":
+		err = check()
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: schema check")
+	os.Exit(2)
+}
+
+func check() error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	db, err := repository.InitDB(*cfg)
+	if err != nil {
+		return err
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	drift, err := repository.CheckSchema(ctx, db)
+	if err != nil {
+		return err
+	}
+	if drift == nil {
+		drift = []repository.SchemaDrift{}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(drift); err != nil {
+		return err
+	}
+	if len(drift) > 0 {
+		return fmt.Errorf("%d differences between the models and the database", len(drift))
+	}
+	return nil
+}
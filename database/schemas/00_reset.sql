@@ -16,9 +16,11 @@ DROP TABLE IF EXISTS certifications CASCADE;
 DROP TABLE IF EXISTS agent_executions CASCADE; -- Will drop partitions too
 DROP TABLE IF EXISTS system_audit_logs CASCADE;
 
+DROP TABLE IF EXISTS credential_leases CASCADE;
 DROP TABLE IF EXISTS agent_resource_access CASCADE;
 DROP TABLE IF EXISTS resource_secrets CASCADE;
 DROP TABLE IF EXISTS resources CASCADE;
+DROP TABLE IF EXISTS resource_type_schema_versions CASCADE;
 DROP TABLE IF EXISTS resource_types CASCADE;
 
 DROP TABLE IF EXISTS application_agent_access CASCADE;
@@ -30,9 +32,14 @@ DROP TABLE IF EXISTS llm_models CASCADE;
 DROP TABLE IF EXISTS llm_providers CASCADE;
 
 DROP TABLE IF EXISTS agent_assignments CASCADE;
+DROP TABLE IF EXISTS agent_change_requests CASCADE;
+DROP TABLE IF EXISTS agent_status_transitions CASCADE;
 DROP TABLE IF EXISTS agent_versions CASCADE;
 DROP TABLE IF EXISTS agents CASCADE;
 
+DROP TABLE IF EXISTS outbound_emails CASCADE;
+DROP TABLE IF EXISTS password_reset_tokens CASCADE;
+DROP TABLE IF EXISTS refresh_tokens CASCADE;
 DROP TABLE IF EXISTS users CASCADE;
 DROP TABLE IF EXISTS organizations CASCADE;
 
@@ -41,10 +48,14 @@ DROP TYPE IF EXISTS audit_action CASCADE;
 DROP TYPE IF EXISTS access_level CASCADE;
 DROP TYPE IF EXISTS billing_cycle CASCADE;
 DROP TYPE IF EXISTS agent_status CASCADE;
+DROP TYPE IF EXISTS agent_risk_level CASCADE;
+DROP TYPE IF EXISTS change_request_status CASCADE;
 DROP TYPE IF EXISTS user_role CASCADE;
+DROP TYPE IF EXISTS email_status CASCADE;
 
 -- 3. Drop Functions
 DROP FUNCTION IF EXISTS update_updated_at_column CASCADE;
+DROP FUNCTION IF EXISTS forbid_audit_log_change CASCADE;
 
 COMMIT;
 
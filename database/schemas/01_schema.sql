@@ -30,9 +30,12 @@ $$ language 'plpgsql';
 CREATE TYPE user_role AS ENUM ('manager', 'admin', 'user');
 CREATE TYPE agent_status AS ENUM ('active', 'inactive', 'maintenance', 'deprecated');
 CREATE TYPE billing_cycle AS ENUM ('monthly', 'yearly', 'one_time', 'custom');
+CREATE TYPE agent_risk_level AS ENUM ('minimal', 'limited', 'high');
+CREATE TYPE change_request_status AS ENUM ('pending', 'approved', 'rejected');
 CREATE TYPE access_level AS ENUM ('read_only', 'read_write');
-CREATE TYPE audit_action AS ENUM ('create', 'update', 'delete', 'login', 'export_data');
+CREATE TYPE audit_action AS ENUM ('create', 'update', 'delete', 'login', 'export_data', 'approve', 'reject', 'read_secret', 'restore', 'issue_lease', 'expire_lease', 'test_connection');
 CREATE TYPE invitation_status AS ENUM ('pending', 'accepted', 'expired', 'revoked');
+CREATE TYPE email_status AS ENUM ('pending', 'sent', 'failed');
 
 -- ============================================================
 -- 2. CORE: IDENTITY & TENANCY
@@ -76,6 +79,55 @@ CREATE TABLE invitations (
     updated_at TIMESTAMP DEFAULT NOW()
 );
 CREATE TRIGGER update_invitations_modtime BEFORE UPDATE ON invitations FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE INDEX idx_invitations_org ON invitations(organization_id, created_at);
+CREATE INDEX idx_invitations_pending_expiry ON invitations(expires_at) WHERE status = 'pending';
+
+-- Server-side refresh tokens (only the SHA-256 hash is stored)
+CREATE TABLE refresh_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- The session the token belongs to, kept across rotations; access tokens carry it as "sid".
+    session_id UUID NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    -- Deferred: a token is revoked in favour of its successor before the successor is inserted.
+    replaced_by_id UUID REFERENCES refresh_tokens(id) ON DELETE SET NULL DEFERRABLE INITIALLY DEFERRED,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+CREATE INDEX idx_refresh_tokens_user ON refresh_tokens(user_id);
+CREATE INDEX idx_refresh_tokens_session ON refresh_tokens(session_id) WHERE revoked_at IS NULL;
+
+-- Single-use password reset tokens (only the SHA-256 hash is stored);
+-- consumed_at is set once the token is used or superseded.
+CREATE TABLE password_reset_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    consumed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+CREATE INDEX idx_password_reset_tokens_user ON password_reset_tokens(user_id);
+
+-- Email outbox: rendered emails are queued in the transaction that warrants
+-- them and delivered with retries. Bodies are cleared once sent or failed.
+CREATE TABLE outbound_emails (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    recipient VARCHAR(255) NOT NULL,
+    template VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    text_body TEXT,
+    html_body TEXT,
+    status email_status NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error TEXT,
+    sent_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+CREATE INDEX idx_outbound_emails_due ON outbound_emails(next_attempt_at) WHERE status = 'pending';
 
 -- ============================================================
 -- 3. AGENT DOMAIN
@@ -86,6 +138,7 @@ CREATE TABLE agents (
     organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
     name VARCHAR(255) NOT NULL,
     status agent_status DEFAULT 'active',
+    risk_level agent_risk_level DEFAULT 'minimal',
 
     -- Financials
     cost_amount DECIMAL(10,2) DEFAULT 0.00,
@@ -118,6 +171,35 @@ CREATE TABLE agent_versions (
 );
 COMMENT ON TABLE agent_versions IS 'Immutable snapshot for AI Governance (EU AI Act)';
 
+CREATE TABLE agent_change_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    agent_id UUID NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    proposed_configuration JSONB NOT NULL,
+    base_version_number INT NOT NULL DEFAULT 0,
+    reason VARCHAR(255) NOT NULL,
+    status change_request_status DEFAULT 'pending',
+    requested_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    review_comment TEXT,
+    reviewed_at TIMESTAMP,
+    applied_version_id UUID REFERENCES agent_versions(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+CREATE INDEX idx_agent_change_requests_agent ON agent_change_requests(agent_id, status);
+COMMENT ON TABLE agent_change_requests IS 'Approval workflow for configuration changes of high-risk agents';
+
+CREATE TABLE agent_status_transitions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    agent_id UUID NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    from_status agent_status NOT NULL,
+    to_status agent_status NOT NULL,
+    reason VARCHAR(255),
+    changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+CREATE INDEX idx_agent_status_transitions_agent ON agent_status_transitions(agent_id, created_at);
+COMMENT ON TABLE agent_status_transitions IS 'Lifecycle history of agents';
+
 CREATE TABLE agent_assignments (
     agent_id UUID NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
@@ -181,7 +263,8 @@ CREATE TABLE application_keys (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     application_id UUID NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
     key_hash VARCHAR(255) NOT NULL,
-    key_prefix VARCHAR(8) NOT NULL,
+    lookup_id VARCHAR(16) UNIQUE, -- Public identifier embedded in the raw key (O(1) lookup)
+    key_prefix VARCHAR(16) NOT NULL,
     name VARCHAR(100),
     last_used_at TIMESTAMP,
     expires_at TIMESTAMP,
@@ -207,10 +290,24 @@ CREATE TABLE resource_types (
     name VARCHAR(100) NOT NULL,
     config_schema JSONB DEFAULT '{}',
     secret_schema JSONB DEFAULT '{}',
+    schema_version INT NOT NULL DEFAULT 1,
     is_active BOOLEAN DEFAULT TRUE,
+    is_builtin BOOLEAN DEFAULT FALSE, -- owned by internal/catalog, synced at API startup
     created_at TIMESTAMP DEFAULT NOW(),
     updated_at TIMESTAMP DEFAULT NOW()
 );
+CREATE TRIGGER update_resource_types_modtime BEFORE UPDATE ON resource_types FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TABLE resource_type_schema_versions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    type_id VARCHAR(50) NOT NULL REFERENCES resource_types(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    version INT NOT NULL,
+    config_schema JSONB DEFAULT '{}',
+    secret_schema JSONB DEFAULT '{}',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(type_id, version)
+);
 
 CREATE TABLE resources (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -218,6 +315,7 @@ CREATE TABLE resources (
     type_id VARCHAR(50) NOT NULL REFERENCES resource_types(id) ON UPDATE CASCADE,
     name VARCHAR(255) NOT NULL,
     connection_details JSONB DEFAULT '{}',
+    last_connection_test JSONB,
     created_at TIMESTAMP DEFAULT NOW(),
     updated_at TIMESTAMP DEFAULT NOW(),
     deleted_at TIMESTAMP
@@ -239,9 +337,25 @@ CREATE TABLE agent_resource_access (
     resource_id UUID NOT NULL REFERENCES resources(id) ON DELETE CASCADE,
     permission access_level DEFAULT 'read_only',
     granted_at TIMESTAMP DEFAULT NOW(),
+    deleted_at TIMESTAMP, -- set with the resource's; see resources.deleted_at
     UNIQUE(agent_id, resource_id)
 );
 
+-- Short-lived credentials handed to agents; expired_at is set once the expiry is audited.
+CREATE TABLE credential_leases (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    resource_id UUID NOT NULL REFERENCES resources(id) ON DELETE CASCADE,
+    agent_id UUID NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    application_id UUID REFERENCES applications(id) ON DELETE SET NULL,
+    access_level access_level NOT NULL,
+    key_version_id VARCHAR(50),
+    issued_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    expired_at TIMESTAMP
+);
+CREATE INDEX idx_credential_leases_pending_expiry ON credential_leases(expires_at) WHERE expired_at IS NULL;
+
 -- ============================================================
 -- 7. TRUST & CERTIFICATIONS
 -- ============================================================
@@ -292,20 +406,37 @@ CREATE TABLE application_certifications (
 -- 8. COMPLIANCE & BIG DATA (PARTITIONING)
 -- ============================================================
 
+-- Append-only: each row hashes its content with the previous row of the same
+-- organization (see SystemAuditLog.ChainHash), so edits and removals break the chain.
 CREATE TABLE system_audit_logs (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    -- RESTRICT: an organization with an audit trail cannot be deleted.
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE RESTRICT,
+    seq BIGINT NOT NULL, -- Position in the organization's chain, from 1
     actor_user_id UUID, -- Nullable if system action
     entity_type VARCHAR(50) NOT NULL,
     entity_id UUID NOT NULL,
     action audit_action NOT NULL,
     changes_json JSONB,
     ip_address VARCHAR(45),
-    occurred_at TIMESTAMP DEFAULT NOW()
+    occurred_at TIMESTAMP DEFAULT NOW(),
+    prev_hash VARCHAR(64) NOT NULL, -- Empty for the first entry
+    hash VARCHAR(64) NOT NULL,
+    UNIQUE(organization_id, seq)
 );
 CREATE INDEX idx_audit_org_date ON system_audit_logs(organization_id, occurred_at);
 CREATE INDEX idx_audit_entity ON system_audit_logs(entity_id);
 
+CREATE OR REPLACE FUNCTION forbid_audit_log_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'system_audit_logs is append-only (% rejected)', TG_OP;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER audit_logs_append_only BEFORE UPDATE OR DELETE ON system_audit_logs FOR EACH ROW EXECUTE FUNCTION forbid_audit_log_change();
+CREATE TRIGGER audit_logs_no_truncate BEFORE TRUNCATE ON system_audit_logs FOR EACH STATEMENT EXECUTE FUNCTION forbid_audit_log_change();
+
 -- Partitioned Table for Executions
 -- NOTE: In partitioned tables, the PK MUST include the partition key.
 CREATE TABLE agent_executions (
@@ -115,13 +115,11 @@ INSERT INTO certifications (name, issuing_authority, description, official_link)
 ON CONFLICT (name) DO NOTHING;
 
 -- ============================================================
--- 4. SEED: RESOURCE TYPES (Standard Tools)
+-- 4. RESOURCE TYPES
 -- ============================================================
-INSERT INTO resource_types (id, name, config_schema, secret_schema) VALUES
-    ('postgres_db', 'PostgreSQL Database', '{"host": "string", "port": "integer", "dbname": "string", "sslmode": "string"}', '{"username": "string", "password": "string"}'),
-    ('aws_s3', 'AWS S3 Bucket', '{"bucket_name": "string", "region": "string"}', '{"access_key_id": "string", "secret_access_key": "string"}'),
-    ('rest_api', 'REST API Endpoint', '{"base_url": "string", "timeout_seconds": "integer"}', '{"api_key": "string", "bearer_token": "string"}')
-ON CONFLICT (id) DO NOTHING;
+-- Built-in resource types (PostgreSQL, MySQL, REST API, S3, Slack) are not seeded
+-- here: internal/catalog owns them and the API syncs them into resource_types at
+-- startup, bumping schema_version whenever an embedded schema changes.
 
 COMMIT;
 
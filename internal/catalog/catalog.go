@@ -0,0 +1,27 @@
+// Package catalog embeds the built-in ResourceTypes. They are the source of
+// truth for their rows in resource_types, which the API reconciles at startup.
+package catalog
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"agentXmap/internal/domain"
+)
+
+//go:embed builtin_types.json
+var builtinTypesJSON []byte
+
+// BuiltinTypes returns the built-in ResourceTypes, active and marked IsBuiltin.
+func BuiltinTypes() ([]domain.ResourceType, error) {
+	var types []domain.ResourceType
+	if err := json.Unmarshal(builtinTypesJSON, &types); err != nil {
+		return nil, fmt.Errorf("catalog: %w", err)
+	}
+	for i := range types {
+		types[i].IsActive = true
+		types[i].IsBuiltin = true
+	}
+	return types, nil
+}
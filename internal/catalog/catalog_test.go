@@ -0,0 +1,23 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltinTypes(t *testing.T) {
+	types, err := BuiltinTypes()
+	assert.NoError(t, err)
+
+	seen := make(map[string]bool)
+	for _, rt := range types {
+		assert.False(t, seen[rt.ID], "duplicate type %s", rt.ID)
+		seen[rt.ID] = true
+		assert.NotEmpty(t, rt.Name, rt.ID)
+		assert.True(t, rt.IsActive && rt.IsBuiltin, rt.ID)
+	}
+	for _, id := range []string{"postgres_db", "mysql_db", "rest_api", "aws_s3", "slack"} {
+		assert.True(t, seen[id], id)
+	}
+}
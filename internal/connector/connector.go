@@ -0,0 +1,140 @@
+// Package connector holds the drivers that know how to reach each ResourceType.
+// A driver receives the validated connection details and the decrypted
+// credentials of a resource and never persists either.
+package connector
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ErrorCode classifies why a connection test failed.
+type ErrorCode string
+
+const (
+	ErrorMisconfigured      ErrorCode = "misconfigured"
+	ErrorAddressNotAllowed  ErrorCode = "address_not_allowed"
+	ErrorUnreachable        ErrorCode = "unreachable"
+	ErrorTimeout            ErrorCode = "timeout"
+	ErrorTLS                ErrorCode = "tls"
+	ErrorAuthFailed         ErrorCode = "auth_failed"
+	ErrorNotFound           ErrorCode = "not_found"
+	ErrorUnexpectedResponse ErrorCode = "unexpected_response"
+	ErrorUnknown            ErrorCode = "unknown"
+)
+
+// Error is the structured failure of a connection test.
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Driver reaches the system behind one ResourceType.
+type Driver interface {
+	// TestConnection connects and authenticates with config and credentials,
+	// returning an *Error describing the first failure. ctx bounds the attempt.
+	TestConnection(ctx context.Context, config, credentials json.RawMessage) error
+}
+
+// Issuer is implemented by drivers that can mint credentials of their own,
+// which the system behind the resource stops accepting after a deadline.
+type Issuer interface {
+	// IssueCredentials uses the resource's credentials to create new ones that
+	// carry no more privileges, are limited to reading when readOnly is set and
+	// cannot be used to authenticate after expiresAt.
+	IssueCredentials(ctx context.Context, config, credentials json.RawMessage, readOnly bool, expiresAt time.Time) (json.RawMessage, error)
+}
+
+// Registry maps ResourceType IDs to their Driver.
+type Registry struct {
+	drivers map[string]Driver
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{drivers: make(map[string]Driver)}
+}
+
+// NewDefaultRegistry registers the built-in drivers under the seeded ResourceType IDs.
+func NewDefaultRegistry(opts Options) *Registry {
+	r := NewRegistry()
+	r.Register("postgres_db", NewPostgresDriver(opts))
+	r.Register("rest_api", NewHTTPDriver(opts))
+	r.Register("aws_s3", NewS3Driver(opts))
+	return r
+}
+
+// Register binds driver to typeID, replacing any previous one.
+func (r *Registry) Register(typeID string, driver Driver) {
+	r.drivers[typeID] = driver
+}
+
+// Get returns the driver of typeID, if any.
+func (r *Registry) Get(typeID string) (Driver, bool) {
+	d, ok := r.drivers[typeID]
+	return d, ok
+}
+
+// decode unmarshals a config or credentials document, reporting malformed ones as misconfigured.
+func decode(raw json.RawMessage, v any, what string) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &Error{Code: ErrorMisconfigured, Message: "invalid " + what, Err: err}
+	}
+	return nil
+}
+
+// classify turns a transport error into an *Error.
+func classify(err error) *Error {
+	var cerr *Error
+	if errors.As(err, &cerr) {
+		return cerr
+	}
+
+	var (
+		dnsErr     *net.DNSError
+		netErr     net.Error
+		certErr    *tls.CertificateVerificationError
+		unknownCA  x509.UnknownAuthorityError
+		hostErr    x509.HostnameError
+		recordErr  tls.RecordHeaderError
+		invalidErr x509.CertificateInvalidError
+	)
+	switch {
+	case errors.Is(err, errAddressNotAllowed):
+		return &Error{Code: ErrorAddressNotAllowed, Message: "host resolves to an address that may not be reached", Err: err}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Code: ErrorTimeout, Message: "connection timed out", Err: err}
+	case errors.As(err, &certErr), errors.As(err, &unknownCA), errors.As(err, &hostErr),
+		errors.As(err, &recordErr), errors.As(err, &invalidErr):
+		return &Error{Code: ErrorTLS, Message: "TLS handshake failed", Err: err}
+	case errors.As(err, &dnsErr):
+		return &Error{Code: ErrorUnreachable, Message: "host could not be resolved", Err: err}
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return &Error{Code: ErrorTimeout, Message: "connection timed out", Err: err}
+	case errors.As(err, &netErr):
+		return &Error{Code: ErrorUnreachable, Message: "host is unreachable", Err: err}
+	default:
+		return &Error{Code: ErrorUnknown, Message: "connection failed", Err: err}
+	}
+}
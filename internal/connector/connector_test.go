@@ -0,0 +1,266 @@
+package connector
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func errorCode(t *testing.T, err error) ErrorCode {
+	t.Helper()
+	cerr, ok := err.(*Error)
+	if !assert.True(t, ok, "want *Error, got %T: %v", err, err) {
+		return ""
+	}
+	return cerr.Code
+}
+
+// loopback lets the drivers reach the test servers, which listen on 127.0.0.1.
+var loopback = Options{AllowPrivateNetworks: true}
+
+// closedAddr returns a local address nothing listens on.
+func closedAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+func TestHTTPDriver_TestConnection(t *testing.T) {
+	ctx := context.Background()
+	var gotAuth, gotKey string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth, gotKey = r.Header.Get("Authorization"), r.Header.Get("X-API-Key")
+		switch r.URL.Path {
+		case "/denied":
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/broken":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	driver := NewHTTPDriver(loopback)
+
+	t.Run("Success Sends Credentials", func(t *testing.T) {
+		err := driver.TestConnection(ctx, json.RawMessage(`{"base_url":"`+srv.URL+`/v1"}`), json.RawMessage(`{"bearer_token":"tok","api_key":"key"}`))
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer tok", gotAuth)
+		assert.Equal(t, "key", gotKey)
+	})
+
+	t.Run("Auth Failed", func(t *testing.T) {
+		err := driver.TestConnection(ctx, json.RawMessage(`{"base_url":"`+srv.URL+`/denied"}`), nil)
+		assert.Equal(t, ErrorAuthFailed, errorCode(t, err))
+		assert.NotContains(t, err.Error(), "401")
+	})
+
+	t.Run("Server Error", func(t *testing.T) {
+		err := driver.TestConnection(ctx, json.RawMessage(`{"base_url":"`+srv.URL+`/broken"}`), nil)
+		assert.Equal(t, ErrorUnexpectedResponse, errorCode(t, err))
+		assert.NotContains(t, err.Error(), "502")
+	})
+
+	t.Run("Private Address Refused", func(t *testing.T) {
+		var reached bool
+		guarded := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { reached = true }))
+		defer guarded.Close()
+
+		for _, baseURL := range []string{guarded.URL, strings.Replace(guarded.URL, "127.0.0.1", "localhost", 1)} {
+			err := NewHTTPDriver(Options{}).TestConnection(ctx, json.RawMessage(`{"base_url":"`+baseURL+`"}`), nil)
+			assert.Equal(t, ErrorAddressNotAllowed, errorCode(t, err), baseURL)
+		}
+		assert.False(t, reached)
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		err := driver.TestConnection(ctx, json.RawMessage(`{"base_url":"http://`+closedAddr(t)+`"}`), nil)
+		assert.Equal(t, ErrorUnreachable, errorCode(t, err))
+	})
+
+	t.Run("Misconfigured", func(t *testing.T) {
+		err := driver.TestConnection(ctx, json.RawMessage(`{"base_url":"ftp://example.com"}`), nil)
+		assert.Equal(t, ErrorMisconfigured, errorCode(t, err))
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+		defer slow.Close()
+		ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+
+		err := driver.TestConnection(ctx, json.RawMessage(`{"base_url":"`+slow.URL+`"}`), nil)
+		assert.Equal(t, ErrorTimeout, errorCode(t, err))
+	})
+}
+
+func TestS3Driver_TestConnection(t *testing.T) {
+	ctx := context.Background()
+	var gotReq *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotReq = r
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/elsewhere":
+			w.Header().Set("X-Amz-Bucket-Region", "eu-west-3")
+			w.WriteHeader(http.StatusMovedPermanently)
+		case "/private":
+			w.WriteHeader(http.StatusForbidden)
+		case "/teapot":
+			w.WriteHeader(http.StatusTeapot)
+		}
+	}))
+	defer srv.Close()
+	driver := NewS3Driver(loopback)
+	creds := json.RawMessage(`{"access_key_id":"AKID","secret_access_key":"secret"}`)
+	config := func(bucket string) json.RawMessage {
+		return json.RawMessage(`{"bucket_name":"` + bucket + `","region":"eu-west-1","endpoint":"` + srv.URL + `"}`)
+	}
+
+	t.Run("Success Is Signed", func(t *testing.T) {
+		err := driver.TestConnection(ctx, config("reports"), creds)
+		assert.NoError(t, err)
+		assert.Equal(t, http.MethodHead, gotReq.Method)
+		assert.Equal(t, "/reports", gotReq.URL.Path)
+		auth := gotReq.Header.Get("Authorization")
+		assert.True(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/"), auth)
+		assert.Contains(t, auth, "/eu-west-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=")
+	})
+
+	t.Run("Bucket Not Found", func(t *testing.T) {
+		assert.Equal(t, ErrorNotFound, errorCode(t, driver.TestConnection(ctx, config("missing"), creds)))
+	})
+
+	t.Run("Access Denied", func(t *testing.T) {
+		assert.Equal(t, ErrorAuthFailed, errorCode(t, driver.TestConnection(ctx, config("private"), creds)))
+	})
+
+	t.Run("Unexpected Status", func(t *testing.T) {
+		err := driver.TestConnection(ctx, config("teapot"), creds)
+		assert.Equal(t, ErrorUnexpectedResponse, errorCode(t, err))
+		assert.NotContains(t, err.Error(), "418")
+	})
+
+	t.Run("Wrong Region", func(t *testing.T) {
+		err := driver.TestConnection(ctx, config("elsewhere"), creds)
+		assert.Equal(t, ErrorMisconfigured, errorCode(t, err))
+		assert.Contains(t, err.Error(), "eu-west-3")
+	})
+
+	t.Run("Missing Credentials", func(t *testing.T) {
+		assert.Equal(t, ErrorMisconfigured, errorCode(t, driver.TestConnection(ctx, config("reports"), nil)))
+	})
+}
+
+func TestSigningKey(t *testing.T) {
+	// Example from the AWS Signature Version 4 documentation.
+	key := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	assert.Equal(t, "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d", hex.EncodeToString(key))
+}
+
+func TestPostgresDriver_TestConnection(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Unreachable", func(t *testing.T) {
+		host, port, _ := net.SplitHostPort(closedAddr(t))
+		err := NewPostgresDriver(loopback).TestConnection(ctx,
+			json.RawMessage(`{"host":"`+host+`","port":`+port+`,"dbname":"app","sslmode":"disable"}`),
+			json.RawMessage(`{"username":"app","password":"pw"}`))
+		assert.Equal(t, ErrorUnreachable, errorCode(t, err))
+		assert.NotContains(t, err.Error(), "pw")
+	})
+
+	t.Run("Private Address Refused", func(t *testing.T) {
+		err := NewPostgresDriver(Options{}).TestConnection(ctx,
+			json.RawMessage(`{"host":"169.254.169.254","port":5432,"dbname":"app","sslmode":"disable"}`), nil)
+		assert.Equal(t, ErrorAddressNotAllowed, errorCode(t, err))
+	})
+
+	t.Run("Misconfigured", func(t *testing.T) {
+		err := NewPostgresDriver(loopback).TestConnection(ctx, json.RawMessage(`{"dbname":"app"}`), nil)
+		assert.Equal(t, ErrorMisconfigured, errorCode(t, err))
+	})
+}
+
+func TestPostgresDriver_IssueCredentials(t *testing.T) {
+	ctx := context.Background()
+	expiresAt := time.Date(2026, 3, 1, 12, 5, 0, 0, time.FixedZone("CET", 3600))
+
+	t.Run("Statements", func(t *testing.T) {
+		issued := postgresCredentials{Username: "axm_lease_0123456789abcdef", Password: "p4ss"}
+
+		assert.Equal(t, []string{
+			`CREATE ROLE "axm_lease_0123456789abcdef" LOGIN INHERIT PASSWORD 'p4ss' VALID UNTIL '2026-03-01T11:05:00Z' IN ROLE "app"`,
+		}, leaseRoleStatements(issued, "app", false, expiresAt))
+		assert.Equal(t, []string{
+			`CREATE ROLE "axm_lease_0123456789abcdef" LOGIN INHERIT PASSWORD 'p4ss' VALID UNTIL '2026-03-01T11:05:00Z' IN ROLE "o""wner"`,
+			`ALTER ROLE "axm_lease_0123456789abcdef" SET default_transaction_read_only = on`,
+		}, leaseRoleStatements(issued, `o"wner`, true, expiresAt))
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		host, port, _ := net.SplitHostPort(closedAddr(t))
+		_, err := NewPostgresDriver(loopback).IssueCredentials(ctx,
+			json.RawMessage(`{"host":"`+host+`","port":`+port+`,"dbname":"app","sslmode":"disable"}`),
+			json.RawMessage(`{"username":"app","password":"pw"}`), true, expiresAt)
+		assert.Equal(t, ErrorUnreachable, errorCode(t, err))
+		assert.NotContains(t, err.Error(), "pw")
+	})
+}
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"fd00:ec2::254", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+		{"224.0.0.1", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, publicAddr(netip.MustParseAddr(tt.addr)), tt.addr)
+	}
+}
+
+func TestNewDefaultRegistry(t *testing.T) {
+	r := NewDefaultRegistry(Options{})
+	for _, id := range []string{"postgres_db", "rest_api", "aws_s3"} {
+		_, ok := r.Get(id)
+		assert.True(t, ok, id)
+	}
+	_, ok := r.Get("mongo")
+	assert.False(t, ok)
+
+	postgres, _ := r.Get("postgres_db")
+	_, ok = postgres.(Issuer)
+	assert.True(t, ok, "postgres_db issues credentials")
+	rest, _ := r.Get("rest_api")
+	_, ok = rest.(Issuer)
+	assert.False(t, ok, "rest_api cannot issue credentials")
+}
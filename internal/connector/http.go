@@ -0,0 +1,73 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// HTTPDriver tests rest_api resources with an authenticated GET of the base URL.
+// Any response other than 401, 403 or a server error counts as a success: the
+// base URL of an API commonly answers 404 while the API itself works.
+type HTTPDriver struct {
+	client *http.Client
+}
+
+// NewHTTPDriver creates an HTTPDriver that does not follow redirects.
+func NewHTTPDriver(opts Options) *HTTPDriver {
+	noRedirect := func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	return &HTTPDriver{client: httpClient(opts, noRedirect)}
+}
+
+type httpConfig struct {
+	BaseURL string `json:"base_url"`
+}
+
+type httpCredentials struct {
+	APIKey      string `json:"api_key"`
+	BearerToken string `json:"bearer_token"`
+}
+
+func (d *HTTPDriver) TestConnection(ctx context.Context, config, credentials json.RawMessage) error {
+	var cfg httpConfig
+	if err := decode(config, &cfg, "connection details"); err != nil {
+		return err
+	}
+	var creds httpCredentials
+	if err := decode(credentials, &creds, "credentials"); err != nil {
+		return err
+	}
+	u, err := url.Parse(cfg.BaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &Error{Code: ErrorMisconfigured, Message: "base_url must be an absolute http(s) URL"}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return &Error{Code: ErrorMisconfigured, Message: "invalid request", Err: err}
+	}
+	if creds.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+creds.BearerToken)
+	}
+	if creds.APIKey != "" {
+		req.Header.Set("X-API-Key", creds.APIKey)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return classify(err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+		return &Error{Code: ErrorAuthFailed, Message: "server rejected the credentials"}
+	case resp.StatusCode >= 500:
+		return &Error{Code: ErrorUnexpectedResponse, Message: "server failed to answer"}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,69 @@
+package connector
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// errAddressNotAllowed is returned by the dialer for addresses outside the public internet.
+var errAddressNotAllowed = errors.New("address not allowed")
+
+// Options governs which networks the drivers may reach.
+type Options struct {
+	// AllowPrivateNetworks lets drivers connect to loopback, private,
+	// link-local and other non-public addresses. Off, a resource cannot be
+	// used to probe the network the API runs in or its cloud metadata endpoint.
+	AllowPrivateNetworks bool
+}
+
+// nonPublicPrefixes are the ranges that netip.Addr's predicates consider
+// global unicast although they are not reachable on the public internet.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which embeds IPv4 addresses
+}
+
+// publicAddr reports whether addr is a unicast address on the public internet.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// dialer returns the dialer every driver connects through. Unless opts allows
+// private networks, it checks each resolved address right before connecting,
+// so a hostname cannot resolve or rebind to an internal address.
+func dialer(opts Options) *net.Dialer {
+	d := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	if !opts.AllowPrivateNetworks {
+		d.Control = func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !publicAddr(addrPort.Addr()) {
+				return errAddressNotAllowed
+			}
+			return nil
+		}
+	}
+	return d
+}
+
+// httpClient returns a client dialing through dialer(opts). It ignores proxy
+// environment variables so that the address check applies to the target itself.
+func httpClient(opts Options, checkRedirect func(*http.Request, []*http.Request) error) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer(opts).DialContext
+	return &http.Client{Transport: transport, CheckRedirect: checkRedirect}
+}
@@ -0,0 +1,178 @@
+package connector
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// leaseRolePrefix names the roles IssueCredentials creates.
+const leaseRolePrefix = "axm_lease_"
+
+// PostgresDriver tests postgres_db resources by opening a session and pinging
+// it, and issues them expiring login roles.
+type PostgresDriver struct {
+	dial pgconn.DialFunc
+}
+
+// NewPostgresDriver creates a PostgresDriver.
+func NewPostgresDriver(opts Options) *PostgresDriver {
+	return &PostgresDriver{dial: dialer(opts).DialContext}
+}
+
+type postgresConfig struct {
+	Host    string `json:"host"`
+	Port    int    `json:"port"`
+	DBName  string `json:"dbname"`
+	SSLMode string `json:"sslmode"`
+}
+
+type postgresCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+func (d *PostgresDriver) TestConnection(ctx context.Context, config, credentials json.RawMessage) error {
+	conn, _, err := d.connect(ctx, config, credentials)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if err := conn.Ping(ctx); err != nil {
+		return classifyPostgres(err)
+	}
+	return nil
+}
+
+// IssueCredentials creates a login role whose password stops working at
+// expiresAt. The role is a member of the resource's own role, so it has its
+// privileges and no others; a read-only one also defaults every transaction
+// to read only. The resource's role must have CREATEROLE. Expired roles are
+// not dropped, and sessions opened before expiresAt are not ended.
+func (d *PostgresDriver) IssueCredentials(ctx context.Context, config, credentials json.RawMessage, readOnly bool, expiresAt time.Time) (json.RawMessage, error) {
+	conn, creds, err := d.connect(ctx, config, credentials)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(context.Background())
+	if creds.Username == "" {
+		return nil, &Error{Code: ErrorMisconfigured, Message: "username is required to issue credentials"}
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, &Error{Code: ErrorUnknown, Message: "credentials could not be generated", Err: err}
+	}
+	issued := postgresCredentials{
+		Username: leaseRolePrefix + hex.EncodeToString(random[:8]),
+		Password: hex.EncodeToString(random[8:]),
+	}
+	err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		for _, stmt := range leaseRoleStatements(issued, creds.Username, readOnly, expiresAt) {
+			if _, err := tx.Exec(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, classifyPostgres(err)
+	}
+	return json.Marshal(issued)
+}
+
+// leaseRoleStatements creates the role of issued as a member of owner. Role
+// statements take no bind parameters, so every value is quoted.
+func leaseRoleStatements(issued postgresCredentials, owner string, readOnly bool, expiresAt time.Time) []string {
+	role := pgx.Identifier{issued.Username}.Sanitize()
+	stmts := []string{
+		"CREATE ROLE " + role + " LOGIN INHERIT PASSWORD " + quoteLiteral(issued.Password) +
+			" VALID UNTIL " + quoteLiteral(expiresAt.UTC().Format(time.RFC3339)) +
+			" IN ROLE " + pgx.Identifier{owner}.Sanitize(),
+	}
+	if readOnly {
+		stmts = append(stmts, "ALTER ROLE "+role+" SET default_transaction_read_only = on")
+	}
+	return stmts
+}
+
+// quoteLiteral quotes s as a string constant, assuming standard_conforming_strings.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// connect opens a session with config and credentials.
+func (d *PostgresDriver) connect(ctx context.Context, config, credentials json.RawMessage) (*pgx.Conn, postgresCredentials, error) {
+	var cfg postgresConfig
+	if err := decode(config, &cfg, "connection details"); err != nil {
+		return nil, postgresCredentials{}, err
+	}
+	var creds postgresCredentials
+	if err := decode(credentials, &creds, "credentials"); err != nil {
+		return nil, creds, err
+	}
+	if cfg.Host == "" {
+		return nil, creds, &Error{Code: ErrorMisconfigured, Message: "host is required"}
+	}
+
+	pgCfg, err := pgx.ParseConfig(postgresURL(cfg, creds))
+	if err != nil {
+		return nil, creds, &Error{Code: ErrorMisconfigured, Message: "invalid connection settings", Err: err}
+	}
+	pgCfg.DialFunc = d.dial
+	conn, err := pgx.ConnectConfig(ctx, pgCfg)
+	if err != nil {
+		return nil, creds, classifyPostgres(err)
+	}
+	return conn, creds, nil
+}
+
+func postgresURL(cfg postgresConfig, creds postgresCredentials) string {
+	port := cfg.Port
+	if port == 0 {
+		port = 5432
+	}
+	sslMode := cfg.SSLMode
+	if sslMode == "" {
+		sslMode = "prefer"
+	}
+	u := url.URL{
+		Scheme:   "postgres",
+		Host:     net.JoinHostPort(cfg.Host, strconv.Itoa(port)),
+		Path:     "/" + cfg.DBName,
+		RawQuery: url.Values{"sslmode": {sslMode}}.Encode(),
+	}
+	if creds.Username != "" {
+		u.User = url.UserPassword(creds.Username, creds.Password)
+	}
+	return u.String()
+}
+
+// classifyPostgres maps server error codes before falling back to transport errors.
+func classifyPostgres(err error) *Error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "28000", "28P01":
+			return &Error{Code: ErrorAuthFailed, Message: "authentication failed", Err: err}
+		case "3D000":
+			return &Error{Code: ErrorNotFound, Message: "database does not exist", Err: err}
+		case "42501":
+			return &Error{Code: ErrorAuthFailed, Message: "permission denied", Err: err}
+		default:
+			return &Error{Code: ErrorUnexpectedResponse, Message: "server rejected the connection", Err: err}
+		}
+	}
+	return classify(err)
+}
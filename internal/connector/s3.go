@@ -0,0 +1,156 @@
+package connector
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash is the hex SHA-256 of an empty request body.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// regionPattern matches AWS region names; the bucket region reported by an
+// endpoint is only echoed back when it looks like one.
+var regionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]{1,2}$`)
+
+// S3Driver tests aws_s3 resources with a SigV4-signed HEAD of the bucket. An
+// endpoint in the connection details targets an S3-compatible store (MinIO,
+// Ceph...) with path-style addressing; without one, AWS is used.
+type S3Driver struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewS3Driver creates an S3Driver.
+func NewS3Driver(opts Options) *S3Driver {
+	return &S3Driver{client: httpClient(opts, nil), now: time.Now}
+}
+
+type s3Config struct {
+	BucketName string `json:"bucket_name"`
+	Region     string `json:"region"`
+	Endpoint   string `json:"endpoint"`
+}
+
+type s3Credentials struct {
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+}
+
+func (d *S3Driver) TestConnection(ctx context.Context, config, credentials json.RawMessage) error {
+	var cfg s3Config
+	if err := decode(config, &cfg, "connection details"); err != nil {
+		return err
+	}
+	var creds s3Credentials
+	if err := decode(credentials, &creds, "credentials"); err != nil {
+		return err
+	}
+	if cfg.BucketName == "" || cfg.Region == "" {
+		return &Error{Code: ErrorMisconfigured, Message: "bucket_name and region are required"}
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return &Error{Code: ErrorMisconfigured, Message: "access_key_id and secret_access_key are required"}
+	}
+
+	target, err := bucketURL(cfg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+	if err != nil {
+		return &Error{Code: ErrorMisconfigured, Message: "invalid request", Err: err}
+	}
+	signV4(req, cfg.Region, creds, d.now())
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return classify(err)
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusForbidden, http.StatusUnauthorized:
+		return &Error{Code: ErrorAuthFailed, Message: "access to the bucket was denied"}
+	case http.StatusNotFound:
+		return &Error{Code: ErrorNotFound, Message: "bucket does not exist"}
+	case http.StatusMovedPermanently, http.StatusBadRequest:
+		if region := resp.Header.Get("X-Amz-Bucket-Region"); region != "" && region != cfg.Region {
+			if !regionPattern.MatchString(region) {
+				return &Error{Code: ErrorMisconfigured, Message: "bucket is in another region"}
+			}
+			return &Error{Code: ErrorMisconfigured, Message: fmt.Sprintf("bucket is in region %s", region)}
+		}
+	}
+	return &Error{Code: ErrorUnexpectedResponse, Message: "server answered with an unexpected status"}
+}
+
+func bucketURL(cfg s3Config) (string, error) {
+	if cfg.Endpoint == "" {
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/", cfg.BucketName, cfg.Region), nil
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", &Error{Code: ErrorMisconfigured, Message: "endpoint must be an absolute http(s) URL"}
+	}
+	return strings.TrimSuffix(u.String(), "/") + "/" + url.PathEscape(cfg.BucketName), nil
+}
+
+// signV4 adds an AWS Signature Version 4 Authorization header for a bodiless S3 request.
+func signV4(req *http.Request, region string, creds s3Credentials, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + emptyPayloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hexSHA256(canonicalRequest)
+	signature := hex.EncodeToString(hmacSHA256(signingKey(creds.SecretAccessKey, date, region, "s3"), stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+creds.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func signingKey(secret, date, region, service string) []byte {
+	k := hmacSHA256([]byte("AWS4"+secret), date)
+	k = hmacSHA256(k, region)
+	k = hmacSHA256(k, service)
+	return hmacSHA256(k, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSHA256(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
@@ -10,6 +10,8 @@ import (
 
 type AgentStatus string
 type BillingCycle string
+type AgentRiskLevel string
+type ChangeRequestStatus string
 
 const (
 	AgentStatusActive      AgentStatus = "active"
@@ -21,6 +23,16 @@ const (
 	BillingCycleYearly  BillingCycle = "yearly"
 	BillingCycleOneTime BillingCycle = "one_time"
 	BillingCycleCustom  BillingCycle = "custom"
+
+	// Risk levels follow the EU AI Act classification. Configuration changes
+	// to high-risk agents go through an approval workflow.
+	AgentRiskLevelMinimal AgentRiskLevel = "minimal"
+	AgentRiskLevelLimited AgentRiskLevel = "limited"
+	AgentRiskLevelHigh    AgentRiskLevel = "high"
+
+	ChangeRequestStatusPending  ChangeRequestStatus = "pending"
+	ChangeRequestStatusApproved ChangeRequestStatus = "approved"
+	ChangeRequestStatusRejected ChangeRequestStatus = "rejected"
 )
 
 // Agent represents an AI Agent.
@@ -29,6 +41,7 @@ type Agent struct {
 	OrganizationID uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex:idx_org_agent_name" json:"organization_id"`
 	Name           string          `gorm:"type:varchar(255);not null;uniqueIndex:idx_org_agent_name" json:"name"`
 	Status         AgentStatus     `gorm:"type:agent_status;default:'active'" json:"status"`
+	RiskLevel      AgentRiskLevel  `gorm:"type:agent_risk_level;default:'minimal'" json:"risk_level"`
 	CostAmount     float64         `gorm:"type:decimal(10,2);default:0.00" json:"cost_amount"`
 	CostCurrency   string          `gorm:"type:varchar(3);default:'EUR'" json:"cost_currency"`
 	BillingCycle   BillingCycle    `gorm:"type:billing_cycle;default:'monthly'" json:"billing_cycle"`
@@ -58,6 +71,40 @@ type AgentVersion struct {
 	Agent Agent `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
 }
 
+// AgentChangeRequest is a configuration change awaiting review. Approving it
+// applies ProposedConfiguration to the agent and records a new AgentVersion.
+// BaseVersionNumber is the agent's latest version when the change was
+// proposed; the request can only be approved while no other version followed.
+type AgentChangeRequest struct {
+	ID                    uuid.UUID           `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	AgentID               uuid.UUID           `gorm:"type:uuid;not null" json:"agent_id"`
+	ProposedConfiguration json.RawMessage     `gorm:"type:jsonb;not null" json:"proposed_configuration" swaggertype:"string"`
+	BaseVersionNumber     int                 `gorm:"not null;default:0" json:"base_version_number" example:"3"`
+	Reason                string              `gorm:"type:varchar(255);not null" json:"reason" example:"Switch to a model with EU data residency"`
+	Status                ChangeRequestStatus `gorm:"type:change_request_status;default:'pending'" json:"status" example:"pending"`
+	RequestedBy           *uuid.UUID          `gorm:"type:uuid" json:"requested_by,omitempty"`
+	ReviewedBy            *uuid.UUID          `gorm:"type:uuid" json:"reviewed_by,omitempty"`
+	ReviewComment         string              `gorm:"type:text" json:"review_comment,omitempty"`
+	ReviewedAt            *time.Time          `json:"reviewed_at,omitempty"`
+	AppliedVersionID      *uuid.UUID          `gorm:"type:uuid" json:"applied_version_id,omitempty"`
+	CreatedAt             time.Time           `gorm:"default:now()" json:"created_at"`
+
+	Agent Agent `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
+
+// AgentStatusTransition records one lifecycle change of an agent.
+type AgentStatusTransition struct {
+	ID         uuid.UUID   `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	AgentID    uuid.UUID   `gorm:"type:uuid;not null" json:"agent_id"`
+	FromStatus AgentStatus `gorm:"type:agent_status;not null" json:"from_status" example:"active"`
+	ToStatus   AgentStatus `gorm:"type:agent_status;not null" json:"to_status" example:"maintenance"`
+	Reason     string      `gorm:"type:varchar(255)" json:"reason,omitempty" example:"Upgrading the vector store"`
+	ChangedBy  *uuid.UUID  `gorm:"type:uuid" json:"changed_by,omitempty"`
+	CreatedAt  time.Time   `gorm:"default:now()" json:"created_at"`
+
+	Agent Agent `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
+
 // AgentAssignment links an agent to a user.
 type AgentAssignment struct {
 	AgentID    uuid.UUID `gorm:"type:uuid;primaryKey" json:"agent_id"`
@@ -24,12 +24,15 @@ type Application struct {
 type ApplicationKey struct {
 	ID            uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
 	ApplicationID uuid.UUID  `gorm:"type:uuid;not null" json:"application_id"`
-	KeyHash       string     `gorm:"type:varchar(255);not null" json:"-"` // Never expose hash
-	KeyPrefix     string     `gorm:"type:varchar(8);not null" json:"key_prefix" example:"sk-live-"`
+	KeyHash       string     `gorm:"type:varchar(255);not null" json:"-"`   // Never expose hash
+	LookupID      *string    `gorm:"type:varchar(16);uniqueIndex" json:"-"` // Public part of the raw key, used to find the row
+	KeyPrefix     string     `gorm:"type:varchar(16);not null" json:"key_prefix" example:"sk-live-a1b2"`
 	Name          string     `gorm:"type:varchar(100)" json:"name" example:"Production Key"`
 	LastUsedAt    *time.Time `json:"last_used_at,omitempty"`
 	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
 	CreatedAt     time.Time  `gorm:"default:now()" json:"created_at"`
+
+	Application Application `gorm:"foreignKey:ApplicationID" json:"-"`
 }
 
 type ApplicationAgentAccess struct {
@@ -42,3 +45,8 @@ type ApplicationAgentAccess struct {
 
 	Agent Agent `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"agent,omitempty"`
 }
+
+// TableName returns the table of 01_schema.sql, which is not pluralized.
+func (ApplicationAgentAccess) TableName() string {
+	return "application_agent_access"
+}
@@ -1,7 +1,13 @@
 package domain
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,11 +16,20 @@ import (
 type AuditAction string
 
 const (
-	AuditActionCreate     AuditAction = "create"
-	AuditActionUpdate     AuditAction = "update"
-	AuditActionDelete     AuditAction = "delete"
-	AuditActionLogin      AuditAction = "login"
-	AuditActionExportData AuditAction = "export_data"
+	AuditActionCreate      AuditAction = "create"
+	AuditActionUpdate      AuditAction = "update"
+	AuditActionDelete      AuditAction = "delete"
+	AuditActionLogin       AuditAction = "login"
+	AuditActionExportData  AuditAction = "export_data"
+	AuditActionApprove     AuditAction = "approve"
+	AuditActionReject      AuditAction = "reject"
+	AuditActionReadSecret  AuditAction = "read_secret"
+	AuditActionRestore     AuditAction = "restore"
+	AuditActionIssueLease  AuditAction = "issue_lease"
+	AuditActionExpireLease AuditAction = "expire_lease"
+	// AuditActionTestConnection records a connection test and its outcome.
+	// Stored credentials it sends are recorded as AuditActionReadSecret first.
+	AuditActionTestConnection AuditAction = "test_connection"
 )
 
 type Certification struct {
@@ -64,16 +79,167 @@ type ApplicationCertification struct {
 	Certification Certification `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"certification,omitempty"`
 }
 
+// SystemAuditLog entries form one hash chain per organization: Seq numbers them
+// from 1 and Hash covers the entry's content and the Hash of the entry before it.
 type SystemAuditLog struct {
 	ID             uuid.UUID       `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
 	OrganizationID uuid.UUID       `gorm:"type:uuid;not null" json:"organization_id"`
+	Seq            int64           `gorm:"not null" json:"seq"`
 	ActorUserID    *uuid.UUID      `gorm:"type:uuid" json:"actor_user_id,omitempty"`
 	EntityType     string          `gorm:"type:varchar(50);not null" json:"entity_type" example:"agent"`
 	EntityID       uuid.UUID       `gorm:"type:uuid;not null" json:"entity_id"`
 	Action         AuditAction     `gorm:"type:audit_action;not null" json:"action" example:"update"`
-	Changes        json.RawMessage `gorm:"type:jsonb" json:"changes,omitempty"`
+	Changes        json.RawMessage `gorm:"column:changes_json;type:jsonb" json:"changes,omitempty"`
 	IPAddress      string          `gorm:"type:varchar(45)" json:"ip_address,omitempty"`
 	OccurredAt     time.Time       `gorm:"default:now()" json:"occurred_at"`
+	// PrevHash is empty for the first entry of an organization.
+	PrevHash string `gorm:"type:varchar(64);not null" json:"prev_hash"`
+	Hash     string `gorm:"type:varchar(64);not null" json:"hash"`
+}
+
+// ChainHash computes the hex SHA-256 of l chained to PrevHash. It covers every
+// column but Hash, with Changes canonicalized since jsonb does not keep the
+// original text, so it can be recomputed from the stored row.
+func (l *SystemAuditLog) ChainHash() (string, error) {
+	changes, err := canonicalJSON(l.Changes)
+	if err != nil {
+		return "", err
+	}
+	content, err := json.Marshal(struct {
+		ID             uuid.UUID       `json:"id"`
+		OrganizationID uuid.UUID       `json:"organization_id"`
+		Seq            int64           `json:"seq"`
+		ActorUserID    *uuid.UUID      `json:"actor_user_id"`
+		EntityType     string          `json:"entity_type"`
+		EntityID       uuid.UUID       `json:"entity_id"`
+		Action         AuditAction     `json:"action"`
+		Changes        json.RawMessage `json:"changes"`
+		IPAddress      string          `json:"ip_address"`
+		OccurredAt     string          `json:"occurred_at"`
+		PrevHash       string          `json:"prev_hash"`
+	}{
+		ID:             l.ID,
+		OrganizationID: l.OrganizationID,
+		Seq:            l.Seq,
+		ActorUserID:    l.ActorUserID,
+		EntityType:     l.EntityType,
+		EntityID:       l.EntityID,
+		Action:         l.Action,
+		Changes:        changes,
+		IPAddress:      l.IPAddress,
+		OccurredAt:     l.OccurredAt.UTC().Format(time.RFC3339Nano),
+		PrevHash:       l.PrevHash,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalJSON re-encodes doc with sorted keys, no insignificant space and
+// numbers written the way jsonb prints them; an empty document becomes null.
+func canonicalJSON(doc json.RawMessage) (json.RawMessage, error) {
+	if len(bytes.TrimSpace(doc)) == 0 {
+		return json.RawMessage("null"), nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	v, err := normalizeNumbers(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// normalizeNumbers rewrites every number of v as jsonb stores it.
+func normalizeNumbers(v any) (any, error) {
+	switch v := v.(type) {
+	case json.Number:
+		n, err := jsonbNumber(string(v))
+		return json.Number(n), err
+	case map[string]any:
+		for k, item := range v {
+			n, err := normalizeNumbers(item)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = n
+		}
+	case []any:
+		for i, item := range v {
+			n, err := normalizeNumbers(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = n
+		}
+	}
+	return v, nil
+}
+
+// maxNumberExponent mirrors the exponent range Postgres accepts for numeric.
+const maxNumberExponent = 1000
+
+// jsonbNumber formats the JSON number literal n like Postgres numeric output:
+// plain decimal notation whose scale is the literal's fraction digits minus
+// its exponent (1e2 is 100, 1.50e1 is 15.0, 1E-2 is 0.01) and no negative zero.
+func jsonbNumber(n string) (string, error) {
+	neg := strings.HasPrefix(n, "-")
+	n = strings.TrimPrefix(n, "-")
+	exp := 0
+	if i := strings.IndexAny(n, "eE"); i >= 0 {
+		e, err := strconv.Atoi(n[i+1:])
+		if err != nil || e > maxNumberExponent || e < -maxNumberExponent {
+			return "", fmt.Errorf("number %q out of range", n)
+		}
+		exp, n = e, n[:i]
+	}
+	intPart, frac, _ := strings.Cut(n, ".")
+	digits := intPart + frac
+	scale := len(frac) - exp
+	if scale < 0 {
+		digits += strings.Repeat("0", -scale)
+		scale = 0
+	}
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	intPart, frac = digits[:len(digits)-scale], digits[len(digits)-scale:]
+	intPart = strings.TrimLeft(intPart, "0")
+	if intPart == "" {
+		intPart = "0"
+	}
+	out := intPart
+	if scale > 0 {
+		out += "." + frac
+	}
+	if neg && strings.Trim(digits, "0") != "" {
+		out = "-" + out
+	}
+	return out, nil
+}
+
+// AuditLogFilter narrows a query of an organization's audit trail. Zero fields
+// match every entry.
+type AuditLogFilter struct {
+	ActorUserID *uuid.UUID
+	EntityType  string
+	EntityID    *uuid.UUID
+	Action      AuditAction
+	// From (inclusive) and To (exclusive) bound OccurredAt.
+	From *time.Time
+	To   *time.Time
 }
 
 // AgentExecution matches the partitioned table.
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONBNumber(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"0", "0"},
+		{"42", "42"},
+		{"-7", "-7"},
+		{"1.0", "1.0"},
+		{"1.50", "1.50"},
+		{"1e2", "100"},
+		{"1E+2", "100"},
+		{"1.5e1", "15"},
+		{"1.50e1", "15.0"},
+		{"1e-2", "0.01"},
+		{"12.5e-3", "0.0125"},
+		{"-0", "0"},
+		{"-0.0e1", "0"},
+		{"-2.5e-1", "-0.25"},
+		{"1e+21", "1000000000000000000000"},
+	}
+	for _, tt := range tests {
+		got, err := jsonbNumber(tt.in)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+
+	_, err := jsonbNumber("1e100000")
+	assert.Error(t, err)
+}
+
+func TestSystemAuditLog_ChainHash_JSONBRoundTrip(t *testing.T) {
+	written := SystemAuditLog{
+		ID:             uuid.New(),
+		OrganizationID: uuid.New(),
+		Seq:            3,
+		EntityType:     "agent",
+		EntityID:       uuid.New(),
+		Action:         AuditActionUpdate,
+		Changes:        json.RawMessage(`{"cost": 1e2, "ratio": 1.50e1, "tiny": 1E-7, "list": [-0, 2.5e-1]}`),
+		OccurredAt:     time.Now(),
+		PrevHash:       "abc",
+	}
+	// The same row as Postgres returns it from the jsonb column.
+	read := written
+	read.Changes = json.RawMessage(`{"cost": 100, "list": [0, 0.25], "ratio": 15.0, "tiny": 0.0000001}`)
+
+	want, err := written.ChainHash()
+	require.NoError(t, err)
+	got, err := read.ChainHash()
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EmailStatus string
+
+const (
+	EmailStatusPending EmailStatus = "pending"
+	EmailStatusSent    EmailStatus = "sent"
+	EmailStatusFailed  EmailStatus = "failed"
+)
+
+// OutboundEmail is a rendered email waiting in the outbox. It is written in
+// the transaction that warrants it and delivered later with retries, so a
+// mail server outage does not lose it. The bodies, which may carry tokens,
+// are cleared once the email is sent or given up on.
+type OutboundEmail struct {
+	ID             uuid.UUID   `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	OrganizationID *uuid.UUID  `gorm:"type:uuid" json:"organization_id,omitempty"`
+	Recipient      string      `gorm:"type:varchar(255);not null" json:"recipient"`
+	Template       string      `gorm:"type:varchar(50);not null" json:"template"`
+	Subject        string      `gorm:"type:varchar(255);not null" json:"subject"`
+	TextBody       string      `gorm:"type:text" json:"-"`
+	HTMLBody       string      `gorm:"type:text" json:"-"`
+	Status         EmailStatus `gorm:"type:email_status;default:'pending';not null" json:"status"`
+	Attempts       int         `gorm:"type:int;default:0;not null" json:"attempts"`
+	NextAttemptAt  time.Time   `gorm:"not null" json:"next_attempt_at"`
+	LastError      string      `gorm:"type:text" json:"last_error,omitempty"`
+	SentAt         *time.Time  `json:"sent_at,omitempty"`
+	CreatedAt      time.Time   `gorm:"default:now()" json:"created_at"`
+}
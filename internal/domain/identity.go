@@ -74,3 +74,32 @@ type Invitation struct {
 	Organization Organization `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"organization,omitempty"`
 	Invitor      User         `gorm:"foreignKey:InvitorID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"invitor,omitempty"`
 }
+
+// RefreshToken is the server-side record of an issued refresh token.
+// Only a SHA-256 hash of the token is stored; rotation links each token to its successor.
+type RefreshToken struct {
+	ID           uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID       uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	SessionID    uuid.UUID  `gorm:"type:uuid;not null" json:"session_id"`
+	TokenHash    string     `gorm:"type:varchar(64);not null;unique" json:"-"`
+	ExpiresAt    time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	ReplacedByID *uuid.UUID `gorm:"type:uuid" json:"replaced_by_id,omitempty"`
+	CreatedAt    time.Time  `gorm:"default:now()" json:"created_at"`
+
+	User User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
+
+// PasswordResetToken lets a user who forgot their password set a new one.
+// Only a SHA-256 hash of the token is stored. ConsumedAt is set once the token
+// is used or superseded by a newer request; a token is valid at most once.
+type PasswordResetToken struct {
+	ID         uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	TokenHash  string     `gorm:"type:varchar(64);not null;unique" json:"-"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	ConsumedAt *time.Time `json:"consumed_at,omitempty"`
+	CreatedAt  time.Time  `gorm:"default:now()" json:"created_at"`
+
+	User User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
@@ -2,10 +2,18 @@ package domain
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// TxManager runs a unit of work atomically. Repository calls made with the
+// ctx handed to fn join the transaction; fn returning an error rolls it back.
+type TxManager interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
 // UserRepository interface defines methods the persistence layer must implement.
 type UserRepository interface {
 	Create(ctx context.Context, user *User) error
@@ -18,8 +26,60 @@ type UserRepository interface {
 // InvitationRepository defines access to Invitations.
 type InvitationRepository interface {
 	Create(ctx context.Context, invitation *Invitation) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Invitation, error)
 	GetByToken(ctx context.Context, token string) (*Invitation, error)
+	// GetPendingByEmail returns the pending, unexpired invitation of email to
+	// the organization, or nil if there is none.
+	GetPendingByEmail(ctx context.Context, orgID uuid.UUID, email string, now time.Time) (*Invitation, error)
+	// ListByOrganization lists the invitations of an organization, newest
+	// first, optionally only those with status.
+	ListByOrganization(ctx context.Context, orgID uuid.UUID, status InvitationStatus) ([]Invitation, error)
 	Update(ctx context.Context, invitation *Invitation) error
+	// ExpireOverdue marks every pending invitation of every organization that
+	// expired by now as expired, and returns the invitations it marked.
+	ExpireOverdue(ctx context.Context, now time.Time) ([]Invitation, error)
+}
+
+// RefreshTokenRepository defines access to server-side refresh tokens.
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *RefreshToken) error
+	GetByHash(ctx context.Context, hash string) (*RefreshToken, error)
+	// Revoke marks the token revoked at, and replaced by replacedByID when it
+	// was rotated. It reports false when the token was already revoked.
+	Revoke(ctx context.Context, id uuid.UUID, at time.Time, replacedByID *uuid.UUID) (bool, error)
+	RevokeAllForUser(ctx context.Context, userID uuid.UUID) error
+	// SessionActive reports whether the session holds a refresh token that is
+	// neither revoked nor expired at now.
+	SessionActive(ctx context.Context, sessionID uuid.UUID, now time.Time) (bool, error)
+}
+
+// PasswordResetTokenRepository defines access to password reset tokens.
+type PasswordResetTokenRepository interface {
+	Create(ctx context.Context, token *PasswordResetToken) error
+	GetByHash(ctx context.Context, hash string) (*PasswordResetToken, error)
+	// Consume marks the token used at, unless it already was or expired by
+	// then. It reports whether this call consumed it.
+	Consume(ctx context.Context, id uuid.UUID, at time.Time) (bool, error)
+	// ConsumeAllForUser invalidates every outstanding token of the user.
+	ConsumeAllForUser(ctx context.Context, userID uuid.UUID, at time.Time) error
+}
+
+// OutboundEmailRepository is the email outbox. It is not tenant-scoped and
+// is meant for the delivery worker.
+type OutboundEmailRepository interface {
+	Create(ctx context.Context, email *OutboundEmail) error
+	// ListDue returns up to limit pending emails whose next attempt is at or
+	// before now, oldest first.
+	ListDue(ctx context.Context, now time.Time, limit int) ([]OutboundEmail, error)
+	// Claim counts an attempt and defers the next one to retryAt, unless
+	// another worker claimed the email since it was listed. It reports whether
+	// this call claimed it.
+	Claim(ctx context.Context, id uuid.UUID, now, retryAt time.Time) (bool, error)
+	// MarkSent records the delivery and clears the bodies.
+	MarkSent(ctx context.Context, id uuid.UUID, at time.Time) error
+	// MarkFailed records a failed attempt. With giveUp the email is not retried
+	// and its bodies are cleared.
+	MarkFailed(ctx context.Context, id uuid.UUID, lastError string, giveUp bool) error
 }
 
 // OrganizationRepository defines access to Organizations.
@@ -35,11 +95,40 @@ type AgentRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*Agent, error)
 	ListByOrg(ctx context.Context, orgID uuid.UUID) ([]Agent, error)
 	ListByStatus(ctx context.Context, orgID uuid.UUID, status AgentStatus) ([]Agent, error)
-	Update(ctx context.Context, agent *Agent) error
+	// Update writes the agent's name and configuration. It reports false when
+	// the agent's status or risk level is no longer the one in agent.
+	Update(ctx context.Context, agent *Agent) (bool, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 
 	// Versioning
 	CreateVersion(ctx context.Context, version *AgentVersion) error
+	// CreateNextVersion assigns version.VersionNumber = latest + 1 and inserts it,
+	// serializing concurrent writers on the agent row.
+	CreateNextVersion(ctx context.Context, version *AgentVersion) error
+	GetLatestVersion(ctx context.Context, agentID uuid.UUID) (*AgentVersion, error)
+	GetVersion(ctx context.Context, agentID uuid.UUID, versionNumber int) (*AgentVersion, error)
+	// ListVersions returns one page of versions, newest first, and the total count.
+	ListVersions(ctx context.Context, agentID uuid.UUID, limit, offset int) ([]AgentVersion, int64, error)
+
+	// Change requests
+	CreateChangeRequest(ctx context.Context, req *AgentChangeRequest) error
+	GetChangeRequest(ctx context.Context, id uuid.UUID) (*AgentChangeRequest, error)
+	// ListChangeRequests returns the agent's change requests, newest first; an empty status matches all.
+	ListChangeRequests(ctx context.Context, agentID uuid.UUID, status ChangeRequestStatus) ([]AgentChangeRequest, error)
+	// ResolveChangeRequest persists the review of a request that is still pending.
+	// It reports false when the request was already resolved.
+	ResolveChangeRequest(ctx context.Context, req *AgentChangeRequest) (bool, error)
+
+	// Lifecycle
+	// UpdateStatus moves the agent from one status to another. It reports false
+	// when the agent's status is no longer from.
+	UpdateStatus(ctx context.Context, agentID uuid.UUID, from, to AgentStatus, userID uuid.UUID) (bool, error)
+	// UpdateRiskLevel reclassifies the agent. It reports false when the agent's
+	// risk level is no longer from.
+	UpdateRiskLevel(ctx context.Context, agentID uuid.UUID, from, to AgentRiskLevel, userID uuid.UUID) (bool, error)
+	CreateStatusTransition(ctx context.Context, transition *AgentStatusTransition) error
+	// ListStatusTransitions returns the agent's lifecycle history, newest first.
+	ListStatusTransitions(ctx context.Context, agentID uuid.UUID) ([]AgentStatusTransition, error)
 
 	// Resources
 	GetResources(ctx context.Context, agentID uuid.UUID) ([]Resource, error)
@@ -47,9 +136,12 @@ type AgentRepository interface {
 	// Assignments
 	GetAssignedUsers(ctx context.Context, agentID uuid.UUID) ([]User, error)
 	GetAssignedAgents(ctx context.Context, userID uuid.UUID) ([]Agent, error)
+	IsAssigned(ctx context.Context, agentID, userID uuid.UUID) (bool, error)
 	GetAssignedLLMs(ctx context.Context, agentID uuid.UUID) ([]AgentLLM, error)
 	GetAssignedApplications(ctx context.Context, agentID uuid.UUID) ([]Application, error)
 	GetCertifications(ctx context.Context, agentID uuid.UUID) ([]Certification, error)
+	// GetAgentCertifications returns the agent's certification links, which carry their expiry.
+	GetAgentCertifications(ctx context.Context, agentID uuid.UUID) ([]AgentCertification, error)
 }
 
 // LLMRepository defines interactions with LLM configurations.
@@ -63,22 +155,108 @@ type LLMRepository interface {
 
 // AuditRepository for compliance logging.
 type AuditRepository interface {
+	// CreateLog appends log to the hash chain of its organization, setting its
+	// ID, Seq, OccurredAt, PrevHash and Hash.
 	CreateLog(ctx context.Context, log *SystemAuditLog) error
+	// ListLogs returns up to limit entries of the organization in ctx matching
+	// filter, newest first. A positive beforeSeq keeps entries with a lower Seq.
+	ListLogs(ctx context.Context, filter AuditLogFilter, beforeSeq int64, limit int) ([]SystemAuditLog, error)
 	CreateExecution(ctx context.Context, exec *AgentExecution) error
 }
 
+// AuditChainRepository reads the audit hash chains of every organization. It
+// is deliberately not tenant-scoped and must only be used by operator tooling.
+type AuditChainRepository interface {
+	// ListChainOrganizations returns the organizations that have audit entries.
+	ListChainOrganizations(ctx context.Context) ([]uuid.UUID, error)
+	// ListChain returns up to limit entries of orgID with Seq above afterSeq, by ascending Seq.
+	ListChain(ctx context.Context, orgID uuid.UUID, afterSeq int64, limit int) ([]SystemAuditLog, error)
+}
+
 // ApplicationRepository defines access to Applications.
 type ApplicationRepository interface {
 	Create(ctx context.Context, app *Application) error
 	GetByID(ctx context.Context, id uuid.UUID) (*Application, error)
 	GetAssignedAgents(ctx context.Context, appID uuid.UUID) ([]Agent, error)
+	// GetAgentAccess returns nil when the application was not given the agent.
+	GetAgentAccess(ctx context.Context, appID, agentID uuid.UUID) (*ApplicationAgentAccess, error)
 	GetCertifications(ctx context.Context, appID uuid.UUID) ([]Certification, error)
 	CreateKey(ctx context.Context, key *ApplicationKey) error
+	GetKeyByLookupID(ctx context.Context, lookupID string) (*ApplicationKey, error)
+	UpdateKeyLastUsed(ctx context.Context, keyID uuid.UUID, usedAt time.Time) error
 }
 
 // ResourceRepository defines access to Resources.
 type ResourceRepository interface {
 	Create(ctx context.Context, res *Resource) error
 	GetByID(ctx context.Context, id uuid.UUID) (*Resource, error)
+	ListByOrg(ctx context.Context, orgID uuid.UUID, filter ResourceFilter) ([]Resource, error)
+	Update(ctx context.Context, res *Resource) error
+	// Delete soft-deletes a resource and its access grants. Call it within a transaction.
+	Delete(ctx context.Context, id uuid.UUID) error
+	// Restore undeletes a soft-deleted resource and the grants deleted with it,
+	// reporting false when no soft-deleted resource has that id. Call it within a transaction.
+	Restore(ctx context.Context, id uuid.UUID) (bool, error)
+	// GetType returns nil when the ResourceType does not exist.
+	GetType(ctx context.Context, id string) (*ResourceType, error)
+	// GetSecret returns nil when the resource has no credentials stored.
+	GetSecret(ctx context.Context, resourceID uuid.UUID) (*ResourceSecret, error)
+	// SaveSecret inserts the secret or replaces the one already stored for its resource.
+	SaveSecret(ctx context.Context, secret *ResourceSecret) error
+	// RecordConnectionTest stores result as the resource's last connection test.
+	RecordConnectionTest(ctx context.Context, resourceID uuid.UUID, result json.RawMessage) error
 	ListAgentsWithAccess(ctx context.Context, resourceID uuid.UUID) ([]Agent, error)
+
+	// Access grants
+	// GetAccess returns nil when agentID has no access to resourceID.
+	GetAccess(ctx context.Context, resourceID, agentID uuid.UUID) (*AgentResourceAccess, error)
+	// CreateAccess fails unless both the agent and the resource belong to the
+	// caller's tenant. It reports false, storing nothing, when the agent already
+	// has access to the resource.
+	CreateAccess(ctx context.Context, access *AgentResourceAccess) (bool, error)
+	// UpdateAccessPermission changes a grant from one level to another, reporting
+	// false when the grant no longer has level from.
+	UpdateAccessPermission(ctx context.Context, id uuid.UUID, from, to AccessLevel) (bool, error)
+	// DeleteAccess removes a grant, reporting false when it was already gone.
+	DeleteAccess(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+// ResourceTypeRepository manages the global ResourceType catalog.
+type ResourceTypeRepository interface {
+	List(ctx context.Context, includeInactive bool) ([]ResourceType, error)
+	// GetByID returns nil when the ResourceType does not exist.
+	GetByID(ctx context.Context, id string) (*ResourceType, error)
+	Create(ctx context.Context, rt *ResourceType) error
+	// Update writes every column of rt unless its schema version is no longer
+	// expectedVersion, reporting whether it was written.
+	Update(ctx context.Context, rt *ResourceType, expectedVersion int) (bool, error)
+	CreateSchemaVersion(ctx context.Context, version *ResourceTypeSchemaVersion) error
+	// ListSchemaVersions returns the schema history of a type, newest first.
+	ListSchemaVersions(ctx context.Context, typeID string) ([]ResourceTypeSchemaVersion, error)
+}
+
+// CredentialLeaseRepository stores the credential leases issued to agents.
+type CredentialLeaseRepository interface {
+	Create(ctx context.Context, lease *CredentialLease) error
+	// ListExpired returns up to limit leases of every organization that expired
+	// at or before now and whose expiry was not recorded yet, oldest first. It is
+	// not tenant-scoped and is meant for the expiry job.
+	ListExpired(ctx context.Context, now time.Time, limit int) ([]CredentialLease, error)
+	// MarkExpired records the expiry of a lease, reporting false when it was
+	// already recorded.
+	MarkExpired(ctx context.Context, id uuid.UUID, at time.Time) (bool, error)
+}
+
+// SecretRotationRepository gives master key rotation access to the ResourceSecrets
+// of every organization. It is deliberately not tenant-scoped and must only be
+// used by operator tooling.
+type SecretRotationRepository interface {
+	// ListSecretsToRotate pages, by ascending ID after afterID, through secrets
+	// not wrapped by keyVersion.
+	ListSecretsToRotate(ctx context.Context, keyVersion string, afterID uuid.UUID, limit int) ([]ResourceSecret, error)
+	// CountSecretsByKeyVersion counts secrets per master key version, "" for none.
+	CountSecretsByKeyVersion(ctx context.Context) (map[string]int64, error)
+	// ReplaceSecretEnvelope stores a rewrapped envelope unless the secret changed
+	// since it was read, reporting whether it was stored.
+	ReplaceSecretEnvelope(ctx context.Context, id uuid.UUID, oldEnvelope, newEnvelope, keyVersion string) (bool, error)
 }
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Principal identifies the authenticated caller of a request.
+type Principal struct {
+	UserID         uuid.UUID
+	OrganizationID uuid.UUID
+	Role           UserRole
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the caller stored by WithPrincipal, if any.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// ApplicationPrincipal identifies an Application authenticated by API key.
+type ApplicationPrincipal struct {
+	ApplicationID  uuid.UUID
+	KeyID          uuid.UUID
+	OrganizationID uuid.UUID
+}
+
+type applicationPrincipalKey struct{}
+
+// WithApplicationPrincipal returns a copy of ctx carrying p.
+func WithApplicationPrincipal(ctx context.Context, p *ApplicationPrincipal) context.Context {
+	return context.WithValue(ctx, applicationPrincipalKey{}, p)
+}
+
+// ApplicationPrincipalFromContext returns the application stored by WithApplicationPrincipal, if any.
+func ApplicationPrincipalFromContext(ctx context.Context) (*ApplicationPrincipal, bool) {
+	p, ok := ctx.Value(applicationPrincipalKey{}).(*ApplicationPrincipal)
+	return p, ok && p != nil
+}
+
+type organizationKey struct{}
+
+// WithOrganization returns a copy of ctx scoped to orgID without an
+// authenticated caller, e.g. for background jobs acting on one tenant.
+func WithOrganization(ctx context.Context, orgID uuid.UUID) context.Context {
+	return context.WithValue(ctx, organizationKey{}, orgID)
+}
+
+// OrganizationFromContext returns the tenant ctx acts for: the Principal's
+// organization, else the ApplicationPrincipal's, else one set by WithOrganization.
+func OrganizationFromContext(ctx context.Context) (uuid.UUID, bool) {
+	if p, ok := PrincipalFromContext(ctx); ok {
+		return p.OrganizationID, true
+	}
+	if app, ok := ApplicationPrincipalFromContext(ctx); ok {
+		return app.OrganizationID, true
+	}
+	orgID, ok := ctx.Value(organizationKey{}).(uuid.UUID)
+	return orgID, ok && orgID != uuid.Nil
+}
+
+type clientIPKey struct{}
+
+// WithClientIP returns a copy of ctx carrying the network address of the
+// client the request came from, recorded in audit entries.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIPFromContext returns the address stored by WithClientIP, or "".
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
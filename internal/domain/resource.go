@@ -20,9 +20,26 @@ type ResourceType struct {
 	Name         string          `gorm:"type:varchar(100);not null" json:"name" example:"PostgreSQL Database"`
 	ConfigSchema json.RawMessage `gorm:"type:jsonb;default:'{}'" json:"config_schema" swaggertype:"string"`
 	SecretSchema json.RawMessage `gorm:"type:jsonb;default:'{}'" json:"secret_schema" swaggertype:"string"`
-	IsActive     bool            `gorm:"default:true" json:"is_active"`
-	CreatedAt    time.Time       `gorm:"default:now()" json:"created_at"`
-	UpdatedAt    time.Time       `gorm:"default:now()" json:"updated_at"`
+	// SchemaVersion increases each time ConfigSchema or SecretSchema changes.
+	SchemaVersion int  `gorm:"not null;default:1" json:"schema_version"`
+	IsActive      bool `gorm:"default:true" json:"is_active"`
+	// IsBuiltin marks types owned by the built-in catalog; their name and schemas
+	// are reconciled at startup and cannot be edited through the API.
+	IsBuiltin bool      `gorm:"default:false" json:"is_builtin"`
+	CreatedAt time.Time `gorm:"default:now()" json:"created_at"`
+	UpdatedAt time.Time `gorm:"default:now()" json:"updated_at"`
+}
+
+// ResourceTypeSchemaVersion records the schemas of a ResourceType at one SchemaVersion.
+type ResourceTypeSchemaVersion struct {
+	ID           uuid.UUID       `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TypeID       string          `gorm:"type:varchar(50);not null" json:"type_id"`
+	Version      int             `gorm:"not null" json:"version"`
+	ConfigSchema json.RawMessage `gorm:"type:jsonb;default:'{}'" json:"config_schema" swaggertype:"string"`
+	SecretSchema json.RawMessage `gorm:"type:jsonb;default:'{}'" json:"secret_schema" swaggertype:"string"`
+	// CreatedBy is nil for versions written by the catalog sync.
+	CreatedBy *uuid.UUID `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt time.Time  `gorm:"default:now()" json:"created_at"`
 }
 
 type Resource struct {
@@ -31,15 +48,34 @@ type Resource struct {
 	TypeID            string          `gorm:"type:varchar(50);not null" json:"type_id"`
 	Name              string          `gorm:"type:varchar(255);not null" json:"name" example:"Production DB"`
 	ConnectionDetails json.RawMessage `gorm:"type:jsonb;default:'{}'" json:"connection_details" swaggertype:"string"`
-	CreatedAt         time.Time       `gorm:"default:now()" json:"created_at"`
-	UpdatedAt         time.Time       `gorm:"default:now()" json:"updated_at"`
-	DeletedAt         gorm.DeletedAt  `gorm:"index" json:"-"`
+	// LastConnectionTest holds the ConnectionTestResult of the latest test, if any.
+	LastConnectionTest json.RawMessage `gorm:"type:jsonb" json:"last_connection_test,omitempty" swaggertype:"string"`
+	CreatedAt          time.Time       `gorm:"default:now()" json:"created_at"`
+	UpdatedAt          time.Time       `gorm:"default:now()" json:"updated_at"`
+	DeletedAt          gorm.DeletedAt  `gorm:"index" json:"-"`
 
 	Organization Organization   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"organization,omitempty"`
 	Type         ResourceType   `gorm:"foreignKey:TypeID" json:"type,omitempty"`
 	Secret       ResourceSecret `gorm:"foreignKey:ResourceID" json:"-"` // Never expose secret relation directly
 }
 
+// ResourceFilter narrows a listing of an organization's resources.
+type ResourceFilter struct {
+	// TypeID keeps resources of this ResourceType when not empty.
+	TypeID string
+	// Deleted lists soft-deleted resources instead of live ones.
+	Deleted bool
+}
+
+// ConnectionTestResult is the outcome of testing connectivity to a Resource.
+type ConnectionTestResult struct {
+	Success      bool      `json:"success"`
+	LatencyMS    int64     `json:"latency_ms"`
+	TestedAt     time.Time `json:"tested_at"`
+	ErrorCode    string    `json:"error_code,omitempty" example:"auth_failed"`
+	ErrorMessage string    `json:"error_message,omitempty"`
+}
+
 type ResourceSecret struct {
 	ID                   uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
 	ResourceID           uuid.UUID `gorm:"type:uuid;not null;unique" json:"resource_id"`
@@ -55,7 +91,29 @@ type AgentResourceAccess struct {
 	ResourceID uuid.UUID   `gorm:"type:uuid;not null" json:"resource_id"`
 	Permission AccessLevel `gorm:"type:access_level;default:'read_only'" json:"permission"`
 	GrantedAt  time.Time   `gorm:"default:now()" json:"granted_at"`
+	// DeletedAt is set together with the resource's, so restoring the resource restores the grant.
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
 	Agent    Agent    `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"agent,omitempty"`
 	Resource Resource `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"resource,omitempty"`
 }
+
+// TableName returns the table of 01_schema.sql, which is not pluralized.
+func (AgentResourceAccess) TableName() string {
+	return "agent_resource_access"
+}
+
+// CredentialLease records credentials of a Resource handed to an Agent for a
+// limited time. ExpiredAt is set once the expiry has been audited.
+type CredentialLease struct {
+	ID             uuid.UUID   `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	OrganizationID uuid.UUID   `gorm:"type:uuid;not null" json:"organization_id"`
+	ResourceID     uuid.UUID   `gorm:"type:uuid;not null" json:"resource_id"`
+	AgentID        uuid.UUID   `gorm:"type:uuid;not null" json:"agent_id"`
+	ApplicationID  *uuid.UUID  `gorm:"type:uuid" json:"application_id,omitempty"`
+	AccessLevel    AccessLevel `gorm:"type:access_level;not null" json:"access_level"`
+	KeyVersionID   string      `gorm:"type:varchar(50)" json:"-"`
+	IssuedAt       time.Time   `gorm:"not null" json:"issued_at"`
+	ExpiresAt      time.Time   `gorm:"not null" json:"expires_at"`
+	ExpiredAt      *time.Time  `json:"-"`
+}
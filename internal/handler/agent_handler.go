@@ -0,0 +1,816 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"agentXmap/internal/domain"
+	"agentXmap/internal/policy"
+	"agentXmap/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreateAgentRequest is the payload for registering a new agent.
+type CreateAgentRequest struct {
+	Name          string          `json:"name" binding:"required" example:"Support Bot"`
+	Configuration json.RawMessage `json:"configuration" swaggertype:"string" example:"{\"model\": \"gpt-4\"}"`
+}
+
+// UpdateAgentRequest replaces the mutable fields of an agent. The status is
+// changed through the lifecycle endpoints.
+type UpdateAgentRequest struct {
+	Name          string          `json:"name" binding:"required" example:"Support Bot"`
+	Configuration json.RawMessage `json:"configuration" swaggertype:"string" example:"{\"model\": \"gpt-4\"}"`
+	// Reason is recorded with the new version; it is mandatory when the
+	// configuration of a high-risk agent changes.
+	Reason string `json:"reason" binding:"max=255" example:"Switch to a model with EU data residency"`
+}
+
+// TransitionAgentRequest justifies a lifecycle transition. The reason is
+// mandatory when entering maintenance or deprecation.
+type TransitionAgentRequest struct {
+	Reason string `json:"reason" binding:"max=255" example:"Upgrading the vector store"`
+}
+
+// SetRiskLevelRequest classifies an agent.
+type SetRiskLevelRequest struct {
+	RiskLevel domain.AgentRiskLevel `json:"risk_level" binding:"required,oneof=minimal limited high" example:"high"`
+}
+
+// ReviewChangeRequestRequest carries the reviewer's comment on approval or rejection.
+type ReviewChangeRequestRequest struct {
+	Comment string `json:"comment" example:"Checked against the DPIA"`
+}
+
+// RollbackAgentRequest restores the configuration of a previous version.
+type RollbackAgentRequest struct {
+	Version int    `json:"version" binding:"required,min=1" example:"2"`
+	Reason  string `json:"reason" binding:"required" example:"Regression introduced in version 3"`
+}
+
+// AgentVersionPage is one page of an agent's configuration history.
+type AgentVersionPage struct {
+	Items  []domain.AgentVersion `json:"items"`
+	Total  int64                 `json:"total" example:"42"`
+	Limit  int                   `json:"limit" example:"20"`
+	Offset int                   `json:"offset" example:"0"`
+}
+
+// AgentResponse is the public representation of an agent.
+type AgentResponse struct {
+	ID             uuid.UUID             `json:"id"`
+	OrganizationID uuid.UUID             `json:"organization_id"`
+	Name           string                `json:"name" example:"Support Bot"`
+	Status         domain.AgentStatus    `json:"status" example:"active"`
+	RiskLevel      domain.AgentRiskLevel `json:"risk_level" example:"minimal"`
+	CostAmount     float64               `json:"cost_amount" example:"49.90"`
+	CostCurrency   string                `json:"cost_currency" example:"EUR"`
+	BillingCycle   domain.BillingCycle   `json:"billing_cycle" example:"monthly"`
+	Configuration  json.RawMessage       `json:"configuration" swaggertype:"string"`
+	CreatedBy      *uuid.UUID            `json:"created_by,omitempty"`
+	UpdatedBy      *uuid.UUID            `json:"updated_by,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+}
+
+// MonthlyCostResponse reports the projected monthly spend of active agents.
+type MonthlyCostResponse struct {
+	OrganizationID uuid.UUID `json:"organization_id"`
+	MonthlyCost    float64   `json:"monthly_cost" example:"1250.50"`
+}
+
+func newAgentResponse(agent *domain.Agent) AgentResponse {
+	return AgentResponse{
+		ID:             agent.ID,
+		OrganizationID: agent.OrganizationID,
+		Name:           agent.Name,
+		Status:         agent.Status,
+		RiskLevel:      agent.RiskLevel,
+		CostAmount:     agent.CostAmount,
+		CostCurrency:   agent.CostCurrency,
+		BillingCycle:   agent.BillingCycle,
+		Configuration:  agent.Configuration,
+		CreatedBy:      agent.CreatedBy,
+		UpdatedBy:      agent.UpdatedBy,
+		CreatedAt:      agent.CreatedAt,
+		UpdatedAt:      agent.UpdatedAt,
+	}
+}
+
+func newAgentResponses(agents []domain.Agent) []AgentResponse {
+	out := make([]AgentResponse, 0, len(agents))
+	for i := range agents {
+		out = append(out, newAgentResponse(&agents[i]))
+	}
+	return out
+}
+
+// respondAgentChange answers 202 with the change request awaiting approval, or 200 with the updated agent.
+func respondAgentChange(c *gin.Context, agent *domain.Agent, changeRequest *domain.AgentChangeRequest) {
+	if changeRequest != nil {
+		c.JSON(http.StatusAccepted, changeRequest)
+		return
+	}
+	c.JSON(http.StatusOK, newAgentResponse(agent))
+}
+
+// AgentHandler exposes AgentService over HTTP.
+type AgentHandler struct {
+	agentService service.AgentService
+}
+
+// NewAgentHandler creates a new AgentHandler.
+func NewAgentHandler(agentService service.AgentService) *AgentHandler {
+	return &AgentHandler{agentService: agentService}
+}
+
+// RegisterRoutes mounts the agent endpoints under rg.
+func (h *AgentHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	canRead := RequirePermission(policy.ResourceAgent, policy.ActionRead)
+	canUpdate := RequirePermission(policy.ResourceAgent, policy.ActionUpdate)
+
+	agents := rg.Group("/agents")
+	{
+		agents.POST("", RequirePermission(policy.ResourceAgent, policy.ActionCreate), h.CreateAgent)
+		agents.GET("", canRead, h.ListAgents)
+		agents.GET("/cost", RequirePermission(policy.ResourceBilling, policy.ActionRead), h.GetActiveMonthlyCost)
+		agents.GET("/assigned", canRead, h.ListAssignedAgents)
+		agents.GET("/:id", canRead, h.GetAgent)
+		agents.PUT("/:id", canUpdate, h.UpdateAgent)
+		agents.DELETE("/:id", RequirePermission(policy.ResourceAgent, policy.ActionDelete), h.DeleteAgent)
+		agents.GET("/:id/resources", canRead, h.ListAgentResources)
+		agents.GET("/:id/users", canRead, h.ListAssignedUsers)
+		agents.GET("/:id/llms", canRead, h.GetAgentLLMs)
+		agents.GET("/:id/applications", canRead, h.ListAssignedApplications)
+		agents.GET("/:id/certifications", canRead, h.ListAgentCertifications)
+		agents.GET("/:id/versions", canRead, h.ListAgentVersions)
+		agents.GET("/:id/versions/diff", canRead, h.DiffAgentVersions)
+		agents.POST("/:id/rollback", canUpdate, h.RollbackAgent)
+		agents.PUT("/:id/risk-level", RequirePermission(policy.ResourceAgentRiskLevel, policy.ActionUpdate), h.SetRiskLevel)
+		agents.GET("/:id/change-requests", RequirePermission(policy.ResourceChangeRequest, policy.ActionRead), h.ListChangeRequests)
+		agents.POST("/:id/activate", canUpdate, h.transitionTo(domain.AgentStatusActive))
+		agents.POST("/:id/deactivate", canUpdate, h.transitionTo(domain.AgentStatusInactive))
+		agents.POST("/:id/maintenance", canUpdate, h.transitionTo(domain.AgentStatusMaintenance))
+		agents.POST("/:id/deprecate", RequirePermission(policy.ResourceAgent, policy.ActionDelete), h.transitionTo(domain.AgentStatusDeprecated))
+		agents.GET("/:id/status-history", canRead, h.ListStatusTransitions)
+	}
+
+	canReview := RequirePermission(policy.ResourceChangeRequest, policy.ActionUpdate)
+
+	changeRequests := rg.Group("/change-requests")
+	{
+		changeRequests.GET("/:id", RequirePermission(policy.ResourceChangeRequest, policy.ActionRead), h.GetChangeRequest)
+		changeRequests.POST("/:id/approve", canReview, h.ApproveChangeRequest)
+		changeRequests.POST("/:id/reject", canReview, h.RejectChangeRequest)
+	}
+}
+
+// agentErrorStatus maps AgentService errors to HTTP status codes.
+func agentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrAgentNotFound), errors.Is(err, service.ErrAgentVersionNotFound),
+		errors.Is(err, service.ErrChangeRequestNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrAgentNameRequired), errors.Is(err, service.ErrRollbackReasonRequired),
+		errors.Is(err, service.ErrChangeReasonRequired), errors.Is(err, service.ErrInvalidRiskLevel),
+		errors.Is(err, service.ErrInvalidAgentStatus), errors.Is(err, service.ErrTransitionReasonRequired):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrChangeRequestNotPending), errors.Is(err, service.ErrChangeRequestStale),
+		errors.Is(err, service.ErrInvalidTransition),
+		errors.Is(err, service.ErrAgentStatusChanged), errors.Is(err, service.ErrAgentChanged),
+		errors.Is(err, service.ErrAgentDeprecated),
+		errors.Is(err, service.ErrValidCertificationRequired):
+		return http.StatusConflict
+	case errors.Is(err, policy.ErrForbidden), errors.Is(err, service.ErrSelfApproval):
+		return http.StatusForbidden
+	case errors.Is(err, policy.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// CreateAgent godoc
+// @Summary Create an agent
+// @Tags agents
+// @Accept json
+// @Produce json
+// @Param request body CreateAgentRequest true "Agent"
+// @Success 201 {object} AgentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /agents [post]
+func (h *AgentHandler) CreateAgent(c *gin.Context) {
+	caller, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	var req CreateAgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	agent, err := h.agentService.CreateAgent(c.Request.Context(), caller.OrganizationID, caller.UserID, req.Name, req.Configuration)
+	if err != nil {
+		respondError(c, agentErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusCreated, newAgentResponse(agent))
+}
+
+// ListAgents godoc
+// @Summary List agents of the caller's organization
+// @Tags agents
+// @Produce json
+// @Param status query string false "Filter by status"
+// @Success 200 {array} AgentResponse
+// @Router /agents [get]
+func (h *AgentHandler) ListAgents(c *gin.Context) {
+	caller, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	var (
+		agents []domain.Agent
+		err    error
+	)
+	if status := c.Query("status"); status != "" {
+		agents, err = h.agentService.ListAgentsByStatus(c.Request.Context(), caller.OrganizationID, domain.AgentStatus(status))
+	} else {
+		agents, err = h.agentService.ListAgents(c.Request.Context(), caller.OrganizationID)
+	}
+	if err != nil {
+		respondError(c, agentErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, newAgentResponses(agents))
+}
+
+// GetActiveMonthlyCost godoc
+// @Summary Projected monthly cost of active agents
+// @Tags agents
+// @Produce json
+// @Success 200 {object} MonthlyCostResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /agents/cost [get]
+func (h *AgentHandler) GetActiveMonthlyCost(c *gin.Context) {
+	caller, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	cost, err := h.agentService.GetActiveMonthlyCost(c.Request.Context(), caller.OrganizationID)
+	if err != nil {
+		respondError(c, agentErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, MonthlyCostResponse{OrganizationID: caller.OrganizationID, MonthlyCost: cost})
+}
+
+// ListAssignedAgents godoc
+// @Summary List agents assigned to a user
+// @Description Defaults to the calling user when user_id is omitted.
+// @Tags agents
+// @Produce json
+// @Param user_id query string false "User ID"
+// @Success 200 {array} AgentResponse
+// @Router /agents/assigned [get]
+func (h *AgentHandler) ListAssignedAgents(c *gin.Context) {
+	caller, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+	userID := caller.UserID
+	if raw := c.Query("user_id"); raw != "" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, errors.New("invalid user_id"))
+			return
+		}
+		userID = parsed
+	}
+
+	agents, err := h.agentService.ListAssignedAgents(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, agentErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, newAgentResponses(agents))
+}
+
+// GetAgent godoc
+// @Summary Get an agent
+// @Tags agents
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Success 200 {object} AgentResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /agents/{id} [get]
+func (h *AgentHandler) GetAgent(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	agent, err := h.agentService.GetAgent(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, agentErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, newAgentResponse(agent))
+}
+
+// UpdateAgent godoc
+// @Summary Update an agent
+// @Description A new configuration version is recorded when the configuration changes.
+// @Description Configuration changes to high-risk agents are not applied: a pending change request is returned with 202.
+// @Description The name is exempt from approval and changes immediately. Fails with 409 when the agent's status or risk level changed concurrently.
+// @Tags agents
+// @Accept json
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Param request body UpdateAgentRequest true "Agent"
+// @Success 200 {object} AgentResponse
+// @Success 202 {object} domain.AgentChangeRequest
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /agents/{id} [put]
+func (h *AgentHandler) UpdateAgent(c *gin.Context) {
+	caller, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req UpdateAgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	agent, changeRequest, err := h.agentService.UpdateAgent(c.Request.Context(), id, caller.UserID, req.Name, req.Configuration, req.Reason)
+	if err != nil {
+		respondError(c, agentErrorStatus(err), err)
+		return
+	}
+	respondAgentChange(c, agent, changeRequest)
+}
+
+// DeleteAgent godoc
+// @Summary Soft-delete an agent
+// @Tags agents
+// @Param id path string true "Agent ID"
+// @Success 204
+// @Failure 403 {object} ErrorResponse
+// @Router /agents/{id} [delete]
+func (h *AgentHandler) DeleteAgent(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.agentService.DeleteAgent(c.Request.Context(), id); err != nil {
+		respondError(c, agentErrorStatus(err), err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListAgentResources godoc
+// @Summary List resources an agent can access
+// @Tags agents
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Success 200 {array} domain.Resource
+// @Router /agents/{id}/resources [get]
+func (h *AgentHandler) ListAgentResources(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	resources, err := h.agentService.ListAgentResources(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, agentErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, resources)
+}
+
+// ListAssignedUsers godoc
+// @Summary List users assigned to an agent
+// @Tags agents
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Success 200 {array} domain.User
+// @Router /agents/{id}/users [get]
+func (h *AgentHandler) ListAssignedUsers(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	users, err := h.agentService.ListAssignedUsers(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, agentErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+// GetAgentLLMs godoc
+// @Summary List LLM models configured for an agent
+// @Tags agents
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Success 200 {array} domain.AgentLLM
+// @Router /agents/{id}/llms [get]
+func (h *AgentHandler) GetAgentLLMs(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	llms, err := h.agentService.GetAgentLLMs(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, agentErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, llms)
+}
+
+// ListAssignedApplications godoc
+// @Summary List applications allowed to invoke an agent
+// @Tags agents
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Success 200 {array} domain.Application
+// @Router /agents/{id}/applications [get]
+func (h *AgentHandler) ListAssignedApplications(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	apps, err := h.agentService.ListAssignedApplications(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, agentErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, apps)
+}
+
+// ListAgentCertifications godoc
+// @Summary List compliance certifications of an agent
+// @Tags agents
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Success 200 {array} domain.Certification
+// @Router /agents/{id}/certifications [get]
+func (h *AgentHandler) ListAgentCertifications(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	certs, err := h.agentService.ListAgentCertifications(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, agentErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, certs)
+}
+
+// ListAgentVersions godoc
+// @Summary List the configuration history of an agent
+// @Description Versions are returned newest first.
+// @Tags agents
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of versions to skip"
+// @Success 200 {object} AgentVersionPage
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /agents/{id}/versions [get]
+func (h *AgentHandler) ListAgentVersions(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	versions, total, err := h.agentService.ListVersions(c.Request.Context(), id, limit, offset)
+	if err != nil {
+		respondError(c, agentErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, AgentVersionPage{Items: versions, Total: total, Limit: limit, Offset: offset})
+}
+
+// DiffAgentVersions godoc
+// @Summary Compare the configuration of two agent versions
+// @Tags agents
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Param from query int true "Source version number"
+// @Param to query int true "Target version number"
+// @Success 200 {object} service.VersionDiff
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /agents/{id}/versions/diff [get]
+func (h *AgentHandler) DiffAgentVersions(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil || from < 1 {
+		respondError(c, http.StatusBadRequest, errors.New("invalid from"))
+		return
+	}
+	to, err := strconv.Atoi(c.Query("to"))
+	if err != nil || to < 1 {
+		respondError(c, http.StatusBadRequest, errors.New("invalid to"))
+		return
+	}
+
+	diff, err := h.agentService.DiffVersions(c.Request.Context(), id, from, to)
+	if err != nil {
+		respondError(c, agentErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
+
+// RollbackAgent godoc
+// @Summary Roll an agent back to a previous configuration
+// @Description The restored configuration is recorded as a new version.
+// @Description High-risk agents are not rolled back directly: a pending change request is returned with 202.
+// @Tags agents
+// @Accept json
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Param request body RollbackAgentRequest true "Target version"
+// @Success 200 {object} AgentResponse
+// @Success 202 {object} domain.AgentChangeRequest
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /agents/{id}/rollback [post]
+func (h *AgentHandler) RollbackAgent(c *gin.Context) {
+	caller, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req RollbackAgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	agent, changeRequest, err := h.agentService.RollbackAgent(c.Request.Context(), id, caller.UserID, req.Version, req.Reason)
+	if err != nil {
+		respondError(c, agentErrorStatus(err), err)
+		return
+	}
+	respondAgentChange(c, agent, changeRequest)
+}
+
+// SetRiskLevel godoc
+// @Summary Classify an agent by risk level
+// @Description Configuration changes to high-risk agents require approval.
+// @Tags agents
+// @Accept json
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Param request body SetRiskLevelRequest true "Risk level"
+// @Success 200 {object} AgentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /agents/{id}/risk-level [put]
+func (h *AgentHandler) SetRiskLevel(c *gin.Context) {
+	caller, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req SetRiskLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	agent, err := h.agentService.SetRiskLevel(c.Request.Context(), id, caller.UserID, req.RiskLevel)
+	if err != nil {
+		respondError(c, agentErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, newAgentResponse(agent))
+}
+
+// ListChangeRequests godoc
+// @Summary List configuration change requests of an agent
+// @Tags change-requests
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Param status query string false "Filter by status (pending, approved, rejected)"
+// @Success 200 {array} domain.AgentChangeRequest
+// @Failure 403 {object} ErrorResponse
+// @Router /agents/{id}/change-requests [get]
+func (h *AgentHandler) ListChangeRequests(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	reqs, err := h.agentService.ListChangeRequests(c.Request.Context(), id, domain.ChangeRequestStatus(c.Query("status")))
+	if err != nil {
+		respondError(c, agentErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, reqs)
+}
+
+// GetChangeRequest godoc
+// @Summary Get a configuration change request
+// @Tags change-requests
+// @Produce json
+// @Param id path string true "Change request ID"
+// @Success 200 {object} domain.AgentChangeRequest
+// @Failure 404 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /change-requests/{id} [get]
+func (h *AgentHandler) GetChangeRequest(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	req, err := h.agentService.GetChangeRequest(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, agentErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+// ApproveChangeRequest godoc
+// @Summary Approve a configuration change request
+// @Description Applies the proposed configuration and records a new agent version. Requesters cannot approve their own requests.
+// @Description Requests whose base version is no longer the agent's latest fail with 409 and can only be rejected.
+// @Tags change-requests
+// @Accept json
+// @Produce json
+// @Param id path string true "Change request ID"
+// @Param request body ReviewChangeRequestRequest false "Review comment"
+// @Success 200 {object} domain.AgentChangeRequest
+// @Failure 404 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /change-requests/{id}/approve [post]
+func (h *AgentHandler) ApproveChangeRequest(c *gin.Context) {
+	h.reviewChangeRequest(c, h.agentService.ApproveChangeRequest)
+}
+
+// RejectChangeRequest godoc
+// @Summary Reject a configuration change request
+// @Tags change-requests
+// @Accept json
+// @Produce json
+// @Param id path string true "Change request ID"
+// @Param request body ReviewChangeRequestRequest false "Review comment"
+// @Success 200 {object} domain.AgentChangeRequest
+// @Failure 404 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /change-requests/{id}/reject [post]
+func (h *AgentHandler) RejectChangeRequest(c *gin.Context) {
+	h.reviewChangeRequest(c, h.agentService.RejectChangeRequest)
+}
+
+type reviewFunc func(ctx context.Context, id, reviewerID uuid.UUID, comment string) (*domain.AgentChangeRequest, error)
+
+func (h *AgentHandler) reviewChangeRequest(c *gin.Context, review reviewFunc) {
+	caller, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	// The comment is optional, and so is the body.
+	var req ReviewChangeRequestRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	changeRequest, err := review(c.Request.Context(), id, caller.UserID, req.Comment)
+	if err != nil {
+		respondError(c, agentErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, changeRequest)
+}
+
+// transitionTo returns the handler moving an agent to status.
+func (h *AgentHandler) transitionTo(status domain.AgentStatus) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h.TransitionAgent(c, status)
+	}
+}
+
+// TransitionAgent godoc
+// @Summary Move an agent through its lifecycle
+// @Description activate, deactivate, maintenance and deprecate move the agent to the matching status.
+// @Description Deprecated is terminal, maintenance and deprecation require a reason, and reactivation requires the agent to hold unexpired certifications.
+// @Tags agents
+// @Accept json
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Param transition path string true "Transition" Enums(activate, deactivate, maintenance, deprecate)
+// @Param request body TransitionAgentRequest false "Reason"
+// @Success 200 {object} AgentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /agents/{id}/{transition} [post]
+func (h *AgentHandler) TransitionAgent(c *gin.Context, status domain.AgentStatus) {
+	caller, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req TransitionAgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	agent, err := h.agentService.TransitionAgent(c.Request.Context(), id, caller.UserID, status, req.Reason)
+	if err != nil {
+		respondError(c, agentErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, newAgentResponse(agent))
+}
+
+// ListStatusTransitions godoc
+// @Summary List the lifecycle history of an agent
+// @Description Transitions are returned newest first.
+// @Tags agents
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Success 200 {array} domain.AgentStatusTransition
+// @Failure 403 {object} ErrorResponse
+// @Router /agents/{id}/status-history [get]
+func (h *AgentHandler) ListStatusTransitions(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	transitions, err := h.agentService.ListStatusTransitions(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, agentErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, transitions)
+}
@@ -0,0 +1,807 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"agentXmap/internal/domain"
+	"agentXmap/internal/policy"
+	"agentXmap/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAgentService is a mock implementation of service.AgentService
+type MockAgentService struct {
+	mock.Mock
+}
+
+func (m *MockAgentService) CreateAgent(ctx context.Context, orgID, userID uuid.UUID, name string, config json.RawMessage) (*domain.Agent, error) {
+	args := m.Called(ctx, orgID, userID, name, config)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Agent), args.Error(1)
+}
+
+func (m *MockAgentService) GetAgent(ctx context.Context, id uuid.UUID) (*domain.Agent, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Agent), args.Error(1)
+}
+
+func (m *MockAgentService) ListAgents(ctx context.Context, orgID uuid.UUID) ([]domain.Agent, error) {
+	args := m.Called(ctx, orgID)
+	return args.Get(0).([]domain.Agent), args.Error(1)
+}
+
+func (m *MockAgentService) ListAgentsByStatus(ctx context.Context, orgID uuid.UUID, status domain.AgentStatus) ([]domain.Agent, error) {
+	args := m.Called(ctx, orgID, status)
+	return args.Get(0).([]domain.Agent), args.Error(1)
+}
+
+func (m *MockAgentService) GetActiveMonthlyCost(ctx context.Context, orgID uuid.UUID) (float64, error) {
+	args := m.Called(ctx, orgID)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *MockAgentService) ListAgentResources(ctx context.Context, agentID uuid.UUID) ([]domain.Resource, error) {
+	args := m.Called(ctx, agentID)
+	return args.Get(0).([]domain.Resource), args.Error(1)
+}
+
+func (m *MockAgentService) ListAssignedUsers(ctx context.Context, agentID uuid.UUID) ([]domain.User, error) {
+	args := m.Called(ctx, agentID)
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+func (m *MockAgentService) ListAssignedAgents(ctx context.Context, userID uuid.UUID) ([]domain.Agent, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]domain.Agent), args.Error(1)
+}
+
+func (m *MockAgentService) GetAgentLLMs(ctx context.Context, agentID uuid.UUID) ([]domain.AgentLLM, error) {
+	args := m.Called(ctx, agentID)
+	return args.Get(0).([]domain.AgentLLM), args.Error(1)
+}
+
+func (m *MockAgentService) ListAssignedApplications(ctx context.Context, agentID uuid.UUID) ([]domain.Application, error) {
+	args := m.Called(ctx, agentID)
+	return args.Get(0).([]domain.Application), args.Error(1)
+}
+
+func (m *MockAgentService) ListAgentCertifications(ctx context.Context, agentID uuid.UUID) ([]domain.Certification, error) {
+	args := m.Called(ctx, agentID)
+	return args.Get(0).([]domain.Certification), args.Error(1)
+}
+
+func (m *MockAgentService) UpdateAgent(ctx context.Context, id, userID uuid.UUID, name string, config json.RawMessage, reason string) (*domain.Agent, *domain.AgentChangeRequest, error) {
+	args := m.Called(ctx, id, userID, name, config, reason)
+	return agentChangeResult(args)
+}
+
+func (m *MockAgentService) TransitionAgent(ctx context.Context, agentID, userID uuid.UUID, to domain.AgentStatus, reason string) (*domain.Agent, error) {
+	args := m.Called(ctx, agentID, userID, to, reason)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Agent), args.Error(1)
+}
+
+func (m *MockAgentService) ListStatusTransitions(ctx context.Context, agentID uuid.UUID) ([]domain.AgentStatusTransition, error) {
+	args := m.Called(ctx, agentID)
+	return args.Get(0).([]domain.AgentStatusTransition), args.Error(1)
+}
+
+// agentChangeResult unpacks the (agent, change request, error) results of a mocked call.
+func agentChangeResult(args mock.Arguments) (*domain.Agent, *domain.AgentChangeRequest, error) {
+	var (
+		agent         *domain.Agent
+		changeRequest *domain.AgentChangeRequest
+	)
+	if args.Get(0) != nil {
+		agent = args.Get(0).(*domain.Agent)
+	}
+	if args.Get(1) != nil {
+		changeRequest = args.Get(1).(*domain.AgentChangeRequest)
+	}
+	return agent, changeRequest, args.Error(2)
+}
+
+func (m *MockAgentService) DeleteAgent(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockAgentService) ListVersions(ctx context.Context, agentID uuid.UUID, limit, offset int) ([]domain.AgentVersion, int64, error) {
+	args := m.Called(ctx, agentID, limit, offset)
+	return args.Get(0).([]domain.AgentVersion), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockAgentService) DiffVersions(ctx context.Context, agentID uuid.UUID, fromVersion, toVersion int) (*service.VersionDiff, error) {
+	args := m.Called(ctx, agentID, fromVersion, toVersion)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.VersionDiff), args.Error(1)
+}
+
+func (m *MockAgentService) RollbackAgent(ctx context.Context, agentID, userID uuid.UUID, versionNumber int, reason string) (*domain.Agent, *domain.AgentChangeRequest, error) {
+	args := m.Called(ctx, agentID, userID, versionNumber, reason)
+	return agentChangeResult(args)
+}
+
+func (m *MockAgentService) SetRiskLevel(ctx context.Context, agentID, userID uuid.UUID, level domain.AgentRiskLevel) (*domain.Agent, error) {
+	args := m.Called(ctx, agentID, userID, level)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Agent), args.Error(1)
+}
+
+func (m *MockAgentService) ListChangeRequests(ctx context.Context, agentID uuid.UUID, status domain.ChangeRequestStatus) ([]domain.AgentChangeRequest, error) {
+	args := m.Called(ctx, agentID, status)
+	return args.Get(0).([]domain.AgentChangeRequest), args.Error(1)
+}
+
+func (m *MockAgentService) GetChangeRequest(ctx context.Context, id uuid.UUID) (*domain.AgentChangeRequest, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AgentChangeRequest), args.Error(1)
+}
+
+func (m *MockAgentService) ApproveChangeRequest(ctx context.Context, id, reviewerID uuid.UUID, comment string) (*domain.AgentChangeRequest, error) {
+	args := m.Called(ctx, id, reviewerID, comment)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AgentChangeRequest), args.Error(1)
+}
+
+func (m *MockAgentService) RejectChangeRequest(ctx context.Context, id, reviewerID uuid.UUID, comment string) (*domain.AgentChangeRequest, error) {
+	args := m.Called(ctx, id, reviewerID, comment)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AgentChangeRequest), args.Error(1)
+}
+
+// withPrincipal stands in for RequireAuth in handler tests.
+func withPrincipal(p *domain.Principal) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if p != nil {
+			c.Request = c.Request.WithContext(domain.WithPrincipal(c.Request.Context(), p))
+		}
+		c.Next()
+	}
+}
+
+func setupAgentRouter(svc service.AgentService, caller *domain.Principal) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api/v1", withPrincipal(caller))
+	NewAgentHandler(svc).RegisterRoutes(api)
+	return r
+}
+
+func newRequest(method, path string, body interface{}) *http.Request {
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestAgentHandler_CreateAgent(t *testing.T) {
+	orgID := uuid.New()
+	userID := uuid.New()
+	caller := &domain.Principal{UserID: userID, OrganizationID: orgID, Role: domain.UserRoleAdmin}
+
+	t.Run("Success", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		agent := &domain.Agent{ID: uuid.New(), OrganizationID: orgID, Name: "Bot", Status: domain.AgentStatusActive}
+		mockSvc.On("CreateAgent", mock.Anything, orgID, userID, "Bot", mock.Anything).Return(agent, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/agents", gin.H{"name": "Bot", "configuration": gin.H{"model": "gpt-4"}}))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var resp AgentResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, agent.ID, resp.ID)
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("Missing Identity", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/agents", gin.H{"name": "Bot"}))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		mockSvc.AssertNotCalled(t, "CreateAgent")
+	})
+
+	t.Run("Missing Name", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/agents", gin.H{}))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestAgentHandler_GetAgent(t *testing.T) {
+	orgID := uuid.New()
+	userID := uuid.New()
+	caller := &domain.Principal{UserID: userID, OrganizationID: orgID, Role: domain.UserRoleAdmin}
+	agentID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		mockSvc.On("GetAgent", mock.Anything, agentID).Return(&domain.Agent{ID: agentID, Name: "Bot"}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/agents/"+agentID.String(), nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		mockSvc.On("GetAgent", mock.Anything, agentID).Return(nil, service.ErrAgentNotFound)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/agents/"+agentID.String(), nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "agent not found")
+	})
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/agents/not-a-uuid", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestAgentHandler_ListAgents(t *testing.T) {
+	orgID := uuid.New()
+	userID := uuid.New()
+	caller := &domain.Principal{UserID: userID, OrganizationID: orgID, Role: domain.UserRoleAdmin}
+
+	t.Run("All", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		mockSvc.On("ListAgents", mock.Anything, orgID).Return([]domain.Agent{{Name: "A"}, {Name: "B"}}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/agents", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp []AgentResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Len(t, resp, 2)
+	})
+
+	t.Run("By Status", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		mockSvc.On("ListAgentsByStatus", mock.Anything, orgID, domain.AgentStatusMaintenance).Return([]domain.Agent{{Name: "A"}}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/agents?status=maintenance", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockSvc.AssertExpectations(t)
+	})
+}
+
+func TestAgentHandler_UpdateAgent(t *testing.T) {
+	orgID := uuid.New()
+	userID := uuid.New()
+	caller := &domain.Principal{UserID: userID, OrganizationID: orgID, Role: domain.UserRoleAdmin}
+	agentID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		mockSvc.On("UpdateAgent", mock.Anything, agentID, userID, "Renamed", mock.Anything, "").
+			Return(&domain.Agent{ID: agentID, Name: "Renamed"}, nil, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPut, "/api/v1/agents/"+agentID.String(), gin.H{"name": "Renamed"}))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("Pending Approval", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		requestID := uuid.New()
+		mockSvc.On("UpdateAgent", mock.Anything, agentID, userID, "Scoring", mock.Anything, "needs gpt-4").
+			Return(&domain.Agent{ID: agentID}, &domain.AgentChangeRequest{ID: requestID, Status: domain.ChangeRequestStatusPending}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPut, "/api/v1/agents/"+agentID.String(), gin.H{
+			"name": "Scoring", "configuration": gin.H{"model": "gpt-4"}, "reason": "needs gpt-4",
+		}))
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Contains(t, w.Body.String(), requestID.String())
+	})
+
+	t.Run("Missing Name", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPut, "/api/v1/agents/"+agentID.String(), gin.H{"configuration": gin.H{}}))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockSvc.AssertNotCalled(t, "UpdateAgent")
+	})
+}
+
+func TestAgentHandler_DeleteAgent(t *testing.T) {
+	orgID := uuid.New()
+	userID := uuid.New()
+	caller := &domain.Principal{UserID: userID, OrganizationID: orgID, Role: domain.UserRoleAdmin}
+	agentID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		mockSvc.On("DeleteAgent", mock.Anything, agentID).Return(nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodDelete, "/api/v1/agents/"+agentID.String(), nil))
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		mockSvc.On("DeleteAgent", mock.Anything, agentID).Return(errors.New("db error"))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodDelete, "/api/v1/agents/"+agentID.String(), nil))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestAgentHandler_GetActiveMonthlyCost(t *testing.T) {
+	orgID := uuid.New()
+	userID := uuid.New()
+	caller := &domain.Principal{UserID: userID, OrganizationID: orgID, Role: domain.UserRoleAdmin}
+
+	mockSvc := new(MockAgentService)
+	router := setupAgentRouter(mockSvc, caller)
+
+	mockSvc.On("GetActiveMonthlyCost", mock.Anything, orgID).Return(150.0, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/agents/cost", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp MonthlyCostResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 150.0, resp.MonthlyCost)
+}
+
+func TestAgentHandler_ListAssignedAgents(t *testing.T) {
+	orgID := uuid.New()
+	userID := uuid.New()
+	caller := &domain.Principal{UserID: userID, OrganizationID: orgID, Role: domain.UserRoleAdmin}
+	otherUserID := uuid.New()
+
+	t.Run("Caller", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		mockSvc.On("ListAssignedAgents", mock.Anything, userID).Return([]domain.Agent{{Name: "A"}}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/agents/assigned", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("Explicit User", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		mockSvc.On("ListAssignedAgents", mock.Anything, otherUserID).Return([]domain.Agent{}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/agents/assigned?user_id="+otherUserID.String(), nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockSvc.AssertExpectations(t)
+	})
+}
+
+func TestAgentHandler_ListAgentResources(t *testing.T) {
+	orgID := uuid.New()
+	userID := uuid.New()
+	caller := &domain.Principal{UserID: userID, OrganizationID: orgID, Role: domain.UserRoleAdmin}
+	agentID := uuid.New()
+
+	mockSvc := new(MockAgentService)
+	router := setupAgentRouter(mockSvc, caller)
+
+	mockSvc.On("ListAgentResources", mock.Anything, agentID).Return([]domain.Resource{{Name: "DB"}}, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/agents/"+agentID.String()+"/resources", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockSvc.AssertExpectations(t)
+}
+
+func TestAgentHandler_ListAgentVersions(t *testing.T) {
+	caller := &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleAdmin}
+	agentID := uuid.New()
+
+	t.Run("Default Pagination", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		mockSvc.On("ListVersions", mock.Anything, agentID, 20, 0).Return([]domain.AgentVersion{{VersionNumber: 2}, {VersionNumber: 1}}, int64(2), nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/agents/"+agentID.String()+"/versions", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var page AgentVersionPage
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Equal(t, int64(2), page.Total)
+		assert.Len(t, page.Items, 2)
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("Limit Is Capped", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		mockSvc.On("ListVersions", mock.Anything, agentID, 100, 40).Return([]domain.AgentVersion{}, int64(0), nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/agents/"+agentID.String()+"/versions?limit=500&offset=40", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("Invalid Offset", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/agents/"+agentID.String()+"/versions?offset=-1", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockSvc.AssertNotCalled(t, "ListVersions")
+	})
+}
+
+func TestAgentHandler_DiffAgentVersions(t *testing.T) {
+	caller := &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleAdmin}
+	agentID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		mockSvc.On("DiffVersions", mock.Anything, agentID, 1, 3).Return(&service.VersionDiff{
+			AgentID: agentID, FromVersion: 1, ToVersion: 3,
+			Changes: []service.ConfigChange{{Path: "/model", Op: service.ChangeChanged, From: "gpt-3.5", To: "gpt-4"}},
+		}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/agents/"+agentID.String()+"/versions/diff?from=1&to=3", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"path":"/model"`)
+	})
+
+	t.Run("Missing Version", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/agents/"+agentID.String()+"/versions/diff?from=1", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Unknown Version", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		mockSvc.On("DiffVersions", mock.Anything, agentID, 1, 9).Return(nil, service.ErrAgentVersionNotFound)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/agents/"+agentID.String()+"/versions/diff?from=1&to=9", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestAgentHandler_RollbackAgent(t *testing.T) {
+	userID := uuid.New()
+	caller := &domain.Principal{UserID: userID, OrganizationID: uuid.New(), Role: domain.UserRoleManager}
+	agentID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		mockSvc.On("RollbackAgent", mock.Anything, agentID, userID, 2, "bad prompt").Return(&domain.Agent{ID: agentID}, nil, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/agents/"+agentID.String()+"/rollback", gin.H{"version": 2, "reason": "bad prompt"}))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("Reason Required", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/agents/"+agentID.String()+"/rollback", gin.H{"version": 2}))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockSvc.AssertNotCalled(t, "RollbackAgent")
+	})
+
+	t.Run("Users Are Forbidden", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, &domain.Principal{UserID: userID, Role: domain.UserRoleUser})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/agents/"+agentID.String()+"/rollback", gin.H{"version": 2, "reason": "x"}))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+func TestAgentHandler_ReviewChangeRequest(t *testing.T) {
+	reviewerID := uuid.New()
+	caller := &domain.Principal{UserID: reviewerID, OrganizationID: uuid.New(), Role: domain.UserRoleManager}
+	requestID := uuid.New()
+
+	t.Run("Approve", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		mockSvc.On("ApproveChangeRequest", mock.Anything, requestID, reviewerID, "checked").
+			Return(&domain.AgentChangeRequest{ID: requestID, Status: domain.ChangeRequestStatusApproved}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/change-requests/"+requestID.String()+"/approve", gin.H{"comment": "checked"}))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("Approve Own Request", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		mockSvc.On("ApproveChangeRequest", mock.Anything, requestID, reviewerID, "").Return(nil, service.ErrSelfApproval)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/change-requests/"+requestID.String()+"/approve", nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Reject Already Resolved", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, caller)
+
+		mockSvc.On("RejectChangeRequest", mock.Anything, requestID, reviewerID, "").Return(nil, service.ErrChangeRequestNotPending)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/change-requests/"+requestID.String()+"/reject", nil))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Users Cannot Review", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, &domain.Principal{UserID: reviewerID, Role: domain.UserRoleUser})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/change-requests/"+requestID.String()+"/approve", nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockSvc.AssertNotCalled(t, "ApproveChangeRequest")
+	})
+}
+
+func TestAgentHandler_SetRiskLevel(t *testing.T) {
+	userID := uuid.New()
+	agentID := uuid.New()
+
+	t.Run("Admin", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, &domain.Principal{UserID: userID, Role: domain.UserRoleAdmin})
+
+		mockSvc.On("SetRiskLevel", mock.Anything, agentID, userID, domain.AgentRiskLevelHigh).
+			Return(&domain.Agent{ID: agentID, RiskLevel: domain.AgentRiskLevelHigh}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPut, "/api/v1/agents/"+agentID.String()+"/risk-level", gin.H{"risk_level": "high"}))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"risk_level":"high"`)
+	})
+
+	t.Run("Manager Forbidden", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, &domain.Principal{UserID: userID, Role: domain.UserRoleManager})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPut, "/api/v1/agents/"+agentID.String()+"/risk-level", gin.H{"risk_level": "minimal"}))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+func TestAgentHandler_TransitionAgent(t *testing.T) {
+	userID := uuid.New()
+	manager := &domain.Principal{UserID: userID, OrganizationID: uuid.New(), Role: domain.UserRoleManager}
+	agentID := uuid.New()
+
+	t.Run("Maintenance", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, manager)
+
+		mockSvc.On("TransitionAgent", mock.Anything, agentID, userID, domain.AgentStatusMaintenance, "reindexing").
+			Return(&domain.Agent{ID: agentID, Status: domain.AgentStatusMaintenance}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/agents/"+agentID.String()+"/maintenance", gin.H{"reason": "reindexing"}))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"maintenance"`)
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("Activate Without Body", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, manager)
+
+		mockSvc.On("TransitionAgent", mock.Anything, agentID, userID, domain.AgentStatusActive, "").
+			Return(nil, service.ErrValidCertificationRequired)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/agents/"+agentID.String()+"/activate", nil))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Invalid Transition", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, manager)
+
+		mockSvc.On("TransitionAgent", mock.Anything, agentID, userID, domain.AgentStatusInactive, "").
+			Return(nil, fmt.Errorf("%w: deprecated to inactive", service.ErrInvalidTransition))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/agents/"+agentID.String()+"/deactivate", nil))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Managers Cannot Deprecate", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, manager)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/agents/"+agentID.String()+"/deprecate", gin.H{"reason": "eol"}))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockSvc.AssertNotCalled(t, "TransitionAgent")
+	})
+
+	t.Run("History", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, manager)
+
+		mockSvc.On("ListStatusTransitions", mock.Anything, agentID).Return([]domain.AgentStatusTransition{
+			{AgentID: agentID, FromStatus: domain.AgentStatusActive, ToStatus: domain.AgentStatusMaintenance},
+		}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/agents/"+agentID.String()+"/status-history", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"to_status":"maintenance"`)
+	})
+}
+
+func TestAgentHandler_Authorization(t *testing.T) {
+	orgID := uuid.New()
+	agentID := uuid.New()
+	user := &domain.Principal{UserID: uuid.New(), OrganizationID: orgID, Role: domain.UserRoleUser}
+	manager := &domain.Principal{UserID: uuid.New(), OrganizationID: orgID, Role: domain.UserRoleManager}
+
+	t.Run("User cannot create", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, user)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/agents", gin.H{"name": "Bot"}))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockSvc.AssertNotCalled(t, "CreateAgent")
+	})
+
+	t.Run("Manager cannot delete", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, manager)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodDelete, "/api/v1/agents/"+agentID.String(), nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockSvc.AssertNotCalled(t, "DeleteAgent")
+	})
+
+	t.Run("User reaches service for reads", func(t *testing.T) {
+		mockSvc := new(MockAgentService)
+		router := setupAgentRouter(mockSvc, user)
+
+		mockSvc.On("GetAgent", mock.Anything, agentID).Return(nil, policy.ErrForbidden)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/agents/"+agentID.String(), nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockSvc.AssertExpectations(t)
+	})
+}
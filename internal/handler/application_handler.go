@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net/http"
+
+	"agentXmap/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ApplicationHandler exposes ApplicationService over HTTP.
+type ApplicationHandler struct {
+	appService service.ApplicationService
+}
+
+// NewApplicationHandler creates a new ApplicationHandler.
+func NewApplicationHandler(appService service.ApplicationService) *ApplicationHandler {
+	return &ApplicationHandler{appService: appService}
+}
+
+// RegisterAppRoutes mounts the endpoints called by Applications themselves.
+// rg must be guarded by RequireAPIKey.
+func (h *ApplicationHandler) RegisterAppRoutes(rg *gin.RouterGroup) {
+	app := rg.Group("/app")
+	{
+		app.GET("/agents", h.ListCallerAgents)
+	}
+}
+
+// ListCallerAgents godoc
+// @Summary List agents the calling application may invoke
+// @Tags applications
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} AgentResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /app/agents [get]
+func (h *ApplicationHandler) ListCallerAgents(c *gin.Context) {
+	caller, ok := currentApplication(c)
+	if !ok {
+		return
+	}
+
+	agents, err := h.appService.ListAssignedAgents(c.Request.Context(), caller.ApplicationID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, newAgentResponses(agents))
+}
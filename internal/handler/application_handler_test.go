@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"agentXmap/internal/domain"
+	"agentXmap/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockApplicationService is a mock implementation of service.ApplicationService
+type MockApplicationService struct {
+	mock.Mock
+}
+
+func (m *MockApplicationService) CreateApplication(ctx context.Context, ownerID uuid.UUID, name, description string) (*domain.Application, error) {
+	args := m.Called(ctx, ownerID, name, description)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Application), args.Error(1)
+}
+
+func (m *MockApplicationService) GetApplication(ctx context.Context, id uuid.UUID) (*domain.Application, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Application), args.Error(1)
+}
+
+func (m *MockApplicationService) ListAssignedAgents(ctx context.Context, appID uuid.UUID) ([]domain.Agent, error) {
+	args := m.Called(ctx, appID)
+	return args.Get(0).([]domain.Agent), args.Error(1)
+}
+
+func (m *MockApplicationService) ListApplicationCertifications(ctx context.Context, appID uuid.UUID) ([]domain.Certification, error) {
+	args := m.Called(ctx, appID)
+	return args.Get(0).([]domain.Certification), args.Error(1)
+}
+
+func (m *MockApplicationService) CreateAPIKey(ctx context.Context, appID uuid.UUID, name string) (string, *domain.ApplicationKey, error) {
+	args := m.Called(ctx, appID, name)
+	if args.Get(1) == nil {
+		return args.String(0), nil, args.Error(2)
+	}
+	return args.String(0), args.Get(1).(*domain.ApplicationKey), args.Error(2)
+}
+
+func (m *MockApplicationService) AuthenticateKey(ctx context.Context, rawKey string) (*domain.ApplicationKey, error) {
+	args := m.Called(ctx, rawKey)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ApplicationKey), args.Error(1)
+}
+
+func setupAppKeyRouter(svc service.ApplicationService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api/v1", RequireAPIKey(svc))
+	NewApplicationHandler(svc).RegisterAppRoutes(api)
+	return r
+}
+
+func TestRequireAPIKey(t *testing.T) {
+	appID := uuid.New()
+	key := &domain.ApplicationKey{
+		ID:            uuid.New(),
+		ApplicationID: appID,
+		Application:   domain.Application{ID: appID, IsActive: true, Owner: domain.User{OrganizationID: uuid.New()}},
+	}
+
+	t.Run("X-API-Key Header", func(t *testing.T) {
+		mockSvc := new(MockApplicationService)
+		router := setupAppKeyRouter(mockSvc)
+
+		mockSvc.On("AuthenticateKey", mock.Anything, "sk-live-good").Return(key, nil)
+		mockSvc.On("ListAssignedAgents", mock.Anything, appID).Return([]domain.Agent{{Name: "A"}}, nil)
+
+		req := newRequest(http.MethodGet, "/api/v1/app/agents", nil)
+		req.Header.Set(HeaderAPIKey, "sk-live-good")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("Bearer Header", func(t *testing.T) {
+		mockSvc := new(MockApplicationService)
+		router := setupAppKeyRouter(mockSvc)
+
+		mockSvc.On("AuthenticateKey", mock.Anything, "sk-live-good").Return(key, nil)
+		mockSvc.On("ListAssignedAgents", mock.Anything, appID).Return([]domain.Agent{}, nil)
+
+		req := newRequest(http.MethodGet, "/api/v1/app/agents", nil)
+		req.Header.Set("Authorization", "Bearer sk-live-good")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Missing Key", func(t *testing.T) {
+		mockSvc := new(MockApplicationService)
+		router := setupAppKeyRouter(mockSvc)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/app/agents", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Expired Key", func(t *testing.T) {
+		mockSvc := new(MockApplicationService)
+		router := setupAppKeyRouter(mockSvc)
+
+		mockSvc.On("AuthenticateKey", mock.Anything, "sk-live-old").Return(nil, service.ErrAPIKeyExpired)
+
+		req := newRequest(http.MethodGet, "/api/v1/app/agents", nil)
+		req.Header.Set(HeaderAPIKey, "sk-live-old")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		mockSvc.AssertNotCalled(t, "ListAssignedAgents", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Inactive Application", func(t *testing.T) {
+		mockSvc := new(MockApplicationService)
+		router := setupAppKeyRouter(mockSvc)
+
+		mockSvc.On("AuthenticateKey", mock.Anything, "sk-live-off").Return(nil, service.ErrApplicationInactive)
+
+		req := newRequest(http.MethodGet, "/api/v1/app/agents", nil)
+		req.Header.Set(HeaderAPIKey, "sk-live-off")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
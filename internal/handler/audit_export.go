@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"agentXmap/internal/domain"
+)
+
+// auditLogWriter encodes audit entries into a download, one at a time.
+type auditLogWriter interface {
+	// Begin writes what precedes the first entry.
+	Begin() error
+	WriteLog(log *domain.SystemAuditLog) error
+	// Flush writes out anything still buffered.
+	Flush() error
+}
+
+type auditExportFormat struct {
+	contentType string
+	extension   string
+	newWriter   func(w io.Writer) auditLogWriter
+}
+
+// auditExportFormats are the formats accepted by AuditHandler.ExportLogs.
+var auditExportFormats = map[string]auditExportFormat{
+	"csv": {
+		contentType: "text/csv; charset=utf-8",
+		extension:   "csv",
+		newWriter:   func(w io.Writer) auditLogWriter { return &csvAuditLogWriter{w: csv.NewWriter(w)} },
+	},
+	"ndjson": {
+		contentType: "application/x-ndjson",
+		extension:   "ndjson",
+		newWriter: func(w io.Writer) auditLogWriter {
+			buf := bufio.NewWriter(w)
+			return &ndjsonAuditLogWriter{buf: buf, enc: json.NewEncoder(buf)}
+		},
+	},
+}
+
+// csvAuditColumns is the header row of CSV exports. changes holds the JSON document.
+var csvAuditColumns = []string{
+	"id", "organization_id", "seq", "actor_user_id", "entity_type", "entity_id",
+	"action", "changes", "ip_address", "occurred_at", "prev_hash", "hash",
+}
+
+type csvAuditLogWriter struct {
+	w *csv.Writer
+}
+
+func (w *csvAuditLogWriter) Begin() error {
+	return w.w.Write(csvAuditColumns)
+}
+
+func (w *csvAuditLogWriter) WriteLog(log *domain.SystemAuditLog) error {
+	actor := ""
+	if log.ActorUserID != nil {
+		actor = log.ActorUserID.String()
+	}
+	changes := ""
+	if len(log.Changes) > 0 {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, log.Changes); err != nil {
+			return err
+		}
+		changes = compact.String()
+	}
+	return w.w.Write([]string{
+		log.ID.String(),
+		log.OrganizationID.String(),
+		strconv.FormatInt(log.Seq, 10),
+		actor,
+		log.EntityType,
+		log.EntityID.String(),
+		string(log.Action),
+		changes,
+		log.IPAddress,
+		log.OccurredAt.UTC().Format(time.RFC3339Nano),
+		log.PrevHash,
+		log.Hash,
+	})
+}
+
+func (w *csvAuditLogWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type ndjsonAuditLogWriter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func (w *ndjsonAuditLogWriter) Begin() error {
+	return nil
+}
+
+// WriteLog writes log as one line: json.Encoder terminates each value with a newline.
+func (w *ndjsonAuditLogWriter) WriteLog(log *domain.SystemAuditLog) error {
+	return w.enc.Encode(log)
+}
+
+func (w *ndjsonAuditLogWriter) Flush() error {
+	return w.buf.Flush()
+}
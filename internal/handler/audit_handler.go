@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"agentXmap/internal/domain"
+	"agentXmap/internal/policy"
+	"agentXmap/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AuditHandler exposes the organization's audit trail over HTTP.
+type AuditHandler struct {
+	auditService service.AuditService
+}
+
+// NewAuditHandler creates a new AuditHandler.
+func NewAuditHandler(auditService service.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// RegisterRoutes mounts the audit log endpoints under rg.
+func (h *AuditHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	logs := rg.Group("/audit-logs", RequirePermission(policy.ResourceAuditLog, policy.ActionRead))
+	{
+		logs.GET("", h.QueryLogs)
+		logs.GET("/export", h.ExportLogs)
+	}
+}
+
+// auditErrorStatus maps AuditService errors to HTTP status codes.
+func auditErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidAuditCursor), errors.Is(err, service.ErrInvalidAuditAction),
+		errors.Is(err, service.ErrInvalidTimeRange):
+		return http.StatusBadRequest
+	case errors.Is(err, policy.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, policy.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// parseAuditFilter reads the audit log filter from the query string,
+// answering 400 when a value is malformed.
+func parseAuditFilter(c *gin.Context) (domain.AuditLogFilter, bool) {
+	filter := domain.AuditLogFilter{
+		EntityType: c.Query("entity_type"),
+		Action:     domain.AuditAction(c.Query("action")),
+	}
+	for name, dst := range map[string]**uuid.UUID{"actor_user_id": &filter.ActorUserID, "entity_id": &filter.EntityID} {
+		if raw := c.Query(name); raw != "" {
+			id, err := uuid.Parse(raw)
+			if err != nil {
+				respondError(c, http.StatusBadRequest, errors.New("invalid "+name))
+				return filter, false
+			}
+			*dst = &id
+		}
+	}
+	for name, dst := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if raw := c.Query(name); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				respondError(c, http.StatusBadRequest, errors.New("invalid "+name))
+				return filter, false
+			}
+			*dst = &t
+		}
+	}
+	return filter, true
+}
+
+// QueryLogs godoc
+// @Summary Query the audit trail
+// @Description Entries of the caller's organization, newest first. Pass next_cursor back as cursor for the next page.
+// @Tags audit
+// @Produce json
+// @Param actor_user_id query string false "Acting user ID"
+// @Param entity_type query string false "Entity type, e.g. agent"
+// @Param entity_id query string false "Entity ID"
+// @Param action query string false "Audit action, e.g. update"
+// @Param from query string false "Earliest occurred_at, RFC 3339, inclusive"
+// @Param to query string false "Latest occurred_at, RFC 3339, exclusive"
+// @Param cursor query string false "Cursor from the previous page"
+// @Param limit query int false "Page size (max 100)" default(20)
+// @Success 200 {object} service.AuditLogPage
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /audit-logs [get]
+func (h *AuditHandler) QueryLogs(c *gin.Context) {
+	filter, ok := parseAuditFilter(c)
+	if !ok {
+		return
+	}
+	limit, ok := parseLimit(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.auditService.QueryLogs(c.Request.Context(), filter, c.Query("cursor"), limit)
+	if err != nil {
+		respondError(c, auditErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// ExportLogs godoc
+// @Summary Export the audit trail
+// @Description Streams every matching entry of the caller's organization, newest first, as CSV or NDJSON. The export is itself audited as export_data. An error after streaming started truncates the download.
+// @Tags audit
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format query string false "csv or ndjson" default(ndjson)
+// @Param actor_user_id query string false "Acting user ID"
+// @Param entity_type query string false "Entity type, e.g. agent"
+// @Param entity_id query string false "Entity ID"
+// @Param action query string false "Audit action, e.g. update"
+// @Param from query string false "Earliest occurred_at, RFC 3339, inclusive"
+// @Param to query string false "Latest occurred_at, RFC 3339, exclusive"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /audit-logs/export [get]
+func (h *AuditHandler) ExportLogs(c *gin.Context) {
+	format, ok := auditExportFormats[c.DefaultQuery("format", "ndjson")]
+	if !ok {
+		respondError(c, http.StatusBadRequest, errors.New("invalid format"))
+		return
+	}
+	filter, ok := parseAuditFilter(c)
+	if !ok {
+		return
+	}
+
+	// Headers go out with the first entry, so errors raised before it still
+	// get a JSON error response.
+	var w auditLogWriter
+	start := func() error {
+		c.Header("Content-Type", format.contentType)
+		c.Header("Content-Disposition", `attachment; filename="audit-logs.`+format.extension+`"`)
+		c.Header("Cache-Control", "no-store")
+		c.Status(http.StatusOK)
+		w = format.newWriter(c.Writer)
+		return w.Begin()
+	}
+	err := h.auditService.ExportLogs(c.Request.Context(), filter, func(log *domain.SystemAuditLog) error {
+		if w == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return w.WriteLog(log)
+	})
+	if err != nil {
+		if w == nil {
+			respondError(c, auditErrorStatus(err), err)
+			return
+		}
+		_ = c.Error(err)
+		return
+	}
+	if w == nil {
+		if err := start(); err != nil {
+			_ = c.Error(err)
+			return
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = c.Error(err)
+	}
+}
@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"agentXmap/internal/domain"
+	"agentXmap/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockAuditService is a mock implementation of service.AuditService
+type MockAuditService struct {
+	mock.Mock
+}
+
+func (m *MockAuditService) LogAction(ctx context.Context, orgID uuid.UUID, actorUserID *uuid.UUID, entityType string, entityID uuid.UUID, action domain.AuditAction, changes json.RawMessage, ipAddress string) error {
+	args := m.Called(ctx, orgID, actorUserID, entityType, entityID, action, changes, ipAddress)
+	return args.Error(0)
+}
+
+func (m *MockAuditService) RecordExecution(ctx context.Context, exec *domain.AgentExecution) error {
+	args := m.Called(ctx, exec)
+	return args.Error(0)
+}
+
+func (m *MockAuditService) QueryLogs(ctx context.Context, filter domain.AuditLogFilter, cursor string, limit int) (*service.AuditLogPage, error) {
+	args := m.Called(ctx, filter, cursor, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.AuditLogPage), args.Error(1)
+}
+
+// ExportLogs hands the entries given to Return to fn, then returns the error given after them.
+func (m *MockAuditService) ExportLogs(ctx context.Context, filter domain.AuditLogFilter, fn func(*domain.SystemAuditLog) error) error {
+	args := m.Called(ctx, filter)
+	for _, log := range args.Get(0).([]domain.SystemAuditLog) {
+		if err := fn(&log); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func setupAuditRouter(svc service.AuditService, caller *domain.Principal) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api/v1", withPrincipal(caller))
+	NewAuditHandler(svc).RegisterRoutes(api)
+	return r
+}
+
+func TestAuditHandler_QueryLogs(t *testing.T) {
+	admin := &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleAdmin}
+
+	t.Run("Success", func(t *testing.T) {
+		mockSvc := new(MockAuditService)
+		router := setupAuditRouter(mockSvc, admin)
+		entityID := uuid.New()
+		from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+		mockSvc.On("QueryLogs", mock.Anything, domain.AuditLogFilter{EntityID: &entityID, Action: domain.AuditActionUpdate, From: &from}, "MTI4", 50).
+			Return(&service.AuditLogPage{Items: []domain.SystemAuditLog{{Seq: 127}}, NextCursor: "MTI3"}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/audit-logs?entity_id="+entityID.String()+"&action=update&from=2026-03-01T00:00:00Z&cursor=MTI4&limit=50", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"next_cursor":"MTI3"`)
+		assert.Contains(t, w.Body.String(), `"seq":127`)
+	})
+
+	t.Run("Invalid Time", func(t *testing.T) {
+		router := setupAuditRouter(new(MockAuditService), admin)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/audit-logs?to=yesterday", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid to")
+	})
+
+	t.Run("Invalid Cursor", func(t *testing.T) {
+		mockSvc := new(MockAuditService)
+		router := setupAuditRouter(mockSvc, admin)
+		mockSvc.On("QueryLogs", mock.Anything, mock.Anything, "bogus", 20).Return(nil, service.ErrInvalidAuditCursor)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/audit-logs?cursor=bogus", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Forbidden for managers", func(t *testing.T) {
+		mockSvc := new(MockAuditService)
+		manager := &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleManager}
+		router := setupAuditRouter(mockSvc, manager)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/audit-logs", nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockSvc.AssertNotCalled(t, "QueryLogs", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestAuditHandler_ExportLogs(t *testing.T) {
+	admin := &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleAdmin}
+	actor := uuid.New()
+	logs := []domain.SystemAuditLog{
+		{ID: uuid.New(), Seq: 2, ActorUserID: &actor, EntityType: "agent", Action: domain.AuditActionUpdate, Changes: json.RawMessage(`{"name": "a,b"}`), Hash: "h2", PrevHash: "h1"},
+		{ID: uuid.New(), Seq: 1, EntityType: "agent", Action: domain.AuditActionCreate, Hash: "h1"},
+	}
+
+	t.Run("CSV", func(t *testing.T) {
+		mockSvc := new(MockAuditService)
+		router := setupAuditRouter(mockSvc, admin)
+		mockSvc.On("ExportLogs", mock.Anything, domain.AuditLogFilter{EntityType: "agent"}).Return(logs, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/audit-logs/export?format=csv&entity_type=agent", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "audit-logs.csv")
+		rows, err := csv.NewReader(w.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+		assert.Equal(t, csvAuditColumns, rows[0])
+		assert.Equal(t, []string{actor.String(), `{"name":"a,b"}`}, []string{rows[1][3], rows[1][7]})
+		assert.Equal(t, "", rows[2][3])
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		mockSvc := new(MockAuditService)
+		router := setupAuditRouter(mockSvc, admin)
+		mockSvc.On("ExportLogs", mock.Anything, domain.AuditLogFilter{}).Return(logs, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/audit-logs/export", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+		lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+		require.Len(t, lines, 2)
+		var first domain.SystemAuditLog
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+		assert.Equal(t, logs[0].ID, first.ID)
+	})
+
+	t.Run("Empty CSV Still Has A Header", func(t *testing.T) {
+		mockSvc := new(MockAuditService)
+		router := setupAuditRouter(mockSvc, admin)
+		mockSvc.On("ExportLogs", mock.Anything, mock.Anything).Return([]domain.SystemAuditLog{}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/audit-logs/export?format=csv", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, strings.Join(csvAuditColumns, ",")+"\n", w.Body.String())
+	})
+
+	t.Run("Error Before Streaming", func(t *testing.T) {
+		mockSvc := new(MockAuditService)
+		router := setupAuditRouter(mockSvc, admin)
+		mockSvc.On("ExportLogs", mock.Anything, mock.Anything).Return([]domain.SystemAuditLog{}, errors.New("audit down"))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/audit-logs/export?format=csv", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+	})
+
+	t.Run("Unknown Format", func(t *testing.T) {
+		mockSvc := new(MockAuditService)
+		router := setupAuditRouter(mockSvc, admin)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/audit-logs/export?format=xlsx", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockSvc.AssertNotCalled(t, "ExportLogs", mock.Anything, mock.Anything)
+	})
+}
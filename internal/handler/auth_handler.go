@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"agentXmap/internal/domain"
+	"agentXmap/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoginRequest carries user credentials.
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required,email" example:"john.doe@acme.com"`
+	Password string `json:"password" binding:"required" example:"s3cret!"`
+}
+
+// RefreshRequest carries a refresh token to rotate or revoke.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// PasswordResetRequest asks for a password reset email.
+type PasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email" example:"john.doe@acme.com"`
+}
+
+// ConfirmPasswordResetRequest sets a new password with a reset token.
+type ConfirmPasswordResetRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required" example:"n3w-s3cret!"`
+}
+
+// LoginResponse is returned after a successful login.
+type LoginResponse struct {
+	User   *domain.User       `json:"user"`
+	Tokens *service.TokenPair `json:"tokens"`
+}
+
+// AuthHandler exposes session management over HTTP.
+type AuthHandler struct {
+	identityService service.IdentityService
+	sessionService  service.SessionService
+}
+
+// NewAuthHandler creates a new AuthHandler.
+func NewAuthHandler(identityService service.IdentityService, sessionService service.SessionService) *AuthHandler {
+	return &AuthHandler{identityService: identityService, sessionService: sessionService}
+}
+
+// RegisterRoutes mounts the auth endpoints. Routes needing a valid access
+// token are mounted on protected.
+func (h *AuthHandler) RegisterRoutes(public, protected *gin.RouterGroup) {
+	auth := public.Group("/auth")
+	{
+		auth.POST("/login", h.Login)
+		auth.POST("/refresh", h.Refresh)
+		auth.POST("/logout", h.Logout)
+		auth.POST("/password-reset", h.RequestPasswordReset)
+		auth.POST("/password-reset/confirm", h.ConfirmPasswordReset)
+	}
+	protected.POST("/auth/logout-all", h.LogoutAll)
+}
+
+// authErrorStatus maps session errors to HTTP status codes.
+func authErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidCredentials),
+		errors.Is(err, service.ErrInvalidToken),
+		errors.Is(err, service.ErrRefreshTokenReused):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrInvalidResetToken),
+		errors.Is(err, service.ErrPasswordTooShort):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrResetQueueFull):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Login godoc
+// @Summary Log in and open a session
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body LoginRequest true "Credentials"
+// @Success 200 {object} LoginResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /auth/login [post]
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	user, tokens, err := h.identityService.Login(c.Request.Context(), req.Email, req.Password)
+	if err != nil {
+		respondError(c, authErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, LoginResponse{User: user, Tokens: tokens})
+}
+
+// Refresh godoc
+// @Summary Rotate a refresh token
+// @Description The presented refresh token is revoked and a new pair is returned.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body RefreshRequest true "Refresh token"
+// @Success 200 {object} service.TokenPair
+// @Failure 401 {object} ErrorResponse
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	tokens, err := h.sessionService.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		respondError(c, authErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout godoc
+// @Summary Revoke a refresh token
+// @Description Ends the session of the refresh token; its access tokens stop working too.
+// @Tags auth
+// @Accept json
+// @Param request body RefreshRequest true "Refresh token"
+// @Success 204
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.sessionService.Revoke(c.Request.Context(), req.RefreshToken); err != nil {
+		respondError(c, authErrorStatus(err), err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// LogoutAll godoc
+// @Summary Revoke every session of the caller
+// @Tags auth
+// @Security BearerAuth
+// @Success 204
+// @Router /auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	caller, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	if err := h.sessionService.RevokeAllForUser(c.Request.Context(), caller.UserID); err != nil {
+		respondError(c, authErrorStatus(err), err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RequestPasswordReset godoc
+// @Summary Request a password reset email
+// @Description Queues the request and answers 202 without looking the email up, so registered and unknown addresses are indistinguishable, also in response time. 503 when too many requests are waiting.
+// @Tags auth
+// @Accept json
+// @Param request body PasswordResetRequest true "Email"
+// @Success 202
+// @Failure 400 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /auth/password-reset [post]
+func (h *AuthHandler) RequestPasswordReset(c *gin.Context) {
+	var req PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.identityService.RequestPasswordReset(c.Request.Context(), req.Email); err != nil {
+		respondError(c, authErrorStatus(err), err)
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+// ConfirmPasswordReset godoc
+// @Summary Set a new password with a reset token
+// @Description The token is single-use. Every session of the user is revoked.
+// @Tags auth
+// @Accept json
+// @Param request body ConfirmPasswordResetRequest true "Token and new password"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Router /auth/password-reset/confirm [post]
+func (h *AuthHandler) ConfirmPasswordReset(c *gin.Context) {
+	var req ConfirmPasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.identityService.ResetPassword(c.Request.Context(), req.Token, req.NewPassword); err != nil {
+		respondError(c, authErrorStatus(err), err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,340 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"agentXmap/internal/domain"
+	"agentXmap/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockSessionService is a mock implementation of service.SessionService
+type MockSessionService struct {
+	mock.Mock
+}
+
+func (m *MockSessionService) IssueTokens(ctx context.Context, user *domain.User) (*service.TokenPair, error) {
+	args := m.Called(ctx, user)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.TokenPair), args.Error(1)
+}
+
+func (m *MockSessionService) Refresh(ctx context.Context, refreshToken string) (*service.TokenPair, error) {
+	args := m.Called(ctx, refreshToken)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.TokenPair), args.Error(1)
+}
+
+func (m *MockSessionService) ValidateAccessToken(ctx context.Context, accessToken string) (*domain.Principal, error) {
+	args := m.Called(ctx, accessToken)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Principal), args.Error(1)
+}
+
+func (m *MockSessionService) Revoke(ctx context.Context, refreshToken string) error {
+	args := m.Called(ctx, refreshToken)
+	return args.Error(0)
+}
+
+func (m *MockSessionService) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+// MockIdentityService is a mock implementation of service.IdentityService
+type MockIdentityService struct {
+	mock.Mock
+}
+
+func (m *MockIdentityService) SignUp(ctx context.Context, orgName, email, password string) (*domain.User, error) {
+	args := m.Called(ctx, orgName, email, password)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockIdentityService) Login(ctx context.Context, email, password string) (*domain.User, *service.TokenPair, error) {
+	args := m.Called(ctx, email, password)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*domain.User), args.Get(1).(*service.TokenPair), args.Error(2)
+}
+
+func (m *MockIdentityService) InviteUsers(ctx context.Context, invitorID uuid.UUID, emails []string, role domain.UserRole) ([]service.InvitationOutcome, error) {
+	args := m.Called(ctx, invitorID, emails, role)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]service.InvitationOutcome), args.Error(1)
+}
+
+func (m *MockIdentityService) AcceptInvitation(ctx context.Context, token, password, firstName, lastName string) (*domain.User, error) {
+	args := m.Called(ctx, token, password, firstName, lastName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockIdentityService) ListInvitations(ctx context.Context, orgID uuid.UUID, status domain.InvitationStatus) ([]domain.Invitation, error) {
+	args := m.Called(ctx, orgID, status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Invitation), args.Error(1)
+}
+
+func (m *MockIdentityService) ResendInvitation(ctx context.Context, id uuid.UUID) (*domain.Invitation, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Invitation), args.Error(1)
+}
+
+func (m *MockIdentityService) RevokeInvitation(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockIdentityService) ExpireInvitations(ctx context.Context, now time.Time) (int, error) {
+	args := m.Called(ctx, now)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockIdentityService) RequestPasswordReset(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *MockIdentityService) ProcessPasswordResets(ctx context.Context, onError func(error)) {
+	m.Called(ctx, onError)
+}
+
+func (m *MockIdentityService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	args := m.Called(ctx, token, newPassword)
+	return args.Error(0)
+}
+
+func setupAuthRouter(sessions service.SessionService) *gin.Engine {
+	return setupIdentityRouter(nil, sessions)
+}
+
+func setupIdentityRouter(identity service.IdentityService, sessions service.SessionService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api/v1")
+	protected := api.Group("", RequireAuth(sessions))
+	NewAuthHandler(identity, sessions).RegisterRoutes(api, protected)
+	return r
+}
+
+func TestRequireAuth(t *testing.T) {
+	caller := &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleUser}
+
+	t.Run("Valid Token", func(t *testing.T) {
+		sessions := new(MockSessionService)
+		router := setupAuthRouter(sessions)
+
+		sessions.On("ValidateAccessToken", mock.Anything, "good").Return(caller, nil)
+		sessions.On("RevokeAllForUser", mock.Anything, caller.UserID).Return(nil)
+
+		req := newRequest(http.MethodPost, "/api/v1/auth/logout-all", nil)
+		req.Header.Set("Authorization", "Bearer good")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		sessions.AssertExpectations(t)
+	})
+
+	t.Run("Missing Header", func(t *testing.T) {
+		sessions := new(MockSessionService)
+		router := setupAuthRouter(sessions)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/auth/logout-all", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		sessions.AssertNotCalled(t, "ValidateAccessToken", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid Token", func(t *testing.T) {
+		sessions := new(MockSessionService)
+		router := setupAuthRouter(sessions)
+
+		sessions.On("ValidateAccessToken", mock.Anything, "bad").Return(nil, service.ErrInvalidToken)
+
+		req := newRequest(http.MethodPost, "/api/v1/auth/logout-all", nil)
+		req.Header.Set("Authorization", "Bearer bad")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		sessions.AssertNotCalled(t, "RevokeAllForUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Session Lookup Fails", func(t *testing.T) {
+		sessions := new(MockSessionService)
+		router := setupAuthRouter(sessions)
+
+		sessions.On("ValidateAccessToken", mock.Anything, "good").Return(nil, errors.New("db down"))
+
+		req := newRequest(http.MethodPost, "/api/v1/auth/logout-all", nil)
+		req.Header.Set("Authorization", "Bearer good")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		sessions.AssertNotCalled(t, "RevokeAllForUser", mock.Anything, mock.Anything)
+	})
+}
+
+func TestCaptureClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	capture := func(trusted []string) string {
+		r := gin.New()
+		assert.NoError(t, r.SetTrustedProxies(trusted))
+		var ip string
+		r.GET("/ip", CaptureClientIP(), func(c *gin.Context) {
+			ip = domain.ClientIPFromContext(c.Request.Context())
+		})
+		req := newRequest(http.MethodGet, "/ip", nil)
+		req.RemoteAddr = "10.0.0.2:41000"
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		return ip
+	}
+
+	t.Run("Trusted Proxy", func(t *testing.T) {
+		assert.Equal(t, "203.0.113.7", capture([]string{"10.0.0.0/8"}))
+	})
+
+	t.Run("Untrusted Proxy", func(t *testing.T) {
+		// A client cannot spoof its address through X-Forwarded-For.
+		assert.Equal(t, "10.0.0.2", capture(nil))
+	})
+}
+
+func TestAuthHandler_Refresh(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		sessions := new(MockSessionService)
+		router := setupAuthRouter(sessions)
+
+		sessions.On("Refresh", mock.Anything, "old").Return(&service.TokenPair{AccessToken: "a", RefreshToken: "new"}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/auth/refresh", gin.H{"refresh_token": "old"}))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"refresh_token":"new"`)
+	})
+
+	t.Run("Reused", func(t *testing.T) {
+		sessions := new(MockSessionService)
+		router := setupAuthRouter(sessions)
+
+		sessions.On("Refresh", mock.Anything, "old").Return(nil, service.ErrRefreshTokenReused)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/auth/refresh", gin.H{"refresh_token": "old"}))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestAuthHandler_Logout(t *testing.T) {
+	sessions := new(MockSessionService)
+	router := setupAuthRouter(sessions)
+
+	sessions.On("Revoke", mock.Anything, "tok").Return(nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/auth/logout", gin.H{"refresh_token": "tok"}))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	sessions.AssertExpectations(t)
+}
+
+func TestAuthHandler_RequestPasswordReset(t *testing.T) {
+	t.Run("Accepted", func(t *testing.T) {
+		identity := new(MockIdentityService)
+		router := setupIdentityRouter(identity, new(MockSessionService))
+
+		identity.On("RequestPasswordReset", mock.Anything, "john@acme.com").Return(nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/auth/password-reset", gin.H{"email": "john@acme.com"}))
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		identity.AssertExpectations(t)
+	})
+
+	t.Run("Invalid Email", func(t *testing.T) {
+		identity := new(MockIdentityService)
+		router := setupIdentityRouter(identity, new(MockSessionService))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/auth/password-reset", gin.H{"email": "nope"}))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		identity.AssertNotCalled(t, "RequestPasswordReset", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Queue Full", func(t *testing.T) {
+		identity := new(MockIdentityService)
+		router := setupIdentityRouter(identity, new(MockSessionService))
+
+		identity.On("RequestPasswordReset", mock.Anything, "john@acme.com").Return(service.ErrResetQueueFull)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/auth/password-reset", gin.H{"email": "john@acme.com"}))
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+}
+
+func TestAuthHandler_ConfirmPasswordReset(t *testing.T) {
+	body := gin.H{"token": "tok", "new_password": "n3w-password"}
+
+	t.Run("Success", func(t *testing.T) {
+		identity := new(MockIdentityService)
+		router := setupIdentityRouter(identity, new(MockSessionService))
+
+		identity.On("ResetPassword", mock.Anything, "tok", "n3w-password").Return(nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/auth/password-reset/confirm", body))
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("Invalid Token", func(t *testing.T) {
+		identity := new(MockIdentityService)
+		router := setupIdentityRouter(identity, new(MockSessionService))
+
+		identity.On("ResetPassword", mock.Anything, "tok", "n3w-password").Return(service.ErrInvalidResetToken)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/auth/password-reset/confirm", body))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
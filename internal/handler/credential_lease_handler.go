@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"agentXmap/internal/policy"
+	"agentXmap/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CredentialLeaseHandler exposes CredentialLeaseService to Applications.
+type CredentialLeaseHandler struct {
+	leaseService service.CredentialLeaseService
+}
+
+// NewCredentialLeaseHandler creates a new CredentialLeaseHandler.
+func NewCredentialLeaseHandler(leaseService service.CredentialLeaseService) *CredentialLeaseHandler {
+	return &CredentialLeaseHandler{leaseService: leaseService}
+}
+
+// RegisterAppRoutes mounts the lease endpoints called by Applications.
+// rg must be guarded by RequireAPIKey.
+func (h *CredentialLeaseHandler) RegisterAppRoutes(rg *gin.RouterGroup) {
+	app := rg.Group("/app")
+	{
+		app.POST("/agents/:agentId/resources/:resourceId/lease", h.IssueLease)
+	}
+}
+
+// leaseErrorStatus maps CredentialLeaseService errors to HTTP status codes.
+func leaseErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrAgentNotFound), errors.Is(err, service.ErrResourceNotFound),
+		errors.Is(err, service.ErrResourceSecretNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrAgentNotInvocable), errors.Is(err, service.ErrAccessNotGranted):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrAgentNotActive), errors.Is(err, service.ErrLeaseNotSupported):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrIssueFailed):
+		return http.StatusBadGateway
+	case errors.Is(err, policy.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// IssueLease godoc
+// @Summary Lease temporary credentials of a resource for an agent
+// @Description The calling application must be allowed to invoke the agent, and the agent must be active and have access to the resource. The resource is asked to mint credentials limited to the agent's access level, which stop working at expires_at; that depends on the access level. The resource's stored credentials are never returned. Types that cannot mint expiring credentials (only postgres_db can) are rejected with 409. Issuance and expiry are audited.
+// @Tags applications
+// @Produce json
+// @Security ApiKeyAuth
+// @Param agentId path string true "Agent ID"
+// @Param resourceId path string true "Resource ID"
+// @Success 201 {object} service.IssuedLease
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /app/agents/{agentId}/resources/{resourceId}/lease [post]
+func (h *CredentialLeaseHandler) IssueLease(c *gin.Context) {
+	if _, ok := currentApplication(c); !ok {
+		return
+	}
+	agentID, ok := parseIDParam(c, "agentId")
+	if !ok {
+		return
+	}
+	resourceID, ok := parseIDParam(c, "resourceId")
+	if !ok {
+		return
+	}
+
+	lease, err := h.leaseService.IssueLease(c.Request.Context(), agentID, resourceID)
+	if err != nil {
+		respondError(c, leaseErrorStatus(err), err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, lease)
+}
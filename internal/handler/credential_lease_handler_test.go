@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"agentXmap/internal/domain"
+	"agentXmap/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockCredentialLeaseService is a mock implementation of service.CredentialLeaseService
+type MockCredentialLeaseService struct {
+	mock.Mock
+}
+
+func (m *MockCredentialLeaseService) IssueLease(ctx context.Context, agentID, resourceID uuid.UUID) (*service.IssuedLease, error) {
+	args := m.Called(ctx, agentID, resourceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.IssuedLease), args.Error(1)
+}
+
+func (m *MockCredentialLeaseService) ExpireLeases(ctx context.Context, now time.Time, batchSize int) (int, error) {
+	args := m.Called(ctx, now, batchSize)
+	return args.Int(0), args.Error(1)
+}
+
+// withApplication stands in for RequireAPIKey.
+func withApplication(app *domain.ApplicationPrincipal) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if app != nil {
+			c.Request = c.Request.WithContext(domain.WithApplicationPrincipal(c.Request.Context(), app))
+		}
+		c.Next()
+	}
+}
+
+func setupLeaseRouter(svc service.CredentialLeaseService, app *domain.ApplicationPrincipal) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api/v1", withApplication(app))
+	NewCredentialLeaseHandler(svc).RegisterAppRoutes(api)
+	return r
+}
+
+func TestCredentialLeaseHandler_IssueLease(t *testing.T) {
+	app := &domain.ApplicationPrincipal{ApplicationID: uuid.New(), OrganizationID: uuid.New()}
+	agentID, resourceID := uuid.New(), uuid.New()
+	path := "/api/v1/app/agents/" + agentID.String() + "/resources/" + resourceID.String() + "/lease"
+
+	t.Run("Success", func(t *testing.T) {
+		mockSvc := new(MockCredentialLeaseService)
+		router := setupLeaseRouter(mockSvc, app)
+
+		issued := &service.IssuedLease{
+			CredentialLease: domain.CredentialLease{ID: uuid.New(), AgentID: agentID, ResourceID: resourceID, AccessLevel: domain.AccessLevelReadOnly, KeyVersionID: "v1"},
+			Credentials:     json.RawMessage(`{"password":"s3cret"}`),
+		}
+		mockSvc.On("IssueLease", mock.Anything, agentID, resourceID).Return(issued, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, path, nil))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		assert.Contains(t, w.Body.String(), `"credentials":{"password":"s3cret"}`)
+		assert.Contains(t, w.Body.String(), `"access_level":"read_only"`)
+		assert.NotContains(t, w.Body.String(), "key_version")
+	})
+
+	t.Run("Agent Without Access", func(t *testing.T) {
+		mockSvc := new(MockCredentialLeaseService)
+		router := setupLeaseRouter(mockSvc, app)
+
+		mockSvc.On("IssueLease", mock.Anything, agentID, resourceID).Return(nil, service.ErrAccessNotGranted)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, path, nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Inactive Agent", func(t *testing.T) {
+		mockSvc := new(MockCredentialLeaseService)
+		router := setupLeaseRouter(mockSvc, app)
+
+		mockSvc.On("IssueLease", mock.Anything, agentID, resourceID).Return(nil, service.ErrAgentNotActive)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, path, nil))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Type Cannot Issue Credentials", func(t *testing.T) {
+		mockSvc := new(MockCredentialLeaseService)
+		router := setupLeaseRouter(mockSvc, app)
+
+		mockSvc.On("IssueLease", mock.Anything, agentID, resourceID).Return(nil, service.ErrLeaseNotSupported)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, path, nil))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("No Application", func(t *testing.T) {
+		mockSvc := new(MockCredentialLeaseService)
+		router := setupLeaseRouter(mockSvc, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, path, nil))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		mockSvc.AssertNotCalled(t, "IssueLease", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
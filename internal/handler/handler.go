@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"agentXmap/internal/domain"
+	"agentXmap/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var errUnauthenticated = errors.New("authentication required")
+
+// ErrorResponse is the body returned for every failed request.
+type ErrorResponse struct {
+	Error string `json:"error" example:"agent not found"`
+	// Fields lists the offending values when a document failed schema validation.
+	Fields []service.FieldError `json:"fields,omitempty"`
+}
+
+// respondError writes err with the given status code and aborts the chain.
+func respondError(c *gin.Context, status int, err error) {
+	var verr *service.SchemaValidationError
+	if errors.As(err, &verr) {
+		c.AbortWithStatusJSON(status, ErrorResponse{Error: verr.Err.Error(), Fields: verr.Fields})
+		return
+	}
+	c.AbortWithStatusJSON(status, ErrorResponse{Error: err.Error()})
+}
+
+// parseIDParam reads a UUID path parameter, answering 400 when malformed.
+func parseIDParam(c *gin.Context, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errors.New("invalid "+name))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// Pagination defaults applied by parsePagination.
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// parsePagination reads the limit and offset query parameters, answering 400
+// when either is malformed. limit defaults to 20 and is capped at 100.
+func parsePagination(c *gin.Context) (limit, offset int, ok bool) {
+	limit, ok = parseLimit(c)
+	if !ok {
+		return 0, 0, false
+	}
+	if raw := c.Query("offset"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			respondError(c, http.StatusBadRequest, errors.New("invalid offset"))
+			return 0, 0, false
+		}
+		offset = n
+	}
+	return limit, offset, true
+}
+
+// parseLimit reads the limit query parameter, answering 400 when malformed.
+// It defaults to 20 and is capped at 100.
+func parseLimit(c *gin.Context) (int, bool) {
+	raw := c.Query("limit")
+	if raw == "" {
+		return defaultPageLimit, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		respondError(c, http.StatusBadRequest, errors.New("invalid limit"))
+		return 0, false
+	}
+	return min(n, maxPageLimit), true
+}
+
+// currentPrincipal returns the authenticated caller set by RequireAuth.
+func currentPrincipal(c *gin.Context) (*domain.Principal, bool) {
+	p, ok := domain.PrincipalFromContext(c.Request.Context())
+	if !ok {
+		respondError(c, http.StatusUnauthorized, errUnauthenticated)
+		return nil, false
+	}
+	return p, true
+}
+
+// currentApplication returns the calling Application set by RequireAPIKey.
+func currentApplication(c *gin.Context) (*domain.ApplicationPrincipal, bool) {
+	app, ok := domain.ApplicationPrincipalFromContext(c.Request.Context())
+	if !ok {
+		respondError(c, http.StatusUnauthorized, errUnauthenticated)
+		return nil, false
+	}
+	return app, true
+}
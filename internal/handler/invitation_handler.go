@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"agentXmap/internal/domain"
+	"agentXmap/internal/policy"
+	"agentXmap/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InviteUsersRequest invites emails into the caller's organization.
+type InviteUsersRequest struct {
+	Emails []string        `json:"emails" binding:"required,min=1,max=100,dive,email" example:"john.doe@acme.com"`
+	Role   domain.UserRole `json:"role" binding:"omitempty,oneof=manager admin user" example:"user"`
+}
+
+// AcceptInvitationRequest completes the registration of an invited user.
+type AcceptInvitationRequest struct {
+	Token     string `json:"token" binding:"required"`
+	Password  string `json:"password" binding:"required" example:"s3cret!"`
+	FirstName string `json:"first_name" example:"John"`
+	LastName  string `json:"last_name" example:"Doe"`
+}
+
+// InvitationHandler exposes invitation management over HTTP.
+type InvitationHandler struct {
+	identityService service.IdentityService
+}
+
+// NewInvitationHandler creates a new InvitationHandler.
+func NewInvitationHandler(identityService service.IdentityService) *InvitationHandler {
+	return &InvitationHandler{identityService: identityService}
+}
+
+// RegisterRoutes mounts the invitation endpoints. Accepting an invitation
+// only needs its token and is mounted on public.
+func (h *InvitationHandler) RegisterRoutes(public, protected *gin.RouterGroup) {
+	public.POST("/invitations/accept", h.AcceptInvitation)
+
+	invitations := protected.Group("/invitations")
+	{
+		invitations.POST("", RequirePermission(policy.ResourceInvitation, policy.ActionCreate), h.InviteUsers)
+		invitations.GET("", RequirePermission(policy.ResourceInvitation, policy.ActionRead), h.ListInvitations)
+		invitations.POST("/:id/resend", RequirePermission(policy.ResourceInvitation, policy.ActionUpdate), h.ResendInvitation)
+		invitations.POST("/:id/revoke", RequirePermission(policy.ResourceInvitation, policy.ActionDelete), h.RevokeInvitation)
+	}
+}
+
+// invitationErrorStatus maps invitation errors to HTTP status codes.
+func invitationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvitationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvitationNotPending), errors.Is(err, service.ErrInvitationClosed),
+		errors.Is(err, service.ErrEmailRegistered):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInvitationExpired):
+		return http.StatusGone
+	case errors.Is(err, service.ErrInvalidInvitation):
+		return http.StatusBadRequest
+	case errors.Is(err, policy.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, policy.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// InviteUsers godoc
+// @Summary Invite users into the caller's organization
+// @Description Every email is reported with its outcome: invited, already_registered (a member of the organization), already_invited, duplicate, invalid or failed. Only admins may invite admins.
+// @Tags invitations
+// @Accept json
+// @Produce json
+// @Param request body InviteUsersRequest true "Emails and role"
+// @Success 200 {array} service.InvitationOutcome
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /invitations [post]
+func (h *InvitationHandler) InviteUsers(c *gin.Context) {
+	caller, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+	var req InviteUsersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.Role == "" {
+		req.Role = domain.UserRoleUser
+	}
+
+	outcomes, err := h.identityService.InviteUsers(c.Request.Context(), caller.UserID, req.Emails, req.Role)
+	if err != nil {
+		respondError(c, invitationErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, outcomes)
+}
+
+// ListInvitations godoc
+// @Summary List the invitations of the caller's organization
+// @Tags invitations
+// @Produce json
+// @Param status query string false "Only invitations with this status" Enums(pending, accepted, expired, revoked)
+// @Success 200 {array} domain.Invitation
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /invitations [get]
+func (h *InvitationHandler) ListInvitations(c *gin.Context) {
+	caller, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+	status := domain.InvitationStatus(c.Query("status"))
+	switch status {
+	case "", domain.InvitationStatusPending, domain.InvitationStatusAccepted,
+		domain.InvitationStatusExpired, domain.InvitationStatusRevoked:
+	default:
+		respondError(c, http.StatusBadRequest, errors.New("invalid status"))
+		return
+	}
+
+	invitations, err := h.identityService.ListInvitations(c.Request.Context(), caller.OrganizationID, status)
+	if err != nil {
+		respondError(c, invitationErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, invitations)
+}
+
+// ResendInvitation godoc
+// @Summary Resend an invitation
+// @Description The invitation gets a new token, valid for another 48 hours; the previous link stops working. Expired invitations can be resent too.
+// @Tags invitations
+// @Produce json
+// @Param id path string true "Invitation ID"
+// @Success 200 {object} domain.Invitation
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /invitations/{id}/resend [post]
+func (h *InvitationHandler) ResendInvitation(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	invitation, err := h.identityService.ResendInvitation(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, invitationErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, invitation)
+}
+
+// RevokeInvitation godoc
+// @Summary Revoke a pending invitation
+// @Tags invitations
+// @Param id path string true "Invitation ID"
+// @Success 204
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /invitations/{id}/revoke [post]
+func (h *InvitationHandler) RevokeInvitation(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.identityService.RevokeInvitation(c.Request.Context(), id); err != nil {
+		respondError(c, invitationErrorStatus(err), err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// AcceptInvitation godoc
+// @Summary Accept an invitation and create the account
+// @Tags invitations
+// @Accept json
+// @Produce json
+// @Param request body AcceptInvitationRequest true "Invitation token and account details"
+// @Success 201 {object} domain.User
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 410 {object} ErrorResponse
+// @Router /invitations/accept [post]
+func (h *InvitationHandler) AcceptInvitation(c *gin.Context) {
+	var req AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := h.identityService.AcceptInvitation(c.Request.Context(), req.Token, req.Password, req.FirstName, req.LastName)
+	if err != nil {
+		respondError(c, invitationErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusCreated, user)
+}
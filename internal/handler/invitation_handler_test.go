@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"agentXmap/internal/domain"
+	"agentXmap/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupInvitationRouter(svc service.IdentityService, caller *domain.Principal) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api/v1", withPrincipal(caller))
+	NewInvitationHandler(svc).RegisterRoutes(api, api)
+	return r
+}
+
+func TestInvitationHandler_InviteUsers(t *testing.T) {
+	admin := &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleAdmin}
+
+	t.Run("Reports Outcomes", func(t *testing.T) {
+		svc := new(MockIdentityService)
+		router := setupInvitationRouter(svc, admin)
+
+		svc.On("InviteUsers", mock.Anything, admin.UserID, []string{"new@acme.com", "member@acme.com"}, domain.UserRoleUser).
+			Return([]service.InvitationOutcome{
+				{Email: "new@acme.com", Status: service.InvitationOutcomeInvited, Invitation: &domain.Invitation{Email: "new@acme.com"}},
+				{Email: "member@acme.com", Status: service.InvitationOutcomeAlreadyRegistered},
+			}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/invitations", gin.H{"emails": []string{"new@acme.com", "member@acme.com"}}))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"invited"`)
+		assert.Contains(t, w.Body.String(), `"status":"already_registered"`)
+	})
+
+	t.Run("Invalid Email", func(t *testing.T) {
+		svc := new(MockIdentityService)
+		router := setupInvitationRouter(svc, admin)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/invitations", gin.H{"emails": []string{"nope"}}))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		svc.AssertNotCalled(t, "InviteUsers", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Forbidden For Users", func(t *testing.T) {
+		svc := new(MockIdentityService)
+		router := setupInvitationRouter(svc, &domain.Principal{UserID: uuid.New(), Role: domain.UserRoleUser})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/invitations", gin.H{"emails": []string{"new@acme.com"}}))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+func TestInvitationHandler_ListInvitations(t *testing.T) {
+	manager := &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleManager}
+
+	t.Run("By Status", func(t *testing.T) {
+		svc := new(MockIdentityService)
+		router := setupInvitationRouter(svc, manager)
+
+		svc.On("ListInvitations", mock.Anything, manager.OrganizationID, domain.InvitationStatusPending).
+			Return([]domain.Invitation{{Email: "john@acme.com", Token: "secret"}}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/invitations?status=pending", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "john@acme.com")
+		assert.NotContains(t, w.Body.String(), "secret")
+	})
+
+	t.Run("Invalid Status", func(t *testing.T) {
+		svc := new(MockIdentityService)
+		router := setupInvitationRouter(svc, manager)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/invitations?status=lost", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestInvitationHandler_ResendInvitation(t *testing.T) {
+	admin := &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleAdmin}
+	id := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		svc := new(MockIdentityService)
+		router := setupInvitationRouter(svc, admin)
+
+		svc.On("ResendInvitation", mock.Anything, id).Return(&domain.Invitation{ID: id, Status: domain.InvitationStatusPending}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/invitations/"+id.String()+"/resend", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Already Accepted", func(t *testing.T) {
+		svc := new(MockIdentityService)
+		router := setupInvitationRouter(svc, admin)
+
+		svc.On("ResendInvitation", mock.Anything, id).Return(nil, service.ErrInvitationClosed)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/invitations/"+id.String()+"/resend", nil))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+}
+
+func TestInvitationHandler_RevokeInvitation(t *testing.T) {
+	admin := &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleAdmin}
+	id := uuid.New()
+
+	for name, tc := range map[string]struct {
+		err    error
+		status int
+	}{
+		"Revoked":   {nil, http.StatusNoContent},
+		"Not Found": {service.ErrInvitationNotFound, http.StatusNotFound},
+	} {
+		t.Run(name, func(t *testing.T) {
+			svc := new(MockIdentityService)
+			router := setupInvitationRouter(svc, admin)
+
+			svc.On("RevokeInvitation", mock.Anything, id).Return(tc.err)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/invitations/"+id.String()+"/revoke", nil))
+
+			assert.Equal(t, tc.status, w.Code)
+		})
+	}
+}
+
+func TestInvitationHandler_AcceptInvitation(t *testing.T) {
+	body := gin.H{"token": "tok", "password": "s3cret!", "first_name": "John", "last_name": "Doe"}
+
+	t.Run("Success", func(t *testing.T) {
+		svc := new(MockIdentityService)
+		router := setupInvitationRouter(svc, nil)
+
+		svc.On("AcceptInvitation", mock.Anything, "tok", "s3cret!", "John", "Doe").Return(&domain.User{Email: "john@acme.com"}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/invitations/accept", body))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), "john@acme.com")
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		svc := new(MockIdentityService)
+		router := setupInvitationRouter(svc, nil)
+
+		svc.On("AcceptInvitation", mock.Anything, "tok", "s3cret!", "John", "Doe").Return(nil, service.ErrInvitationExpired)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/invitations/accept", body))
+
+		assert.Equal(t, http.StatusGone, w.Code)
+	})
+
+	t.Run("Unexpected Error", func(t *testing.T) {
+		svc := new(MockIdentityService)
+		router := setupInvitationRouter(svc, nil)
+
+		svc.On("AcceptInvitation", mock.Anything, "tok", "s3cret!", "John", "Doe").Return(nil, errors.New("db down"))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/invitations/accept", body))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
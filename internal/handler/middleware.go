@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"agentXmap/internal/domain"
+	"agentXmap/internal/policy"
+	"agentXmap/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ContextKeyPrincipal is the gin context key holding the authenticated *domain.Principal.
+	ContextKeyPrincipal = "principal"
+	// ContextKeyApplication is the gin context key holding the authenticated *domain.ApplicationPrincipal.
+	ContextKeyApplication = "application"
+
+	// HeaderAPIKey carries an Application API key; "Authorization: Bearer sk-live-..." is accepted too.
+	HeaderAPIKey = "X-API-Key"
+)
+
+// bearerToken returns the token of an "Authorization: Bearer <token>" header.
+func bearerToken(c *gin.Context) string {
+	scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return token
+}
+
+// CaptureClientIP stores the client address of the request in its context for
+// audit entries. It follows X-Forwarded-For only from the engine's trusted proxies.
+func CaptureClientIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(domain.WithClientIP(c.Request.Context(), c.ClientIP()))
+		c.Next()
+	}
+}
+
+// RequireAuth validates the Bearer access token and stores the caller's
+// user and organization in both the gin and the request context.
+func RequireAuth(sessions service.SessionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := bearerToken(c)
+		if token == "" {
+			respondError(c, http.StatusUnauthorized, errUnauthenticated)
+			return
+		}
+
+		principal, err := sessions.ValidateAccessToken(c.Request.Context(), token)
+		if err != nil {
+			respondError(c, authErrorStatus(err), err)
+			return
+		}
+
+		c.Set(ContextKeyPrincipal, principal)
+		c.Request = c.Request.WithContext(domain.WithPrincipal(c.Request.Context(), principal))
+		c.Next()
+	}
+}
+
+// RequireAPIKey authenticates an Application by API key and stores it in
+// both the gin and the request context.
+func RequireAPIKey(apps service.ApplicationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rawKey := c.GetHeader(HeaderAPIKey)
+		if rawKey == "" {
+			rawKey = bearerToken(c)
+		}
+		if rawKey == "" {
+			respondError(c, http.StatusUnauthorized, errUnauthenticated)
+			return
+		}
+
+		key, err := apps.AuthenticateKey(c.Request.Context(), rawKey)
+		if err != nil {
+			status := http.StatusUnauthorized
+			if errors.Is(err, service.ErrApplicationInactive) {
+				status = http.StatusForbidden
+			}
+			respondError(c, status, err)
+			return
+		}
+
+		app := &domain.ApplicationPrincipal{
+			ApplicationID:  key.ApplicationID,
+			KeyID:          key.ID,
+			OrganizationID: key.Application.Owner.OrganizationID,
+		}
+		c.Set(ContextKeyApplication, app)
+		c.Request = c.Request.WithContext(domain.WithApplicationPrincipal(c.Request.Context(), app))
+		c.Next()
+	}
+}
+
+// RequirePermission rejects callers whose role can never perform action on
+// resource. Decisions that depend on the target (AllowIfAssigned) are left to
+// the service layer. Must run after RequireAuth.
+func RequirePermission(resource policy.Resource, action policy.Action) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caller, ok := currentPrincipal(c)
+		if !ok {
+			return
+		}
+		if policy.Decide(caller.Role, resource, action) == policy.Deny {
+			respondError(c, http.StatusForbidden, policy.ErrForbidden)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,477 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"agentXmap/internal/domain"
+	"agentXmap/internal/policy"
+	"agentXmap/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreateResourceRequest is the payload for registering a resource. The connection
+// details must match the config schema of the resource type.
+type CreateResourceRequest struct {
+	TypeID            string          `json:"type_id" binding:"required" example:"postgres_db"`
+	Name              string          `json:"name" binding:"required" example:"Production DB"`
+	ConnectionDetails json.RawMessage `json:"connection_details" swaggertype:"string" example:"{\"host\": \"db.internal\", \"port\": 5432}"`
+}
+
+// UpdateResourceRequest replaces the name and connection details of a resource.
+type UpdateResourceRequest struct {
+	Name              string          `json:"name" binding:"required" example:"Production DB"`
+	ConnectionDetails json.RawMessage `json:"connection_details" swaggertype:"string" example:"{\"host\": \"db.internal\", \"port\": 5432}"`
+}
+
+// SetResourceSecretRequest replaces the credentials of a resource. They must
+// match the secret schema of the resource type.
+type SetResourceSecretRequest struct {
+	Credentials json.RawMessage `json:"credentials" binding:"required" swaggertype:"string" example:"{\"username\": \"app\", \"password\": \"s3cret\"}"`
+}
+
+// GrantAccessRequest gives an agent access to a resource. read_write requires an admin.
+type GrantAccessRequest struct {
+	AgentID    uuid.UUID          `json:"agent_id" binding:"required"`
+	Permission domain.AccessLevel `json:"permission" binding:"required,oneof=read_only read_write" example:"read_only"`
+}
+
+// ChangeAccessRequest upgrades or downgrades an existing grant. Upgrading requires an admin.
+type ChangeAccessRequest struct {
+	Permission domain.AccessLevel `json:"permission" binding:"required,oneof=read_only read_write" example:"read_write"`
+}
+
+// ResourceResponse is the public representation of a resource. Credentials are never returned.
+type ResourceResponse struct {
+	ID                uuid.UUID       `json:"id"`
+	OrganizationID    uuid.UUID       `json:"organization_id"`
+	TypeID            string          `json:"type_id" example:"postgres_db"`
+	Name              string          `json:"name" example:"Production DB"`
+	ConnectionDetails json.RawMessage `json:"connection_details" swaggertype:"string"`
+	HasCredentials    bool            `json:"has_credentials"`
+	// LastConnectionTest is the domain.ConnectionTestResult of the latest test, if any.
+	LastConnectionTest json.RawMessage `json:"last_connection_test,omitempty" swaggertype:"string"`
+	CreatedAt          time.Time       `json:"created_at"`
+	UpdatedAt          time.Time       `json:"updated_at"`
+}
+
+func newResourceResponses(resources []domain.Resource) []ResourceResponse {
+	out := make([]ResourceResponse, len(resources))
+	for i := range resources {
+		out[i] = newResourceResponse(&resources[i])
+	}
+	return out
+}
+
+func newResourceResponse(res *domain.Resource) ResourceResponse {
+	return ResourceResponse{
+		ID:                 res.ID,
+		OrganizationID:     res.OrganizationID,
+		TypeID:             res.TypeID,
+		Name:               res.Name,
+		ConnectionDetails:  res.ConnectionDetails,
+		HasCredentials:     res.Secret.ID != uuid.Nil,
+		LastConnectionTest: res.LastConnectionTest,
+		CreatedAt:          res.CreatedAt,
+		UpdatedAt:          res.UpdatedAt,
+	}
+}
+
+// ResourceHandler exposes ResourceService over HTTP.
+type ResourceHandler struct {
+	resourceService service.ResourceService
+}
+
+// NewResourceHandler creates a new ResourceHandler.
+func NewResourceHandler(resourceService service.ResourceService) *ResourceHandler {
+	return &ResourceHandler{resourceService: resourceService}
+}
+
+// RegisterRoutes mounts the resource endpoints under rg.
+func (h *ResourceHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	resources := rg.Group("/resources")
+	{
+		resources.POST("", RequirePermission(policy.ResourceResource, policy.ActionCreate), h.CreateResource)
+		resources.GET("", RequirePermission(policy.ResourceResource, policy.ActionRead), h.ListResources)
+		resources.GET("/:id", RequirePermission(policy.ResourceResource, policy.ActionRead), h.GetResource)
+		resources.PUT("/:id", RequirePermission(policy.ResourceResource, policy.ActionUpdate), h.UpdateResource)
+		resources.DELETE("/:id", RequirePermission(policy.ResourceResource, policy.ActionDelete), h.DeleteResource)
+		resources.POST("/:id/restore", RequirePermission(policy.ResourceResource, policy.ActionDelete), h.RestoreResource)
+		resources.PUT("/:id/secret", RequirePermission(policy.ResourceResourceSecret, policy.ActionUpdate), h.SetSecret)
+		resources.GET("/:id/secret", RequirePermission(policy.ResourceResourceSecret, policy.ActionRead), h.RevealSecret)
+		resources.POST("/:id/test", RequirePermission(policy.ResourceResource, policy.ActionUpdate), h.TestConnection)
+		resources.POST("/:id/access", RequirePermission(policy.ResourceResourceAccess, policy.ActionCreate), h.GrantAccess)
+		resources.PUT("/:id/access/:agentId", RequirePermission(policy.ResourceResourceAccess, policy.ActionUpdate), h.ChangeAccess)
+		resources.DELETE("/:id/access/:agentId", RequirePermission(policy.ResourceResourceAccess, policy.ActionDelete), h.RevokeAccess)
+	}
+}
+
+// resourceErrorStatus maps ResourceService errors to HTTP status codes.
+func resourceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrResourceNotFound), errors.Is(err, service.ErrResourceSecretNotFound),
+		errors.Is(err, service.ErrAgentNotFound), errors.Is(err, service.ErrAccessNotGranted):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrAccessAlreadyGranted), errors.Is(err, service.ErrAccessChanged):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrResourceNameRequired), errors.Is(err, service.ErrResourceTypeRequired),
+		errors.Is(err, service.ErrResourceTypeNotFound), errors.Is(err, service.ErrResourceTypeInactive),
+		errors.Is(err, service.ErrInvalidConnectionDetails), errors.Is(err, service.ErrInvalidResourceCredentials),
+		errors.Is(err, service.ErrConnectionTestUnsupported), errors.Is(err, service.ErrInvalidAccessLevel):
+		return http.StatusBadRequest
+	case errors.Is(err, policy.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, policy.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// CreateResource godoc
+// @Summary Register a resource
+// @Description Connection details are validated against the config schema of the resource type; violations are listed in fields.
+// @Tags resources
+// @Accept json
+// @Produce json
+// @Param request body CreateResourceRequest true "Resource"
+// @Success 201 {object} ResourceResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /resources [post]
+func (h *ResourceHandler) CreateResource(c *gin.Context) {
+	caller, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	var req CreateResourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	res, err := h.resourceService.CreateResource(c.Request.Context(), caller.OrganizationID, req.TypeID, req.Name, req.ConnectionDetails)
+	if err != nil {
+		respondError(c, resourceErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusCreated, newResourceResponse(res))
+}
+
+// GetResource godoc
+// @Summary Get a resource
+// @Tags resources
+// @Produce json
+// @Param id path string true "Resource ID"
+// @Success 200 {object} ResourceResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /resources/{id} [get]
+func (h *ResourceHandler) GetResource(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	res, err := h.resourceService.GetResource(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, resourceErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, newResourceResponse(res))
+}
+
+// ListResources godoc
+// @Summary List the resources of the caller's organization
+// @Tags resources
+// @Produce json
+// @Param type_id query string false "Only resources of this type"
+// @Param deleted query bool false "List soft-deleted resources instead (admins only)"
+// @Success 200 {array} ResourceResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /resources [get]
+func (h *ResourceHandler) ListResources(c *gin.Context) {
+	caller, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	filter := domain.ResourceFilter{TypeID: c.Query("type_id")}
+	if raw := c.Query("deleted"); raw != "" {
+		deleted, err := strconv.ParseBool(raw)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, errors.New("invalid deleted"))
+			return
+		}
+		filter.Deleted = deleted
+	}
+
+	resources, err := h.resourceService.ListResources(c.Request.Context(), caller.OrganizationID, filter)
+	if err != nil {
+		respondError(c, resourceErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, newResourceResponses(resources))
+}
+
+// UpdateResource godoc
+// @Summary Update a resource
+// @Description Connection details are validated against the config schema of the resource type; violations are listed in fields.
+// @Tags resources
+// @Accept json
+// @Produce json
+// @Param id path string true "Resource ID"
+// @Param request body UpdateResourceRequest true "Resource"
+// @Success 200 {object} ResourceResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /resources/{id} [put]
+func (h *ResourceHandler) UpdateResource(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req UpdateResourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	res, err := h.resourceService.UpdateResource(c.Request.Context(), id, req.Name, req.ConnectionDetails)
+	if err != nil {
+		respondError(c, resourceErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, newResourceResponse(res))
+}
+
+// DeleteResource godoc
+// @Summary Soft-delete a resource
+// @Description The access grants of its agents are suspended until the resource is restored.
+// @Tags resources
+// @Param id path string true "Resource ID"
+// @Success 204
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /resources/{id} [delete]
+func (h *ResourceHandler) DeleteResource(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.resourceService.DeleteResource(c.Request.Context(), id); err != nil {
+		respondError(c, resourceErrorStatus(err), err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RestoreResource godoc
+// @Summary Restore a soft-deleted resource
+// @Description The access grants suspended by the deletion are restored with it.
+// @Tags resources
+// @Produce json
+// @Param id path string true "Resource ID"
+// @Success 200 {object} ResourceResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /resources/{id}/restore [post]
+func (h *ResourceHandler) RestoreResource(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	res, err := h.resourceService.RestoreResource(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, resourceErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, newResourceResponse(res))
+}
+
+// SetSecret godoc
+// @Summary Store the credentials of a resource
+// @Description Credentials are validated against the secret schema of the resource type, then encrypted. They are never returned by this endpoint.
+// @Tags resources
+// @Accept json
+// @Produce json
+// @Param id path string true "Resource ID"
+// @Param request body SetResourceSecretRequest true "Credentials"
+// @Success 200 {object} service.SecretMetadata
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /resources/{id}/secret [put]
+func (h *ResourceHandler) SetSecret(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req SetResourceSecretRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	meta, err := h.resourceService.SetSecret(c.Request.Context(), id, req.Credentials)
+	if err != nil {
+		respondError(c, resourceErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, meta)
+}
+
+// RevealSecret godoc
+// @Summary Reveal the credentials of a resource
+// @Description Admins only. Every disclosure is recorded in the audit log.
+// @Tags resources
+// @Produce json
+// @Param id path string true "Resource ID"
+// @Success 200 {object} service.RevealedSecret
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /resources/{id}/secret [get]
+func (h *ResourceHandler) RevealSecret(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	secret, err := h.resourceService.RevealSecret(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, resourceErrorStatus(err), err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, secret)
+}
+
+// TestConnection godoc
+// @Summary Test connectivity to a resource
+// @Description Connects with the stored credentials through the driver of the resource type. A failed test answers 200 with success false, an error_code and the latency; the outcome is kept as the resource's last_connection_test.
+// @Tags resources
+// @Produce json
+// @Param id path string true "Resource ID"
+// @Success 200 {object} domain.ConnectionTestResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /resources/{id}/test [post]
+func (h *ResourceHandler) TestConnection(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	result, err := h.resourceService.TestConnection(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, resourceErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GrantAccess godoc
+// @Summary Give an agent access to a resource
+// @Description Managers may grant read_only; read_write requires an admin. Audited.
+// @Tags resources
+// @Accept json
+// @Produce json
+// @Param id path string true "Resource ID"
+// @Param request body GrantAccessRequest true "Grant"
+// @Success 201 {object} domain.AgentResourceAccess
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /resources/{id}/access [post]
+func (h *ResourceHandler) GrantAccess(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req GrantAccessRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	access, err := h.resourceService.GrantAccess(c.Request.Context(), id, req.AgentID, req.Permission)
+	if err != nil {
+		respondError(c, resourceErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusCreated, access)
+}
+
+// ChangeAccess godoc
+// @Summary Upgrade or downgrade the access of an agent to a resource
+// @Description Upgrading to read_write requires an admin. Audited.
+// @Tags resources
+// @Accept json
+// @Produce json
+// @Param id path string true "Resource ID"
+// @Param agentId path string true "Agent ID"
+// @Param request body ChangeAccessRequest true "Access level"
+// @Success 200 {object} domain.AgentResourceAccess
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /resources/{id}/access/{agentId} [put]
+func (h *ResourceHandler) ChangeAccess(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	agentID, ok := parseIDParam(c, "agentId")
+	if !ok {
+		return
+	}
+
+	var req ChangeAccessRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	access, err := h.resourceService.ChangeAccess(c.Request.Context(), id, agentID, req.Permission)
+	if err != nil {
+		respondError(c, resourceErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, access)
+}
+
+// RevokeAccess godoc
+// @Summary Revoke the access of an agent to a resource
+// @Tags resources
+// @Param id path string true "Resource ID"
+// @Param agentId path string true "Agent ID"
+// @Success 204
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /resources/{id}/access/{agentId} [delete]
+func (h *ResourceHandler) RevokeAccess(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	agentID, ok := parseIDParam(c, "agentId")
+	if !ok {
+		return
+	}
+
+	if err := h.resourceService.RevokeAccess(c.Request.Context(), id, agentID); err != nil {
+		respondError(c, resourceErrorStatus(err), err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,573 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"agentXmap/internal/domain"
+	"agentXmap/internal/policy"
+	"agentXmap/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockResourceService is a mock implementation of service.ResourceService
+type MockResourceService struct {
+	mock.Mock
+}
+
+func (m *MockResourceService) CreateResource(ctx context.Context, orgID uuid.UUID, typeID, name string, config json.RawMessage) (*domain.Resource, error) {
+	args := m.Called(ctx, orgID, typeID, name, config)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Resource), args.Error(1)
+}
+
+func (m *MockResourceService) GetResource(ctx context.Context, id uuid.UUID) (*domain.Resource, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Resource), args.Error(1)
+}
+
+func (m *MockResourceService) ListResources(ctx context.Context, orgID uuid.UUID, filter domain.ResourceFilter) ([]domain.Resource, error) {
+	args := m.Called(ctx, orgID, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Resource), args.Error(1)
+}
+
+func (m *MockResourceService) DeleteResource(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockResourceService) RestoreResource(ctx context.Context, id uuid.UUID) (*domain.Resource, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Resource), args.Error(1)
+}
+
+func (m *MockResourceService) UpdateResource(ctx context.Context, id uuid.UUID, name string, config json.RawMessage) (*domain.Resource, error) {
+	args := m.Called(ctx, id, name, config)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Resource), args.Error(1)
+}
+
+func (m *MockResourceService) ValidateCredentials(ctx context.Context, typeID string, credentials json.RawMessage) error {
+	args := m.Called(ctx, typeID, credentials)
+	return args.Error(0)
+}
+
+func (m *MockResourceService) SetSecret(ctx context.Context, resourceID uuid.UUID, credentials json.RawMessage) (*service.SecretMetadata, error) {
+	args := m.Called(ctx, resourceID, credentials)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.SecretMetadata), args.Error(1)
+}
+
+func (m *MockResourceService) RevealSecret(ctx context.Context, resourceID uuid.UUID) (*service.RevealedSecret, error) {
+	args := m.Called(ctx, resourceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.RevealedSecret), args.Error(1)
+}
+
+func (m *MockResourceService) TestConnection(ctx context.Context, resourceID uuid.UUID) (*domain.ConnectionTestResult, error) {
+	args := m.Called(ctx, resourceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ConnectionTestResult), args.Error(1)
+}
+
+func (m *MockResourceService) ListAgentsWithAccess(ctx context.Context, resourceID uuid.UUID) ([]domain.Agent, error) {
+	args := m.Called(ctx, resourceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Agent), args.Error(1)
+}
+
+func (m *MockResourceService) GrantAccess(ctx context.Context, resourceID, agentID uuid.UUID, level domain.AccessLevel) (*domain.AgentResourceAccess, error) {
+	args := m.Called(ctx, resourceID, agentID, level)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AgentResourceAccess), args.Error(1)
+}
+
+func (m *MockResourceService) ChangeAccess(ctx context.Context, resourceID, agentID uuid.UUID, level domain.AccessLevel) (*domain.AgentResourceAccess, error) {
+	args := m.Called(ctx, resourceID, agentID, level)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AgentResourceAccess), args.Error(1)
+}
+
+func (m *MockResourceService) RevokeAccess(ctx context.Context, resourceID, agentID uuid.UUID) error {
+	args := m.Called(ctx, resourceID, agentID)
+	return args.Error(0)
+}
+
+func setupResourceRouter(svc service.ResourceService, caller *domain.Principal) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api/v1", withPrincipal(caller))
+	NewResourceHandler(svc).RegisterRoutes(api)
+	return r
+}
+
+func TestResourceHandler_CreateResource(t *testing.T) {
+	orgID := uuid.New()
+	caller := &domain.Principal{UserID: uuid.New(), OrganizationID: orgID, Role: domain.UserRoleManager}
+	body := gin.H{"type_id": "postgres_db", "name": "DB", "connection_details": gin.H{"host": "db"}}
+
+	t.Run("Success", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, caller)
+
+		res := &domain.Resource{ID: uuid.New(), OrganizationID: orgID, TypeID: "postgres_db", Name: "DB"}
+		mockSvc.On("CreateResource", mock.Anything, orgID, "postgres_db", "DB", mock.Anything).Return(res, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/resources", body))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), `"type_id":"postgres_db"`)
+	})
+
+	t.Run("Invalid Connection Details", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, caller)
+
+		verr := &service.SchemaValidationError{
+			Err: service.ErrInvalidConnectionDetails,
+			Fields: []service.FieldError{
+				{Field: "/port", Message: "got string, want integer"},
+			},
+		}
+		mockSvc.On("CreateResource", mock.Anything, orgID, "postgres_db", "DB", mock.Anything).Return(nil, verr)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/resources", body))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var resp ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "invalid connection details", resp.Error)
+		assert.Equal(t, verr.Fields, resp.Fields)
+	})
+
+	t.Run("Unknown Type", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, caller)
+
+		mockSvc.On("CreateResource", mock.Anything, orgID, "postgres_db", "DB", mock.Anything).Return(nil, service.ErrResourceTypeNotFound)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/resources", body))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.NotContains(t, w.Body.String(), `"fields"`)
+	})
+
+	t.Run("Forbidden for users", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		user := &domain.Principal{UserID: uuid.New(), OrganizationID: orgID, Role: domain.UserRoleUser}
+		router := setupResourceRouter(mockSvc, user)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/resources", body))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockSvc.AssertNotCalled(t, "CreateResource", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestResourceHandler_UpdateResource(t *testing.T) {
+	caller := &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleAdmin}
+	resID := uuid.New()
+	body := gin.H{"name": "DB", "connection_details": gin.H{"host": "db"}}
+
+	t.Run("Success", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, caller)
+
+		mockSvc.On("UpdateResource", mock.Anything, resID, "DB", mock.Anything).Return(&domain.Resource{ID: resID, Name: "DB"}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPut, "/api/v1/resources/"+resID.String(), body))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, caller)
+
+		mockSvc.On("UpdateResource", mock.Anything, resID, "DB", mock.Anything).Return(nil, service.ErrResourceNotFound)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPut, "/api/v1/resources/"+resID.String(), body))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestResourceHandler_SetSecret(t *testing.T) {
+	userID := uuid.New()
+	caller := &domain.Principal{UserID: userID, OrganizationID: uuid.New(), Role: domain.UserRoleManager}
+	resID := uuid.New()
+	path := "/api/v1/resources/" + resID.String() + "/secret"
+
+	t.Run("Success", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, caller)
+
+		mockSvc.On("SetSecret", mock.Anything, resID, mock.Anything).
+			Return(&service.SecretMetadata{ResourceID: resID, KeyVersionID: "v1"}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPut, path, gin.H{"credentials": gin.H{"username": "app", "password": "s3cret"}}))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "s3cret")
+	})
+
+	t.Run("Missing Credentials", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, caller)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPut, path, gin.H{}))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Invalid Credentials", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, caller)
+
+		verr := &service.SchemaValidationError{
+			Err:    service.ErrInvalidResourceCredentials,
+			Fields: []service.FieldError{{Field: "/password", Message: "is required"}},
+		}
+		mockSvc.On("SetSecret", mock.Anything, resID, mock.Anything).Return(nil, verr)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPut, path, gin.H{"credentials": gin.H{"username": "app"}}))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), `"field":"/password"`)
+	})
+}
+
+func TestResourceHandler_RevealSecret(t *testing.T) {
+	resID := uuid.New()
+	path := "/api/v1/resources/" + resID.String() + "/secret"
+
+	t.Run("Admin", func(t *testing.T) {
+		userID := uuid.New()
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, &domain.Principal{UserID: userID, OrganizationID: uuid.New(), Role: domain.UserRoleAdmin})
+
+		mockSvc.On("RevealSecret", mock.Anything, resID).Return(&service.RevealedSecret{
+			SecretMetadata: service.SecretMetadata{ResourceID: resID, KeyVersionID: "v1"},
+			Credentials:    json.RawMessage(`{"username":"app"}`),
+		}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, path, nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		assert.Contains(t, w.Body.String(), `"credentials":{"username":"app"}`)
+	})
+
+	t.Run("Forbidden for managers", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleManager})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, path, nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockSvc.AssertNotCalled(t, "RevealSecret", mock.Anything, mock.Anything)
+	})
+
+	t.Run("No Credentials", func(t *testing.T) {
+		userID := uuid.New()
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, &domain.Principal{UserID: userID, OrganizationID: uuid.New(), Role: domain.UserRoleAdmin})
+
+		mockSvc.On("RevealSecret", mock.Anything, resID).Return(nil, service.ErrResourceSecretNotFound)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, path, nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestResourceHandler_TestConnection(t *testing.T) {
+	resID := uuid.New()
+	path := "/api/v1/resources/" + resID.String() + "/test"
+	manager := &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleManager}
+
+	t.Run("Failed Test Is A Result", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, manager)
+
+		mockSvc.On("TestConnection", mock.Anything, resID).Return(&domain.ConnectionTestResult{
+			LatencyMS: 42, ErrorCode: "auth_failed", ErrorMessage: "authentication failed",
+		}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, path, nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"success":false`)
+		assert.Contains(t, w.Body.String(), `"latency_ms":42`)
+		assert.Contains(t, w.Body.String(), `"error_code":"auth_failed"`)
+	})
+
+	t.Run("Unsupported Type", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, manager)
+
+		mockSvc.On("TestConnection", mock.Anything, resID).Return(nil, service.ErrConnectionTestUnsupported)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, path, nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Forbidden for users", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleUser})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, path, nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockSvc.AssertNotCalled(t, "TestConnection", mock.Anything, mock.Anything)
+	})
+}
+
+func TestResourceHandler_GrantAccess(t *testing.T) {
+	resID, agentID := uuid.New(), uuid.New()
+	path := "/api/v1/resources/" + resID.String() + "/access"
+
+	t.Run("Success", func(t *testing.T) {
+		caller := &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleManager}
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, caller)
+
+		mockSvc.On("GrantAccess", mock.Anything, resID, agentID, domain.AccessLevelReadOnly).
+			Return(&domain.AgentResourceAccess{ID: uuid.New(), AgentID: agentID, ResourceID: resID, Permission: domain.AccessLevelReadOnly}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, path, gin.H{"agent_id": agentID, "permission": "read_only"}))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), `"permission":"read_only"`)
+	})
+
+	t.Run("Invalid Permission", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleAdmin})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, path, gin.H{"agent_id": agentID, "permission": "owner"}))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockSvc.AssertNotCalled(t, "GrantAccess", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Already Granted", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleManager})
+
+		mockSvc.On("GrantAccess", mock.Anything, resID, agentID, domain.AccessLevelReadOnly).Return(nil, service.ErrAccessAlreadyGranted)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, path, gin.H{"agent_id": agentID, "permission": "read_only"}))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Forbidden for users", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleUser})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, path, gin.H{"agent_id": agentID, "permission": "read_only"}))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+func TestResourceHandler_ChangeAccess(t *testing.T) {
+	resID, agentID := uuid.New(), uuid.New()
+	path := "/api/v1/resources/" + resID.String() + "/access/" + agentID.String()
+
+	t.Run("Upgrade Forbidden For Managers", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleManager})
+
+		mockSvc.On("ChangeAccess", mock.Anything, resID, agentID, domain.AccessLevelReadWrite).Return(nil, policy.ErrForbidden)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPut, path, gin.H{"permission": "read_write"}))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Not Granted", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleAdmin})
+
+		mockSvc.On("ChangeAccess", mock.Anything, resID, agentID, domain.AccessLevelReadOnly).Return(nil, service.ErrAccessNotGranted)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPut, path, gin.H{"permission": "read_only"}))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestResourceHandler_RevokeAccess(t *testing.T) {
+	resID, agentID := uuid.New(), uuid.New()
+	caller := &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleManager}
+	mockSvc := new(MockResourceService)
+	router := setupResourceRouter(mockSvc, caller)
+
+	mockSvc.On("RevokeAccess", mock.Anything, resID, agentID).Return(nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newRequest(http.MethodDelete, "/api/v1/resources/"+resID.String()+"/access/"+agentID.String(), nil))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockSvc.AssertExpectations(t)
+}
+
+func TestResourceHandler_ListResources(t *testing.T) {
+	orgID := uuid.New()
+
+	t.Run("By Type", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, &domain.Principal{UserID: uuid.New(), OrganizationID: orgID, Role: domain.UserRoleUser})
+
+		mockSvc.On("ListResources", mock.Anything, orgID, domain.ResourceFilter{TypeID: "postgres_db"}).
+			Return([]domain.Resource{{ID: uuid.New(), OrganizationID: orgID, TypeID: "postgres_db", Name: "DB"}}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/resources?type_id=postgres_db", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var got []ResourceResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		assert.Len(t, got, 1)
+	})
+
+	t.Run("Deleted", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, &domain.Principal{UserID: uuid.New(), OrganizationID: orgID, Role: domain.UserRoleAdmin})
+
+		mockSvc.On("ListResources", mock.Anything, orgID, domain.ResourceFilter{Deleted: true}).Return([]domain.Resource{}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/resources?deleted=true", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "[]", w.Body.String())
+	})
+
+	t.Run("Invalid Deleted Flag", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, &domain.Principal{UserID: uuid.New(), OrganizationID: orgID, Role: domain.UserRoleAdmin})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/resources?deleted=maybe", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestResourceHandler_DeleteResource(t *testing.T) {
+	resID := uuid.New()
+	path := "/api/v1/resources/" + resID.String()
+
+	t.Run("Admin", func(t *testing.T) {
+		caller := &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleAdmin}
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, caller)
+
+		mockSvc.On("DeleteResource", mock.Anything, resID).Return(nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodDelete, path, nil))
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("Forbidden for managers", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleManager})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodDelete, path, nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockSvc.AssertNotCalled(t, "DeleteResource", mock.Anything, mock.Anything)
+	})
+}
+
+func TestResourceHandler_RestoreResource(t *testing.T) {
+	resID := uuid.New()
+	path := "/api/v1/resources/" + resID.String() + "/restore"
+	caller := &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleAdmin}
+
+	t.Run("Success", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, caller)
+
+		mockSvc.On("RestoreResource", mock.Anything, resID).
+			Return(&domain.Resource{ID: resID, OrganizationID: caller.OrganizationID, Name: "DB"}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, path, nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), resID.String())
+	})
+
+	t.Run("Not Deleted", func(t *testing.T) {
+		mockSvc := new(MockResourceService)
+		router := setupResourceRouter(mockSvc, caller)
+
+		mockSvc.On("RestoreResource", mock.Anything, resID).Return(nil, service.ErrResourceNotFound)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, path, nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"agentXmap/internal/policy"
+	"agentXmap/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateResourceTypeRequest registers a custom resource type. Omitted schemas accept any document.
+type CreateResourceTypeRequest struct {
+	ID           string          `json:"id" binding:"required" example:"ftp_server"`
+	Name         string          `json:"name" binding:"required" example:"FTP Server"`
+	ConfigSchema json.RawMessage `json:"config_schema" swaggertype:"string" example:"{\"type\": \"object\", \"required\": [\"host\"]}"`
+	SecretSchema json.RawMessage `json:"secret_schema" swaggertype:"string" example:"{\"type\": \"object\", \"required\": [\"password\"]}"`
+}
+
+// UpdateResourceTypeRequest renames a custom resource type and replaces the
+// schemas that are present. Changing a schema bumps schema_version.
+type UpdateResourceTypeRequest struct {
+	Name         string          `json:"name" binding:"required" example:"FTP Server"`
+	ConfigSchema json.RawMessage `json:"config_schema" swaggertype:"string"`
+	SecretSchema json.RawMessage `json:"secret_schema" swaggertype:"string"`
+}
+
+// ResourceTypeHandler exposes ResourceTypeService over HTTP.
+type ResourceTypeHandler struct {
+	typeService service.ResourceTypeService
+}
+
+// NewResourceTypeHandler creates a new ResourceTypeHandler.
+func NewResourceTypeHandler(typeService service.ResourceTypeService) *ResourceTypeHandler {
+	return &ResourceTypeHandler{typeService: typeService}
+}
+
+// RegisterRoutes mounts the resource type endpoints under rg.
+func (h *ResourceTypeHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	types := rg.Group("/resource-types")
+	{
+		types.GET("", RequirePermission(policy.ResourceResourceType, policy.ActionRead), h.ListResourceTypes)
+		types.POST("", RequirePermission(policy.ResourceResourceType, policy.ActionCreate), h.CreateResourceType)
+		types.GET("/:id", RequirePermission(policy.ResourceResourceType, policy.ActionRead), h.GetResourceType)
+		types.PUT("/:id", RequirePermission(policy.ResourceResourceType, policy.ActionUpdate), h.UpdateResourceType)
+		types.GET("/:id/versions", RequirePermission(policy.ResourceResourceType, policy.ActionRead), h.ListSchemaVersions)
+		types.POST("/:id/activate", RequirePermission(policy.ResourceResourceType, policy.ActionUpdate), h.ActivateResourceType)
+		types.POST("/:id/deactivate", RequirePermission(policy.ResourceResourceType, policy.ActionUpdate), h.DeactivateResourceType)
+	}
+}
+
+// resourceTypeErrorStatus maps ResourceTypeService errors to HTTP status codes.
+func resourceTypeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrResourceTypeNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrResourceTypeExists), errors.Is(err, service.ErrBuiltinResourceType),
+		errors.Is(err, service.ErrResourceTypeChanged):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInvalidResourceTypeID), errors.Is(err, service.ErrResourceTypeNameRequired),
+		errors.Is(err, service.ErrInvalidSchema):
+		return http.StatusBadRequest
+	case errors.Is(err, policy.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, policy.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ListResourceTypes godoc
+// @Summary List resource types
+// @Tags resource-types
+// @Produce json
+// @Param include_inactive query bool false "Include deactivated types"
+// @Success 200 {array} domain.ResourceType
+// @Failure 400 {object} ErrorResponse
+// @Router /resource-types [get]
+func (h *ResourceTypeHandler) ListResourceTypes(c *gin.Context) {
+	includeInactive := false
+	if raw := c.Query("include_inactive"); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, errors.New("invalid include_inactive"))
+			return
+		}
+		includeInactive = v
+	}
+
+	types, err := h.typeService.ListResourceTypes(c.Request.Context(), includeInactive)
+	if err != nil {
+		respondError(c, resourceTypeErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, types)
+}
+
+// GetResourceType godoc
+// @Summary Get a resource type
+// @Tags resource-types
+// @Produce json
+// @Param id path string true "Resource type ID"
+// @Success 200 {object} domain.ResourceType
+// @Failure 404 {object} ErrorResponse
+// @Router /resource-types/{id} [get]
+func (h *ResourceTypeHandler) GetResourceType(c *gin.Context) {
+	rt, err := h.typeService.GetResourceType(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, resourceTypeErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, rt)
+}
+
+// CreateResourceType godoc
+// @Summary Register a custom resource type
+// @Description Schemas must be valid JSON Schemas. The type starts active at schema version 1. Only admins of the platform operator organization may change the catalog.
+// @Tags resource-types
+// @Accept json
+// @Produce json
+// @Param request body CreateResourceTypeRequest true "Resource type"
+// @Success 201 {object} domain.ResourceType
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /resource-types [post]
+func (h *ResourceTypeHandler) CreateResourceType(c *gin.Context) {
+	caller, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	var req CreateResourceTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	rt, err := h.typeService.CreateResourceType(c.Request.Context(), req.ID, caller.UserID, service.ResourceTypeInput{
+		Name:         req.Name,
+		ConfigSchema: req.ConfigSchema,
+		SecretSchema: req.SecretSchema,
+	})
+	if err != nil {
+		respondError(c, resourceTypeErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusCreated, rt)
+}
+
+// UpdateResourceType godoc
+// @Summary Update a custom resource type
+// @Description Built-in types are managed by the catalog and cannot be edited. Existing resources are validated against new schemas on their next update. Only admins of the platform operator organization may change the catalog.
+// @Tags resource-types
+// @Accept json
+// @Produce json
+// @Param id path string true "Resource type ID"
+// @Param request body UpdateResourceTypeRequest true "Resource type"
+// @Success 200 {object} domain.ResourceType
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /resource-types/{id} [put]
+func (h *ResourceTypeHandler) UpdateResourceType(c *gin.Context) {
+	caller, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateResourceTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	rt, err := h.typeService.UpdateResourceType(c.Request.Context(), c.Param("id"), caller.UserID, service.ResourceTypeInput{
+		Name:         req.Name,
+		ConfigSchema: req.ConfigSchema,
+		SecretSchema: req.SecretSchema,
+	})
+	if err != nil {
+		respondError(c, resourceTypeErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, rt)
+}
+
+// ListSchemaVersions godoc
+// @Summary List the schema history of a resource type
+// @Tags resource-types
+// @Produce json
+// @Param id path string true "Resource type ID"
+// @Success 200 {array} domain.ResourceTypeSchemaVersion
+// @Failure 404 {object} ErrorResponse
+// @Router /resource-types/{id}/versions [get]
+func (h *ResourceTypeHandler) ListSchemaVersions(c *gin.Context) {
+	versions, err := h.typeService.ListSchemaVersions(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, resourceTypeErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, versions)
+}
+
+// ActivateResourceType godoc
+// @Summary Reactivate a resource type
+// @Description Only admins of the platform operator organization may change the catalog.
+// @Tags resource-types
+// @Produce json
+// @Param id path string true "Resource type ID"
+// @Success 200 {object} domain.ResourceType
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /resource-types/{id}/activate [post]
+func (h *ResourceTypeHandler) ActivateResourceType(c *gin.Context) {
+	h.setActive(c, true)
+}
+
+// DeactivateResourceType godoc
+// @Summary Deactivate a resource type
+// @Description No new resources can use an inactive type; existing resources keep working. Only admins of the platform operator organization may change the catalog.
+// @Tags resource-types
+// @Produce json
+// @Param id path string true "Resource type ID"
+// @Success 200 {object} domain.ResourceType
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /resource-types/{id}/deactivate [post]
+func (h *ResourceTypeHandler) DeactivateResourceType(c *gin.Context) {
+	h.setActive(c, false)
+}
+
+func (h *ResourceTypeHandler) setActive(c *gin.Context, active bool) {
+	rt, err := h.typeService.SetResourceTypeActive(c.Request.Context(), c.Param("id"), active)
+	if err != nil {
+		respondError(c, resourceTypeErrorStatus(err), err)
+		return
+	}
+	c.JSON(http.StatusOK, rt)
+}
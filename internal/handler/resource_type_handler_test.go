@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"agentXmap/internal/domain"
+	"agentXmap/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockResourceTypeService is a mock implementation of service.ResourceTypeService
+type MockResourceTypeService struct {
+	mock.Mock
+}
+
+func (m *MockResourceTypeService) ListResourceTypes(ctx context.Context, includeInactive bool) ([]domain.ResourceType, error) {
+	args := m.Called(ctx, includeInactive)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.ResourceType), args.Error(1)
+}
+
+func (m *MockResourceTypeService) GetResourceType(ctx context.Context, id string) (*domain.ResourceType, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ResourceType), args.Error(1)
+}
+
+func (m *MockResourceTypeService) CreateResourceType(ctx context.Context, id string, userID uuid.UUID, input service.ResourceTypeInput) (*domain.ResourceType, error) {
+	args := m.Called(ctx, id, userID, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ResourceType), args.Error(1)
+}
+
+func (m *MockResourceTypeService) UpdateResourceType(ctx context.Context, id string, userID uuid.UUID, input service.ResourceTypeInput) (*domain.ResourceType, error) {
+	args := m.Called(ctx, id, userID, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ResourceType), args.Error(1)
+}
+
+func (m *MockResourceTypeService) SetResourceTypeActive(ctx context.Context, id string, active bool) (*domain.ResourceType, error) {
+	args := m.Called(ctx, id, active)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ResourceType), args.Error(1)
+}
+
+func (m *MockResourceTypeService) ListSchemaVersions(ctx context.Context, id string) ([]domain.ResourceTypeSchemaVersion, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.ResourceTypeSchemaVersion), args.Error(1)
+}
+
+func (m *MockResourceTypeService) SyncBuiltinTypes(ctx context.Context, builtins []domain.ResourceType) (*service.CatalogSyncReport, error) {
+	args := m.Called(ctx, builtins)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.CatalogSyncReport), args.Error(1)
+}
+
+func setupResourceTypeRouter(svc service.ResourceTypeService, caller *domain.Principal) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api/v1", withPrincipal(caller))
+	NewResourceTypeHandler(svc).RegisterRoutes(api)
+	return r
+}
+
+func TestResourceTypeHandler_ListResourceTypes(t *testing.T) {
+	caller := &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleUser}
+
+	t.Run("Include Inactive", func(t *testing.T) {
+		mockSvc := new(MockResourceTypeService)
+		router := setupResourceTypeRouter(mockSvc, caller)
+
+		mockSvc.On("ListResourceTypes", mock.Anything, true).Return([]domain.ResourceType{{ID: "aws_s3", IsBuiltin: true}}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/resource-types?include_inactive=true", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"is_builtin":true`)
+	})
+
+	t.Run("Invalid Flag", func(t *testing.T) {
+		router := setupResourceTypeRouter(new(MockResourceTypeService), caller)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodGet, "/api/v1/resource-types?include_inactive=maybe", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestResourceTypeHandler_CreateResourceType(t *testing.T) {
+	admin := &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleAdmin}
+	body := gin.H{"id": "ftp_server", "name": "FTP Server", "config_schema": gin.H{"type": "object"}}
+
+	t.Run("Success", func(t *testing.T) {
+		mockSvc := new(MockResourceTypeService)
+		router := setupResourceTypeRouter(mockSvc, admin)
+
+		mockSvc.On("CreateResourceType", mock.Anything, "ftp_server", admin.UserID, mock.MatchedBy(func(in service.ResourceTypeInput) bool {
+			return in.Name == "FTP Server" && string(in.ConfigSchema) == `{"type":"object"}` && in.SecretSchema == nil
+		})).Return(&domain.ResourceType{ID: "ftp_server", SchemaVersion: 1}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/resource-types", body))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), `"schema_version":1`)
+	})
+
+	t.Run("Already Exists", func(t *testing.T) {
+		mockSvc := new(MockResourceTypeService)
+		router := setupResourceTypeRouter(mockSvc, admin)
+
+		mockSvc.On("CreateResourceType", mock.Anything, "ftp_server", admin.UserID, mock.Anything).Return(nil, service.ErrResourceTypeExists)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/resource-types", body))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Forbidden for managers", func(t *testing.T) {
+		mockSvc := new(MockResourceTypeService)
+		manager := &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleManager}
+		router := setupResourceTypeRouter(mockSvc, manager)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/resource-types", body))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockSvc.AssertNotCalled(t, "CreateResourceType", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestResourceTypeHandler_UpdateResourceType(t *testing.T) {
+	admin := &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleAdmin}
+
+	t.Run("Builtin", func(t *testing.T) {
+		mockSvc := new(MockResourceTypeService)
+		router := setupResourceTypeRouter(mockSvc, admin)
+
+		mockSvc.On("UpdateResourceType", mock.Anything, "aws_s3", admin.UserID, mock.Anything).Return(nil, service.ErrBuiltinResourceType)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPut, "/api/v1/resource-types/aws_s3", gin.H{"name": "S3"}))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Invalid Schema", func(t *testing.T) {
+		mockSvc := new(MockResourceTypeService)
+		router := setupResourceTypeRouter(mockSvc, admin)
+
+		mockSvc.On("UpdateResourceType", mock.Anything, "ftp_server", admin.UserID, mock.Anything).Return(nil, service.ErrInvalidSchema)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPut, "/api/v1/resource-types/ftp_server", gin.H{"name": "FTP", "config_schema": gin.H{"type": 1}}))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestResourceTypeHandler_DeactivateResourceType(t *testing.T) {
+	admin := &domain.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleAdmin}
+
+	t.Run("Success", func(t *testing.T) {
+		mockSvc := new(MockResourceTypeService)
+		router := setupResourceTypeRouter(mockSvc, admin)
+
+		mockSvc.On("SetResourceTypeActive", mock.Anything, "aws_s3", false).Return(&domain.ResourceType{ID: "aws_s3"}, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/resource-types/aws_s3/deactivate", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"is_active":false`)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		mockSvc := new(MockResourceTypeService)
+		router := setupResourceTypeRouter(mockSvc, admin)
+
+		mockSvc.On("SetResourceTypeActive", mock.Anything, "mongo", false).Return(nil, service.ErrResourceTypeNotFound)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newRequest(http.MethodPost, "/api/v1/resource-types/mongo/deactivate", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,119 @@
+// Package mail delivers email through a pluggable Mailer. The SMTP backend
+// hands messages to a relay; the file backend writes them to an outbox
+// directory instead, for development and tests.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+var ErrInvalidHeader = errors.New("mail header must not contain line breaks")
+
+// Message is an email to a single recipient. HTML is optional.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer sends email.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config selects and configures the Mailer built by NewMailer.
+type Config struct {
+	// Backend is "smtp" or "file" (default).
+	Backend string
+	// From is the sender address of every message.
+	From string
+	// SMTPHost, SMTPPort and the optional credentials reach the relay. The
+	// credentials are only sent once the connection is upgraded with STARTTLS.
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	// OutboxDir is where the file backend writes messages.
+	OutboxDir string
+}
+
+// NewMailer builds the Mailer described by cfg.
+func NewMailer(cfg Config) (Mailer, error) {
+	if cfg.From == "" {
+		return nil, errors.New("mail: a sender address is required")
+	}
+	switch cfg.Backend {
+	case "", "file":
+		return NewFileMailer(cfg.OutboxDir, cfg.From)
+	case "smtp":
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From)
+	default:
+		return nil, fmt.Errorf("unsupported mail backend %q", cfg.Backend)
+	}
+}
+
+// compose renders msg as an RFC 5322 message: plain text alone, or text and
+// HTML as multipart/alternative. Bodies are quoted-printable.
+func compose(from string, msg Message, date time.Time) ([]byte, error) {
+	for _, v := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, ErrInvalidHeader
+		}
+	}
+
+	var head, body bytes.Buffer
+	fmt.Fprintf(&head, "From: %s\r\n", from)
+	fmt.Fprintf(&head, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&head, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&head, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	head.WriteString("MIME-Version: 1.0\r\n")
+
+	if msg.HTML == "" {
+		head.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		head.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&body, msg.Text); err != nil {
+			return nil, err
+		}
+		return append(head.Bytes(), body.Bytes()...), nil
+	}
+
+	parts := multipart.NewWriter(&body)
+	fmt.Fprintf(&head, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", parts.Boundary())
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return append(head.Bytes(), body.Bytes()...), nil
+}
+
+func writeQuotedPrintable(w interface{ Write([]byte) (int, error) }, s string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(s)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
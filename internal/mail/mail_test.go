@@ -0,0 +1,240 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parse(t *testing.T, raw []byte) (*mail.Message, map[string]string) {
+	t.Helper()
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	require.NoError(t, err)
+
+	bodies := map[string]string{}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	if mediaType != "multipart/alternative" {
+		body, _ := io.ReadAll(msg.Body)
+		bodies[mediaType] = string(body)
+		return msg, bodies
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			return msg, bodies
+		}
+		require.NoError(t, err)
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		// NextPart decodes quoted-printable.
+		body, _ := io.ReadAll(part)
+		bodies[partType] = string(body)
+	}
+}
+
+func TestCompose(t *testing.T) {
+	date := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Text And HTML", func(t *testing.T) {
+		raw, err := compose("noreply@agentxmap.io", Message{
+			To:      "john@acme.com",
+			Subject: "Réinitialisation",
+			Text:    "Hello John, a long line " + strings.Repeat("x", 100),
+			HTML:    "<p>Hello John</p>",
+		}, date)
+		require.NoError(t, err)
+
+		msg, bodies := parse(t, raw)
+		assert.Equal(t, "noreply@agentxmap.io", msg.Header.Get("From"))
+		assert.Equal(t, "john@acme.com", msg.Header.Get("To"))
+		subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+		assert.NoError(t, err)
+		assert.Equal(t, "Réinitialisation", subject)
+		assert.Equal(t, "Hello John, a long line "+strings.Repeat("x", 100), bodies["text/plain"])
+		assert.Equal(t, "<p>Hello John</p>", bodies["text/html"])
+	})
+
+	t.Run("Text Only", func(t *testing.T) {
+		raw, err := compose("noreply@agentxmap.io", Message{To: "john@acme.com", Subject: "Hi", Text: "plain"}, date)
+		require.NoError(t, err)
+
+		_, bodies := parse(t, raw)
+		assert.Equal(t, map[string]string{"text/plain": "plain"}, bodies)
+	})
+
+	t.Run("Header Injection", func(t *testing.T) {
+		_, err := compose("noreply@agentxmap.io", Message{To: "john@acme.com\r\nBcc: eve@evil.com", Subject: "Hi"}, date)
+		assert.ErrorIs(t, err, ErrInvalidHeader)
+	})
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	mailer, err := NewFileMailer(dir, "noreply@agentxmap.io")
+	require.NoError(t, err)
+
+	require.NoError(t, mailer.Send(context.Background(), Message{To: "john@acme.com", Subject: "Hi", Text: "one"}))
+	require.NoError(t, mailer.Send(context.Background(), Message{To: "jane@acme.com", Subject: "Hi", Text: "two"}))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	for _, file := range files {
+		assert.Equal(t, ".eml", filepath.Ext(file))
+	}
+	raw, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	msg, _ := parse(t, raw)
+	assert.Contains(t, []string{"john@acme.com", "jane@acme.com"}, msg.Header.Get("To"))
+}
+
+// fakeSMTP accepts one message and hands its envelope and data to received.
+func fakeSMTP(t *testing.T, received chan<- []string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+		var envelope []string
+		reply("220 fake ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			switch cmd := strings.ToUpper(strings.Fields(line + " ")[0]); cmd {
+			case "EHLO", "HELO":
+				reply("250 fake")
+			case "MAIL", "RCPT":
+				envelope = append(envelope, line)
+				reply("250 ok")
+			case "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				received <- append(envelope, data.String())
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestSMTPMailer(t *testing.T) {
+	received := make(chan []string, 1)
+	host, port, err := net.SplitHostPort(fakeSMTP(t, received))
+	require.NoError(t, err)
+	portNum, err := net.LookupPort("tcp", port)
+	require.NoError(t, err)
+
+	mailer, err := NewSMTPMailer(host, portNum, "", "", "agentXmap <noreply@agentxmap.io>")
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, mailer.Send(ctx, Message{To: "john@acme.com", Subject: "Hi", Text: "hello", HTML: "<p>hello</p>"}))
+
+	got := <-received
+	assert.Equal(t, "MAIL FROM:<noreply@agentxmap.io>", got[0])
+	assert.Equal(t, "RCPT TO:<john@acme.com>", got[1])
+	msg, bodies := parse(t, []byte(got[2]))
+	assert.Equal(t, "john@acme.com", msg.Header.Get("To"))
+	assert.Equal(t, "hello", bodies["text/plain"])
+}
+
+func TestNewMailer(t *testing.T) {
+	t.Run("File By Default", func(t *testing.T) {
+		mailer, err := NewMailer(Config{From: "noreply@agentxmap.io", OutboxDir: t.TempDir()})
+		assert.NoError(t, err)
+		assert.IsType(t, &FileMailer{}, mailer)
+	})
+
+	t.Run("SMTP", func(t *testing.T) {
+		mailer, err := NewMailer(Config{Backend: "smtp", From: "noreply@agentxmap.io", SMTPHost: "smtp.acme.com"})
+		assert.NoError(t, err)
+		assert.IsType(t, &SMTPMailer{}, mailer)
+	})
+
+	t.Run("Unknown Backend", func(t *testing.T) {
+		_, err := NewMailer(Config{Backend: "pigeon", From: "noreply@agentxmap.io"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Sender Required", func(t *testing.T) {
+		_, err := NewMailer(Config{OutboxDir: t.TempDir()})
+		assert.Error(t, err)
+	})
+}
+
+func TestTemplates(t *testing.T) {
+	templates, err := LoadTemplates()
+	require.NoError(t, err)
+	expires := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Invitation", func(t *testing.T) {
+		msg, err := templates.Render(TemplateInvitation, InvitationData{
+			OrganizationName: "Acme <Corp>",
+			InvitorName:      "Jane",
+			Role:             "user",
+			AcceptURL:        "https://app.agentxmap.io/invitations/accept?token=abc&x=1",
+			ExpiresAt:        expires,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "You are invited to join Acme <Corp>", msg.Subject)
+		assert.Contains(t, msg.Text, "Jane invited you to join Acme <Corp> on agentXmap as user.")
+		assert.Contains(t, msg.Text, "https://app.agentxmap.io/invitations/accept?token=abc&x=1")
+		assert.Contains(t, msg.Text, "1 Mar 2026 12:00 UTC")
+		// The HTML body escapes what users control.
+		assert.Contains(t, msg.HTML, "Acme &lt;Corp&gt;")
+		assert.Contains(t, msg.HTML, `href="https://app.agentxmap.io/invitations/accept?token=abc&amp;x=1"`)
+	})
+
+	t.Run("Password Reset", func(t *testing.T) {
+		msg, err := templates.Render(TemplatePasswordReset, PasswordResetData{Name: "John", ResetURL: "https://app.agentxmap.io/reset-password?token=abc", ExpiresAt: expires})
+		require.NoError(t, err)
+		assert.Equal(t, "Reset your agentXmap password", msg.Subject)
+		assert.Contains(t, msg.Text, "Hello John,")
+		assert.Contains(t, msg.HTML, `href="https://app.agentxmap.io/reset-password?token=abc"`)
+	})
+
+	t.Run("Notification Subject Stays On One Line", func(t *testing.T) {
+		msg, err := templates.Render(TemplateNotification, NotificationData{Subject: "Agent\r\nBcc: eve@evil.com", Message: "<b>down</b>"})
+		require.NoError(t, err)
+		assert.Equal(t, "Agent Bcc: eve@evil.com", msg.Subject)
+		assert.Contains(t, msg.Text, "<b>down</b>")
+		assert.Contains(t, msg.HTML, "&lt;b&gt;down&lt;/b&gt;")
+	})
+}
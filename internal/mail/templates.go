@@ -0,0 +1,84 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Template names. Each has a "<name>.subject" and "<name>.text" block in
+// templates/<name>.txt.tmpl and a "<name>.html" block in templates/<name>.html.tmpl.
+const (
+	TemplateInvitation    = "invitation"
+	TemplatePasswordReset = "password_reset"
+	TemplateNotification  = "notification"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// InvitationData fills the invitation template.
+type InvitationData struct {
+	OrganizationName string
+	InvitorName      string
+	Role             string
+	AcceptURL        string
+	ExpiresAt        time.Time
+}
+
+// PasswordResetData fills the password_reset template.
+type PasswordResetData struct {
+	Name      string
+	ResetURL  string
+	ExpiresAt time.Time
+}
+
+// NotificationData fills the notification template.
+type NotificationData struct {
+	Name    string
+	Subject string
+	Message string
+}
+
+// Templates renders the built-in emails. HTML bodies are escaped by html/template.
+type Templates struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// LoadTemplates parses the embedded templates.
+func LoadTemplates() (*Templates, error) {
+	text, err := texttemplate.ParseFS(templateFS, "templates/*.txt.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("mail templates: %w", err)
+	}
+	html, err := htmltemplate.ParseFS(templateFS, "templates/*.html.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("mail templates: %w", err)
+	}
+	return &Templates{text: text, html: html}, nil
+}
+
+// Render fills template name with data into a Message without recipient.
+func (t *Templates) Render(name string, data any) (Message, error) {
+	var subject, text, html bytes.Buffer
+	if err := t.text.ExecuteTemplate(&subject, name+".subject", data); err != nil {
+		return Message{}, fmt.Errorf("render %s: %w", name, err)
+	}
+	if err := t.text.ExecuteTemplate(&text, name+".text", data); err != nil {
+		return Message{}, fmt.Errorf("render %s: %w", name, err)
+	}
+	if err := t.html.ExecuteTemplate(&html, name+".html", data); err != nil {
+		return Message{}, fmt.Errorf("render %s: %w", name, err)
+	}
+	return Message{
+		// A subject built from user input must not break the headers.
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    strings.TrimSpace(html.String()) + "\n",
+	}, nil
+}
@@ -0,0 +1,196 @@
+// Package policy centralizes role-based authorization decisions.
+//
+// The decision table maps (resource, action, role) to a Decision. Services
+// enforce it through Authorize / Authorizer.AuthorizeAgent, and HTTP handlers
+// reject obviously forbidden calls early with the same table.
+package policy
+
+import (
+	"context"
+	"errors"
+
+	"agentXmap/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+type Resource string
+type Action string
+
+const (
+	ResourceAgent       Resource = "agent"
+	ResourceBilling     Resource = "billing"
+	ResourceResource    Resource = "resource"
+	ResourceApplication Resource = "application"
+	ResourceAPIKey      Resource = "api_key"
+	ResourceInvitation  Resource = "invitation"
+	// ResourceChangeRequest covers reviewing pending configuration changes of high-risk agents.
+	ResourceChangeRequest Resource = "change_request"
+	// ResourceAgentRiskLevel covers classifying an agent; lowering it lifts the approval requirement.
+	ResourceAgentRiskLevel Resource = "agent_risk_level"
+	// ResourceResourceSecret covers the credentials of a resource; reading them discloses plaintext.
+	ResourceResourceSecret Resource = "resource_secret"
+	// ResourceResourceAccess covers granting, changing and revoking agent access to a resource.
+	ResourceResourceAccess Resource = "resource_access"
+	// ResourceResourceWriteAccess covers giving an agent read_write access, by a new grant or an upgrade.
+	ResourceResourceWriteAccess Resource = "resource_write_access"
+	// ResourceResourceType covers the catalog of resource types shared by every organization.
+	ResourceResourceType Resource = "resource_type"
+	// ResourceAdminRole covers giving a user the admin role, which no other role may grant.
+	ResourceAdminRole Resource = "admin_role"
+	// ResourceAuditLog covers querying and exporting the organization's audit trail.
+	ResourceAuditLog Resource = "audit_log"
+
+	ActionCreate Action = "create"
+	ActionRead   Action = "read"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Decision is the outcome of a role check.
+type Decision int
+
+const (
+	Deny Decision = iota
+	Allow
+	// AllowIfAssigned grants access only to agents the user has an AgentAssignment for.
+	AllowIfAssigned
+)
+
+var (
+	ErrForbidden       = errors.New("forbidden")
+	ErrUnauthenticated = errors.New("no authenticated principal")
+)
+
+type roleDecisions map[domain.UserRole]Decision
+
+var (
+	everyone      = roleDecisions{domain.UserRoleAdmin: Allow, domain.UserRoleManager: Allow, domain.UserRoleUser: Allow}
+	managers      = roleDecisions{domain.UserRoleAdmin: Allow, domain.UserRoleManager: Allow}
+	adminsOnly    = roleDecisions{domain.UserRoleAdmin: Allow}
+	assignedUsers = roleDecisions{domain.UserRoleAdmin: Allow, domain.UserRoleManager: Allow, domain.UserRoleUser: AllowIfAssigned}
+)
+
+// decisionTable is the single source of truth for role permissions.
+// Any (resource, action, role) absent from the table is denied.
+var decisionTable = map[Resource]map[Action]roleDecisions{
+	ResourceAgent: {
+		ActionCreate: managers,
+		ActionRead:   assignedUsers,
+		ActionUpdate: managers,
+		ActionDelete: adminsOnly,
+	},
+	ResourceBilling: {
+		ActionRead: managers,
+	},
+	ResourceResource: {
+		ActionCreate: managers,
+		ActionRead:   everyone,
+		ActionUpdate: managers,
+		ActionDelete: adminsOnly,
+	},
+	ResourceApplication: {
+		ActionCreate: managers,
+		ActionRead:   everyone,
+		ActionUpdate: managers,
+		ActionDelete: adminsOnly,
+	},
+	ResourceAPIKey: {
+		ActionCreate: adminsOnly,
+		ActionRead:   managers,
+		ActionDelete: adminsOnly,
+	},
+	ResourceInvitation: {
+		ActionCreate: managers,
+		ActionRead:   managers,
+		ActionUpdate: managers,
+		ActionDelete: managers,
+	},
+	ResourceChangeRequest: {
+		ActionRead:   managers,
+		ActionUpdate: managers,
+	},
+	ResourceAgentRiskLevel: {
+		ActionUpdate: adminsOnly,
+	},
+	ResourceResourceSecret: {
+		ActionRead:   adminsOnly,
+		ActionUpdate: managers,
+	},
+	ResourceResourceAccess: {
+		ActionCreate: managers,
+		ActionUpdate: managers,
+		ActionDelete: managers,
+	},
+	ResourceResourceWriteAccess: {
+		ActionCreate: adminsOnly,
+	},
+	ResourceResourceType: {
+		ActionCreate: adminsOnly,
+		ActionRead:   everyone,
+		ActionUpdate: adminsOnly,
+	},
+	ResourceAdminRole: {
+		ActionCreate: adminsOnly,
+	},
+	ResourceAuditLog: {
+		ActionRead: adminsOnly,
+	},
+}
+
+// Decide looks up the decision for role performing action on resource.
+func Decide(role domain.UserRole, resource Resource, action Action) Decision {
+	return decisionTable[resource][action][role]
+}
+
+// Authorize checks the principal in ctx against the decision table.
+// AllowIfAssigned is treated as Deny here; use Authorizer.AuthorizeAgent for agent instances.
+func Authorize(ctx context.Context, resource Resource, action Action) error {
+	p, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+	if Decide(p.Role, resource, action) != Allow {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// AssignmentChecker reports whether a user is assigned to an agent.
+type AssignmentChecker interface {
+	IsAssigned(ctx context.Context, agentID, userID uuid.UUID) (bool, error)
+}
+
+// Authorizer enforces decisions that depend on agent assignments.
+type Authorizer struct {
+	assignments AssignmentChecker
+}
+
+// NewAuthorizer creates a new Authorizer.
+func NewAuthorizer(assignments AssignmentChecker) *Authorizer {
+	return &Authorizer{assignments: assignments}
+}
+
+// AuthorizeAgent checks that the principal in ctx may perform action on agentID.
+func (a *Authorizer) AuthorizeAgent(ctx context.Context, action Action, agentID uuid.UUID) error {
+	p, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+
+	switch Decide(p.Role, ResourceAgent, action) {
+	case Allow:
+		return nil
+	case AllowIfAssigned:
+		assigned, err := a.assignments.IsAssigned(ctx, agentID, p.UserID)
+		if err != nil {
+			return err
+		}
+		if !assigned {
+			return ErrForbidden
+		}
+		return nil
+	default:
+		return ErrForbidden
+	}
+}
@@ -0,0 +1,143 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"agentXmap/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecide(t *testing.T) {
+	const (
+		admin   = domain.UserRoleAdmin
+		manager = domain.UserRoleManager
+		user    = domain.UserRoleUser
+	)
+
+	tests := []struct {
+		resource Resource
+		action   Action
+		want     map[domain.UserRole]Decision
+	}{
+		{ResourceAgent, ActionCreate, map[domain.UserRole]Decision{admin: Allow, manager: Allow, user: Deny}},
+		{ResourceAgent, ActionRead, map[domain.UserRole]Decision{admin: Allow, manager: Allow, user: AllowIfAssigned}},
+		{ResourceAgent, ActionUpdate, map[domain.UserRole]Decision{admin: Allow, manager: Allow, user: Deny}},
+		{ResourceAgent, ActionDelete, map[domain.UserRole]Decision{admin: Allow, manager: Deny, user: Deny}},
+		{ResourceBilling, ActionRead, map[domain.UserRole]Decision{admin: Allow, manager: Allow, user: Deny}},
+		{ResourceResource, ActionCreate, map[domain.UserRole]Decision{admin: Allow, manager: Allow, user: Deny}},
+		{ResourceResource, ActionRead, map[domain.UserRole]Decision{admin: Allow, manager: Allow, user: Allow}},
+		{ResourceResource, ActionUpdate, map[domain.UserRole]Decision{admin: Allow, manager: Allow, user: Deny}},
+		{ResourceResource, ActionDelete, map[domain.UserRole]Decision{admin: Allow, manager: Deny, user: Deny}},
+		{ResourceApplication, ActionCreate, map[domain.UserRole]Decision{admin: Allow, manager: Allow, user: Deny}},
+		{ResourceApplication, ActionRead, map[domain.UserRole]Decision{admin: Allow, manager: Allow, user: Allow}},
+		{ResourceApplication, ActionUpdate, map[domain.UserRole]Decision{admin: Allow, manager: Allow, user: Deny}},
+		{ResourceApplication, ActionDelete, map[domain.UserRole]Decision{admin: Allow, manager: Deny, user: Deny}},
+		{ResourceAPIKey, ActionCreate, map[domain.UserRole]Decision{admin: Allow, manager: Deny, user: Deny}},
+		{ResourceAPIKey, ActionRead, map[domain.UserRole]Decision{admin: Allow, manager: Allow, user: Deny}},
+		{ResourceAPIKey, ActionUpdate, map[domain.UserRole]Decision{admin: Deny, manager: Deny, user: Deny}},
+		{ResourceAPIKey, ActionDelete, map[domain.UserRole]Decision{admin: Allow, manager: Deny, user: Deny}},
+		{ResourceInvitation, ActionCreate, map[domain.UserRole]Decision{admin: Allow, manager: Allow, user: Deny}},
+		{ResourceChangeRequest, ActionRead, map[domain.UserRole]Decision{admin: Allow, manager: Allow, user: Deny}},
+		{ResourceChangeRequest, ActionUpdate, map[domain.UserRole]Decision{admin: Allow, manager: Allow, user: Deny}},
+		{ResourceAgentRiskLevel, ActionUpdate, map[domain.UserRole]Decision{admin: Allow, manager: Deny, user: Deny}},
+		{ResourceResourceAccess, ActionCreate, map[domain.UserRole]Decision{admin: Allow, manager: Allow, user: Deny}},
+		{ResourceResourceAccess, ActionUpdate, map[domain.UserRole]Decision{admin: Allow, manager: Allow, user: Deny}},
+		{ResourceResourceAccess, ActionDelete, map[domain.UserRole]Decision{admin: Allow, manager: Allow, user: Deny}},
+		{ResourceResourceWriteAccess, ActionCreate, map[domain.UserRole]Decision{admin: Allow, manager: Deny, user: Deny}},
+		{ResourceResourceType, ActionCreate, map[domain.UserRole]Decision{admin: Allow, manager: Deny, user: Deny}},
+		{ResourceResourceType, ActionRead, map[domain.UserRole]Decision{admin: Allow, manager: Allow, user: Allow}},
+		{ResourceResourceType, ActionUpdate, map[domain.UserRole]Decision{admin: Allow, manager: Deny, user: Deny}},
+		{ResourceResourceType, ActionDelete, map[domain.UserRole]Decision{admin: Deny, manager: Deny, user: Deny}},
+		{ResourceAdminRole, ActionCreate, map[domain.UserRole]Decision{admin: Allow, manager: Deny, user: Deny}},
+		{ResourceAuditLog, ActionRead, map[domain.UserRole]Decision{admin: Allow, manager: Deny, user: Deny}},
+		{ResourceAuditLog, ActionDelete, map[domain.UserRole]Decision{admin: Deny, manager: Deny, user: Deny}},
+	}
+
+	for _, tt := range tests {
+		for role, want := range tt.want {
+			t.Run(string(tt.resource)+"/"+string(tt.action)+"/"+string(role), func(t *testing.T) {
+				assert.Equal(t, want, Decide(role, tt.resource, tt.action))
+			})
+		}
+	}
+
+	t.Run("Unknown role is denied", func(t *testing.T) {
+		assert.Equal(t, Deny, Decide(domain.UserRole("owner"), ResourceAgent, ActionRead))
+	})
+
+	t.Run("Unknown resource is denied", func(t *testing.T) {
+		assert.Equal(t, Deny, Decide(admin, Resource("billing_plan"), ActionRead))
+	})
+}
+
+func TestAuthorize(t *testing.T) {
+	withRole := func(role domain.UserRole) context.Context {
+		return domain.WithPrincipal(context.Background(), &domain.Principal{UserID: uuid.New(), Role: role})
+	}
+
+	assert.NoError(t, Authorize(withRole(domain.UserRoleManager), ResourceAgent, ActionCreate))
+	assert.ErrorIs(t, Authorize(withRole(domain.UserRoleUser), ResourceAgent, ActionCreate), ErrForbidden)
+	// AllowIfAssigned needs an agent instance, so a role-only check denies it.
+	assert.ErrorIs(t, Authorize(withRole(domain.UserRoleUser), ResourceAgent, ActionRead), ErrForbidden)
+	assert.ErrorIs(t, Authorize(context.Background(), ResourceAgent, ActionRead), ErrUnauthenticated)
+}
+
+type stubAssignments struct {
+	assigned bool
+	err      error
+	calls    int
+}
+
+func (s *stubAssignments) IsAssigned(ctx context.Context, agentID, userID uuid.UUID) (bool, error) {
+	s.calls++
+	return s.assigned, s.err
+}
+
+func TestAuthorizer_AuthorizeAgent(t *testing.T) {
+	agentID := uuid.New()
+	withRole := func(role domain.UserRole) context.Context {
+		return domain.WithPrincipal(context.Background(), &domain.Principal{UserID: uuid.New(), Role: role})
+	}
+
+	t.Run("Admin skips assignment lookup", func(t *testing.T) {
+		stub := &stubAssignments{}
+		err := NewAuthorizer(stub).AuthorizeAgent(withRole(domain.UserRoleAdmin), ActionDelete, agentID)
+		assert.NoError(t, err)
+		assert.Zero(t, stub.calls)
+	})
+
+	t.Run("Assigned user may read", func(t *testing.T) {
+		stub := &stubAssignments{assigned: true}
+		err := NewAuthorizer(stub).AuthorizeAgent(withRole(domain.UserRoleUser), ActionRead, agentID)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, stub.calls)
+	})
+
+	t.Run("Unassigned user may not read", func(t *testing.T) {
+		stub := &stubAssignments{}
+		err := NewAuthorizer(stub).AuthorizeAgent(withRole(domain.UserRoleUser), ActionRead, agentID)
+		assert.ErrorIs(t, err, ErrForbidden)
+	})
+
+	t.Run("Assigned user may not update", func(t *testing.T) {
+		stub := &stubAssignments{assigned: true}
+		err := NewAuthorizer(stub).AuthorizeAgent(withRole(domain.UserRoleUser), ActionUpdate, agentID)
+		assert.ErrorIs(t, err, ErrForbidden)
+		assert.Zero(t, stub.calls)
+	})
+
+	t.Run("Lookup error is returned", func(t *testing.T) {
+		stub := &stubAssignments{err: errors.New("db error")}
+		err := NewAuthorizer(stub).AuthorizeAgent(withRole(domain.UserRoleUser), ActionRead, agentID)
+		assert.EqualError(t, err, "db error")
+	})
+
+	t.Run("No principal", func(t *testing.T) {
+		err := NewAuthorizer(&stubAssignments{}).AuthorizeAgent(context.Background(), ActionRead, agentID)
+		assert.ErrorIs(t, err, ErrUnauthenticated)
+	})
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type agentRepository struct {
@@ -15,18 +16,27 @@ type agentRepository struct {
 }
 
 // NewAgentRepository creates a new postgres repository for Agents.
+// Every query is restricted to the organization carried by ctx.
 func NewAgentRepository(db *gorm.DB) domain.AgentRepository {
 	return &agentRepository{db: db}
 }
 
 func (r *agentRepository) Create(ctx context.Context, agent *domain.Agent) error {
-	return r.db.WithContext(ctx).Create(agent).Error
+	if err := claimTenant(ctx, &agent.OrganizationID); err != nil {
+		return err
+	}
+	return conn(ctx, r.db).Create(agent).Error
 }
 
 func (r *agentRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Agent, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
 	var agent domain.Agent
 	// Preload minimal relations
-	if err := r.db.WithContext(ctx).Preload("Versions").Preload("Organization").First(&agent, "id = ?", id).Error; err != nil {
+	if err := conn(ctx, r.db).Preload("Versions").Preload("Organization").
+		Where("id = ?", id).Scopes(inOrganization("agents", orgID)).First(&agent).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -36,51 +46,341 @@ func (r *agentRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Ag
 }
 
 func (r *agentRepository) ListByOrg(ctx context.Context, orgID uuid.UUID) ([]domain.Agent, error) {
+	if err := claimTenant(ctx, &orgID); err != nil {
+		return nil, err
+	}
 	var agents []domain.Agent
-	if err := r.db.WithContext(ctx).Where("organization_id = ?", orgID).Find(&agents).Error; err != nil {
+	if err := conn(ctx, r.db).Where("organization_id = ?", orgID).Find(&agents).Error; err != nil {
 		return nil, err
 	}
 	return agents, nil
 }
 
 func (r *agentRepository) ListByStatus(ctx context.Context, orgID uuid.UUID, status domain.AgentStatus) ([]domain.Agent, error) {
+	if err := claimTenant(ctx, &orgID); err != nil {
+		return nil, err
+	}
 	var agents []domain.Agent
-	if err := r.db.WithContext(ctx).Where("organization_id = ? AND status = ?", orgID, status).Find(&agents).Error; err != nil {
+	if err := conn(ctx, r.db).Where("organization_id = ? AND status = ?", orgID, status).Find(&agents).Error; err != nil {
 		return nil, err
 	}
 	return agents, nil
 }
 
 func (r *agentRepository) GetAssignedLLMs(ctx context.Context, agentID uuid.UUID) ([]domain.AgentLLM, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
 	var agentLLMs []domain.AgentLLM
 	// Load AgentLLM with associated LLMModel details
-	if err := r.db.WithContext(ctx).
+	if err := conn(ctx, r.db).
 		Preload("LLMModel").
 		Where("agent_id = ?", agentID).
+		Scopes(agentInOrganization("agent_id", orgID)).
 		Find(&agentLLMs).Error; err != nil {
 		return nil, err
 	}
 	return agentLLMs, nil
 }
 
-func (r *agentRepository) Update(ctx context.Context, agent *domain.Agent) error {
-	return r.db.WithContext(ctx).Save(agent).Error
+// Update writes the name and configuration of agent. The write only applies
+// while the stored status and risk level still equal agent's, so a stale read
+// cannot undo a concurrent transition or reclassification; it reports false
+// otherwise, or when the agent is not in the caller's tenant. It never moves
+// an agent across organizations.
+func (r *agentRepository) Update(ctx context.Context, agent *domain.Agent) (bool, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return false, err
+	}
+	if agent.OrganizationID != orgID {
+		return false, ErrCrossTenant
+	}
+	result := conn(ctx, r.db).Model(&domain.Agent{}).
+		Where("id = ? AND status = ? AND risk_level = ?", agent.ID, agent.Status, agent.RiskLevel).
+		Scopes(inOrganization("agents", orgID)).
+		Updates(map[string]interface{}{
+			"name":          agent.Name,
+			"configuration": agent.Configuration,
+			"updated_by":    agent.UpdatedBy,
+			"updated_at":    agent.UpdatedAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
 }
 
 func (r *agentRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&domain.Agent{}, "id = ?", id).Error
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+	result := conn(ctx, r.db).
+		Where("id = ?", id).
+		Scopes(inOrganization("agents", orgID)).
+		Delete(&domain.Agent{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
 func (r *agentRepository) CreateVersion(ctx context.Context, version *domain.AgentVersion) error {
-	return r.db.WithContext(ctx).Create(version).Error
+	if err := r.ensureAgent(ctx, version.AgentID); err != nil {
+		return err
+	}
+	return conn(ctx, r.db).Create(version).Error
+}
+
+func (r *agentRepository) CreateNextVersion(ctx context.Context, version *domain.AgentVersion) error {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+	return NewTxManager(r.db).WithinTransaction(ctx, func(ctx context.Context) error {
+		db := conn(ctx, r.db)
+
+		// Lock the agent row so concurrent writers allocate numbers one at a time
+		// instead of colliding on UNIQUE(agent_id, version_number).
+		var agent domain.Agent
+		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			Where("id = ?", version.AgentID).
+			Scopes(inOrganization("agents", orgID)).
+			Take(&agent).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrCrossTenant
+			}
+			return err
+		}
+
+		var latest int
+		if err := db.Model(&domain.AgentVersion{}).
+			Select("COALESCE(MAX(version_number), 0)").
+			Where("agent_id = ?", version.AgentID).
+			Scan(&latest).Error; err != nil {
+			return err
+		}
+
+		version.VersionNumber = latest + 1
+		return db.Create(version).Error
+	})
+}
+
+func (r *agentRepository) GetLatestVersion(ctx context.Context, agentID uuid.UUID) (*domain.AgentVersion, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var version domain.AgentVersion
+	if err := conn(ctx, r.db).
+		Where("agent_id = ?", agentID).
+		Scopes(agentInOrganization("agent_id", orgID)).
+		Order("version_number DESC").
+		First(&version).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &version, nil
+}
+
+func (r *agentRepository) GetVersion(ctx context.Context, agentID uuid.UUID, versionNumber int) (*domain.AgentVersion, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var version domain.AgentVersion
+	if err := conn(ctx, r.db).
+		Where("agent_id = ? AND version_number = ?", agentID, versionNumber).
+		Scopes(agentInOrganization("agent_id", orgID)).
+		First(&version).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &version, nil
+}
+
+func (r *agentRepository) ListVersions(ctx context.Context, agentID uuid.UUID, limit, offset int) ([]domain.AgentVersion, int64, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := conn(ctx, r.db).Model(&domain.AgentVersion{}).
+		Where("agent_id = ?", agentID).
+		Scopes(agentInOrganization("agent_id", orgID)).
+		Session(&gorm.Session{}) // reused for the count and the page
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var versions []domain.AgentVersion
+	if err := query.Order("version_number DESC").Limit(limit).Offset(offset).Find(&versions).Error; err != nil {
+		return nil, 0, err
+	}
+	return versions, total, nil
+}
+
+func (r *agentRepository) CreateChangeRequest(ctx context.Context, req *domain.AgentChangeRequest) error {
+	if err := r.ensureAgent(ctx, req.AgentID); err != nil {
+		return err
+	}
+	return conn(ctx, r.db).Create(req).Error
+}
+
+func (r *agentRepository) GetChangeRequest(ctx context.Context, id uuid.UUID) (*domain.AgentChangeRequest, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var req domain.AgentChangeRequest
+	if err := conn(ctx, r.db).
+		Where("id = ?", id).
+		Scopes(agentInOrganization("agent_id", orgID)).
+		First(&req).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &req, nil
+}
+
+func (r *agentRepository) ListChangeRequests(ctx context.Context, agentID uuid.UUID, status domain.ChangeRequestStatus) ([]domain.AgentChangeRequest, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := conn(ctx, r.db).
+		Where("agent_id = ?", agentID).
+		Scopes(agentInOrganization("agent_id", orgID))
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var reqs []domain.AgentChangeRequest
+	if err := query.Order("created_at DESC").Find(&reqs).Error; err != nil {
+		return nil, err
+	}
+	return reqs, nil
+}
+
+func (r *agentRepository) ResolveChangeRequest(ctx context.Context, req *domain.AgentChangeRequest) (bool, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return false, err
+	}
+	// The status guard makes approve/reject races resolve the request only once.
+	result := conn(ctx, r.db).Model(&domain.AgentChangeRequest{}).
+		Where("id = ? AND status = ?", req.ID, domain.ChangeRequestStatusPending).
+		Scopes(agentInOrganization("agent_id", orgID)).
+		Updates(map[string]interface{}{
+			"status":             req.Status,
+			"reviewed_by":        req.ReviewedBy,
+			"review_comment":     req.ReviewComment,
+			"reviewed_at":        req.ReviewedAt,
+			"applied_version_id": req.AppliedVersionID,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *agentRepository) UpdateStatus(ctx context.Context, agentID uuid.UUID, from, to domain.AgentStatus, userID uuid.UUID) (bool, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return false, err
+	}
+	// The status guard serializes concurrent transitions of the same agent.
+	result := conn(ctx, r.db).Model(&domain.Agent{}).
+		Where("id = ? AND status = ?", agentID, from).
+		Scopes(inOrganization("agents", orgID)).
+		Updates(map[string]interface{}{"status": to, "updated_by": userID})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *agentRepository) UpdateRiskLevel(ctx context.Context, agentID uuid.UUID, from, to domain.AgentRiskLevel, userID uuid.UUID) (bool, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return false, err
+	}
+	result := conn(ctx, r.db).Model(&domain.Agent{}).
+		Where("id = ? AND risk_level = ?", agentID, from).
+		Scopes(inOrganization("agents", orgID)).
+		Updates(map[string]interface{}{"risk_level": to, "updated_by": userID})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *agentRepository) CreateStatusTransition(ctx context.Context, transition *domain.AgentStatusTransition) error {
+	if err := r.ensureAgent(ctx, transition.AgentID); err != nil {
+		return err
+	}
+	return conn(ctx, r.db).Create(transition).Error
+}
+
+func (r *agentRepository) ListStatusTransitions(ctx context.Context, agentID uuid.UUID) ([]domain.AgentStatusTransition, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var transitions []domain.AgentStatusTransition
+	if err := conn(ctx, r.db).
+		Where("agent_id = ?", agentID).
+		Scopes(agentInOrganization("agent_id", orgID)).
+		Order("created_at DESC").
+		Find(&transitions).Error; err != nil {
+		return nil, err
+	}
+	return transitions, nil
+}
+
+// ensureAgent fails with ErrCrossTenant unless agentID belongs to the caller's tenant.
+func (r *agentRepository) ensureAgent(ctx context.Context, agentID uuid.UUID) error {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+	var count int64
+	if err := conn(ctx, r.db).Model(&domain.Agent{}).
+		Where("id = ?", agentID).
+		Scopes(inOrganization("agents", orgID)).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrCrossTenant
+	}
+	return nil
 }
 
 func (r *agentRepository) GetResources(ctx context.Context, agentID uuid.UUID) ([]domain.Resource, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
 	var resources []domain.Resource
 	// Join AgentResourceAccess to find resources linked to this agent
-	err := r.db.WithContext(ctx).
-		Joins("JOIN agent_resource_accesses ON agent_resource_accesses.resource_id = resources.id").
-		Where("agent_resource_accesses.agent_id = ?", agentID).
+	err = conn(ctx, r.db).
+		Joins("JOIN agent_resource_access ON agent_resource_access.resource_id = resources.id AND agent_resource_access.deleted_at IS NULL").
+		Where("agent_resource_access.agent_id = ?", agentID).
+		Scopes(inOrganization("resources", orgID)).
 		Find(&resources).Error
 	if err != nil {
 		return nil, err
@@ -89,11 +389,16 @@ func (r *agentRepository) GetResources(ctx context.Context, agentID uuid.UUID) (
 }
 
 func (r *agentRepository) GetAssignedUsers(ctx context.Context, agentID uuid.UUID) ([]domain.User, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
 	var users []domain.User
 	// Join AgentAssignment to find users linked to this agent
-	err := r.db.WithContext(ctx).
+	err = conn(ctx, r.db).
 		Joins("JOIN agent_assignments ON agent_assignments.user_id = users.id").
 		Where("agent_assignments.agent_id = ?", agentID).
+		Scopes(inOrganization("users", orgID)).
 		Find(&users).Error
 	if err != nil {
 		return nil, err
@@ -102,11 +407,16 @@ func (r *agentRepository) GetAssignedUsers(ctx context.Context, agentID uuid.UUI
 }
 
 func (r *agentRepository) GetAssignedAgents(ctx context.Context, userID uuid.UUID) ([]domain.Agent, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
 	var agents []domain.Agent
 	// Join AgentAssignment to find agents linked to this user
-	err := r.db.WithContext(ctx).
+	err = conn(ctx, r.db).
 		Joins("JOIN agent_assignments ON agent_assignments.agent_id = agents.id").
 		Where("agent_assignments.user_id = ?", userID).
+		Scopes(inOrganization("agents", orgID)).
 		Find(&agents).Error
 	if err != nil {
 		return nil, err
@@ -114,17 +424,34 @@ func (r *agentRepository) GetAssignedAgents(ctx context.Context, userID uuid.UUI
 	return agents, nil
 }
 
+func (r *agentRepository) IsAssigned(ctx context.Context, agentID, userID uuid.UUID) (bool, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return false, err
+	}
+	var count int64
+	err = conn(ctx, r.db).
+		Model(&domain.AgentAssignment{}).
+		Where("agent_id = ? AND user_id = ?", agentID, userID).
+		Scopes(agentInOrganization("agent_id", orgID)).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetAssignedApplications lists the applications with access to the agent.
 func (r *agentRepository) GetAssignedApplications(ctx context.Context, agentID uuid.UUID) ([]domain.Application, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
 	var apps []domain.Application
-	// Join ApplicationAgentAccess (and then Application if needed, but ApplicationAgentAccess belongs to Application? No, ApplicationAgentAccess links Application and Agent)
-	// struct: ApplicationAgentAccess has ApplicationID and AgentID.
-	// We want to list Applications.
-	// JOIN application_agent_accesses ON application_agent_accesses.application_id = applications.id
-	// WHERE application_agent_accesses.agent_id = ?
-
-	err := r.db.WithContext(ctx).
-		Joins("JOIN application_agent_accesses ON application_agent_accesses.application_id = applications.id").
-		Where("application_agent_accesses.agent_id = ?", agentID).
+	err = conn(ctx, r.db).
+		Joins("JOIN application_agent_access ON application_agent_access.application_id = applications.id").
+		Where("application_agent_access.agent_id = ?", agentID).
+		Scopes(ownerInOrganization("applications.owner_id", orgID)).
 		Find(&apps).Error
 	if err != nil {
 		return nil, err
@@ -133,13 +460,33 @@ func (r *agentRepository) GetAssignedApplications(ctx context.Context, agentID u
 }
 
 func (r *agentRepository) GetCertifications(ctx context.Context, agentID uuid.UUID) ([]domain.Certification, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
 	var certifications []domain.Certification
-	err := r.db.WithContext(ctx).
+	err = conn(ctx, r.db).
 		Joins("JOIN agent_certifications ON agent_certifications.certification_id = certifications.id").
 		Where("agent_certifications.agent_id = ?", agentID).
+		Scopes(agentInOrganization("agent_certifications.agent_id", orgID)).
 		Find(&certifications).Error
 	if err != nil {
 		return nil, err
 	}
 	return certifications, nil
 }
+
+func (r *agentRepository) GetAgentCertifications(ctx context.Context, agentID uuid.UUID) ([]domain.AgentCertification, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var links []domain.AgentCertification
+	if err := conn(ctx, r.db).
+		Where("agent_id = ?", agentID).
+		Scopes(agentInOrganization("agent_id", orgID)).
+		Find(&links).Error; err != nil {
+		return nil, err
+	}
+	return links, nil
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"regexp"
 	"testing"
@@ -18,11 +19,12 @@ import (
 func TestAgentRepository_Create(t *testing.T) {
 	db, mock := setupMockDB(t)
 	repo := NewAgentRepository(db)
-	ctx := context.TODO()
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
 
 	agent := &domain.Agent{
 		ID:             uuid.New(),
-		OrganizationID: uuid.New(),
+		OrganizationID: orgID,
 		Name:           "Test Agent",
 		Status:         domain.AgentStatusActive,
 		CreatedAt:      time.Now(),
@@ -40,9 +42,9 @@ func TestAgentRepository_Create(t *testing.T) {
 			input: agent,
 			mock: func() {
 				mock.ExpectBegin()
-				// GORM inserted 12 args in the failed log.
+				// GORM inserts 13 args (risk_level included).
 				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "agents"`)).
-					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
 					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(agent.ID))
 				mock.ExpectCommit()
 			},
@@ -79,10 +81,10 @@ func TestAgentRepository_Create(t *testing.T) {
 func TestAgentRepository_GetByID(t *testing.T) {
 	db, mock := setupMockDB(t)
 	repo := NewAgentRepository(db)
-	ctx := context.TODO()
 
 	id := uuid.New()
 	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
 
 	tests := []struct {
 		name    string
@@ -99,8 +101,8 @@ func TestAgentRepository_GetByID(t *testing.T) {
 				rows := sqlmock.NewRows([]string{"id", "name", "organization_id", "status", "cost_amount", "cost_currency", "billing_cycle", "configuration"}).
 					AddRow(id, "Agent 007", orgID, "active", 0.0, "EUR", "monthly", []byte("{}"))
 
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "agents" WHERE id = $1`)).
-					WithArgs(id, 1).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "agents" WHERE id = $1 AND agents.organization_id = $2`)).
+					WithArgs(id, orgID, 1).
 					WillReturnRows(rows)
 
 				// 2. Preloads
@@ -121,8 +123,8 @@ func TestAgentRepository_GetByID(t *testing.T) {
 			name: "Not Found",
 			id:   id,
 			mock: func() {
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "agents" WHERE id = $1`)).
-					WithArgs(id, 1).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "agents" WHERE id = $1 AND agents.organization_id = $2`)).
+					WithArgs(id, orgID, 1).
 					WillReturnError(gorm.ErrRecordNotFound)
 			},
 			want:    nil,
@@ -155,9 +157,9 @@ func TestAgentRepository_GetByID(t *testing.T) {
 func TestAgentRepository_ListByOrg(t *testing.T) {
 	db, mock := setupMockDB(t)
 	repo := NewAgentRepository(db)
-	ctx := context.TODO()
 
 	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
 
 	tests := []struct {
 		name    string
@@ -197,3 +199,442 @@ func TestAgentRepository_ListByOrg(t *testing.T) {
 		})
 	}
 }
+
+func TestAgentRepository_IsAssigned(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewAgentRepository(db)
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+
+	agentID := uuid.New()
+	userID := uuid.New()
+
+	tests := []struct {
+		name  string
+		count int
+		want  bool
+	}{
+		{name: "Assigned", count: 1, want: true},
+		{name: "Not assigned", count: 0, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "agent_assignments" WHERE (agent_id = $1 AND user_id = $2) AND agent_id IN (SELECT id FROM agents WHERE organization_id = $3)`)).
+				WithArgs(agentID, userID, orgID).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.count))
+
+			got, err := repo.IsAssigned(ctx, agentID, userID)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAgentRepository_CreateNextVersion(t *testing.T) {
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	agentID := uuid.New()
+
+	t.Run("Allocates latest + 1 under a row lock", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewAgentRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "agents" WHERE id = $1 AND agents.organization_id = $2 AND "agents"."deleted_at" IS NULL LIMIT $3 FOR UPDATE`)).
+			WithArgs(agentID, orgID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(agentID))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(MAX(version_number), 0) FROM "agent_versions" WHERE agent_id = $1`)).
+			WithArgs(agentID).
+			WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(4))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "agent_versions"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()))
+		mock.ExpectCommit()
+
+		version := &domain.AgentVersion{AgentID: agentID, ConfigurationSnapshot: []byte(`{}`)}
+		assert.NoError(t, repo.CreateNextVersion(ctx, version))
+		assert.Equal(t, 5, version.VersionNumber)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("First version", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewAgentRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(agentID))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(MAX(version_number), 0)`)).
+			WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(0))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "agent_versions"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()))
+		mock.ExpectCommit()
+
+		version := &domain.AgentVersion{AgentID: agentID, ConfigurationSnapshot: []byte(`{}`)}
+		assert.NoError(t, repo.CreateNextVersion(ctx, version))
+		assert.Equal(t, 1, version.VersionNumber)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown agent rolls back", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewAgentRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE`)).
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectRollback()
+
+		err := repo.CreateNextVersion(ctx, &domain.AgentVersion{AgentID: agentID})
+		assert.ErrorIs(t, err, ErrCrossTenant)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Insert failure rolls back", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewAgentRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(agentID))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(MAX(version_number), 0)`)).
+			WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "agent_versions"`)).
+			WillReturnError(errors.New("duplicate key value violates unique constraint"))
+		mock.ExpectRollback()
+
+		err := repo.CreateNextVersion(ctx, &domain.AgentVersion{AgentID: agentID, ConfigurationSnapshot: []byte(`{}`)})
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAgentRepository_GetLatestVersion(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewAgentRepository(db)
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	agentID := uuid.New()
+
+	t.Run("Found", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "agent_versions" WHERE agent_id = $1 AND agent_id IN (SELECT id FROM agents WHERE organization_id = $2) ORDER BY version_number DESC`)).
+			WithArgs(agentID, orgID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "agent_id", "version_number"}).AddRow(uuid.New(), agentID, 3))
+
+		got, err := repo.GetLatestVersion(ctx, agentID)
+		assert.NoError(t, err)
+		if assert.NotNil(t, got) {
+			assert.Equal(t, 3, got.VersionNumber)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("None", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "agent_versions"`)).
+			WillReturnError(gorm.ErrRecordNotFound)
+
+		got, err := repo.GetLatestVersion(ctx, agentID)
+		assert.NoError(t, err)
+		assert.Nil(t, got)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAgentRepository_ListVersions(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewAgentRepository(db)
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	agentID := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "agent_versions" WHERE agent_id = $1 AND agent_id IN (SELECT id FROM agents WHERE organization_id = $2)`)).
+		WithArgs(agentID, orgID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "agent_versions" WHERE agent_id = $1 AND agent_id IN (SELECT id FROM agents WHERE organization_id = $2) ORDER BY version_number DESC LIMIT $3 OFFSET $4`)).
+		WithArgs(agentID, orgID, 2, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "version_number"}).
+			AddRow(uuid.New(), 5).
+			AddRow(uuid.New(), 4))
+
+	versions, total, err := repo.ListVersions(ctx, agentID, 2, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), total)
+	assert.Len(t, versions, 2)
+	assert.Equal(t, 5, versions[0].VersionNumber)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAgentRepository_GetVersion(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewAgentRepository(db)
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	agentID := uuid.New()
+
+	t.Run("Found", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "agent_versions" WHERE (agent_id = $1 AND version_number = $2) AND agent_id IN (SELECT id FROM agents WHERE organization_id = $3)`)).
+			WithArgs(agentID, 2, orgID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "agent_id", "version_number"}).AddRow(uuid.New(), agentID, 2))
+
+		got, err := repo.GetVersion(ctx, agentID, 2)
+		assert.NoError(t, err)
+		if assert.NotNil(t, got) {
+			assert.Equal(t, 2, got.VersionNumber)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "agent_versions"`)).
+			WillReturnError(gorm.ErrRecordNotFound)
+
+		got, err := repo.GetVersion(ctx, agentID, 9)
+		assert.NoError(t, err)
+		assert.Nil(t, got)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAgentRepository_CreateChangeRequest(t *testing.T) {
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	agentID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewAgentRepository(db)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "agents" WHERE id = $1 AND agents.organization_id = $2`)).
+			WithArgs(agentID, orgID).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "agent_change_requests"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "created_at"}).AddRow(uuid.New(), "pending", time.Now()))
+		mock.ExpectCommit()
+
+		err := repo.CreateChangeRequest(ctx, &domain.AgentChangeRequest{AgentID: agentID, ProposedConfiguration: []byte(`{}`), Reason: "r"})
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Agent Of Another Tenant", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewAgentRepository(db)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "agents"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+		err := repo.CreateChangeRequest(ctx, &domain.AgentChangeRequest{AgentID: agentID})
+		assert.ErrorIs(t, err, ErrCrossTenant)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAgentRepository_ListChangeRequests(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewAgentRepository(db)
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	agentID := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "agent_change_requests" WHERE agent_id = $1 AND status = $2 AND agent_id IN (SELECT id FROM agents WHERE organization_id = $3) ORDER BY created_at DESC`)).
+		WithArgs(agentID, domain.ChangeRequestStatusPending, orgID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "agent_id", "status"}).AddRow(uuid.New(), agentID, "pending"))
+
+	reqs, err := repo.ListChangeRequests(ctx, agentID, domain.ChangeRequestStatusPending)
+	assert.NoError(t, err)
+	assert.Len(t, reqs, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAgentRepository_ResolveChangeRequest(t *testing.T) {
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	reviewerID := uuid.New()
+	now := time.Now()
+	req := &domain.AgentChangeRequest{
+		ID:         uuid.New(),
+		Status:     domain.ChangeRequestStatusRejected,
+		ReviewedBy: &reviewerID,
+		ReviewedAt: &now,
+	}
+
+	t.Run("Pending", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewAgentRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "agent_change_requests" SET`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		resolved, err := repo.ResolveChangeRequest(ctx, req)
+		assert.NoError(t, err)
+		assert.True(t, resolved)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Already Resolved", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewAgentRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "agent_change_requests" SET`)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		resolved, err := repo.ResolveChangeRequest(ctx, req)
+		assert.NoError(t, err)
+		assert.False(t, resolved)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAgentRepository_UpdateStatus(t *testing.T) {
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	agentID := uuid.New()
+	userID := uuid.New()
+
+	t.Run("Current Status Matches", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewAgentRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "agents" SET "status"=$1,"updated_by"=$2,"updated_at"=$3 WHERE (id = $4 AND status = $5) AND agents.organization_id = $6`)).
+			WithArgs(domain.AgentStatusMaintenance, userID, sqlmock.AnyArg(), agentID, domain.AgentStatusActive, orgID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		ok, err := repo.UpdateStatus(ctx, agentID, domain.AgentStatusActive, domain.AgentStatusMaintenance, userID)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Status Changed Concurrently", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewAgentRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "agents" SET`)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		ok, err := repo.UpdateStatus(ctx, agentID, domain.AgentStatusActive, domain.AgentStatusMaintenance, userID)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAgentRepository_Update(t *testing.T) {
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	userID := uuid.New()
+	agent := &domain.Agent{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		Name:           "Agent",
+		Status:         domain.AgentStatusActive,
+		RiskLevel:      domain.AgentRiskLevelMinimal,
+		Configuration:  json.RawMessage(`{"model":"gpt-4"}`),
+		UpdatedBy:      &userID,
+		UpdatedAt:      time.Now(),
+	}
+
+	t.Run("Writes Content Only", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewAgentRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "agents" SET "configuration"=$1,"name"=$2,"updated_at"=$3,"updated_by"=$4 WHERE (id = $5 AND status = $6 AND risk_level = $7) AND agents.organization_id = $8`)).
+			WithArgs(sqlmock.AnyArg(), "Agent", sqlmock.AnyArg(), &userID, agent.ID, domain.AgentStatusActive, domain.AgentRiskLevelMinimal, orgID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		updated, err := repo.Update(ctx, agent)
+		assert.NoError(t, err)
+		assert.True(t, updated)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Transitioned Since Read", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewAgentRepository(db)
+
+		// A concurrent transition moved the status away from the one read.
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "agents" SET`)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		updated, err := repo.Update(ctx, agent)
+		assert.NoError(t, err)
+		assert.False(t, updated)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAgentRepository_UpdateRiskLevel(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewAgentRepository(db)
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	agentID := uuid.New()
+	userID := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "agents" SET "risk_level"=$1,"updated_by"=$2,"updated_at"=$3 WHERE (id = $4 AND risk_level = $5) AND agents.organization_id = $6`)).
+		WithArgs(domain.AgentRiskLevelHigh, userID, sqlmock.AnyArg(), agentID, domain.AgentRiskLevelMinimal, orgID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	ok, err := repo.UpdateRiskLevel(ctx, agentID, domain.AgentRiskLevelMinimal, domain.AgentRiskLevelHigh, userID)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAgentRepository_ListStatusTransitions(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewAgentRepository(db)
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	agentID := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "agent_status_transitions" WHERE agent_id = $1 AND agent_id IN (SELECT id FROM agents WHERE organization_id = $2) ORDER BY created_at DESC`)).
+		WithArgs(agentID, orgID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "agent_id", "from_status", "to_status"}).
+			AddRow(uuid.New(), agentID, "maintenance", "active").
+			AddRow(uuid.New(), agentID, "active", "maintenance"))
+
+	transitions, err := repo.ListStatusTransitions(ctx, agentID)
+	assert.NoError(t, err)
+	if assert.Len(t, transitions, 2) {
+		assert.Equal(t, domain.AgentStatusActive, transitions[0].ToStatus)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAgentRepository_GetAgentCertifications(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewAgentRepository(db)
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	agentID := uuid.New()
+	expires := time.Now().Add(24 * time.Hour)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "agent_certifications" WHERE agent_id = $1 AND agent_id IN (SELECT id FROM agents WHERE organization_id = $2)`)).
+		WithArgs(agentID, orgID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "agent_id", "certification_id", "expires_at"}).
+			AddRow(uuid.New(), agentID, uuid.New(), expires))
+
+	links, err := repo.GetAgentCertifications(ctx, agentID)
+	assert.NoError(t, err)
+	if assert.Len(t, links, 1) {
+		assert.NotNil(t, links[0].ExpiresAt)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
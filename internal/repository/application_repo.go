@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"agentXmap/internal/domain"
 
@@ -9,13 +11,6 @@ import (
 	"gorm.io/gorm"
 )
 
-// Add Interface to domain/interfaces.go first?
-// No, I should assume I need to implement what makes sense or update domain/interfaces.go in parallel.
-// But as per plan I should stick to adding implementation unless I forgot the interface definition.
-// Checking previous steps, I defined `UserRepository`, `AgentRepository`, `LLMRepository`, `AuditRepository`.
-// I missed `ApplicationRepository` and `ResourceRepository` in the initial `interfaces.go`.
-// I will create the implementations and then update the interfaces.go file.
-
 type applicationRepository struct {
 	db *gorm.DB
 }
@@ -24,29 +19,87 @@ func NewApplicationRepository(db *gorm.DB) *applicationRepository {
 	return &applicationRepository{db: db}
 }
 
+// Create inserts app once its owner is confirmed to belong to the caller's tenant.
 func (r *applicationRepository) Create(ctx context.Context, app *domain.Application) error {
-	return r.db.WithContext(ctx).Create(app).Error
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+	if err := r.ensureExists(conn(ctx, r.db).Model(&domain.User{}).Where("id = ?", app.OwnerID).Scopes(inOrganization("users", orgID))); err != nil {
+		return err
+	}
+	return conn(ctx, r.db).Create(app).Error
 }
 
 func (r *applicationRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Application, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
 	var app domain.Application
-	if err := r.db.WithContext(ctx).Preload("Keys").Preload("AgentAccess").First(&app, "id = ?", id).Error; err != nil {
+	if err := conn(ctx, r.db).Preload("Keys").Preload("AgentAccess").
+		Where("id = ?", id).Scopes(ownerInOrganization("owner_id", orgID)).First(&app).Error; err != nil {
 		return nil, err
 	}
 	return &app, nil
 }
 
+// CreateKey inserts key once its application is confirmed to belong to the caller's tenant.
 func (r *applicationRepository) CreateKey(ctx context.Context, key *domain.ApplicationKey) error {
-	return r.db.WithContext(ctx).Create(key).Error
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+	if err := r.ensureExists(conn(ctx, r.db).Model(&domain.Application{}).Where("id = ?", key.ApplicationID).Scopes(ownerInOrganization("owner_id", orgID))); err != nil {
+		return err
+	}
+	return conn(ctx, r.db).Create(key).Error
+}
+
+// ensureExists fails with ErrCrossTenant when query matches no row.
+func (r *applicationRepository) ensureExists(query *gorm.DB) error {
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrCrossTenant
+	}
+	return nil
 }
 
+// GetKeyByLookupID is deliberately not tenant-scoped: it runs before the
+// caller is known and is what establishes the Application's tenant.
+func (r *applicationRepository) GetKeyByLookupID(ctx context.Context, lookupID string) (*domain.ApplicationKey, error) {
+	var key domain.ApplicationKey
+	if err := conn(ctx, r.db).
+		Preload("Application.Owner").
+		First(&key, "lookup_id = ?", lookupID).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// UpdateKeyLastUsed only touches a key that GetKeyByLookupID just authenticated.
+func (r *applicationRepository) UpdateKeyLastUsed(ctx context.Context, keyID uuid.UUID, usedAt time.Time) error {
+	return conn(ctx, r.db).
+		Model(&domain.ApplicationKey{}).
+		Where("id = ?", keyID).
+		UpdateColumn("last_used_at", usedAt).Error
+}
+
+// GetAssignedAgents returns the agents the application may invoke. Agents
+// linked with can_invoke off are left out.
 func (r *applicationRepository) GetAssignedAgents(ctx context.Context, appID uuid.UUID) ([]domain.Agent, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
 	var agents []domain.Agent
-	// Join ApplicationAgentAccess to find agents linked to this application
-	// Remember ApplicationAgentAccess has ApplicationID and AgentID
-	err := r.db.WithContext(ctx).
-		Joins("JOIN application_agent_accesses ON application_agent_accesses.agent_id = agents.id").
-		Where("application_agent_accesses.application_id = ?", appID).
+	err = conn(ctx, r.db).
+		Joins("JOIN application_agent_access ON application_agent_access.agent_id = agents.id").
+		Where("application_agent_access.application_id = ? AND application_agent_access.can_invoke", appID).
+		Scopes(inOrganization("agents", orgID)).
 		Find(&agents).Error
 	if err != nil {
 		return nil, err
@@ -54,11 +107,35 @@ func (r *applicationRepository) GetAssignedAgents(ctx context.Context, appID uui
 	return agents, nil
 }
 
+// GetAgentAccess returns nil when the application has no access to the agent.
+func (r *applicationRepository) GetAgentAccess(ctx context.Context, appID, agentID uuid.UUID) (*domain.ApplicationAgentAccess, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var access domain.ApplicationAgentAccess
+	if err := conn(ctx, r.db).
+		Where("application_id = ? AND agent_id = ?", appID, agentID).
+		Scopes(agentInOrganization("agent_id", orgID)).
+		First(&access).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &access, nil
+}
+
 func (r *applicationRepository) GetCertifications(ctx context.Context, appID uuid.UUID) ([]domain.Certification, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
 	var certifications []domain.Certification
-	err := r.db.WithContext(ctx).
+	err = conn(ctx, r.db).
 		Joins("JOIN application_certifications ON application_certifications.certification_id = certifications.id").
 		Where("application_certifications.application_id = ?", appID).
+		Scopes(applicationInOrganization("application_certifications.application_id", orgID)).
 		Find(&certifications).Error
 	if err != nil {
 		return nil, err
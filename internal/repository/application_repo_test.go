@@ -17,7 +17,8 @@ import (
 func TestApplicationRepository_GetByID(t *testing.T) {
 	db, mock := setupMockDB(t)
 	repo := NewApplicationRepository(db)
-	ctx := context.TODO()
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
 
 	id := uuid.New()
 
@@ -32,22 +33,14 @@ func TestApplicationRepository_GetByID(t *testing.T) {
 			id:   id,
 			mock: func() {
 				// 1. Get App
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "applications" WHERE id = $1`)).
-					WithArgs(id, 1).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "applications" WHERE id = $1 AND owner_id IN (SELECT id FROM users WHERE organization_id = $2)`)).
+					WithArgs(id, orgID, 1).
 					WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(id, "App 1"))
 
 				// 2. Preloads
 
-				// AgentAccess - GORM pluralizes default table names.
-				// The struct field is AgentAccess []ApplicationAgentAccess.
-				// The struct ApplicationAgentAccess has TableName()?
-				// If not, GORM might use "application_agent_accesses".
-				// The error showed actual sql: "application_agent_accesses".
-				// My code expected "application_agent_access".
-				// Updating test to expect "application_agent_accesses" OR just "application_agent_access".
-				// I will use regex partial match to be safe or match actual.
-				// Based on error: actual "application_agent_accesses".
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "application_agent_accesses" WHERE "application_agent_accesses"."application_id" = $1`)).
+				// AgentAccess
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "application_agent_access" WHERE "application_agent_access"."application_id" = $1`)).
 					WithArgs(id).
 					WillReturnRows(sqlmock.NewRows(nil))
 
@@ -77,10 +70,12 @@ func TestApplicationRepository_GetByID(t *testing.T) {
 func TestApplicationRepository_Create(t *testing.T) {
 	db, mock := setupMockDB(t)
 	repo := NewApplicationRepository(db)
-	ctx := context.TODO()
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
 
 	app := &domain.Application{
 		ID:        uuid.New(),
+		OwnerID:   uuid.New(),
 		Name:      "Test App",
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -96,6 +91,7 @@ func TestApplicationRepository_Create(t *testing.T) {
 			name:  "Success",
 			input: app,
 			mock: func() {
+				expectOwnerInTenant(mock, app.OwnerID, orgID, 1)
 				mock.ExpectBegin()
 				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "applications"`)).
 					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
@@ -108,6 +104,7 @@ func TestApplicationRepository_Create(t *testing.T) {
 			name:  "Error",
 			input: app,
 			mock: func() {
+				expectOwnerInTenant(mock, app.OwnerID, orgID, 1)
 				mock.ExpectBegin()
 				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "applications"`)).
 					WillReturnError(errors.New("db error"))
@@ -130,3 +127,102 @@ func TestApplicationRepository_Create(t *testing.T) {
 		})
 	}
 }
+
+func TestApplicationRepository_GetKeyByLookupID(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewApplicationRepository(db)
+	ctx := context.TODO()
+
+	keyID := uuid.New()
+	appID := uuid.New()
+	ownerID := uuid.New()
+	lookupID := "0123456789abcdef"
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "application_keys" WHERE lookup_id = $1`)).
+		WithArgs(lookupID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "application_id", "lookup_id"}).AddRow(keyID, appID, lookupID))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "applications" WHERE "applications"."id" = $1`)).
+		WithArgs(appID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id", "is_active"}).AddRow(appID, ownerID, true))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE "users"."id" = $1`)).
+		WithArgs(ownerID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(ownerID))
+
+	key, err := repo.GetKeyByLookupID(ctx, lookupID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, key) {
+		assert.Equal(t, keyID, key.ID)
+		assert.True(t, key.Application.IsActive)
+		assert.Equal(t, ownerID, key.Application.Owner.ID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApplicationRepository_UpdateKeyLastUsed(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewApplicationRepository(db)
+	ctx := context.TODO()
+
+	keyID := uuid.New()
+	usedAt := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "application_keys" SET "last_used_at"=$1 WHERE id = $2`)).
+		WithArgs(usedAt, keyID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.UpdateKeyLastUsed(ctx, keyID, usedAt))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApplicationRepository_GetAgentAccess(t *testing.T) {
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	appID, agentID := uuid.New(), uuid.New()
+	query := regexp.QuoteMeta(`SELECT * FROM "application_agent_access" WHERE (application_id = $1 AND agent_id = $2) AND agent_id IN (SELECT id FROM agents WHERE organization_id = $3)`)
+
+	t.Run("Found", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewApplicationRepository(db)
+
+		mock.ExpectQuery(query).
+			WithArgs(appID, agentID, orgID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "application_id", "agent_id", "can_invoke"}).AddRow(uuid.New(), appID, agentID, true))
+
+		access, err := repo.GetAgentAccess(ctx, appID, agentID)
+		assert.NoError(t, err)
+		if assert.NotNil(t, access) {
+			assert.True(t, access.CanInvoke)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not Assigned", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewApplicationRepository(db)
+
+		mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		access, err := repo.GetAgentAccess(ctx, appID, agentID)
+		assert.NoError(t, err)
+		assert.Nil(t, access)
+	})
+}
+
+func TestApplicationRepository_GetAssignedAgents(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewApplicationRepository(db)
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	appID := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`JOIN application_agent_access ON application_agent_access.agent_id = agents.id WHERE (application_agent_access.application_id = $1 AND application_agent_access.can_invoke) AND agents.organization_id = $2 AND "agents"."deleted_at" IS NULL`)).
+		WithArgs(appID, orgID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(uuid.New(), "Invocable"))
+
+	agents, err := repo.GetAssignedAgents(ctx, appID)
+	assert.NoError(t, err)
+	assert.Len(t, agents, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
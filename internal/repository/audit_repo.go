@@ -2,9 +2,11 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"agentXmap/internal/domain"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -12,14 +14,100 @@ type auditRepository struct {
 	db *gorm.DB
 }
 
-func NewAuditRepository(db *gorm.DB) domain.AuditRepository {
+func NewAuditRepository(db *gorm.DB) *auditRepository {
 	return &auditRepository{db: db}
 }
 
+// CreateLog serializes writers per organization with a transaction-scoped
+// advisory lock, so each entry extends the current head of the chain.
 func (r *auditRepository) CreateLog(ctx context.Context, log *domain.SystemAuditLog) error {
-	return r.db.WithContext(ctx).Create(log).Error
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtextextended(?, 0))", "audit:"+log.OrganizationID.String()).Error; err != nil {
+			return err
+		}
+		var head domain.SystemAuditLog
+		if err := tx.Select("seq", "hash").
+			Where("organization_id = ?", log.OrganizationID).
+			Order("seq DESC").Limit(1).
+			Find(&head).Error; err != nil {
+			return err
+		}
+
+		if log.ID == uuid.Nil {
+			log.ID = uuid.New()
+		}
+		if log.OccurredAt.IsZero() {
+			log.OccurredAt = time.Now()
+		}
+		// Stored as a TIMESTAMP: keep what survives the round trip.
+		log.OccurredAt = log.OccurredAt.UTC().Truncate(time.Microsecond)
+		log.Seq = head.Seq + 1
+		log.PrevHash = head.Hash
+		hash, err := log.ChainHash()
+		if err != nil {
+			return err
+		}
+		log.Hash = hash
+		return tx.Create(log).Error
+	})
+}
+
+func (r *auditRepository) ListLogs(ctx context.Context, filter domain.AuditLogFilter, beforeSeq int64, limit int) ([]domain.SystemAuditLog, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := conn(ctx, r.db).Where("system_audit_logs.organization_id = ?", orgID)
+	if beforeSeq > 0 {
+		query = query.Where("system_audit_logs.seq < ?", beforeSeq)
+	}
+	if filter.ActorUserID != nil {
+		query = query.Where("system_audit_logs.actor_user_id = ?", *filter.ActorUserID)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("system_audit_logs.entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != nil {
+		query = query.Where("system_audit_logs.entity_id = ?", *filter.EntityID)
+	}
+	if filter.Action != "" {
+		query = query.Where("system_audit_logs.action = ?", filter.Action)
+	}
+	if filter.From != nil {
+		query = query.Where("system_audit_logs.occurred_at >= ?", filter.From.UTC())
+	}
+	if filter.To != nil {
+		query = query.Where("system_audit_logs.occurred_at < ?", filter.To.UTC())
+	}
+
+	var logs []domain.SystemAuditLog
+	if err := query.Order("system_audit_logs.seq DESC").Limit(limit).Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return logs, nil
 }
 
 func (r *auditRepository) CreateExecution(ctx context.Context, exec *domain.AgentExecution) error {
-	return r.db.WithContext(ctx).Create(exec).Error
+	return conn(ctx, r.db).Create(exec).Error
+}
+
+func (r *auditRepository) ListChainOrganizations(ctx context.Context) ([]uuid.UUID, error) {
+	var orgIDs []uuid.UUID
+	if err := conn(ctx, r.db).Model(&domain.SystemAuditLog{}).
+		Distinct("organization_id").Order("organization_id").
+		Pluck("organization_id", &orgIDs).Error; err != nil {
+		return nil, err
+	}
+	return orgIDs, nil
+}
+
+func (r *auditRepository) ListChain(ctx context.Context, orgID uuid.UUID, afterSeq int64, limit int) ([]domain.SystemAuditLog, error) {
+	var logs []domain.SystemAuditLog
+	if err := conn(ctx, r.db).
+		Where("organization_id = ? AND seq > ?", orgID, afterSeq).
+		Order("seq").Limit(limit).
+		Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return logs, nil
 }
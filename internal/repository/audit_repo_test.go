@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -31,8 +32,8 @@ func TestAuditRepository_CreateLog(t *testing.T) {
 	userID := uuid.New()
 	entityID := uuid.New()
 
-	t.Run("Success", func(t *testing.T) {
-		log := &domain.SystemAuditLog{
+	newLog := func() *domain.SystemAuditLog {
+		return &domain.SystemAuditLog{
 			OrganizationID: orgID,
 			ActorUserID:    &userID,
 			EntityType:     "agent",
@@ -41,19 +42,70 @@ func TestAuditRepository_CreateLog(t *testing.T) {
 			Changes:        json.RawMessage(`{}`),
 			IPAddress:      "127.0.0.1",
 		}
+	}
+	lock := regexp.QuoteMeta(`SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`)
+	head := regexp.QuoteMeta(`SELECT "seq","hash" FROM "system_audit_logs" WHERE organization_id = $1 ORDER BY seq DESC LIMIT $2`)
+
+	t.Run("Extends The Chain", func(t *testing.T) {
+		log := newLog()
+		prev := strings.Repeat("a", 64)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(lock).WithArgs("audit:" + orgID.String()).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(head).WithArgs(orgID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"seq", "hash"}).AddRow(41, prev))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "system_audit_logs"`)).
+			WithArgs(orgID, 42, userID, "agent", entityID, "create", log.Changes, "127.0.0.1", prev, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "occurred_at"}))
+		mock.ExpectCommit()
+
+		err := repo.CreateLog(ctx, log)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+
+		assert.NotEqual(t, uuid.Nil, log.ID)
+		assert.Equal(t, int64(42), log.Seq)
+		assert.Equal(t, prev, log.PrevHash)
+		hash, err := log.ChainHash()
+		assert.NoError(t, err)
+		assert.Equal(t, hash, log.Hash)
+	})
+
+	t.Run("Starts A Chain", func(t *testing.T) {
+		log := newLog()
 
 		mock.ExpectBegin()
+		mock.ExpectExec(lock).WithArgs("audit:" + orgID.String()).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(head).WithArgs(orgID, 1).WillReturnRows(sqlmock.NewRows([]string{"seq", "hash"}))
 		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "system_audit_logs"`)).
-			WithArgs(orgID, userID, "agent", entityID, "create", log.Changes, "127.0.0.1").
-			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()))
+			WithArgs(orgID, 1, userID, "agent", entityID, "create", log.Changes, "127.0.0.1", "", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "occurred_at"}))
 		mock.ExpectCommit()
 
 		err := repo.CreateLog(ctx, log)
 		assert.NoError(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, int64(1), log.Seq)
+		assert.Empty(t, log.PrevHash)
 	})
 }
 
+func TestAuditRepository_ListChain(t *testing.T) {
+	gormDB, mock := setupMockDB(t)
+	repo := NewAuditRepository(gormDB)
+	orgID := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "system_audit_logs" WHERE organization_id = $1 AND seq > $2 ORDER BY seq LIMIT $3`)).
+		WithArgs(orgID, 10, 50).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "seq"}).AddRow(uuid.New(), orgID, 11))
+
+	logs, err := repo.ListChain(context.Background(), orgID, 10, 50)
+	assert.NoError(t, err)
+	assert.Len(t, logs, 1)
+	assert.Equal(t, int64(11), logs[0].Seq)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestAuditRepository_CreateExecution(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -98,3 +150,30 @@ func TestAuditRepository_CreateExecution(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestAuditRepository_ListLogs(t *testing.T) {
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.Background(), orgID)
+
+	t.Run("Filters Newest First", func(t *testing.T) {
+		gormDB, mock := setupMockDB(t)
+		repo := NewAuditRepository(gormDB)
+		entityID := uuid.New()
+		from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "system_audit_logs" WHERE system_audit_logs.organization_id = $1 AND system_audit_logs.seq < $2 AND system_audit_logs.entity_id = $3 AND system_audit_logs.action = $4 AND system_audit_logs.occurred_at >= $5 ORDER BY system_audit_logs.seq DESC LIMIT $6`)).
+			WithArgs(orgID, 30, entityID, "update", from, 20).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "seq"}).AddRow(uuid.New(), orgID, 29))
+
+		logs, err := repo.ListLogs(ctx, domain.AuditLogFilter{EntityID: &entityID, Action: domain.AuditActionUpdate, From: &from}, 30, 20)
+		assert.NoError(t, err)
+		assert.Len(t, logs, 1)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("No Tenant", func(t *testing.T) {
+		gormDB, _ := setupMockDB(t)
+		_, err := NewAuditRepository(gormDB).ListLogs(context.Background(), domain.AuditLogFilter{}, 0, 20)
+		assert.ErrorIs(t, err, ErrNoTenant)
+	})
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"agentXmap/internal/domain"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type credentialLeaseRepository struct {
+	db *gorm.DB
+}
+
+func NewCredentialLeaseRepository(db *gorm.DB) *credentialLeaseRepository {
+	return &credentialLeaseRepository{db: db}
+}
+
+func (r *credentialLeaseRepository) Create(ctx context.Context, lease *domain.CredentialLease) error {
+	if err := claimTenant(ctx, &lease.OrganizationID); err != nil {
+		return err
+	}
+	return conn(ctx, r.db).Create(lease).Error
+}
+
+func (r *credentialLeaseRepository) ListExpired(ctx context.Context, now time.Time, limit int) ([]domain.CredentialLease, error) {
+	var leases []domain.CredentialLease
+	if err := conn(ctx, r.db).
+		Where("expired_at IS NULL AND expires_at <= ?", now).
+		Order("expires_at").Limit(limit).
+		Find(&leases).Error; err != nil {
+		return nil, err
+	}
+	return leases, nil
+}
+
+func (r *credentialLeaseRepository) MarkExpired(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	result := conn(ctx, r.db).Model(&domain.CredentialLease{}).
+		Where("id = ? AND expired_at IS NULL", id).
+		UpdateColumn("expired_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
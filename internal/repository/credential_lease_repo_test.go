@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"agentXmap/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCredentialLeaseRepository_Create(t *testing.T) {
+	orgID := uuid.New()
+	now := time.Now()
+	lease := &domain.CredentialLease{
+		ResourceID:  uuid.New(),
+		AgentID:     uuid.New(),
+		AccessLevel: domain.AccessLevelReadOnly,
+		IssuedAt:    now,
+		ExpiresAt:   now.Add(time.Minute),
+	}
+
+	t.Run("Stamps Tenant", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewCredentialLeaseRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "credential_leases"`)).
+			WithArgs(orgID, lease.ResourceID, lease.AgentID, nil, domain.AccessLevelReadOnly, "", now, lease.ExpiresAt, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()))
+		mock.ExpectCommit()
+
+		assert.NoError(t, repo.Create(domain.WithOrganization(context.TODO(), orgID), lease))
+		assert.Equal(t, orgID, lease.OrganizationID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("No Tenant", func(t *testing.T) {
+		db, _ := setupMockDB(t)
+		repo := NewCredentialLeaseRepository(db)
+
+		assert.ErrorIs(t, repo.Create(context.TODO(), &domain.CredentialLease{}), ErrNoTenant)
+	})
+}
+
+func TestCredentialLeaseRepository_ListExpired(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewCredentialLeaseRepository(db)
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "credential_leases" WHERE expired_at IS NULL AND expires_at <= $1 ORDER BY expires_at LIMIT $2`)).
+		WithArgs(now, 50).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id"}).AddRow(uuid.New(), uuid.New()))
+
+	leases, err := repo.ListExpired(context.TODO(), now, 50)
+	assert.NoError(t, err)
+	assert.Len(t, leases, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCredentialLeaseRepository_MarkExpired(t *testing.T) {
+	id := uuid.New()
+	now := time.Now()
+
+	for name, rows := range map[string]int64{"Recorded": 1, "Already Recorded": 0} {
+		t.Run(name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			repo := NewCredentialLeaseRepository(db)
+
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "credential_leases" SET "expired_at"=$1 WHERE id = $2 AND expired_at IS NULL`)).
+				WithArgs(now, id).
+				WillReturnResult(sqlmock.NewResult(0, rows))
+			mock.ExpectCommit()
+
+			ok, err := repo.MarkExpired(context.TODO(), id, now)
+			assert.NoError(t, err)
+			assert.Equal(t, rows == 1, ok)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -2,10 +2,14 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"agentXmap/internal/domain"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type invitationRepository struct {
@@ -18,12 +22,20 @@ func NewInvitationRepository(db *gorm.DB) domain.InvitationRepository {
 }
 
 func (r *invitationRepository) Create(ctx context.Context, invitation *domain.Invitation) error {
-	return r.db.WithContext(ctx).Create(invitation).Error
+	return conn(ctx, r.db).Create(invitation).Error
+}
+
+func (r *invitationRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Invitation, error) {
+	var invitation domain.Invitation
+	if err := conn(ctx, r.db).First(&invitation, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &invitation, nil
 }
 
 func (r *invitationRepository) GetByToken(ctx context.Context, token string) (*domain.Invitation, error) {
 	var invitation domain.Invitation
-	if err := r.db.WithContext(ctx).
+	if err := conn(ctx, r.db).
 		Preload("Organization").
 		Preload("Invitor").
 		First(&invitation, "token = ?", token).Error; err != nil {
@@ -32,6 +44,39 @@ func (r *invitationRepository) GetByToken(ctx context.Context, token string) (*d
 	return &invitation, nil
 }
 
+func (r *invitationRepository) GetPendingByEmail(ctx context.Context, orgID uuid.UUID, email string, now time.Time) (*domain.Invitation, error) {
+	var invitation domain.Invitation
+	if err := conn(ctx, r.db).
+		Where("organization_id = ? AND email = ? AND status = ? AND expires_at > ?", orgID, email, domain.InvitationStatusPending, now).
+		First(&invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *invitationRepository) ListByOrganization(ctx context.Context, orgID uuid.UUID, status domain.InvitationStatus) ([]domain.Invitation, error) {
+	query := conn(ctx, r.db).Where("organization_id = ?", orgID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var invitations []domain.Invitation
+	if err := query.Order("created_at DESC").Find(&invitations).Error; err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
 func (r *invitationRepository) Update(ctx context.Context, invitation *domain.Invitation) error {
-	return r.db.WithContext(ctx).Save(invitation).Error
+	return conn(ctx, r.db).Save(invitation).Error
+}
+
+func (r *invitationRepository) ExpireOverdue(ctx context.Context, now time.Time) ([]domain.Invitation, error) {
+	var expired []domain.Invitation
+	err := conn(ctx, r.db).Model(&expired).Clauses(clause.Returning{}).
+		Where("status = ? AND expires_at <= ?", domain.InvitationStatusPending, now).
+		UpdateColumn("status", domain.InvitationStatusExpired).Error
+	return expired, err
 }
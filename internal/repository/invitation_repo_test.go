@@ -143,3 +143,86 @@ func TestInvitationRepository_Update(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestInvitationRepository_GetPendingByEmail(t *testing.T) {
+	orgID := uuid.New()
+	now := time.Now()
+	query := regexp.QuoteMeta(`SELECT * FROM "invitations" WHERE organization_id = $1 AND email = $2 AND status = $3 AND expires_at > $4 ORDER BY "invitations"."id" LIMIT $5`)
+
+	t.Run("Pending", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewInvitationRepository(db)
+		id := uuid.New()
+
+		mock.ExpectQuery(query).
+			WithArgs(orgID, "john@acme.com", domain.InvitationStatusPending, now, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(id, "john@acme.com"))
+
+		invitation, err := repo.GetPendingByEmail(context.TODO(), orgID, "john@acme.com", now)
+		assert.NoError(t, err)
+		assert.Equal(t, id, invitation.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("None", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewInvitationRepository(db)
+
+		mock.ExpectQuery(query).WillReturnError(gorm.ErrRecordNotFound)
+
+		invitation, err := repo.GetPendingByEmail(context.TODO(), orgID, "john@acme.com", now)
+		assert.NoError(t, err)
+		assert.Nil(t, invitation)
+	})
+}
+
+func TestInvitationRepository_ListByOrganization(t *testing.T) {
+	orgID := uuid.New()
+
+	t.Run("All", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewInvitationRepository(db)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "invitations" WHERE organization_id = $1 ORDER BY created_at DESC`)).
+			WithArgs(orgID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()).AddRow(uuid.New()))
+
+		invitations, err := repo.ListByOrganization(context.TODO(), orgID, "")
+		assert.NoError(t, err)
+		assert.Len(t, invitations, 2)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("By Status", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewInvitationRepository(db)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "invitations" WHERE organization_id = $1 AND status = $2 ORDER BY created_at DESC`)).
+			WithArgs(orgID, domain.InvitationStatusPending).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()))
+
+		invitations, err := repo.ListByOrganization(context.TODO(), orgID, domain.InvitationStatusPending)
+		assert.NoError(t, err)
+		assert.Len(t, invitations, 1)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestInvitationRepository_ExpireOverdue(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewInvitationRepository(db)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "invitations" SET "status"=$1 WHERE status = $2 AND expires_at <= $3 RETURNING *`)).
+		WithArgs(domain.InvitationStatusExpired, domain.InvitationStatusPending, now).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).
+			AddRow(uuid.New(), domain.InvitationStatusExpired).
+			AddRow(uuid.New(), domain.InvitationStatusExpired))
+	mock.ExpectCommit()
+
+	expired, err := repo.ExpireOverdue(context.TODO(), now)
+	assert.NoError(t, err)
+	assert.Len(t, expired, 2)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
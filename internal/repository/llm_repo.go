@@ -20,7 +20,7 @@ func NewLLMRepository(db *gorm.DB) domain.LLMRepository {
 func (r *llmRepository) ListProviders(ctx context.Context) ([]domain.LLMProvider, error) {
 	var providers []domain.LLMProvider
 	// Preload Models for each provider
-	if err := r.db.WithContext(ctx).Preload("Models").Find(&providers).Error; err != nil {
+	if err := conn(ctx, r.db).Preload("Models").Find(&providers).Error; err != nil {
 		return nil, err
 	}
 	return providers, nil
@@ -28,7 +28,7 @@ func (r *llmRepository) ListProviders(ctx context.Context) ([]domain.LLMProvider
 
 func (r *llmRepository) GetModel(ctx context.Context, id uuid.UUID) (*domain.LLMModel, error) {
 	var model domain.LLMModel
-	if err := r.db.WithContext(ctx).Preload("Provider").First(&model, "id = ?", id).Error; err != nil {
+	if err := conn(ctx, r.db).Preload("Provider").First(&model, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 	return &model, nil
@@ -36,7 +36,7 @@ func (r *llmRepository) GetModel(ctx context.Context, id uuid.UUID) (*domain.LLM
 
 func (r *llmRepository) ListModels(ctx context.Context, providerID uuid.UUID) ([]domain.LLMModel, error) {
 	var models []domain.LLMModel
-	query := r.db.WithContext(ctx)
+	query := conn(ctx, r.db)
 	if providerID != uuid.Nil {
 		query = query.Where("provider_id = ?", providerID)
 	}
@@ -48,7 +48,7 @@ func (r *llmRepository) ListModels(ctx context.Context, providerID uuid.UUID) ([
 
 func (r *llmRepository) ListAgentsUsingModel(ctx context.Context, modelID uuid.UUID) ([]domain.Agent, error) {
 	var agents []domain.Agent
-	if err := r.db.WithContext(ctx).
+	if err := conn(ctx, r.db).
 		Joins("JOIN agent_llms ON agent_llms.agent_id = agents.id").
 		Where("agent_llms.llm_model_id = ?", modelID).
 		Find(&agents).Error; err != nil {
@@ -59,7 +59,7 @@ func (r *llmRepository) ListAgentsUsingModel(ctx context.Context, modelID uuid.U
 
 func (r *llmRepository) GetCertifications(ctx context.Context, modelID uuid.UUID) ([]domain.Certification, error) {
 	var certifications []domain.Certification
-	err := r.db.WithContext(ctx).
+	err := conn(ctx, r.db).
 		Joins("JOIN llm_model_certifications ON llm_model_certifications.certification_id = certifications.id").
 		Where("llm_model_certifications.llm_model_id = ?", modelID).
 		Find(&certifications).Error
@@ -19,12 +19,12 @@ func NewOrganizationRepository(db *gorm.DB) domain.OrganizationRepository {
 }
 
 func (r *organizationRepository) Create(ctx context.Context, org *domain.Organization) error {
-	return r.db.WithContext(ctx).Create(org).Error
+	return conn(ctx, r.db).Create(org).Error
 }
 
 func (r *organizationRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Organization, error) {
 	var org domain.Organization
-	if err := r.db.WithContext(ctx).First(&org, "id = ?", id).Error; err != nil {
+	if err := conn(ctx, r.db).First(&org, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 	return &org, nil
@@ -32,7 +32,7 @@ func (r *organizationRepository) GetByID(ctx context.Context, id uuid.UUID) (*do
 
 func (r *organizationRepository) GetBySlug(ctx context.Context, slug string) (*domain.Organization, error) {
 	var org domain.Organization
-	if err := r.db.WithContext(ctx).First(&org, "slug = ?", slug).Error; err != nil {
+	if err := conn(ctx, r.db).First(&org, "slug = ?", slug).Error; err != nil {
 		return nil, err
 	}
 	return &org, nil
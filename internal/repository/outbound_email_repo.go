@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"agentXmap/internal/domain"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type outboundEmailRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboundEmailRepository creates a new postgres repository for the email outbox.
+func NewOutboundEmailRepository(db *gorm.DB) domain.OutboundEmailRepository {
+	return &outboundEmailRepository{db: db}
+}
+
+func (r *outboundEmailRepository) Create(ctx context.Context, email *domain.OutboundEmail) error {
+	return conn(ctx, r.db).Create(email).Error
+}
+
+func (r *outboundEmailRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]domain.OutboundEmail, error) {
+	var emails []domain.OutboundEmail
+	if err := conn(ctx, r.db).
+		Where("status = ? AND next_attempt_at <= ?", domain.EmailStatusPending, now).
+		Order("next_attempt_at").Limit(limit).
+		Find(&emails).Error; err != nil {
+		return nil, err
+	}
+	return emails, nil
+}
+
+func (r *outboundEmailRepository) Claim(ctx context.Context, id uuid.UUID, now, retryAt time.Time) (bool, error) {
+	result := conn(ctx, r.db).Model(&domain.OutboundEmail{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", id, domain.EmailStatusPending, now).
+		UpdateColumns(map[string]any{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": retryAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *outboundEmailRepository) MarkSent(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return conn(ctx, r.db).Model(&domain.OutboundEmail{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]any{
+			"status":     domain.EmailStatusSent,
+			"sent_at":    at,
+			"last_error": "",
+			"text_body":  "",
+			"html_body":  "",
+		}).Error
+}
+
+func (r *outboundEmailRepository) MarkFailed(ctx context.Context, id uuid.UUID, lastError string, giveUp bool) error {
+	columns := map[string]any{"last_error": lastError}
+	if giveUp {
+		columns["status"] = domain.EmailStatusFailed
+		columns["text_body"] = ""
+		columns["html_body"] = ""
+	}
+	return conn(ctx, r.db).Model(&domain.OutboundEmail{}).
+		Where("id = ?", id).
+		UpdateColumns(columns).Error
+}
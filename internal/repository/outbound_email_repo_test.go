@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"agentXmap/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutboundEmailRepository_Create(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewOutboundEmailRepository(db)
+
+	email := &domain.OutboundEmail{
+		Recipient:     "john@acme.com",
+		Template:      "invitation",
+		Subject:       "You are invited",
+		TextBody:      "hello",
+		NextAttemptAt: time.Now(),
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "outbound_emails"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "attempts", "created_at"}).AddRow(uuid.New(), "pending", 0, time.Now()))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.Create(context.TODO(), email))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboundEmailRepository_ListDue(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewOutboundEmailRepository(db)
+	now := time.Now()
+	id := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "outbound_emails" WHERE status = $1 AND next_attempt_at <= $2 ORDER BY next_attempt_at LIMIT $3`)).
+		WithArgs(domain.EmailStatusPending, now, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipient"}).AddRow(id, "john@acme.com"))
+
+	emails, err := repo.ListDue(context.TODO(), now, 10)
+	assert.NoError(t, err)
+	assert.Len(t, emails, 1)
+	assert.Equal(t, id, emails[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboundEmailRepository_Claim(t *testing.T) {
+	id := uuid.New()
+	now := time.Now()
+	retryAt := now.Add(time.Minute)
+
+	for name, rows := range map[string]int64{"Claimed": 1, "Claimed Elsewhere": 0} {
+		t.Run(name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			repo := NewOutboundEmailRepository(db)
+
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "outbound_emails" SET "attempts"=attempts + 1,"next_attempt_at"=$1 WHERE id = $2 AND status = $3 AND next_attempt_at <= $4`)).
+				WithArgs(retryAt, id, domain.EmailStatusPending, now).
+				WillReturnResult(sqlmock.NewResult(0, rows))
+			mock.ExpectCommit()
+
+			ok, err := repo.Claim(context.TODO(), id, now, retryAt)
+			assert.NoError(t, err)
+			assert.Equal(t, rows == 1, ok)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestOutboundEmailRepository_MarkSent(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewOutboundEmailRepository(db)
+	id := uuid.New()
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "outbound_emails" SET "html_body"=$1,"last_error"=$2,"sent_at"=$3,"status"=$4,"text_body"=$5 WHERE id = $6`)).
+		WithArgs("", "", now, domain.EmailStatusSent, "", id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.MarkSent(context.TODO(), id, now))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboundEmailRepository_MarkFailed(t *testing.T) {
+	id := uuid.New()
+
+	t.Run("Retry", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewOutboundEmailRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "outbound_emails" SET "last_error"=$1 WHERE id = $2`)).
+			WithArgs("connection refused", id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, repo.MarkFailed(context.TODO(), id, "connection refused", false))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Give Up", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewOutboundEmailRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "outbound_emails" SET "html_body"=$1,"last_error"=$2,"status"=$3,"text_body"=$4 WHERE id = $5`)).
+			WithArgs("", "connection refused", domain.EmailStatusFailed, "", id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, repo.MarkFailed(context.TODO(), id, "connection refused", true))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"agentXmap/internal/domain"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type passwordResetTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewPasswordResetTokenRepository creates a new postgres repository for PasswordResetTokens.
+func NewPasswordResetTokenRepository(db *gorm.DB) domain.PasswordResetTokenRepository {
+	return &passwordResetTokenRepository{db: db}
+}
+
+func (r *passwordResetTokenRepository) Create(ctx context.Context, token *domain.PasswordResetToken) error {
+	return conn(ctx, r.db).Create(token).Error
+}
+
+func (r *passwordResetTokenRepository) GetByHash(ctx context.Context, hash string) (*domain.PasswordResetToken, error) {
+	var token domain.PasswordResetToken
+	if err := conn(ctx, r.db).First(&token, "token_hash = ?", hash).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Consume is a conditional update, so of two concurrent resets with the same
+// token only one succeeds.
+func (r *passwordResetTokenRepository) Consume(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	res := conn(ctx, r.db).
+		Model(&domain.PasswordResetToken{}).
+		Where("id = ? AND consumed_at IS NULL AND expires_at > ?", id, at).
+		Update("consumed_at", at)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *passwordResetTokenRepository) ConsumeAllForUser(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	return conn(ctx, r.db).
+		Model(&domain.PasswordResetToken{}).
+		Where("user_id = ? AND consumed_at IS NULL", userID).
+		Update("consumed_at", at).Error
+}
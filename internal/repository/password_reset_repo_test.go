@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"agentXmap/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordResetTokenRepository_Create(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewPasswordResetTokenRepository(db)
+	ctx := context.TODO()
+
+	token := &domain.PasswordResetToken{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		TokenHash: "hash",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "password_reset_tokens"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.Create(ctx, token))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPasswordResetTokenRepository_GetByHash(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewPasswordResetTokenRepository(db)
+	ctx := context.TODO()
+	id := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "password_reset_tokens" WHERE token_hash = $1`)).
+		WithArgs("hash", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "token_hash"}).AddRow(id, "hash"))
+
+	token, err := repo.GetByHash(ctx, "hash")
+	assert.NoError(t, err)
+	assert.Equal(t, id, token.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPasswordResetTokenRepository_Consume(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewPasswordResetTokenRepository(db)
+	ctx := context.TODO()
+	id := uuid.New()
+	now := time.Now()
+	query := regexp.QuoteMeta(`UPDATE "password_reset_tokens" SET "consumed_at"=$1 WHERE id = $2 AND consumed_at IS NULL AND expires_at > $3`)
+
+	t.Run("Consumed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(query).WithArgs(now, id, now).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		ok, err := repo.Consume(ctx, id, now)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Already Consumed Or Expired", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(query).WithArgs(now, id, now).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		ok, err := repo.Consume(ctx, id, now)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPasswordResetTokenRepository_ConsumeAllForUser(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewPasswordResetTokenRepository(db)
+	ctx := context.TODO()
+	userID := uuid.New()
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "password_reset_tokens" SET "consumed_at"=$1 WHERE user_id = $2 AND consumed_at IS NULL`)).
+		WithArgs(now, userID).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.ConsumeAllForUser(ctx, userID, now))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
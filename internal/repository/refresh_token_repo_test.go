@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"agentXmap/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestRefreshTokenRepository_Create(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewRefreshTokenRepository(db)
+	ctx := context.TODO()
+
+	token := &domain.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		TokenHash: "hash",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "refresh_tokens"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.Create(ctx, token))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefreshTokenRepository_GetByHash(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewRefreshTokenRepository(db)
+	ctx := context.TODO()
+
+	t.Run("Found", func(t *testing.T) {
+		id := uuid.New()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "refresh_tokens" WHERE token_hash = $1`)).
+			WithArgs("hash", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "token_hash"}).AddRow(id, "hash"))
+
+		token, err := repo.GetByHash(ctx, "hash")
+		assert.NoError(t, err)
+		assert.Equal(t, id, token.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "refresh_tokens" WHERE token_hash = $1`)).
+			WithArgs("missing", 1).
+			WillReturnError(gorm.ErrRecordNotFound)
+
+		token, err := repo.GetByHash(ctx, "missing")
+		assert.Error(t, err)
+		assert.Nil(t, token)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRefreshTokenRepository_Revoke(t *testing.T) {
+	ctx := context.TODO()
+	id := uuid.New()
+	successor := uuid.New()
+
+	t.Run("Rotated", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewRefreshTokenRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "refresh_tokens" SET "replaced_by_id"=$1,"revoked_at"=$2 WHERE id = $3 AND revoked_at IS NULL`)).
+			WithArgs(&successor, sqlmock.AnyArg(), id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		revoked, err := repo.Revoke(ctx, id, time.Now(), &successor)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Already Revoked", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewRefreshTokenRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "refresh_tokens" SET`)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		revoked, err := repo.Revoke(ctx, id, time.Now(), nil)
+		assert.NoError(t, err)
+		assert.False(t, revoked)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRefreshTokenRepository_RevokeAllForUser(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewRefreshTokenRepository(db)
+	ctx := context.TODO()
+	userID := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "refresh_tokens" SET "revoked_at"=$1 WHERE user_id = $2 AND revoked_at IS NULL`)).
+		WithArgs(sqlmock.AnyArg(), userID).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.RevokeAllForUser(ctx, userID))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefreshTokenRepository_SessionActive(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewRefreshTokenRepository(db)
+	ctx := context.TODO()
+	sessionID := uuid.New()
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "refresh_tokens" WHERE session_id = $1 AND revoked_at IS NULL AND expires_at > $2`)).
+		WithArgs(sessionID, now).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	active, err := repo.SessionActive(ctx, sessionID, now)
+	assert.NoError(t, err)
+	assert.True(t, active)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
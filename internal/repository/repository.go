@@ -30,8 +30,12 @@ func InitDB(cfg config.Config) (*gorm.DB, error) {
 		logLevel = logger.Error
 	}
 
+	// Timestamps are TIMESTAMP columns holding UTC: the session runs in UTC
+	// for NOW() defaults, GORM's own timestamps use UTC and the services
+	// write time.Now().UTC().
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logLevel),
+		Logger:  logger.Default.LogMode(logLevel),
+		NowFunc: func() time.Time { return time.Now().UTC() },
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
@@ -56,6 +60,7 @@ func AutoMigrate(db *gorm.DB) error {
 	return db.AutoMigrate(
 		&domain.Organization{},
 		&domain.User{},
+		&domain.RefreshToken{},
 		&domain.Agent{},
 		&domain.AgentVersion{},
 		&domain.AgentAssignment{},
@@ -2,11 +2,15 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"time"
 
 	"agentXmap/internal/domain"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type resourceRepository struct {
@@ -18,24 +22,333 @@ func NewResourceRepository(db *gorm.DB) *resourceRepository {
 }
 
 func (r *resourceRepository) Create(ctx context.Context, res *domain.Resource) error {
-	return r.db.WithContext(ctx).Create(res).Error
+	if err := claimTenant(ctx, &res.OrganizationID); err != nil {
+		return err
+	}
+	return conn(ctx, r.db).Create(res).Error
 }
 
 func (r *resourceRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Resource, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
 	var res domain.Resource
-	if err := r.db.WithContext(ctx).Preload("Type").Preload("Secret").First(&res, "id = ?", id).Error; err != nil {
+	if err := conn(ctx, r.db).Preload("Type").Preload("Secret").
+		Where("id = ?", id).Scopes(inOrganization("resources", orgID)).First(&res).Error; err != nil {
 		return nil, err
 	}
 	return &res, nil
 }
 
+func (r *resourceRepository) ListByOrg(ctx context.Context, orgID uuid.UUID, filter domain.ResourceFilter) ([]domain.Resource, error) {
+	if err := claimTenant(ctx, &orgID); err != nil {
+		return nil, err
+	}
+	query := conn(ctx, r.db).Preload("Secret").Where("resources.organization_id = ?", orgID)
+	if filter.Deleted {
+		query = query.Unscoped().Where("resources.deleted_at IS NOT NULL")
+	}
+	if filter.TypeID != "" {
+		query = query.Where("resources.type_id = ?", filter.TypeID)
+	}
+	var resources []domain.Resource
+	if err := query.Order("resources.name").Find(&resources).Error; err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
+func (r *resourceRepository) Update(ctx context.Context, res *domain.Resource) error {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+	if res.OrganizationID != orgID {
+		return ErrCrossTenant
+	}
+	result := conn(ctx, r.db).Model(res).
+		Scopes(inOrganization("resources", orgID)).
+		Select("*").Omit(clause.Associations).
+		Updates(res)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *resourceRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	result := conn(ctx, r.db).Model(&domain.Resource{}).
+		Where("id = ?", id).
+		Scopes(inOrganization("resources", orgID)).
+		UpdateColumn("deleted_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return conn(ctx, r.db).Model(&domain.AgentResourceAccess{}).
+		Where("resource_id = ?", id).
+		UpdateColumn("deleted_at", now).Error
+}
+
+func (r *resourceRepository) Restore(ctx context.Context, id uuid.UUID) (bool, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return false, err
+	}
+	var res domain.Resource
+	if err := conn(ctx, r.db).Unscoped().
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Scopes(inOrganization("resources", orgID)).
+		First(&res).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	// Revoking a grant deletes it for good, so every soft-deleted grant of the
+	// resource was deleted along with it.
+	if err := conn(ctx, r.db).Unscoped().Model(&domain.AgentResourceAccess{}).
+		Where("resource_id = ? AND deleted_at IS NOT NULL", id).
+		UpdateColumn("deleted_at", nil).Error; err != nil {
+		return false, err
+	}
+	result := conn(ctx, r.db).Unscoped().Model(&domain.Resource{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Scopes(inOrganization("resources", orgID)).
+		UpdateColumn("deleted_at", nil)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// GetType returns the ResourceType with the given id, or nil when there is none.
+// Resource types are a global catalog and are not scoped to an organization.
+func (r *resourceRepository) GetType(ctx context.Context, id string) (*domain.ResourceType, error) {
+	var rt domain.ResourceType
+	if err := conn(ctx, r.db).Where("id = ?", id).First(&rt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rt, nil
+}
+
+func (r *resourceRepository) GetSecret(ctx context.Context, resourceID uuid.UUID) (*domain.ResourceSecret, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var secret domain.ResourceSecret
+	if err := conn(ctx, r.db).
+		Where("resource_id = ?", resourceID).
+		Scopes(resourceInOrganization("resource_id", orgID)).
+		First(&secret).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &secret, nil
+}
+
+func (r *resourceRepository) SaveSecret(ctx context.Context, secret *domain.ResourceSecret) error {
+	if err := r.ensureResource(ctx, secret.ResourceID); err != nil {
+		return err
+	}
+	return conn(ctx, r.db).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "resource_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"encrypted_credentials", "key_version_id", "updated_at"}),
+	}).Create(secret).Error
+}
+
+// ensureResource fails with ErrCrossTenant unless resourceID belongs to the caller's tenant.
+func (r *resourceRepository) ensureResource(ctx context.Context, resourceID uuid.UUID) error {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+	var count int64
+	if err := conn(ctx, r.db).Model(&domain.Resource{}).
+		Where("id = ?", resourceID).
+		Scopes(inOrganization("resources", orgID)).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrCrossTenant
+	}
+	return nil
+}
+
+func (r *resourceRepository) ListSecretsToRotate(ctx context.Context, keyVersion string, afterID uuid.UUID, limit int) ([]domain.ResourceSecret, error) {
+	var secrets []domain.ResourceSecret
+	if err := conn(ctx, r.db).
+		Where("(key_version_id IS NULL OR key_version_id <> ?) AND id > ?", keyVersion, afterID).
+		Order("id").
+		Limit(limit).
+		Find(&secrets).Error; err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+func (r *resourceRepository) CountSecretsByKeyVersion(ctx context.Context) (map[string]int64, error) {
+	var rows []struct {
+		KeyVersionID string
+		Count        int64
+	}
+	if err := conn(ctx, r.db).Model(&domain.ResourceSecret{}).
+		Select("COALESCE(key_version_id, '') AS key_version_id, COUNT(*) AS count").
+		Group("key_version_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.KeyVersionID] += row.Count
+	}
+	return counts, nil
+}
+
+func (r *resourceRepository) ReplaceSecretEnvelope(ctx context.Context, id uuid.UUID, oldEnvelope, newEnvelope, keyVersion string) (bool, error) {
+	result := conn(ctx, r.db).Model(&domain.ResourceSecret{}).
+		Where("id = ? AND encrypted_credentials = ?", id, oldEnvelope).
+		Updates(map[string]any{"encrypted_credentials": newEnvelope, "key_version_id": keyVersion})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
 func (r *resourceRepository) ListAgentsWithAccess(ctx context.Context, resourceID uuid.UUID) ([]domain.Agent, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
 	var agents []domain.Agent
-	if err := r.db.WithContext(ctx).
-		Joins("JOIN agent_resource_accesses ON agent_resource_accesses.agent_id = agents.id").
-		Where("agent_resource_accesses.resource_id = ?", resourceID).
+	if err := conn(ctx, r.db).
+		Joins("JOIN agent_resource_access ON agent_resource_access.agent_id = agents.id AND agent_resource_access.deleted_at IS NULL").
+		Where("agent_resource_access.resource_id = ?", resourceID).
+		Scopes(inOrganization("agents", orgID)).
 		Find(&agents).Error; err != nil {
 		return nil, err
 	}
 	return agents, nil
 }
+
+func (r *resourceRepository) RecordConnectionTest(ctx context.Context, resourceID uuid.UUID, result json.RawMessage) error {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+	res := conn(ctx, r.db).Model(&domain.Resource{}).
+		Where("id = ?", resourceID).
+		Scopes(inOrganization("resources", orgID)).
+		UpdateColumn("last_connection_test", result)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *resourceRepository) GetAccess(ctx context.Context, resourceID, agentID uuid.UUID) (*domain.AgentResourceAccess, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var access domain.AgentResourceAccess
+	if err := conn(ctx, r.db).
+		Where("resource_id = ? AND agent_id = ?", resourceID, agentID).
+		Scopes(resourceInOrganization("resource_id", orgID)).
+		First(&access).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &access, nil
+}
+
+func (r *resourceRepository) CreateAccess(ctx context.Context, access *domain.AgentResourceAccess) (bool, error) {
+	if err := r.ensureResource(ctx, access.ResourceID); err != nil {
+		return false, err
+	}
+	if err := r.ensureAgent(ctx, access.AgentID); err != nil {
+		return false, err
+	}
+	// Of two concurrent grants of the same pair, the unique index keeps the first.
+	result := conn(ctx, r.db).Omit(clause.Associations).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "agent_id"}, {Name: "resource_id"}}, DoNothing: true}).
+		Create(access)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *resourceRepository) UpdateAccessPermission(ctx context.Context, id uuid.UUID, from, to domain.AccessLevel) (bool, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return false, err
+	}
+	// The permission guard serializes concurrent changes of the same grant.
+	result := conn(ctx, r.db).Model(&domain.AgentResourceAccess{}).
+		Where("id = ? AND permission = ?", id, from).
+		Scopes(resourceInOrganization("resource_id", orgID)).
+		Update("permission", to)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *resourceRepository) DeleteAccess(ctx context.Context, id uuid.UUID) (bool, error) {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return false, err
+	}
+	// Revocation is permanent; soft deletion is reserved for grants of deleted resources.
+	result := conn(ctx, r.db).Unscoped().
+		Where("id = ?", id).
+		Scopes(resourceInOrganization("resource_id", orgID)).
+		Delete(&domain.AgentResourceAccess{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ensureAgent fails with ErrCrossTenant unless agentID belongs to the caller's tenant.
+func (r *resourceRepository) ensureAgent(ctx context.Context, agentID uuid.UUID) error {
+	orgID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+	var count int64
+	if err := conn(ctx, r.db).Model(&domain.Agent{}).
+		Where("id = ?", agentID).
+		Scopes(inOrganization("agents", orgID)).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrCrossTenant
+	}
+	return nil
+}
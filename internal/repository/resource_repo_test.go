@@ -18,7 +18,8 @@ import (
 func TestResourceRepository_GetByID(t *testing.T) {
 	db, mock := setupMockDB(t)
 	repo := NewResourceRepository(db)
-	ctx := context.TODO()
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
 
 	id := uuid.New()
 
@@ -33,8 +34,8 @@ func TestResourceRepository_GetByID(t *testing.T) {
 			id:   id,
 			mock: func() {
 				// 1. Get Resource
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "resources" WHERE id = $1`)).
-					WithArgs(id, 1).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "resources" WHERE id = $1 AND resources.organization_id = $2`)).
+					WithArgs(id, orgID, 1).
 					WillReturnRows(sqlmock.NewRows([]string{"id", "type_id"}).AddRow(id, "postgres"))
 
 				// 2. Preloads
@@ -55,8 +56,8 @@ func TestResourceRepository_GetByID(t *testing.T) {
 			name: "Not Found",
 			id:   id,
 			mock: func() {
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "resources" WHERE id = $1`)).
-					WithArgs(id, 1).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "resources" WHERE id = $1 AND resources.organization_id = $2`)).
+					WithArgs(id, orgID, 1).
 					WillReturnError(gorm.ErrRecordNotFound)
 			},
 			wantErr: true,
@@ -81,7 +82,7 @@ func TestResourceRepository_GetByID(t *testing.T) {
 func TestResourceRepository_Create(t *testing.T) {
 	db, mock := setupMockDB(t)
 	repo := NewResourceRepository(db)
-	ctx := context.TODO()
+	ctx := domain.WithOrganization(context.TODO(), uuid.New())
 
 	res := &domain.Resource{
 		ID:        uuid.New(),
@@ -135,3 +136,497 @@ func TestResourceRepository_Create(t *testing.T) {
 		})
 	}
 }
+
+func TestResourceRepository_Update(t *testing.T) {
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+
+	t.Run("Success", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+		res := &domain.Resource{ID: uuid.New(), OrganizationID: orgID, TypeID: "postgres_db", Name: "DB"}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "resources" SET`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, repo.Update(ctx, res))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Other Organization", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+		res := &domain.Resource{ID: uuid.New(), OrganizationID: uuid.New(), Name: "DB"}
+
+		assert.ErrorIs(t, repo.Update(ctx, res), ErrCrossTenant)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestResourceRepository_GetType(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("Found", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "resource_types" WHERE id = $1 ORDER BY "resource_types"."id" LIMIT $2`)).
+			WithArgs("postgres_db", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "config_schema", "is_active"}).
+				AddRow("postgres_db", "PostgreSQL Database", []byte(`{"type":"object"}`), true))
+
+		rt, err := repo.GetType(ctx, "postgres_db")
+		assert.NoError(t, err)
+		if assert.NotNil(t, rt) {
+			assert.Equal(t, "PostgreSQL Database", rt.Name)
+			assert.True(t, rt.IsActive)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "resource_types"`)).
+			WillReturnError(gorm.ErrRecordNotFound)
+
+		rt, err := repo.GetType(ctx, "unknown")
+		assert.NoError(t, err)
+		assert.Nil(t, rt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestResourceRepository_GetSecret(t *testing.T) {
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	resourceID := uuid.New()
+
+	t.Run("Found", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "resource_secrets" WHERE resource_id = $1 AND resource_id IN (SELECT id FROM resources WHERE organization_id = $2) ORDER BY "resource_secrets"."id" LIMIT $3`)).
+			WithArgs(resourceID, orgID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "resource_id", "encrypted_credentials", "key_version_id"}).
+				AddRow(uuid.New(), resourceID, "{}", "v1"))
+
+		secret, err := repo.GetSecret(ctx, resourceID)
+		assert.NoError(t, err)
+		if assert.NotNil(t, secret) {
+			assert.Equal(t, "v1", secret.KeyVersionID)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("None Stored", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "resource_secrets"`)).
+			WillReturnError(gorm.ErrRecordNotFound)
+
+		secret, err := repo.GetSecret(ctx, resourceID)
+		assert.NoError(t, err)
+		assert.Nil(t, secret)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestResourceRepository_SaveSecret(t *testing.T) {
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	resourceID := uuid.New()
+
+	t.Run("Upsert", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "resources" WHERE id = $1 AND resources.organization_id = $2`)).
+			WithArgs(resourceID, orgID).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "resource_secrets"`) + `.*` +
+			regexp.QuoteMeta(`ON CONFLICT ("resource_id") DO UPDATE SET "encrypted_credentials"="excluded"."encrypted_credentials","key_version_id"="excluded"."key_version_id","updated_at"="excluded"."updated_at"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()))
+		mock.ExpectCommit()
+
+		err := repo.SaveSecret(ctx, &domain.ResourceSecret{ResourceID: resourceID, EncryptedCredentials: "{}", KeyVersionID: "v1"})
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Resource Of Another Organization", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "resources"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+		err := repo.SaveSecret(ctx, &domain.ResourceSecret{ResourceID: resourceID, EncryptedCredentials: "{}"})
+		assert.ErrorIs(t, err, ErrCrossTenant)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestResourceRepository_ListSecretsToRotate(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewResourceRepository(db)
+	afterID := uuid.New()
+
+	// Unscoped: rotation runs without an organization in context.
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "resource_secrets" WHERE (key_version_id IS NULL OR key_version_id <> $1) AND id > $2 ORDER BY id LIMIT $3`)).
+		WithArgs("v2", afterID, 50).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "key_version_id"}).AddRow(uuid.New(), "v1"))
+
+	secrets, err := repo.ListSecretsToRotate(context.TODO(), "v2", afterID, 50)
+	assert.NoError(t, err)
+	assert.Len(t, secrets, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResourceRepository_CountSecretsByKeyVersion(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewResourceRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(key_version_id, '') AS key_version_id, COUNT(*) AS count FROM "resource_secrets" GROUP BY "key_version_id"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"key_version_id", "count"}).AddRow("v1", 3).AddRow("v2", 5).AddRow("", 1))
+
+	counts, err := repo.CountSecretsByKeyVersion(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"v1": 3, "v2": 5, "": 1}, counts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResourceRepository_ReplaceSecretEnvelope(t *testing.T) {
+	id := uuid.New()
+
+	t.Run("Replaced", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "resource_secrets" SET "encrypted_credentials"=$1,"key_version_id"=$2,"updated_at"=$3 WHERE id = $4 AND encrypted_credentials = $5`)).
+			WithArgs("new", "v2", sqlmock.AnyArg(), id, "old").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		ok, err := repo.ReplaceSecretEnvelope(context.TODO(), id, "old", "new", "v2")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Changed Concurrently", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "resource_secrets" SET`)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		ok, err := repo.ReplaceSecretEnvelope(context.TODO(), id, "old", "new", "v2")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestResourceRepository_RecordConnectionTest(t *testing.T) {
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	id := uuid.New()
+	result := []byte(`{"success":true,"latency_ms":12}`)
+
+	t.Run("Success", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "resources" SET "last_connection_test"=$1 WHERE id = $2 AND resources.organization_id = $3 AND "resources"."deleted_at" IS NULL`)).
+			WithArgs(result, id, orgID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, repo.RecordConnectionTest(ctx, id, result))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "resources" SET`)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		assert.ErrorIs(t, repo.RecordConnectionTest(ctx, id, result), gorm.ErrRecordNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestResourceRepository_GetAccess(t *testing.T) {
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	resID, agentID := uuid.New(), uuid.New()
+
+	t.Run("Found", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "agent_resource_access" WHERE (resource_id = $1 AND agent_id = $2) AND resource_id IN (SELECT id FROM resources WHERE organization_id = $3)`)).
+			WithArgs(resID, agentID, orgID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "agent_id", "resource_id", "permission"}).
+				AddRow(uuid.New(), agentID, resID, "read_write"))
+
+		access, err := repo.GetAccess(ctx, resID, agentID)
+		assert.NoError(t, err)
+		if assert.NotNil(t, access) {
+			assert.Equal(t, domain.AccessLevelReadWrite, access.Permission)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not Granted", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "agent_resource_access"`)).
+			WillReturnError(gorm.ErrRecordNotFound)
+
+		access, err := repo.GetAccess(ctx, resID, agentID)
+		assert.NoError(t, err)
+		assert.Nil(t, access)
+	})
+}
+
+func TestResourceRepository_CreateAccess(t *testing.T) {
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	access := &domain.AgentResourceAccess{ID: uuid.New(), AgentID: uuid.New(), ResourceID: uuid.New(), Permission: domain.AccessLevelReadOnly}
+
+	t.Run("Success", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "resources"`)).
+			WithArgs(access.ResourceID, orgID).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "agents"`)).
+			WithArgs(access.AgentID, orgID).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "agent_resource_access"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"granted_at"}).AddRow(time.Now()))
+		mock.ExpectCommit()
+
+		created, err := repo.CreateAccess(ctx, access)
+		assert.NoError(t, err)
+		assert.True(t, created)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Granted Concurrently", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "resources"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "agents"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`ON CONFLICT ("agent_id","resource_id") DO NOTHING`)).
+			WillReturnRows(sqlmock.NewRows([]string{"granted_at"}))
+		mock.ExpectCommit()
+
+		created, err := repo.CreateAccess(ctx, access)
+		assert.NoError(t, err)
+		assert.False(t, created)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Agent Of Another Organization", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "resources"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "agents"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+		_, err := repo.CreateAccess(ctx, access)
+		assert.ErrorIs(t, err, ErrCrossTenant)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestResourceRepository_UpdateAccessPermission(t *testing.T) {
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	id := uuid.New()
+
+	db, mock := setupMockDB(t)
+	repo := NewResourceRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "agent_resource_access" SET "permission"=$1 WHERE (id = $2 AND permission = $3) AND resource_id IN (SELECT id FROM resources WHERE organization_id = $4)`)).
+		WithArgs(domain.AccessLevelReadOnly, id, domain.AccessLevelReadWrite, orgID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	ok, err := repo.UpdateAccessPermission(ctx, id, domain.AccessLevelReadWrite, domain.AccessLevelReadOnly)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResourceRepository_DeleteAccess(t *testing.T) {
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	id := uuid.New()
+
+	db, mock := setupMockDB(t)
+	repo := NewResourceRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "agent_resource_access" WHERE id = $1 AND resource_id IN (SELECT id FROM resources WHERE organization_id = $2)`)).
+		WithArgs(id, orgID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	ok, err := repo.DeleteAccess(ctx, id)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResourceRepository_ListByOrg(t *testing.T) {
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+
+	t.Run("By Type", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "resources" WHERE resources.organization_id = $1 AND resources.type_id = $2 AND "resources"."deleted_at" IS NULL ORDER BY resources.name`)).
+			WithArgs(orgID, "postgres_db").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "type_id", "name"}).
+				AddRow(uuid.New(), orgID, "postgres_db", "DB"))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "resource_secrets"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		resources, err := repo.ListByOrg(ctx, orgID, domain.ResourceFilter{TypeID: "postgres_db"})
+		assert.NoError(t, err)
+		assert.Len(t, resources, 1)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Deleted", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "resources" WHERE resources.organization_id = $1 AND resources.deleted_at IS NOT NULL ORDER BY resources.name`)).
+			WithArgs(orgID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		resources, err := repo.ListByOrg(ctx, orgID, domain.ResourceFilter{Deleted: true})
+		assert.NoError(t, err)
+		assert.Empty(t, resources)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Other Organization", func(t *testing.T) {
+		db, _ := setupMockDB(t)
+		repo := NewResourceRepository(db)
+
+		_, err := repo.ListByOrg(ctx, uuid.New(), domain.ResourceFilter{})
+		assert.ErrorIs(t, err, ErrCrossTenant)
+	})
+}
+
+func TestResourceRepository_Delete(t *testing.T) {
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	id := uuid.New()
+
+	t.Run("Deletes Grants With The Resource", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "resources" SET "deleted_at"=$1 WHERE id = $2 AND resources.organization_id = $3 AND "resources"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), id, orgID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "agent_resource_access" SET "deleted_at"=$1 WHERE resource_id = $2 AND "agent_resource_access"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), id).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		assert.NoError(t, repo.Delete(ctx, id))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "resources" SET "deleted_at"`)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		assert.ErrorIs(t, repo.Delete(ctx, id), gorm.ErrRecordNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestResourceRepository_Restore(t *testing.T) {
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+	id := uuid.New()
+	deletedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Restores Grants Deleted With The Resource", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "resources" WHERE (id = $1 AND deleted_at IS NOT NULL) AND resources.organization_id = $2`)).
+			WithArgs(id, orgID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "deleted_at"}).AddRow(id, orgID, deletedAt))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "agent_resource_access" SET "deleted_at"=$1 WHERE resource_id = $2 AND deleted_at IS NOT NULL`)).
+			WithArgs(nil, id).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "resources" SET "deleted_at"=$1 WHERE (id = $2 AND deleted_at IS NOT NULL) AND resources.organization_id = $3`)).
+			WithArgs(nil, id, orgID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		ok, err := repo.Restore(ctx, id)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not Deleted", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceRepository(db)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "resources"`)).
+			WillReturnError(gorm.ErrRecordNotFound)
+
+		ok, err := repo.Restore(ctx, id)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"agentXmap/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// resourceTypeRepository manages the ResourceType catalog. Resource types are
+// shared by every organization and are not tenant-scoped.
+type resourceTypeRepository struct {
+	db *gorm.DB
+}
+
+func NewResourceTypeRepository(db *gorm.DB) *resourceTypeRepository {
+	return &resourceTypeRepository{db: db}
+}
+
+func (r *resourceTypeRepository) List(ctx context.Context, includeInactive bool) ([]domain.ResourceType, error) {
+	query := conn(ctx, r.db)
+	if !includeInactive {
+		query = query.Where("is_active = ?", true)
+	}
+	var types []domain.ResourceType
+	if err := query.Order("id").Find(&types).Error; err != nil {
+		return nil, err
+	}
+	return types, nil
+}
+
+func (r *resourceTypeRepository) GetByID(ctx context.Context, id string) (*domain.ResourceType, error) {
+	var rt domain.ResourceType
+	if err := conn(ctx, r.db).Where("id = ?", id).First(&rt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rt, nil
+}
+
+func (r *resourceTypeRepository) Create(ctx context.Context, rt *domain.ResourceType) error {
+	return conn(ctx, r.db).Create(rt).Error
+}
+
+func (r *resourceTypeRepository) Update(ctx context.Context, rt *domain.ResourceType, expectedVersion int) (bool, error) {
+	// The version guard keeps concurrent schema edits from overwriting each other.
+	result := conn(ctx, r.db).Model(rt).
+		Where("schema_version = ?", expectedVersion).
+		Select("name", "config_schema", "secret_schema", "schema_version", "is_active", "is_builtin").
+		Updates(rt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *resourceTypeRepository) CreateSchemaVersion(ctx context.Context, version *domain.ResourceTypeSchemaVersion) error {
+	return conn(ctx, r.db).Create(version).Error
+}
+
+func (r *resourceTypeRepository) ListSchemaVersions(ctx context.Context, typeID string) ([]domain.ResourceTypeSchemaVersion, error) {
+	var versions []domain.ResourceTypeSchemaVersion
+	if err := conn(ctx, r.db).
+		Where("type_id = ?", typeID).
+		Order("version DESC").
+		Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
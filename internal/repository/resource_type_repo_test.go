@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"agentXmap/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceTypeRepository_List(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("Active Only", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceTypeRepository(db)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "resource_types" WHERE is_active = $1 ORDER BY id`)).
+			WithArgs(true).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "schema_version", "is_active"}).
+				AddRow("postgres_db", "PostgreSQL Database", 2, true))
+
+		types, err := repo.List(ctx, false)
+		assert.NoError(t, err)
+		if assert.Len(t, types, 1) {
+			assert.Equal(t, 2, types[0].SchemaVersion)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Including Inactive", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceTypeRepository(db)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "resource_types" ORDER BY id`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		_, err := repo.List(ctx, true)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestResourceTypeRepository_Create(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewResourceTypeRepository(db)
+	rt := &domain.ResourceType{ID: "crm", Name: "CRM", ConfigSchema: []byte(`{}`), SecretSchema: []byte(`{}`), SchemaVersion: 1, IsActive: true}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "resource_types" ("id","name","schema_version","is_active","is_builtin","config_schema","secret_schema")`)).
+		WithArgs("crm", "CRM", 1, true, false, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "updated_at"}).AddRow(time.Now(), time.Now()))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.Create(context.TODO(), rt))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResourceTypeRepository_Update(t *testing.T) {
+	rt := &domain.ResourceType{ID: "crm", Name: "CRM", ConfigSchema: []byte(`{}`), SecretSchema: []byte(`{}`), SchemaVersion: 3, IsActive: true}
+
+	t.Run("Success", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceTypeRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "resource_types" SET "name"=$1,"config_schema"=$2,"secret_schema"=$3,"schema_version"=$4,"is_active"=$5,"is_builtin"=$6,"updated_at"=$7 WHERE schema_version = $8 AND "id" = $9`)).
+			WithArgs("CRM", sqlmock.AnyArg(), sqlmock.AnyArg(), 3, true, false, sqlmock.AnyArg(), 2, "crm").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		ok, err := repo.Update(context.TODO(), rt, 2)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Changed Concurrently", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceTypeRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "resource_types" SET`)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		ok, err := repo.Update(context.TODO(), rt, 2)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestResourceTypeRepository_ListSchemaVersions(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewResourceTypeRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "resource_type_schema_versions" WHERE type_id = $1 ORDER BY version DESC`)).
+		WithArgs("crm").
+		WillReturnRows(sqlmock.NewRows([]string{"type_id", "version", "config_schema", "created_at"}).
+			AddRow("crm", 2, []byte(`{}`), time.Now()).
+			AddRow("crm", 1, []byte(`{}`), time.Now()))
+
+	versions, err := repo.ListSchemaVersions(context.TODO(), "crm")
+	assert.NoError(t, err)
+	assert.Len(t, versions, 2)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	t.Run("Error", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		repo := NewResourceTypeRepository(db)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "resource_type_schema_versions"`)).
+			WillReturnError(errors.New("db error"))
+
+		_, err := repo.ListSchemaVersions(context.TODO(), "crm")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"agentXmap/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// SchemaDrift is one difference between the GORM models and the live database.
+// Table is empty for enum drift.
+type SchemaDrift struct {
+	Table   string `json:"table,omitempty"`
+	Column  string `json:"column,omitempty"`
+	Enum    string `json:"enum,omitempty"`
+	Problem string `json:"problem"`
+}
+
+func (d SchemaDrift) String() string {
+	switch {
+	case d.Enum != "":
+		return fmt.Sprintf("enum %s: %s", d.Enum, d.Problem)
+	case d.Column != "":
+		return fmt.Sprintf("%s.%s: %s", d.Table, d.Column, d.Problem)
+	default:
+		return fmt.Sprintf("%s: %s", d.Table, d.Problem)
+	}
+}
+
+// schemaModels are the models checked against the database: every table of
+// 01_schema.sql the application reads or writes.
+var schemaModels = []any{
+	&domain.Organization{},
+	&domain.User{},
+	&domain.Invitation{},
+	&domain.RefreshToken{},
+	&domain.PasswordResetToken{},
+	&domain.OutboundEmail{},
+	&domain.Agent{},
+	&domain.AgentVersion{},
+	&domain.AgentChangeRequest{},
+	&domain.AgentStatusTransition{},
+	&domain.AgentAssignment{},
+	&domain.LLMProvider{},
+	&domain.LLMModel{},
+	&domain.AgentLLM{},
+	&domain.Application{},
+	&domain.ApplicationKey{},
+	&domain.ApplicationAgentAccess{},
+	&domain.ResourceType{},
+	&domain.ResourceTypeSchemaVersion{},
+	&domain.Resource{},
+	&domain.ResourceSecret{},
+	&domain.AgentResourceAccess{},
+	&domain.CredentialLease{},
+	&domain.Certification{},
+	&domain.LLMModelCertification{},
+	&domain.AgentCertification{},
+	&domain.ApplicationCertification{},
+	&domain.SystemAuditLog{},
+	&domain.AgentExecution{},
+}
+
+// schemaEnums lists, per Postgres enum type, the values the application writes.
+var schemaEnums = map[string][]string{
+	"user_role":             enumValues(domain.UserRoleManager, domain.UserRoleAdmin, domain.UserRoleUser),
+	"agent_status":          enumValues(domain.AgentStatusActive, domain.AgentStatusInactive, domain.AgentStatusMaintenance, domain.AgentStatusDeprecated),
+	"billing_cycle":         enumValues(domain.BillingCycleMonthly, domain.BillingCycleYearly, domain.BillingCycleOneTime, domain.BillingCycleCustom),
+	"agent_risk_level":      enumValues(domain.AgentRiskLevelMinimal, domain.AgentRiskLevelLimited, domain.AgentRiskLevelHigh),
+	"change_request_status": enumValues(domain.ChangeRequestStatusPending, domain.ChangeRequestStatusApproved, domain.ChangeRequestStatusRejected),
+	"access_level":          enumValues(domain.AccessLevelReadOnly, domain.AccessLevelReadWrite),
+	"invitation_status":     enumValues(domain.InvitationStatusPending, domain.InvitationStatusAccepted, domain.InvitationStatusExpired, domain.InvitationStatusRevoked),
+	"email_status":          enumValues(domain.EmailStatusPending, domain.EmailStatusSent, domain.EmailStatusFailed),
+	"audit_action": enumValues(
+		domain.AuditActionCreate, domain.AuditActionUpdate, domain.AuditActionDelete, domain.AuditActionLogin,
+		domain.AuditActionExportData, domain.AuditActionApprove, domain.AuditActionReject, domain.AuditActionReadSecret,
+		domain.AuditActionRestore, domain.AuditActionIssueLease, domain.AuditActionExpireLease,
+		domain.AuditActionTestConnection,
+	),
+}
+
+func enumValues[T ~string](values ...T) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = string(v)
+	}
+	return out
+}
+
+// udtNames maps the gorm type tags used by the models to Postgres udt_name.
+// Enum types map to themselves.
+var udtNames = map[string]string{
+	"uuid":    "uuid",
+	"jsonb":   "jsonb",
+	"text":    "text",
+	"varchar": "varchar",
+	"decimal": "numeric",
+	"int":     "int4",
+	"float":   "float8",
+}
+
+type liveColumn struct {
+	TableName     string
+	ColumnName    string
+	UdtName       string
+	IsNullable    string
+	ColumnDefault *string
+}
+
+type liveEnumValue struct {
+	TypeName  string
+	EnumLabel string
+}
+
+// CheckSchema compares the models and enums of the application with the
+// tables of the current schema. It reports missing tables and columns,
+// columns of another type, NOT NULL columns without a default that no model
+// writes, and enum values either side lacks. Tables no model maps are ignored.
+func CheckSchema(ctx context.Context, db *gorm.DB) ([]SchemaDrift, error) {
+	return checkSchema(ctx, db, schemaModels, schemaEnums)
+}
+
+func checkSchema(ctx context.Context, db *gorm.DB, models []any, enums map[string][]string) ([]SchemaDrift, error) {
+	var columns []liveColumn
+	if err := db.WithContext(ctx).Raw(`SELECT table_name, column_name, udt_name, is_nullable, column_default
+		FROM information_schema.columns WHERE table_schema = current_schema()`).
+		Scan(&columns).Error; err != nil {
+		return nil, err
+	}
+	var labels []liveEnumValue
+	if err := db.WithContext(ctx).Raw(`SELECT t.typname AS type_name, e.enumlabel AS enum_label
+		FROM pg_type t JOIN pg_enum e ON e.enumtypid = t.oid JOIN pg_namespace n ON n.oid = t.typnamespace
+		WHERE n.nspname = current_schema() ORDER BY t.typname, e.enumsortorder`).
+		Scan(&labels).Error; err != nil {
+		return nil, err
+	}
+
+	tables := map[string]map[string]liveColumn{}
+	for _, c := range columns {
+		if tables[c.TableName] == nil {
+			tables[c.TableName] = map[string]liveColumn{}
+		}
+		tables[c.TableName][c.ColumnName] = c
+	}
+
+	var drift []SchemaDrift
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, fmt.Errorf("parse model %T: %w", model, err)
+		}
+		table := stmt.Schema.Table
+		live, ok := tables[table]
+		if !ok {
+			drift = append(drift, SchemaDrift{Table: table, Problem: "table does not exist"})
+			continue
+		}
+
+		mapped := map[string]bool{}
+		for _, name := range stmt.Schema.DBNames {
+			mapped[name] = true
+			col, ok := live[name]
+			if !ok {
+				drift = append(drift, SchemaDrift{Table: table, Column: name, Problem: "column does not exist"})
+				continue
+			}
+			if want := expectedUDT(stmt.Schema.FieldsByDBName[name].TagSettings["TYPE"], enums); want != "" && col.UdtName != want {
+				drift = append(drift, SchemaDrift{Table: table, Column: name, Problem: fmt.Sprintf("column is %s, model expects %s", col.UdtName, want)})
+			}
+		}
+		// Inserts through the model would fail on these.
+		names := make([]string, 0, len(live))
+		for name := range live {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			col := live[name]
+			if !mapped[name] && col.IsNullable == "NO" && col.ColumnDefault == nil {
+				drift = append(drift, SchemaDrift{Table: table, Column: name, Problem: "NOT NULL column without default is not mapped by the model"})
+			}
+		}
+	}
+
+	liveEnums := map[string][]string{}
+	for _, l := range labels {
+		liveEnums[l.TypeName] = append(liveEnums[l.TypeName], l.EnumLabel)
+	}
+	enumNames := make([]string, 0, len(enums))
+	for name := range enums {
+		enumNames = append(enumNames, name)
+	}
+	sort.Strings(enumNames)
+	for _, name := range enumNames {
+		live, ok := liveEnums[name]
+		if !ok {
+			drift = append(drift, SchemaDrift{Enum: name, Problem: "enum type does not exist"})
+			continue
+		}
+		for _, v := range missingValues(enums[name], live) {
+			drift = append(drift, SchemaDrift{Enum: name, Problem: fmt.Sprintf("value %q is missing from the database", v)})
+		}
+		for _, v := range missingValues(live, enums[name]) {
+			drift = append(drift, SchemaDrift{Enum: name, Problem: fmt.Sprintf("value %q is unknown to the application", v)})
+		}
+	}
+	return drift, nil
+}
+
+// expectedUDT returns the udt_name a column of gorm type typ must have, or ""
+// when the model does not pin it.
+func expectedUDT(typ string, enums map[string][]string) string {
+	typ = strings.ToLower(typ)
+	if i := strings.IndexByte(typ, '('); i >= 0 {
+		typ = typ[:i]
+	}
+	if _, ok := enums[typ]; ok {
+		return typ
+	}
+	return udtNames[typ]
+}
+
+// missingValues returns the values of want absent from have, in order.
+func missingValues(want, have []string) []string {
+	present := map[string]bool{}
+	for _, v := range have {
+		present[v] = true
+	}
+	var missing []string
+	for _, v := range want {
+		if !present[v] {
+			missing = append(missing, v)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"agentXmap/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestCheckSchema(t *testing.T) {
+	columnsQuery := regexp.QuoteMeta(`FROM information_schema.columns WHERE table_schema = current_schema()`)
+	enumsQuery := regexp.QuoteMeta(`FROM pg_type t JOIN pg_enum e ON e.enumtypid = t.oid`)
+	columnRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"table_name", "column_name", "udt_name", "is_nullable", "column_default"})
+	}
+	enumRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"type_name", "enum_label"})
+	}
+	enums := map[string][]string{"access_level": {"read_only", "read_write"}}
+	// As created by 01_schema.sql.
+	accessColumns := func(rows *sqlmock.Rows) *sqlmock.Rows {
+		return rows.
+			AddRow("agent_resource_access", "id", "uuid", "NO", "gen_random_uuid()").
+			AddRow("agent_resource_access", "agent_id", "uuid", "NO", nil).
+			AddRow("agent_resource_access", "resource_id", "uuid", "NO", nil).
+			AddRow("agent_resource_access", "permission", "access_level", "YES", "'read_only'::access_level").
+			AddRow("agent_resource_access", "granted_at", "timestamp", "YES", "now()").
+			AddRow("agent_resource_access", "deleted_at", "timestamp", "YES", nil)
+	}
+
+	t.Run("In Sync", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		mock.ExpectQuery(columnsQuery).WillReturnRows(accessColumns(columnRows()).
+			AddRow("system_audit_logs", "id", "uuid", "NO", "gen_random_uuid()").
+			AddRow("system_audit_logs", "organization_id", "uuid", "NO", nil).
+			AddRow("system_audit_logs", "seq", "int8", "NO", nil).
+			AddRow("system_audit_logs", "actor_user_id", "uuid", "YES", nil).
+			AddRow("system_audit_logs", "entity_type", "varchar", "NO", nil).
+			AddRow("system_audit_logs", "entity_id", "uuid", "NO", nil).
+			AddRow("system_audit_logs", "action", "audit_action", "NO", nil).
+			AddRow("system_audit_logs", "changes_json", "jsonb", "YES", nil).
+			AddRow("system_audit_logs", "ip_address", "varchar", "YES", nil).
+			AddRow("system_audit_logs", "occurred_at", "timestamp", "YES", "now()").
+			AddRow("system_audit_logs", "prev_hash", "varchar", "NO", nil).
+			AddRow("system_audit_logs", "hash", "varchar", "NO", nil))
+		mock.ExpectQuery(enumsQuery).WillReturnRows(enumRows().
+			AddRow("access_level", "read_only").
+			AddRow("access_level", "read_write").
+			AddRow("audit_action", "create"))
+
+		drift, err := checkSchema(context.Background(), db,
+			[]any{&domain.AgentResourceAccess{}, &domain.SystemAuditLog{}},
+			map[string][]string{"access_level": enums["access_level"], "audit_action": {"create"}})
+		assert.NoError(t, err)
+		assert.Empty(t, drift)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Drift", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		mock.ExpectQuery(columnsQuery).WillReturnRows(columnRows().
+			AddRow("agent_resource_access", "id", "uuid", "NO", "gen_random_uuid()").
+			AddRow("agent_resource_access", "agent_id", "uuid", "NO", nil).
+			AddRow("agent_resource_access", "resource_id", "varchar", "NO", nil).
+			AddRow("agent_resource_access", "permission", "access_level", "YES", "'read_only'::access_level").
+			AddRow("agent_resource_access", "granted_at", "timestamp", "YES", "now()").
+			AddRow("agent_resource_access", "granted_by", "uuid", "NO", nil).
+			AddRow("agent_resource_access", "note", "text", "YES", nil))
+		mock.ExpectQuery(enumsQuery).WillReturnRows(enumRows().
+			AddRow("access_level", "read_only").
+			AddRow("access_level", "admin"))
+
+		drift, err := checkSchema(context.Background(), db,
+			[]any{&domain.AgentResourceAccess{}, &domain.CredentialLease{}},
+			map[string][]string{"access_level": enums["access_level"], "user_role": {"admin"}})
+		assert.NoError(t, err)
+		assert.Equal(t, []SchemaDrift{
+			{Table: "agent_resource_access", Column: "resource_id", Problem: "column is varchar, model expects uuid"},
+			{Table: "agent_resource_access", Column: "deleted_at", Problem: "column does not exist"},
+			{Table: "agent_resource_access", Column: "granted_by", Problem: "NOT NULL column without default is not mapped by the model"},
+			{Table: "credential_leases", Problem: "table does not exist"},
+			{Enum: "access_level", Problem: `value "read_write" is missing from the database`},
+			{Enum: "access_level", Problem: `value "admin" is unknown to the application`},
+			{Enum: "user_role", Problem: "enum type does not exist"},
+		}, drift)
+	})
+}
+
+func TestSchemaModels(t *testing.T) {
+	db, _ := setupMockDB(t)
+
+	// Every gorm type tag must be one CheckSchema knows, so no column goes unchecked.
+	for _, model := range schemaModels {
+		stmt := &gorm.Statement{DB: db}
+		if !assert.NoError(t, stmt.Parse(model)) {
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if typ := field.TagSettings["TYPE"]; field.DBName != "" && typ != "" {
+				assert.NotEmpty(t, expectedUDT(typ, schemaEnums), "%s.%s has type %s", stmt.Schema.Table, field.DBName, typ)
+			}
+		}
+	}
+}
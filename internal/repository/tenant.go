@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"agentXmap/internal/domain"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Tenant-owned repositories (agents, resources, applications) restrict every
+// query to the organization carried by ctx; see domain.OrganizationFromContext.
+var (
+	ErrNoTenant    = errors.New("no organization in context")
+	ErrCrossTenant = errors.New("record belongs to another organization")
+)
+
+// tenantFrom returns the organization ctx is scoped to.
+func tenantFrom(ctx context.Context) (uuid.UUID, error) {
+	orgID, ok := domain.OrganizationFromContext(ctx)
+	if !ok {
+		return uuid.Nil, ErrNoTenant
+	}
+	return orgID, nil
+}
+
+// claimTenant stamps orgID on a new row, or rejects it when it already names another tenant.
+func claimTenant(ctx context.Context, orgID *uuid.UUID) error {
+	tenant, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+	if *orgID == uuid.Nil {
+		*orgID = tenant
+	}
+	if *orgID != tenant {
+		return ErrCrossTenant
+	}
+	return nil
+}
+
+// inOrganization scopes a query to rows of table owned by orgID.
+func inOrganization(table string, orgID uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(table+".organization_id = ?", orgID)
+	}
+}
+
+// agentInOrganization scopes a query to rows whose column references an agent owned by orgID.
+func agentInOrganization(column string, orgID uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(column+" IN (SELECT id FROM agents WHERE organization_id = ?)", orgID)
+	}
+}
+
+// resourceInOrganization scopes a query to rows whose column references a resource owned by orgID.
+func resourceInOrganization(column string, orgID uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(column+" IN (SELECT id FROM resources WHERE organization_id = ?)", orgID)
+	}
+}
+
+// ownerInOrganization scopes a query to rows whose column references a user of orgID.
+// Applications carry no organization_id and belong to their owner's organization.
+func ownerInOrganization(column string, orgID uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(column+" IN (SELECT id FROM users WHERE organization_id = ?)", orgID)
+	}
+}
+
+// applicationInOrganization scopes a query to rows whose column references an application of orgID.
+func applicationInOrganization(column string, orgID uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(column+" IN (SELECT id FROM applications WHERE owner_id IN (SELECT id FROM users WHERE organization_id = ?))", orgID)
+	}
+}
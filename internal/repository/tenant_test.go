@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"agentXmap/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// expectOwnerInTenant expects the ownership check run before inserting an application.
+func expectOwnerInTenant(mock sqlmock.Sqlmock, ownerID, orgID uuid.UUID, count int) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "users" WHERE id = $1 AND users.organization_id = $2`)).
+		WithArgs(ownerID, orgID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+}
+
+func TestTenantIsolation_MissingTenant(t *testing.T) {
+	db, mock := setupMockDB(t)
+	ctx := context.TODO()
+	id := uuid.New()
+
+	_, err := NewAgentRepository(db).GetByID(ctx, id)
+	assert.ErrorIs(t, err, ErrNoTenant)
+
+	_, err = NewResourceRepository(db).GetByID(ctx, id)
+	assert.ErrorIs(t, err, ErrNoTenant)
+
+	_, err = NewApplicationRepository(db).GetByID(ctx, id)
+	assert.ErrorIs(t, err, ErrNoTenant)
+
+	err = NewAgentRepository(db).Create(ctx, &domain.Agent{Name: "Agent"})
+	assert.ErrorIs(t, err, ErrNoTenant)
+
+	// No query may reach the database without a tenant.
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTenantIsolation_ContextSources(t *testing.T) {
+	orgID := uuid.New()
+
+	got, err := tenantFrom(domain.WithPrincipal(context.TODO(), &domain.Principal{OrganizationID: orgID}))
+	assert.NoError(t, err)
+	assert.Equal(t, orgID, got)
+
+	got, err = tenantFrom(domain.WithApplicationPrincipal(context.TODO(), &domain.ApplicationPrincipal{OrganizationID: orgID}))
+	assert.NoError(t, err)
+	assert.Equal(t, orgID, got)
+
+	got, err = tenantFrom(domain.WithOrganization(context.TODO(), orgID))
+	assert.NoError(t, err)
+	assert.Equal(t, orgID, got)
+}
+
+func TestTenantIsolation_CrossTenantReads(t *testing.T) {
+	db, mock := setupMockDB(t)
+	orgA := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgA)
+	foreignID := uuid.New() // exists, but in another organization
+
+	t.Run("Agent", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "agents" WHERE id = $1 AND agents.organization_id = $2`)).
+			WithArgs(foreignID, orgA, 1).
+			WillReturnRows(sqlmock.NewRows(nil))
+
+		agent, err := NewAgentRepository(db).GetByID(ctx, foreignID)
+		assert.NoError(t, err)
+		assert.Nil(t, agent)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Resource", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "resources" WHERE id = $1 AND resources.organization_id = $2`)).
+			WithArgs(foreignID, orgA, 1).
+			WillReturnRows(sqlmock.NewRows(nil))
+
+		_, err := NewResourceRepository(db).GetByID(ctx, foreignID)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Application", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "applications" WHERE id = $1 AND owner_id IN (SELECT id FROM users WHERE organization_id = $2)`)).
+			WithArgs(foreignID, orgA, 1).
+			WillReturnRows(sqlmock.NewRows(nil))
+
+		_, err := NewApplicationRepository(db).GetByID(ctx, foreignID)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Agent listing for another organization", func(t *testing.T) {
+		_, err := NewAgentRepository(db).ListByOrg(ctx, uuid.New())
+		assert.ErrorIs(t, err, ErrCrossTenant)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Agent children", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "resources"."id"`)).
+			WithArgs(foreignID, orgA).
+			WillReturnRows(sqlmock.NewRows(nil))
+
+		resources, err := NewAgentRepository(db).GetResources(ctx, foreignID)
+		assert.NoError(t, err)
+		assert.Empty(t, resources)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestTenantIsolation_CrossTenantWrites(t *testing.T) {
+	db, mock := setupMockDB(t)
+	orgA := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgA)
+
+	t.Run("Create agent in another organization", func(t *testing.T) {
+		err := NewAgentRepository(db).Create(ctx, &domain.Agent{OrganizationID: uuid.New(), Name: "Agent"})
+		assert.ErrorIs(t, err, ErrCrossTenant)
+	})
+
+	t.Run("Create resource in another organization", func(t *testing.T) {
+		err := NewResourceRepository(db).Create(ctx, &domain.Resource{OrganizationID: uuid.New(), Name: "DB"})
+		assert.ErrorIs(t, err, ErrCrossTenant)
+	})
+
+	t.Run("Move agent to another organization", func(t *testing.T) {
+		_, err := NewAgentRepository(db).Update(ctx, &domain.Agent{ID: uuid.New(), OrganizationID: uuid.New()})
+		assert.ErrorIs(t, err, ErrCrossTenant)
+	})
+
+	t.Run("Update foreign agent", func(t *testing.T) {
+		agent := &domain.Agent{ID: uuid.New(), OrganizationID: orgA, Name: "Agent"}
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "agents" SET`)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		updated, err := NewAgentRepository(db).Update(ctx, agent)
+		assert.NoError(t, err)
+		assert.False(t, updated)
+	})
+
+	t.Run("Delete foreign agent", func(t *testing.T) {
+		id := uuid.New()
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "agents" SET "deleted_at"=$1 WHERE id = $2 AND agents.organization_id = $3`)).
+			WithArgs(sqlmock.AnyArg(), id, orgA).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := NewAgentRepository(db).Delete(ctx, id)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Version for foreign agent", func(t *testing.T) {
+		agentID := uuid.New()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "agents" WHERE id = $1 AND agents.organization_id = $2`)).
+			WithArgs(agentID, orgA).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+		err := NewAgentRepository(db).CreateVersion(ctx, &domain.AgentVersion{AgentID: agentID})
+		assert.ErrorIs(t, err, ErrCrossTenant)
+	})
+
+	t.Run("Application owned by another organization's user", func(t *testing.T) {
+		ownerID := uuid.New()
+		expectOwnerInTenant(mock, ownerID, orgA, 0)
+
+		err := NewApplicationRepository(db).Create(ctx, &domain.Application{OwnerID: ownerID, Name: "App"})
+		assert.ErrorIs(t, err, ErrCrossTenant)
+	})
+
+	t.Run("Key for foreign application", func(t *testing.T) {
+		appID := uuid.New()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "applications" WHERE id = $1 AND owner_id IN (SELECT id FROM users WHERE organization_id = $2)`)).
+			WithArgs(appID, orgA).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+		err := NewApplicationRepository(db).CreateKey(ctx, &domain.ApplicationKey{ApplicationID: appID})
+		assert.ErrorIs(t, err, ErrCrossTenant)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
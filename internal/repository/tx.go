@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+
+	"agentXmap/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+type txManager struct {
+	db *gorm.DB
+}
+
+// NewTxManager creates a TxManager backed by GORM transactions.
+func NewTxManager(db *gorm.DB) domain.TxManager {
+	return &txManager{db: db}
+}
+
+// WithinTransaction runs fn in a transaction that repositories join through ctx.
+// A nested call reuses the outer transaction.
+func (m *txManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+	return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// conn returns the transaction carried by ctx, or db when there is none.
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"agentXmap/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxManager_WithinTransaction(t *testing.T) {
+	orgID := uuid.New()
+	ctx := domain.WithOrganization(context.TODO(), orgID)
+
+	// createAgentWithVersion mirrors AgentService.CreateAgent.
+	createAgentWithVersion := func(ctx context.Context, txm domain.TxManager, repo domain.AgentRepository, agent *domain.Agent) error {
+		return txm.WithinTransaction(ctx, func(ctx context.Context) error {
+			if err := repo.Create(ctx, agent); err != nil {
+				return err
+			}
+			return repo.CreateVersion(ctx, &domain.AgentVersion{AgentID: agent.ID, VersionNumber: 1})
+		})
+	}
+
+	t.Run("Commit", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		agent := &domain.Agent{ID: uuid.New(), OrganizationID: orgID, Name: "Agent"}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "agents"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(agent.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "agents"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "agent_versions"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()))
+		mock.ExpectCommit()
+
+		err := createAgentWithVersion(ctx, NewTxManager(db), NewAgentRepository(db), agent)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Rollback when the version insert fails", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		agent := &domain.Agent{ID: uuid.New(), OrganizationID: orgID, Name: "Agent"}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "agents"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(agent.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "agents"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "agent_versions"`)).
+			WillReturnError(errors.New("db error"))
+		mock.ExpectRollback()
+
+		err := createAgentWithVersion(ctx, NewTxManager(db), NewAgentRepository(db), agent)
+		assert.EqualError(t, err, "db error")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Rollback orphan organization", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		orgRepo := NewOrganizationRepository(db)
+		userRepo := NewUserRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "organizations"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "users"`)).
+			WillReturnError(errors.New("duplicate key value violates unique constraint"))
+		mock.ExpectRollback()
+
+		err := NewTxManager(db).WithinTransaction(context.TODO(), func(ctx context.Context) error {
+			org := &domain.Organization{Name: "Org", Slug: "org"}
+			if err := orgRepo.Create(ctx, org); err != nil {
+				return err
+			}
+			return userRepo.Create(ctx, &domain.User{OrganizationID: org.ID, Email: "a@b.c"})
+		})
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Nested calls join the outer transaction", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		txm := NewTxManager(db)
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		err := txm.WithinTransaction(ctx, func(ctx context.Context) error {
+			return txm.WithinTransaction(ctx, func(ctx context.Context) error {
+				return errors.New("inner failure")
+			})
+		})
+		assert.EqualError(t, err, "inner failure")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
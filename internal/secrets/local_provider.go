@@ -0,0 +1,126 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ProviderConfig selects and configures the KeyProvider built by NewKeyProvider.
+type ProviderConfig struct {
+	// Provider names the backend; only "local" is available today.
+	Provider string
+	// MasterKeys is a keyring of comma-separated "version:base64key" entries.
+	MasterKeys string
+	// MasterKeyFile, when set, holds the keyring instead of MasterKeys.
+	MasterKeyFile string
+	// CurrentKeyVersion selects the key that wraps new data keys; it defaults
+	// to the last key of the keyring.
+	CurrentKeyVersion string
+}
+
+// NewKeyProvider builds the KeyProvider described by cfg.
+func NewKeyProvider(cfg ProviderConfig) (KeyProvider, error) {
+	switch cfg.Provider {
+	case "", "local":
+		spec := cfg.MasterKeys
+		if cfg.MasterKeyFile != "" {
+			raw, err := os.ReadFile(cfg.MasterKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("read master key file: %w", err)
+			}
+			spec = string(raw)
+		}
+		return NewLocalKeyProviderFromSpec(spec, cfg.CurrentKeyVersion)
+	default:
+		return nil, fmt.Errorf("unsupported key provider %q", cfg.Provider)
+	}
+}
+
+// LocalKeyProvider keeps versioned AES-256 master keys in memory and wraps
+// data keys with AES-GCM. Retired versions stay in the keyring so that data
+// keys they wrapped can still be unwrapped.
+type LocalKeyProvider struct {
+	keys    map[string][]byte
+	current string
+}
+
+// NewLocalKeyProvider creates a provider over keys, wrapping with keys[current].
+func NewLocalKeyProvider(keys map[string][]byte, current string) (*LocalKeyProvider, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKeyVersion, current)
+	}
+	for version, key := range keys {
+		if len(key) != dataKeySize {
+			return nil, fmt.Errorf("master key %q must be %d bytes, got %d", version, dataKeySize, len(key))
+		}
+	}
+	return &LocalKeyProvider{keys: keys, current: current}, nil
+}
+
+// NewLocalKeyProviderFromSpec parses a keyring such as "v1:<base64>,v2:<base64>".
+// An empty current selects the last key listed.
+func NewLocalKeyProviderFromSpec(spec, current string) (*LocalKeyProvider, error) {
+	keys := make(map[string][]byte)
+	last := ""
+	for _, entry := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		version, encoded, ok := strings.Cut(entry, ":")
+		if !ok || version == "" {
+			return nil, errors.New("master keys must be listed as version:base64key")
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("master key %q is not valid base64: %w", version, err)
+		}
+		if _, dup := keys[version]; dup {
+			return nil, fmt.Errorf("master key %q is listed twice", version)
+		}
+		keys[version] = key
+		last = version
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no master key configured")
+	}
+	if current == "" {
+		current = last
+	}
+	return NewLocalKeyProvider(keys, current)
+}
+
+func (p *LocalKeyProvider) CurrentKeyVersion(context.Context) (string, error) {
+	return p.current, nil
+}
+
+func (p *LocalKeyProvider) WrapKey(_ context.Context, dataKey []byte) ([]byte, string, error) {
+	nonce, ciphertext, err := encryptGCM(p.keys[p.current], dataKey, []byte(p.current))
+	if err != nil {
+		return nil, "", err
+	}
+	return append(nonce, ciphertext...), p.current, nil
+}
+
+func (p *LocalKeyProvider) UnwrapKey(_ context.Context, keyVersion string, wrapped []byte) ([]byte, error) {
+	key, ok := p.keys[keyVersion]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKeyVersion, keyVersion)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < gcm.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	dataKey, err := gcm.Open(nil, wrapped[:gcm.NonceSize()], wrapped[gcm.NonceSize():], []byte(keyVersion))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return dataKey, nil
+}
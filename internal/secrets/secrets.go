@@ -0,0 +1,176 @@
+// Package secrets implements envelope encryption: every secret is sealed with
+// its own AES-256-GCM data key, and that data key is wrapped by a master key
+// held by a KeyProvider. Master keys never leave the provider, so the provider
+// can be swapped for a KMS or a Vault transit engine without touching callers.
+package secrets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	// dataKeySize selects AES-256 for data keys.
+	dataKeySize = 32
+	// algorithmAESGCM identifies the envelope format stored in sealed secrets.
+	algorithmAESGCM = "AES-256-GCM"
+)
+
+var (
+	ErrUnknownKeyVersion = errors.New("unknown master key version")
+	ErrDecrypt           = errors.New("secret could not be decrypted")
+)
+
+// KeyProvider wraps and unwraps data keys with master keys it never discloses.
+type KeyProvider interface {
+	// CurrentKeyVersion names the master key WrapKey currently uses.
+	CurrentKeyVersion(ctx context.Context) (string, error)
+	// WrapKey encrypts dataKey under the current master key and returns the
+	// version of that key, to be passed back to UnwrapKey.
+	WrapKey(ctx context.Context, dataKey []byte) (wrapped []byte, keyVersion string, err error)
+	// UnwrapKey decrypts a data key wrapped under the given master key version.
+	UnwrapKey(ctx context.Context, keyVersion string, wrapped []byte) ([]byte, error)
+}
+
+// envelope is the serialized form of a sealed secret.
+type envelope struct {
+	Algorithm  string `json:"alg"`
+	WrappedKey []byte `json:"key"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ct"`
+}
+
+// Cipher seals and opens secrets using data keys wrapped by a KeyProvider.
+type Cipher struct {
+	provider KeyProvider
+}
+
+// NewCipher creates a Cipher backed by provider.
+func NewCipher(provider KeyProvider) *Cipher {
+	return &Cipher{provider: provider}
+}
+
+// Seal encrypts plaintext under a fresh data key. aad binds the ciphertext to
+// its owner, e.g. a resource ID: Open fails unless given the same aad.
+// It returns the sealed envelope and the master key version that wrapped the data key.
+func (c *Cipher) Seal(ctx context.Context, plaintext, aad []byte) (sealed, keyVersion string, err error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", "", err
+	}
+	defer clear(dataKey)
+
+	nonce, ciphertext, err := encryptGCM(dataKey, plaintext, aad)
+	if err != nil {
+		return "", "", err
+	}
+	wrapped, keyVersion, err := c.provider.WrapKey(ctx, dataKey)
+	if err != nil {
+		return "", "", fmt.Errorf("wrap data key: %w", err)
+	}
+
+	raw, err := json.Marshal(envelope{Algorithm: algorithmAESGCM, WrappedKey: wrapped, Nonce: nonce, Ciphertext: ciphertext})
+	if err != nil {
+		return "", "", err
+	}
+	return string(raw), keyVersion, nil
+}
+
+// Open decrypts a secret produced by Seal.
+func (c *Cipher) Open(ctx context.Context, sealed, keyVersion string, aad []byte) ([]byte, error) {
+	var env envelope
+	if err := json.Unmarshal([]byte(sealed), &env); err != nil {
+		return nil, fmt.Errorf("%w: malformed envelope", ErrDecrypt)
+	}
+	if env.Algorithm != algorithmAESGCM {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrDecrypt, env.Algorithm)
+	}
+
+	dataKey, err := c.provider.UnwrapKey(ctx, keyVersion, env.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(dataKey)
+
+	plaintext, err := decryptGCM(dataKey, env.Nonce, env.Ciphertext, aad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// Rewrap re-encrypts the data key of a sealed secret under the current master
+// key, leaving the secret itself untouched. It is the unit of work of master
+// key rotation and never exposes the plaintext.
+func (c *Cipher) Rewrap(ctx context.Context, sealed, keyVersion string) (resealed, newKeyVersion string, err error) {
+	var env envelope
+	if err := json.Unmarshal([]byte(sealed), &env); err != nil {
+		return "", "", fmt.Errorf("%w: malformed envelope", ErrDecrypt)
+	}
+
+	dataKey, err := c.provider.UnwrapKey(ctx, keyVersion, env.WrappedKey)
+	if err != nil {
+		return "", "", err
+	}
+	defer clear(dataKey)
+
+	env.WrappedKey, newKeyVersion, err = c.provider.WrapKey(ctx, dataKey)
+	if err != nil {
+		return "", "", fmt.Errorf("wrap data key: %w", err)
+	}
+	raw, err := json.Marshal(env)
+	if err != nil {
+		return "", "", err
+	}
+	return string(raw), newKeyVersion, nil
+}
+
+// CurrentKeyVersion names the master key that wraps newly sealed secrets.
+func (c *Cipher) CurrentKeyVersion(ctx context.Context) (string, error) {
+	return c.provider.CurrentKeyVersion(ctx)
+}
+
+// GenerateMasterKey returns a random key suitable for a LocalKeyProvider keyring.
+func GenerateMasterKey() ([]byte, error) {
+	key := make([]byte, dataKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func encryptGCM(key, plaintext, aad []byte) (nonce, ciphertext []byte, err error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return nonce, gcm.Seal(nil, nonce, plaintext, aad), nil
+}
+
+func decryptGCM(key, nonce, ciphertext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	return gcm.Open(nil, nonce, ciphertext, aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,152 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, dataKeySize)
+}
+
+func TestCipher_SealOpen(t *testing.T) {
+	ctx := context.Background()
+	provider, err := NewLocalKeyProvider(map[string][]byte{"v1": testKey(1)}, "v1")
+	require.NoError(t, err)
+	c := NewCipher(provider)
+	plaintext := []byte(`{"username":"app","password":"s3cret"}`)
+	aad := []byte("resource-1")
+
+	sealed, version, err := c.Seal(ctx, plaintext, aad)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", version)
+	assert.NotContains(t, sealed, "s3cret")
+
+	t.Run("Round Trip", func(t *testing.T) {
+		opened, err := c.Open(ctx, sealed, version, aad)
+		assert.NoError(t, err)
+		assert.Equal(t, plaintext, opened)
+	})
+
+	t.Run("Fresh Data Key Per Secret", func(t *testing.T) {
+		again, _, err := c.Seal(ctx, plaintext, aad)
+		assert.NoError(t, err)
+		assert.NotEqual(t, sealed, again)
+	})
+
+	t.Run("Other Owner", func(t *testing.T) {
+		_, err := c.Open(ctx, sealed, version, []byte("resource-2"))
+		assert.ErrorIs(t, err, ErrDecrypt)
+	})
+
+	t.Run("Unknown Key Version", func(t *testing.T) {
+		_, err := c.Open(ctx, sealed, "v9", aad)
+		assert.ErrorIs(t, err, ErrUnknownKeyVersion)
+	})
+
+	t.Run("Tampered Envelope", func(t *testing.T) {
+		_, err := c.Open(ctx, "not json", version, aad)
+		assert.ErrorIs(t, err, ErrDecrypt)
+	})
+
+	t.Run("Retired Key Still Opens", func(t *testing.T) {
+		rotated, err := NewLocalKeyProvider(map[string][]byte{"v1": testKey(1), "v2": testKey(2)}, "v2")
+		require.NoError(t, err)
+		opened, err := NewCipher(rotated).Open(ctx, sealed, "v1", aad)
+		assert.NoError(t, err)
+		assert.Equal(t, plaintext, opened)
+	})
+}
+
+func TestCipher_Rewrap(t *testing.T) {
+	ctx := context.Background()
+	oldProvider, err := NewLocalKeyProvider(map[string][]byte{"v1": testKey(1)}, "v1")
+	require.NoError(t, err)
+	sealed, _, err := NewCipher(oldProvider).Seal(ctx, []byte("s3cret"), []byte("resource-1"))
+	require.NoError(t, err)
+
+	rotated, err := NewLocalKeyProvider(map[string][]byte{"v1": testKey(1), "v2": testKey(2)}, "v2")
+	require.NoError(t, err)
+	c := NewCipher(rotated)
+
+	resealed, version, err := c.Rewrap(ctx, sealed, "v1")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", version)
+	assert.NotEqual(t, sealed, resealed)
+
+	t.Run("Opens With The New Version Only", func(t *testing.T) {
+		opened, err := c.Open(ctx, resealed, "v2", []byte("resource-1"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("s3cret"), opened)
+
+		_, err = c.Open(ctx, resealed, "v1", []byte("resource-1"))
+		assert.ErrorIs(t, err, ErrDecrypt)
+	})
+
+	t.Run("Survives Retiring The Old Key", func(t *testing.T) {
+		v2Only, err := NewLocalKeyProvider(map[string][]byte{"v2": testKey(2)}, "v2")
+		require.NoError(t, err)
+		opened, err := NewCipher(v2Only).Open(ctx, resealed, "v2", []byte("resource-1"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("s3cret"), opened)
+	})
+
+	t.Run("Wrong Source Version", func(t *testing.T) {
+		_, _, err := c.Rewrap(ctx, sealed, "v2")
+		assert.ErrorIs(t, err, ErrDecrypt)
+	})
+}
+
+func TestNewLocalKeyProviderFromSpec(t *testing.T) {
+	k1 := base64.StdEncoding.EncodeToString(testKey(1))
+	k2 := base64.StdEncoding.EncodeToString(testKey(2))
+
+	t.Run("Defaults To Last Key", func(t *testing.T) {
+		p, err := NewLocalKeyProviderFromSpec("v1:"+k1+", v2:"+k2, "")
+		require.NoError(t, err)
+		_, version, err := p.WrapKey(context.Background(), testKey(9))
+		assert.NoError(t, err)
+		assert.Equal(t, "v2", version)
+	})
+
+	t.Run("Explicit Current", func(t *testing.T) {
+		p, err := NewLocalKeyProviderFromSpec("v1:"+k1+",v2:"+k2, "v1")
+		require.NoError(t, err)
+		assert.Equal(t, "v1", p.current)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, spec := range []string{"", "v1", "v1:%%%", "v1:" + base64.StdEncoding.EncodeToString([]byte("short")), "v1:" + k1 + ",v1:" + k2} {
+			_, err := NewLocalKeyProviderFromSpec(spec, "")
+			assert.Error(t, err, spec)
+		}
+	})
+
+	t.Run("Unknown Current", func(t *testing.T) {
+		_, err := NewLocalKeyProviderFromSpec("v1:"+k1, "v2")
+		assert.ErrorIs(t, err, ErrUnknownKeyVersion)
+	})
+}
+
+func TestNewKeyProvider(t *testing.T) {
+	spec := "v1:" + base64.StdEncoding.EncodeToString(testKey(1)) + "\n"
+
+	t.Run("From File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "keyring")
+		require.NoError(t, os.WriteFile(path, []byte(spec), 0o600))
+		_, err := NewKeyProvider(ProviderConfig{MasterKeyFile: path})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Unsupported Provider", func(t *testing.T) {
+		_, err := NewKeyProvider(ProviderConfig{Provider: "vault", MasterKeys: spec})
+		assert.Error(t, err)
+	})
+}
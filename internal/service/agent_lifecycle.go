@@ -0,0 +1,31 @@
+package service
+
+import (
+	"agentXmap/internal/domain"
+	"slices"
+)
+
+// agentTransitions is the agent lifecycle state machine: the statuses each
+// status may move to. Deprecated is terminal.
+var agentTransitions = map[domain.AgentStatus][]domain.AgentStatus{
+	domain.AgentStatusActive:      {domain.AgentStatusInactive, domain.AgentStatusMaintenance, domain.AgentStatusDeprecated},
+	domain.AgentStatusInactive:    {domain.AgentStatusActive, domain.AgentStatusMaintenance, domain.AgentStatusDeprecated},
+	domain.AgentStatusMaintenance: {domain.AgentStatusActive, domain.AgentStatusInactive, domain.AgentStatusDeprecated},
+	domain.AgentStatusDeprecated:  {},
+}
+
+// CanTransition reports whether an agent may move from one status to another.
+func CanTransition(from, to domain.AgentStatus) bool {
+	return slices.Contains(agentTransitions[from], to)
+}
+
+// isKnownStatus reports whether status is part of the lifecycle.
+func isKnownStatus(status domain.AgentStatus) bool {
+	_, ok := agentTransitions[status]
+	return ok
+}
+
+// transitionNeedsReason reports whether entering status must be justified.
+func transitionNeedsReason(to domain.AgentStatus) bool {
+	return to == domain.AgentStatusMaintenance || to == domain.AgentStatusDeprecated
+}
@@ -2,14 +2,57 @@ package service
 
 import (
 	"agentXmap/internal/domain"
+	"agentXmap/internal/policy"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+var (
+	ErrAgentNotFound          = errors.New("agent not found")
+	ErrAgentNameRequired      = errors.New("agent name is required")
+	ErrAgentVersionNotFound   = errors.New("agent version not found")
+	ErrRollbackReasonRequired = errors.New("rollback reason is required")
+
+	ErrChangeReasonRequired    = errors.New("a reason is required to change a high-risk agent")
+	ErrChangeRequestNotFound   = errors.New("change request not found")
+	ErrChangeRequestNotPending = errors.New("change request is no longer pending")
+	ErrChangeRequestStale      = errors.New("the agent's configuration changed since the request was made")
+	ErrSelfApproval            = errors.New("change requests must be approved by someone other than the requester")
+	ErrInvalidRiskLevel        = errors.New("invalid risk level")
+
+	ErrInvalidAgentStatus         = errors.New("invalid agent status")
+	ErrInvalidTransition          = errors.New("invalid status transition")
+	ErrTransitionReasonRequired   = errors.New("a reason is required for this status transition")
+	ErrValidCertificationRequired = errors.New("reactivation requires valid certifications")
+	ErrAgentStatusChanged         = errors.New("agent status was changed concurrently")
+	ErrAgentChanged               = errors.New("agent was changed concurrently")
+	ErrAgentDeprecated            = errors.New("deprecated agents cannot be modified")
+)
+
+const (
+	// auditEntityAgent is the SystemAuditLog entity type of agents.
+	auditEntityAgent = "agent"
+	// auditEntityChangeRequest is the SystemAuditLog entity type of change requests.
+	auditEntityChangeRequest = "agent_change_request"
+)
+
+// maxReasonLength mirrors agent_versions.reason_for_change VARCHAR(255).
+const maxReasonLength = 255
+
+// VersionDiff lists the configuration changes between two versions of an agent.
+type VersionDiff struct {
+	AgentID     uuid.UUID      `json:"agent_id"`
+	FromVersion int            `json:"from_version" example:"1"`
+	ToVersion   int            `json:"to_version" example:"3"`
+	Changes     []ConfigChange `json:"changes"`
+}
+
 // AgentService defines the interface for agent management.
 type AgentService interface {
 	CreateAgent(ctx context.Context, orgID, userID uuid.UUID, name string, config json.RawMessage) (*domain.Agent, error)
@@ -23,76 +66,157 @@ type AgentService interface {
 	GetAgentLLMs(ctx context.Context, agentID uuid.UUID) ([]domain.AgentLLM, error)
 	ListAssignedApplications(ctx context.Context, agentID uuid.UUID) ([]domain.Application, error)
 	ListAgentCertifications(ctx context.Context, agentID uuid.UUID) ([]domain.Certification, error)
-	UpdateAgent(ctx context.Context, id, userID uuid.UUID, name string, config json.RawMessage, status domain.AgentStatus) (*domain.Agent, error)
+	// UpdateAgent applies the change, except for configuration changes of high-risk
+	// agents, which are returned as a pending change request instead. Names do not
+	// alter an agent's behaviour and are renamed without approval.
+	// The status is changed through TransitionAgent only.
+	UpdateAgent(ctx context.Context, id, userID uuid.UUID, name string, config json.RawMessage, reason string) (*domain.Agent, *domain.AgentChangeRequest, error)
+	TransitionAgent(ctx context.Context, agentID, userID uuid.UUID, to domain.AgentStatus, reason string) (*domain.Agent, error)
+	ListStatusTransitions(ctx context.Context, agentID uuid.UUID) ([]domain.AgentStatusTransition, error)
 	DeleteAgent(ctx context.Context, id uuid.UUID) error
+	ListVersions(ctx context.Context, agentID uuid.UUID, limit, offset int) ([]domain.AgentVersion, int64, error)
+	DiffVersions(ctx context.Context, agentID uuid.UUID, fromVersion, toVersion int) (*VersionDiff, error)
+	RollbackAgent(ctx context.Context, agentID, userID uuid.UUID, versionNumber int, reason string) (*domain.Agent, *domain.AgentChangeRequest, error)
+	SetRiskLevel(ctx context.Context, agentID, userID uuid.UUID, level domain.AgentRiskLevel) (*domain.Agent, error)
+	ListChangeRequests(ctx context.Context, agentID uuid.UUID, status domain.ChangeRequestStatus) ([]domain.AgentChangeRequest, error)
+	GetChangeRequest(ctx context.Context, id uuid.UUID) (*domain.AgentChangeRequest, error)
+	ApproveChangeRequest(ctx context.Context, id, reviewerID uuid.UUID, comment string) (*domain.AgentChangeRequest, error)
+	RejectChangeRequest(ctx context.Context, id, reviewerID uuid.UUID, comment string) (*domain.AgentChangeRequest, error)
 }
 
 type DefaultAgentService struct {
-	agentRepo domain.AgentRepository
+	agentRepo    domain.AgentRepository
+	txManager    domain.TxManager
+	auditService AuditService
+	authz        *policy.Authorizer
 }
 
 // NewAgentService creates a new instance of DefaultAgentService.
-func NewAgentService(agentRepo domain.AgentRepository) *DefaultAgentService {
+// Every operation is authorized against the Principal carried by ctx.
+func NewAgentService(agentRepo domain.AgentRepository, txManager domain.TxManager, auditService AuditService) *DefaultAgentService {
 	return &DefaultAgentService{
-		agentRepo: agentRepo,
+		agentRepo:    agentRepo,
+		txManager:    txManager,
+		auditService: auditService,
+		authz:        policy.NewAuthorizer(agentRepo),
 	}
 }
 
 func (s *DefaultAgentService) CreateAgent(ctx context.Context, orgID, userID uuid.UUID, name string, config json.RawMessage) (*domain.Agent, error) {
+	if err := policy.Authorize(ctx, policy.ResourceAgent, policy.ActionCreate); err != nil {
+		return nil, err
+	}
+	name = strings.TrimSpace(name)
 	if name == "" {
-		return nil, errors.New("agent name is required")
+		return nil, ErrAgentNameRequired
 	}
 
 	agent := &domain.Agent{
 		OrganizationID: orgID,
 		Name:           name,
 		Status:         domain.AgentStatusActive,
+		RiskLevel:      domain.AgentRiskLevelMinimal,
 		Configuration:  config,
 		CreatedBy:      &userID,
 		UpdatedBy:      &userID,
 	}
 
-	if err := s.agentRepo.Create(ctx, agent); err != nil {
-		return nil, err
-	}
-
-	// Create initial version
-	version := &domain.AgentVersion{
-		AgentID:               agent.ID,
-		VersionNumber:         1,
-		ConfigurationSnapshot: config,
-		ReasonForChange:       "Initial creation",
-		CreatedBy:             &userID,
-	}
+	// The agent and its initial version are created atomically.
+	err := s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.agentRepo.Create(ctx, agent); err != nil {
+			return err
+		}
 
-	if err := s.agentRepo.CreateVersion(ctx, version); err != nil {
-		// Log error but generally agent is created.
-		// Ideally transactional.
+		version := &domain.AgentVersion{
+			AgentID:               agent.ID,
+			VersionNumber:         1,
+			ConfigurationSnapshot: config,
+			ReasonForChange:       "Initial creation",
+			CreatedBy:             &userID,
+		}
+		if err := s.agentRepo.CreateVersion(ctx, version); err != nil {
+			return err
+		}
+		return s.auditAgent(ctx, agent, domain.AuditActionCreate, nil)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return agent, nil
 }
 
 func (s *DefaultAgentService) GetAgent(ctx context.Context, id uuid.UUID) (*domain.Agent, error) {
+	if err := s.authz.AuthorizeAgent(ctx, policy.ActionRead, id); err != nil {
+		return nil, err
+	}
 	agent, err := s.agentRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 	if agent == nil {
-		return nil, errors.New("agent not found")
+		return nil, ErrAgentNotFound
 	}
 	return agent, nil
 }
 
 func (s *DefaultAgentService) ListAgents(ctx context.Context, orgID uuid.UUID) ([]domain.Agent, error) {
+	restricted, userID, err := s.readScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if restricted {
+		return s.listAssignedInOrg(ctx, userID, orgID, func(domain.Agent) bool { return true })
+	}
 	return s.agentRepo.ListByOrg(ctx, orgID)
 }
 
 func (s *DefaultAgentService) ListAgentsByStatus(ctx context.Context, orgID uuid.UUID, status domain.AgentStatus) ([]domain.Agent, error) {
+	restricted, userID, err := s.readScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if restricted {
+		return s.listAssignedInOrg(ctx, userID, orgID, func(a domain.Agent) bool { return a.Status == status })
+	}
 	return s.agentRepo.ListByStatus(ctx, orgID, status)
 }
 
+// readScope reports whether the caller may only read agents assigned to them.
+func (s *DefaultAgentService) readScope(ctx context.Context) (restricted bool, userID uuid.UUID, err error) {
+	p, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return false, uuid.Nil, policy.ErrUnauthenticated
+	}
+	switch policy.Decide(p.Role, policy.ResourceAgent, policy.ActionRead) {
+	case policy.Allow:
+		return false, p.UserID, nil
+	case policy.AllowIfAssigned:
+		return true, p.UserID, nil
+	default:
+		return false, uuid.Nil, policy.ErrForbidden
+	}
+}
+
+// listAssignedInOrg returns the agents of orgID assigned to userID that satisfy keep.
+func (s *DefaultAgentService) listAssignedInOrg(ctx context.Context, userID, orgID uuid.UUID, keep func(domain.Agent) bool) ([]domain.Agent, error) {
+	assigned, err := s.agentRepo.GetAssignedAgents(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	agents := make([]domain.Agent, 0, len(assigned))
+	for _, a := range assigned {
+		if a.OrganizationID == orgID && keep(a) {
+			agents = append(agents, a)
+		}
+	}
+	return agents, nil
+}
+
 func (s *DefaultAgentService) GetActiveMonthlyCost(ctx context.Context, orgID uuid.UUID) (float64, error) {
+	if err := policy.Authorize(ctx, policy.ResourceBilling, policy.ActionRead); err != nil {
+		return 0, err
+	}
 	agents, err := s.agentRepo.ListByStatus(ctx, orgID, domain.AgentStatusActive)
 	if err != nil {
 		return 0, err
@@ -123,41 +247,71 @@ func (s *DefaultAgentService) GetActiveMonthlyCost(ctx context.Context, orgID uu
 	return totalCost, nil
 }
 
+// ListAgentResources lists the resources the agent has been granted access to.
 func (s *DefaultAgentService) ListAgentResources(ctx context.Context, agentID uuid.UUID) ([]domain.Resource, error) {
-	// First check if agent exists?
-	// The repo query will just return empty list if agent doesn't exist or has no resources.
-	// But good practice maybe to check agent existence for 404?
-	// For now, let's just return what the repo gives.
+	if err := s.authz.AuthorizeAgent(ctx, policy.ActionRead, agentID); err != nil {
+		return nil, err
+	}
 	return s.agentRepo.GetResources(ctx, agentID)
 }
 
 func (s *DefaultAgentService) ListAssignedUsers(ctx context.Context, agentID uuid.UUID) ([]domain.User, error) {
+	if err := s.authz.AuthorizeAgent(ctx, policy.ActionRead, agentID); err != nil {
+		return nil, err
+	}
 	return s.agentRepo.GetAssignedUsers(ctx, agentID)
 }
 
 func (s *DefaultAgentService) ListAssignedAgents(ctx context.Context, userID uuid.UUID) ([]domain.Agent, error) {
+	p, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, policy.ErrUnauthenticated
+	}
+	// Anyone may list their own assignments; looking at someone else's needs full read access.
+	if p.UserID != userID && policy.Decide(p.Role, policy.ResourceAgent, policy.ActionRead) != policy.Allow {
+		return nil, policy.ErrForbidden
+	}
 	return s.agentRepo.GetAssignedAgents(ctx, userID)
 }
 
 func (s *DefaultAgentService) GetAgentLLMs(ctx context.Context, agentID uuid.UUID) ([]domain.AgentLLM, error) {
+	if err := s.authz.AuthorizeAgent(ctx, policy.ActionRead, agentID); err != nil {
+		return nil, err
+	}
 	return s.agentRepo.GetAssignedLLMs(ctx, agentID)
 }
 
 func (s *DefaultAgentService) ListAssignedApplications(ctx context.Context, agentID uuid.UUID) ([]domain.Application, error) {
+	if err := s.authz.AuthorizeAgent(ctx, policy.ActionRead, agentID); err != nil {
+		return nil, err
+	}
 	return s.agentRepo.GetAssignedApplications(ctx, agentID)
 }
 
 func (s *DefaultAgentService) ListAgentCertifications(ctx context.Context, agentID uuid.UUID) ([]domain.Certification, error) {
+	if err := s.authz.AuthorizeAgent(ctx, policy.ActionRead, agentID); err != nil {
+		return nil, err
+	}
 	return s.agentRepo.GetCertifications(ctx, agentID)
 }
 
-func (s *DefaultAgentService) UpdateAgent(ctx context.Context, id, userID uuid.UUID, name string, config json.RawMessage, status domain.AgentStatus) (*domain.Agent, error) {
+func (s *DefaultAgentService) UpdateAgent(ctx context.Context, id, userID uuid.UUID, name string, config json.RawMessage, reason string) (*domain.Agent, *domain.AgentChangeRequest, error) {
+	if err := s.authz.AuthorizeAgent(ctx, policy.ActionUpdate, id); err != nil {
+		return nil, nil, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, nil, ErrAgentNameRequired
+	}
 	agent, err := s.agentRepo.GetByID(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if agent == nil {
-		return nil, errors.New("agent not found")
+		return nil, nil, ErrAgentNotFound
+	}
+	if agent.Status == domain.AgentStatusDeprecated {
+		return nil, nil, ErrAgentDeprecated
 	}
 
 	// Check if config changed
@@ -165,53 +319,552 @@ func (s *DefaultAgentService) UpdateAgent(ctx context.Context, id, userID uuid.U
 	if string(agent.Configuration) != string(config) {
 		configChanged = true
 	}
+	needsApproval := configChanged && requiresApproval(agent)
+	if needsApproval && reason == "" {
+		return nil, nil, ErrChangeReasonRequired
+	}
 
+	before := agentSnapshot(agent)
 	agent.Name = name
-	agent.Status = status
-	agent.Configuration = config
+	if !needsApproval {
+		agent.Configuration = config
+	}
 	agent.UpdatedBy = &userID
-	agent.UpdatedAt = time.Now()
-
-	if err := s.agentRepo.Update(ctx, agent); err != nil {
-		return nil, err
-	}
-
-	if configChanged {
-		// Calculate new version number
-		newVersionNum := 1
-		if len(agent.Versions) > 0 {
-			// Assuming versions are loaded or ordered, find max.
-			// Ideally repo should provide GetLatestVersion or we just count + 1 if we loaded all (which might be heavy).
-			// Start simple: existing versions list might be partial if not preload all.
-			// Ideally we query DB for max version.
-			// For this iteration, let's assume we implement a logic to get items.
-			// Or we just increment based on count if preloaded?
-			// Let's rely on versions being preloaded in GetByID (as per repo implementation).
-			for _, v := range agent.Versions {
-				if v.VersionNumber >= newVersionNum {
-					newVersionNum = v.VersionNumber + 1
-				}
-			}
-		} else {
-			// Fallback if no versions loaded, assume 2 (since 1 was initial)?
-			// Or assume 1 + 1.
-			newVersionNum = 2
+	agent.UpdatedAt = time.Now().UTC()
+
+	var changeRequest *domain.AgentChangeRequest
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.updateAgent(ctx, agent); err != nil {
+			return err
+		}
+		if err := s.auditAgent(ctx, agent, domain.AuditActionUpdate, before); err != nil {
+			return err
+		}
+		if !configChanged {
+			return nil
+		}
+		if needsApproval {
+			var err error
+			changeRequest, err = s.proposeChange(ctx, agent, userID, config, reason)
+			return err
 		}
 
+		if reason == "" {
+			reason = "Configuration updated"
+		}
+		// The repository allocates the version number under a row lock.
 		version := &domain.AgentVersion{
 			AgentID:               agent.ID,
-			VersionNumber:         newVersionNum,
 			ConfigurationSnapshot: config,
-			ReasonForChange:       "Configuration updated",
+			ReasonForChange:       truncateReason(reason),
 			CreatedBy:             &userID,
 		}
-		_ = s.agentRepo.CreateVersion(ctx, version)
+		return s.agentRepo.CreateNextVersion(ctx, version)
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return agent, nil
+	return agent, changeRequest, nil
 }
 
 func (s *DefaultAgentService) DeleteAgent(ctx context.Context, id uuid.UUID) error {
-	// Soft delete is handled by Repository/GORM
-	return s.agentRepo.Delete(ctx, id)
+	if err := s.authz.AuthorizeAgent(ctx, policy.ActionDelete, id); err != nil {
+		return err
+	}
+	agent, err := s.agentRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if agent == nil {
+		return ErrAgentNotFound
+	}
+	return s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Soft delete is handled by Repository/GORM
+		if err := s.agentRepo.Delete(ctx, id); err != nil {
+			return err
+		}
+		return logMutation(ctx, s.auditService, agent.OrganizationID, auditEntityAgent, agent.ID, domain.AuditActionDelete, agentSnapshot(agent), nil)
+	})
+}
+
+// ListVersions returns one page of the agent's configuration history, newest first.
+func (s *DefaultAgentService) ListVersions(ctx context.Context, agentID uuid.UUID, limit, offset int) ([]domain.AgentVersion, int64, error) {
+	if err := s.authz.AuthorizeAgent(ctx, policy.ActionRead, agentID); err != nil {
+		return nil, 0, err
+	}
+	return s.agentRepo.ListVersions(ctx, agentID, limit, offset)
+}
+
+// DiffVersions compares the configuration snapshots of two versions of an agent.
+func (s *DefaultAgentService) DiffVersions(ctx context.Context, agentID uuid.UUID, fromVersion, toVersion int) (*VersionDiff, error) {
+	if err := s.authz.AuthorizeAgent(ctx, policy.ActionRead, agentID); err != nil {
+		return nil, err
+	}
+
+	from, err := s.getVersion(ctx, agentID, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.getVersion(ctx, agentID, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := DiffConfigurations(from.ConfigurationSnapshot, to.ConfigurationSnapshot)
+	if err != nil {
+		return nil, err
+	}
+	return &VersionDiff{AgentID: agentID, FromVersion: fromVersion, ToVersion: toVersion, Changes: changes}, nil
+}
+
+// RollbackAgent restores the configuration of a previous version. History is
+// never rewritten: the restored snapshot is recorded as a new version. For
+// high-risk agents the rollback is returned as a pending change request.
+func (s *DefaultAgentService) RollbackAgent(ctx context.Context, agentID, userID uuid.UUID, versionNumber int, reason string) (*domain.Agent, *domain.AgentChangeRequest, error) {
+	if err := s.authz.AuthorizeAgent(ctx, policy.ActionUpdate, agentID); err != nil {
+		return nil, nil, err
+	}
+	if reason == "" {
+		return nil, nil, ErrRollbackReasonRequired
+	}
+
+	agent, err := s.agentRepo.GetByID(ctx, agentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if agent == nil {
+		return nil, nil, ErrAgentNotFound
+	}
+	if agent.Status == domain.AgentStatusDeprecated {
+		return nil, nil, ErrAgentDeprecated
+	}
+	target, err := s.getVersion(ctx, agentID, versionNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reasonForChange := truncateReason(fmt.Sprintf("Rollback to version %d: %s", versionNumber, reason))
+
+	if requiresApproval(agent) {
+		var changeRequest *domain.AgentChangeRequest
+		err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			changeRequest, err = s.proposeChange(ctx, agent, userID, target.ConfigurationSnapshot, reasonForChange)
+			return err
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return agent, changeRequest, nil
+	}
+
+	before := agentSnapshot(agent)
+	agent.Configuration = target.ConfigurationSnapshot
+	agent.UpdatedBy = &userID
+	agent.UpdatedAt = time.Now().UTC()
+
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.updateAgent(ctx, agent); err != nil {
+			return err
+		}
+		if err := s.agentRepo.CreateNextVersion(ctx, &domain.AgentVersion{
+			AgentID:               agent.ID,
+			ConfigurationSnapshot: target.ConfigurationSnapshot,
+			ReasonForChange:       reasonForChange,
+			CreatedBy:             &userID,
+		}); err != nil {
+			return err
+		}
+		return s.auditAgent(ctx, agent, domain.AuditActionUpdate, before)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return agent, nil, nil
+}
+
+func (s *DefaultAgentService) getVersion(ctx context.Context, agentID uuid.UUID, versionNumber int) (*domain.AgentVersion, error) {
+	version, err := s.agentRepo.GetVersion(ctx, agentID, versionNumber)
+	if err != nil {
+		return nil, err
+	}
+	if version == nil {
+		return nil, ErrAgentVersionNotFound
+	}
+	return version, nil
+}
+
+// TransitionAgent moves an agent through its lifecycle and records the
+// transition. Entering maintenance or deprecation requires a reason,
+// reactivation requires valid certifications, and deprecation, being terminal,
+// is reserved to the roles allowed to delete agents.
+func (s *DefaultAgentService) TransitionAgent(ctx context.Context, agentID, userID uuid.UUID, to domain.AgentStatus, reason string) (*domain.Agent, error) {
+	action := policy.ActionUpdate
+	if to == domain.AgentStatusDeprecated {
+		action = policy.ActionDelete
+	}
+	if err := s.authz.AuthorizeAgent(ctx, action, agentID); err != nil {
+		return nil, err
+	}
+	if !isKnownStatus(to) {
+		return nil, ErrInvalidAgentStatus
+	}
+
+	agent, err := s.agentRepo.GetByID(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if agent == nil {
+		return nil, ErrAgentNotFound
+	}
+
+	from := agent.Status
+	if !CanTransition(from, to) {
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, from, to)
+	}
+	if reason == "" && transitionNeedsReason(to) {
+		return nil, ErrTransitionReasonRequired
+	}
+	if to == domain.AgentStatusActive {
+		if err := s.checkCertifications(ctx, agentID); err != nil {
+			return nil, err
+		}
+	}
+
+	before := agentSnapshot(agent)
+	agent.Status = to
+	agent.UpdatedBy = &userID
+	agent.UpdatedAt = time.Now().UTC()
+
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		moved, err := s.agentRepo.UpdateStatus(ctx, agentID, from, to, userID)
+		if err != nil {
+			return err
+		}
+		if !moved {
+			return ErrAgentStatusChanged
+		}
+		if err := s.agentRepo.CreateStatusTransition(ctx, &domain.AgentStatusTransition{
+			AgentID:    agentID,
+			FromStatus: from,
+			ToStatus:   to,
+			Reason:     truncateReason(reason),
+			ChangedBy:  &userID,
+		}); err != nil {
+			return err
+		}
+		return s.auditAgent(ctx, agent, domain.AuditActionUpdate, before)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return agent, nil
+}
+
+// ListStatusTransitions returns the lifecycle history of an agent, newest first.
+func (s *DefaultAgentService) ListStatusTransitions(ctx context.Context, agentID uuid.UUID) ([]domain.AgentStatusTransition, error) {
+	if err := s.authz.AuthorizeAgent(ctx, policy.ActionRead, agentID); err != nil {
+		return nil, err
+	}
+	return s.agentRepo.ListStatusTransitions(ctx, agentID)
+}
+
+// checkCertifications requires the agent to hold at least one certification
+// and none that has expired.
+func (s *DefaultAgentService) checkCertifications(ctx context.Context, agentID uuid.UUID) error {
+	links, err := s.agentRepo.GetAgentCertifications(ctx, agentID)
+	if err != nil {
+		return err
+	}
+	if len(links) == 0 {
+		return fmt.Errorf("%w: the agent has no certification", ErrValidCertificationRequired)
+	}
+	now := time.Now().UTC()
+	for _, link := range links {
+		if link.ExpiresAt != nil && !link.ExpiresAt.After(now) {
+			return fmt.Errorf("%w: certification %s expired on %s", ErrValidCertificationRequired, link.CertificationID, link.ExpiresAt.Format(time.DateOnly))
+		}
+	}
+	return nil
+}
+
+// truncateReason fits reason into agent_versions.reason_for_change without splitting a rune.
+func truncateReason(reason string) string {
+	if runes := []rune(reason); len(runes) > maxReasonLength {
+		return string(runes[:maxReasonLength])
+	}
+	return reason
+}
+
+// requiresApproval reports whether configuration changes to agent must be reviewed first.
+func requiresApproval(agent *domain.Agent) bool {
+	return agent.RiskLevel == domain.AgentRiskLevelHigh
+}
+
+// SetRiskLevel classifies an agent. Only admins may do so, since lowering the
+// level lifts the approval requirement on configuration changes.
+func (s *DefaultAgentService) SetRiskLevel(ctx context.Context, agentID, userID uuid.UUID, level domain.AgentRiskLevel) (*domain.Agent, error) {
+	if err := policy.Authorize(ctx, policy.ResourceAgentRiskLevel, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+	switch level {
+	case domain.AgentRiskLevelMinimal, domain.AgentRiskLevelLimited, domain.AgentRiskLevelHigh:
+	default:
+		return nil, ErrInvalidRiskLevel
+	}
+
+	agent, err := s.agentRepo.GetByID(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if agent == nil {
+		return nil, ErrAgentNotFound
+	}
+	if agent.RiskLevel == level {
+		return agent, nil
+	}
+
+	before := agentSnapshot(agent)
+	from := agent.RiskLevel
+	agent.RiskLevel = level
+	agent.UpdatedBy = &userID
+	agent.UpdatedAt = time.Now().UTC()
+
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		moved, err := s.agentRepo.UpdateRiskLevel(ctx, agent.ID, from, level, userID)
+		if err != nil {
+			return err
+		}
+		if !moved {
+			return ErrAgentChanged
+		}
+		return s.auditAgent(ctx, agent, domain.AuditActionUpdate, before)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return agent, nil
+}
+
+// ListChangeRequests returns the change requests of an agent, newest first.
+// An empty status lists requests in every state.
+func (s *DefaultAgentService) ListChangeRequests(ctx context.Context, agentID uuid.UUID, status domain.ChangeRequestStatus) ([]domain.AgentChangeRequest, error) {
+	if err := policy.Authorize(ctx, policy.ResourceChangeRequest, policy.ActionRead); err != nil {
+		return nil, err
+	}
+	return s.agentRepo.ListChangeRequests(ctx, agentID, status)
+}
+
+func (s *DefaultAgentService) GetChangeRequest(ctx context.Context, id uuid.UUID) (*domain.AgentChangeRequest, error) {
+	if err := policy.Authorize(ctx, policy.ResourceChangeRequest, policy.ActionRead); err != nil {
+		return nil, err
+	}
+	return s.getChangeRequest(ctx, id)
+}
+
+// ApproveChangeRequest applies a pending change request: the agent takes the
+// proposed configuration and a new version is recorded. The requester cannot
+// approve their own request, and a request whose base version is no longer the
+// agent's latest fails with ErrChangeRequestStale; it can only be rejected.
+func (s *DefaultAgentService) ApproveChangeRequest(ctx context.Context, id, reviewerID uuid.UUID, comment string) (*domain.AgentChangeRequest, error) {
+	req, agent, err := s.pendingChangeRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.RequestedBy != nil && *req.RequestedBy == reviewerID {
+		return nil, ErrSelfApproval
+	}
+	if agent.Status == domain.AgentStatusDeprecated {
+		return nil, ErrAgentDeprecated
+	}
+
+	now := time.Now().UTC()
+	before := agentSnapshot(agent)
+	pending := changeRequestSnapshot(req)
+	agent.Configuration = req.ProposedConfiguration
+	agent.UpdatedBy = &reviewerID
+	agent.UpdatedAt = now
+
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.updateAgent(ctx, agent); err != nil {
+			return err
+		}
+		version := &domain.AgentVersion{
+			AgentID:               agent.ID,
+			ConfigurationSnapshot: req.ProposedConfiguration,
+			ReasonForChange:       req.Reason,
+			CreatedBy:             req.RequestedBy,
+		}
+		if err := s.agentRepo.CreateNextVersion(ctx, version); err != nil {
+			return err
+		}
+		// CreateNextVersion holds the agent row lock, so no version can slip in
+		// between this check and the commit.
+		if version.VersionNumber != req.BaseVersionNumber+1 {
+			return ErrChangeRequestStale
+		}
+
+		req.Status = domain.ChangeRequestStatusApproved
+		req.AppliedVersionID = &version.ID
+		if err := s.resolveChangeRequest(ctx, agent, req, pending, reviewerID, comment, now, domain.AuditActionApprove); err != nil {
+			return err
+		}
+		return s.auditAgent(ctx, agent, domain.AuditActionUpdate, before)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// RejectChangeRequest closes a pending change request without touching the agent.
+func (s *DefaultAgentService) RejectChangeRequest(ctx context.Context, id, reviewerID uuid.UUID, comment string) (*domain.AgentChangeRequest, error) {
+	req, agent, err := s.pendingChangeRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := changeRequestSnapshot(req)
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		req.Status = domain.ChangeRequestStatusRejected
+		return s.resolveChangeRequest(ctx, agent, req, pending, reviewerID, comment, time.Now().UTC(), domain.AuditActionReject)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// proposeChange records a pending change request for agent, based on its
+// latest version, and audits it. It must run inside a transaction.
+func (s *DefaultAgentService) proposeChange(ctx context.Context, agent *domain.Agent, userID uuid.UUID, config json.RawMessage, reason string) (*domain.AgentChangeRequest, error) {
+	base := 0
+	latest, err := s.agentRepo.GetLatestVersion(ctx, agent.ID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil {
+		base = latest.VersionNumber
+	}
+	req := &domain.AgentChangeRequest{
+		AgentID:               agent.ID,
+		ProposedConfiguration: config,
+		BaseVersionNumber:     base,
+		Reason:                truncateReason(reason),
+		Status:                domain.ChangeRequestStatusPending,
+		RequestedBy:           &userID,
+	}
+	if err := s.agentRepo.CreateChangeRequest(ctx, req); err != nil {
+		return nil, err
+	}
+	if err := s.auditChangeRequest(ctx, agent.OrganizationID, req, domain.AuditActionCreate, nil); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// pendingChangeRequest loads a change request under review together with its agent.
+func (s *DefaultAgentService) pendingChangeRequest(ctx context.Context, id uuid.UUID) (*domain.AgentChangeRequest, *domain.Agent, error) {
+	if err := policy.Authorize(ctx, policy.ResourceChangeRequest, policy.ActionUpdate); err != nil {
+		return nil, nil, err
+	}
+	req, err := s.getChangeRequest(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if req.Status != domain.ChangeRequestStatusPending {
+		return nil, nil, ErrChangeRequestNotPending
+	}
+
+	agent, err := s.agentRepo.GetByID(ctx, req.AgentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if agent == nil {
+		return nil, nil, ErrAgentNotFound
+	}
+	return req, agent, nil
+}
+
+// resolveChangeRequest stores the review of req, whose pending state is
+// before, and audits it. It must run inside a transaction.
+func (s *DefaultAgentService) resolveChangeRequest(ctx context.Context, agent *domain.Agent, req *domain.AgentChangeRequest, before auditSnapshot, reviewerID uuid.UUID, comment string, at time.Time, action domain.AuditAction) error {
+	req.ReviewedBy = &reviewerID
+	req.ReviewComment = comment
+	req.ReviewedAt = &at
+
+	resolved, err := s.agentRepo.ResolveChangeRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+	if !resolved {
+		// Another reviewer won the race; the transaction rolls back.
+		return ErrChangeRequestNotPending
+	}
+	return s.auditChangeRequest(ctx, agent.OrganizationID, req, action, before)
+}
+
+// updateAgent writes the name and configuration of agent. It fails with
+// ErrAgentChanged when the agent's status or risk level moved on since agent
+// was read, so lifecycle and classification changes are never overwritten.
+func (s *DefaultAgentService) updateAgent(ctx context.Context, agent *domain.Agent) error {
+	updated, err := s.agentRepo.Update(ctx, agent)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrAgentChanged
+	}
+	return nil
+}
+
+// agentSnapshot is the audited state of an agent.
+func agentSnapshot(agent *domain.Agent) auditSnapshot {
+	return auditSnapshot{
+		"name":          agent.Name,
+		"status":        agent.Status,
+		"risk_level":    agent.RiskLevel,
+		"configuration": agent.Configuration,
+		"cost_amount":   agent.CostAmount,
+		"cost_currency": agent.CostCurrency,
+		"billing_cycle": agent.BillingCycle,
+	}
+}
+
+// auditAgent records a creation (nil before) or a change of agent from before.
+// It must run inside the transaction of the change.
+func (s *DefaultAgentService) auditAgent(ctx context.Context, agent *domain.Agent, action domain.AuditAction, before auditSnapshot) error {
+	return logMutation(ctx, s.auditService, agent.OrganizationID, auditEntityAgent, agent.ID, action, before, agentSnapshot(agent))
+}
+
+// changeRequestSnapshot is the audited state of a change request.
+func changeRequestSnapshot(req *domain.AgentChangeRequest) auditSnapshot {
+	return auditSnapshot{
+		"agent_id":               req.AgentID,
+		"proposed_configuration": req.ProposedConfiguration,
+		"base_version_number":    req.BaseVersionNumber,
+		"reason":                 req.Reason,
+		"status":                 req.Status,
+		"requested_by":           req.RequestedBy,
+		"reviewed_by":            req.ReviewedBy,
+		"review_comment":         req.ReviewComment,
+		"applied_version_id":     req.AppliedVersionID,
+	}
+}
+
+// auditChangeRequest records the creation (nil before) or review of req.
+func (s *DefaultAgentService) auditChangeRequest(ctx context.Context, orgID uuid.UUID, req *domain.AgentChangeRequest, action domain.AuditAction, before auditSnapshot) error {
+	return logMutation(ctx, s.auditService, orgID, auditEntityChangeRequest, req.ID, action, before, changeRequestSnapshot(req))
+}
+
+func (s *DefaultAgentService) getChangeRequest(ctx context.Context, id uuid.UUID) (*domain.AgentChangeRequest, error) {
+	req, err := s.agentRepo.GetChangeRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req == nil {
+		return nil, ErrChangeRequestNotFound
+	}
+	return req, nil
 }
@@ -2,10 +2,12 @@ package service
 
 import (
 	"agentXmap/internal/domain"
+	"agentXmap/internal/policy"
 	"context"
 	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -44,9 +46,9 @@ func (m *MockAgentRepository) ListByStatus(ctx context.Context, orgID uuid.UUID,
 	return args.Get(0).([]domain.Agent), args.Error(1)
 }
 
-func (m *MockAgentRepository) Update(ctx context.Context, agent *domain.Agent) error {
+func (m *MockAgentRepository) Update(ctx context.Context, agent *domain.Agent) (bool, error) {
 	args := m.Called(ctx, agent)
-	return args.Error(0)
+	return args.Bool(0), args.Error(1)
 }
 
 func (m *MockAgentRepository) Delete(ctx context.Context, id uuid.UUID) error {
@@ -59,6 +61,32 @@ func (m *MockAgentRepository) CreateVersion(ctx context.Context, version *domain
 	return args.Error(0)
 }
 
+func (m *MockAgentRepository) CreateNextVersion(ctx context.Context, version *domain.AgentVersion) error {
+	args := m.Called(ctx, version)
+	return args.Error(0)
+}
+
+func (m *MockAgentRepository) GetLatestVersion(ctx context.Context, agentID uuid.UUID) (*domain.AgentVersion, error) {
+	args := m.Called(ctx, agentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AgentVersion), args.Error(1)
+}
+
+func (m *MockAgentRepository) GetVersion(ctx context.Context, agentID uuid.UUID, versionNumber int) (*domain.AgentVersion, error) {
+	args := m.Called(ctx, agentID, versionNumber)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AgentVersion), args.Error(1)
+}
+
+func (m *MockAgentRepository) ListVersions(ctx context.Context, agentID uuid.UUID, limit, offset int) ([]domain.AgentVersion, int64, error) {
+	args := m.Called(ctx, agentID, limit, offset)
+	return args.Get(0).([]domain.AgentVersion), args.Get(1).(int64), args.Error(2)
+}
+
 func (m *MockAgentRepository) GetResources(ctx context.Context, agentID uuid.UUID) ([]domain.Resource, error) {
 	args := m.Called(ctx, agentID)
 	if args.Get(0) == nil {
@@ -85,6 +113,11 @@ func (m *MockAgentRepository) GetAssignedAgents(ctx context.Context, userID uuid
 	return args.Get(0).([]domain.Agent), args.Error(1)
 }
 
+func (m *MockAgentRepository) IsAssigned(ctx context.Context, agentID, userID uuid.UUID) (bool, error) {
+	args := m.Called(ctx, agentID, userID)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockAgentRepository) GetAssignedApplications(ctx context.Context, agentID uuid.UUID) ([]domain.Application, error) {
 	args := m.Called(ctx, agentID)
 	return args.Get(0).([]domain.Application), args.Error(1)
@@ -95,10 +128,115 @@ func (m *MockAgentRepository) GetCertifications(ctx context.Context, agentID uui
 	return args.Get(0).([]domain.Certification), args.Error(1)
 }
 
+func (m *MockAgentRepository) CreateChangeRequest(ctx context.Context, req *domain.AgentChangeRequest) error {
+	args := m.Called(ctx, req)
+	if req.ID == uuid.Nil {
+		req.ID = uuid.New()
+	}
+	return args.Error(0)
+}
+
+func (m *MockAgentRepository) GetChangeRequest(ctx context.Context, id uuid.UUID) (*domain.AgentChangeRequest, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AgentChangeRequest), args.Error(1)
+}
+
+func (m *MockAgentRepository) ListChangeRequests(ctx context.Context, agentID uuid.UUID, status domain.ChangeRequestStatus) ([]domain.AgentChangeRequest, error) {
+	args := m.Called(ctx, agentID, status)
+	return args.Get(0).([]domain.AgentChangeRequest), args.Error(1)
+}
+
+func (m *MockAgentRepository) ResolveChangeRequest(ctx context.Context, req *domain.AgentChangeRequest) (bool, error) {
+	args := m.Called(ctx, req)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAgentRepository) UpdateStatus(ctx context.Context, agentID uuid.UUID, from, to domain.AgentStatus, userID uuid.UUID) (bool, error) {
+	args := m.Called(ctx, agentID, from, to, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAgentRepository) UpdateRiskLevel(ctx context.Context, agentID uuid.UUID, from, to domain.AgentRiskLevel, userID uuid.UUID) (bool, error) {
+	args := m.Called(ctx, agentID, from, to, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAgentRepository) CreateStatusTransition(ctx context.Context, transition *domain.AgentStatusTransition) error {
+	args := m.Called(ctx, transition)
+	return args.Error(0)
+}
+
+func (m *MockAgentRepository) ListStatusTransitions(ctx context.Context, agentID uuid.UUID) ([]domain.AgentStatusTransition, error) {
+	args := m.Called(ctx, agentID)
+	return args.Get(0).([]domain.AgentStatusTransition), args.Error(1)
+}
+
+func (m *MockAgentRepository) GetAgentCertifications(ctx context.Context, agentID uuid.UUID) ([]domain.AgentCertification, error) {
+	args := m.Called(ctx, agentID)
+	return args.Get(0).([]domain.AgentCertification), args.Error(1)
+}
+
+// MockAuditService
+type MockAuditService struct {
+	mock.Mock
+}
+
+func (m *MockAuditService) LogAction(ctx context.Context, orgID uuid.UUID, actorUserID *uuid.UUID, entityType string, entityID uuid.UUID, action domain.AuditAction, changes json.RawMessage, ipAddress string) error {
+	args := m.Called(ctx, orgID, actorUserID, entityType, entityID, action, changes, ipAddress)
+	return args.Error(0)
+}
+
+func (m *MockAuditService) RecordExecution(ctx context.Context, exec *domain.AgentExecution) error {
+	args := m.Called(ctx, exec)
+	return args.Error(0)
+}
+
+func (m *MockAuditService) QueryLogs(ctx context.Context, filter domain.AuditLogFilter, cursor string, limit int) (*AuditLogPage, error) {
+	args := m.Called(ctx, filter, cursor, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*AuditLogPage), args.Error(1)
+}
+
+func (m *MockAuditService) ExportLogs(ctx context.Context, filter domain.AuditLogFilter, fn func(*domain.SystemAuditLog) error) error {
+	args := m.Called(ctx, filter, fn)
+	return args.Error(0)
+}
+
+// acceptingAudit returns a MockAuditService that accepts every LogAction call.
+func acceptingAudit() *MockAuditService {
+	m := new(MockAuditService)
+	m.On("LogAction", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	return m
+}
+
+// MockTxManager runs the unit of work inline and records how often it was used.
+type MockTxManager struct {
+	calls int
+}
+
+func (m *MockTxManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.calls++
+	return fn(ctx)
+}
+
+// principalContext returns a context carrying a caller with the given role.
+func principalContext(role domain.UserRole) context.Context {
+	return domain.WithPrincipal(context.Background(), &domain.Principal{
+		UserID:         uuid.New(),
+		OrganizationID: uuid.New(),
+		Role:           role,
+	})
+}
+
 func TestAgentService_CreateAgent(t *testing.T) {
 	mockRepo := new(MockAgentRepository)
-	service := NewAgentService(mockRepo)
-	ctx := context.Background()
+	service := NewAgentService(mockRepo, new(MockTxManager), acceptingAudit())
+	ctx := principalContext(domain.UserRoleAdmin)
 	orgID := uuid.New()
 	userID := uuid.New()
 
@@ -129,7 +267,7 @@ func TestAgentService_CreateAgent(t *testing.T) {
 
 	t.Run("Duplicate Name", func(t *testing.T) {
 		mockRepo := new(MockAgentRepository)
-		service := NewAgentService(mockRepo)
+		service := NewAgentService(mockRepo, new(MockTxManager), acceptingAudit())
 		name := "Duplicate Agent"
 		config := json.RawMessage(`{}`)
 
@@ -140,17 +278,42 @@ func TestAgentService_CreateAgent(t *testing.T) {
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "duplicate key")
 	})
+
+	t.Run("Version Error", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		txManager := new(MockTxManager)
+		service := NewAgentService(mockRepo, txManager, acceptingAudit())
+
+		mockRepo.On("Create", ctx, mock.Anything).Return(nil)
+		mockRepo.On("CreateVersion", ctx, mock.Anything).Return(errors.New("db error"))
+
+		agent, err := service.CreateAgent(ctx, orgID, userID, "Agent", json.RawMessage(`{}`))
+		assert.EqualError(t, err, "db error")
+		assert.Nil(t, agent)
+		assert.Equal(t, 1, txManager.calls)
+	})
 }
 
 func TestAgentService_UpdateAgent(t *testing.T) {
-	ctx := context.Background()
+	ctx := principalContext(domain.UserRoleAdmin)
 	orgID := uuid.New()
 	userID := uuid.New()
 	agentID := uuid.New()
 
+	t.Run("Blank Name", func(t *testing.T) {
+		for _, name := range []string{"", "   \t"} {
+			mockRepo := new(MockAgentRepository)
+			service := NewAgentService(mockRepo, new(MockTxManager), acceptingAudit())
+
+			_, _, err := service.UpdateAgent(ctx, agentID, userID, name, json.RawMessage(`{}`), "")
+			assert.ErrorIs(t, err, ErrAgentNameRequired)
+			mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		}
+	})
+
 	t.Run("Success - Config Changed", func(t *testing.T) {
 		mockRepo := new(MockAgentRepository)
-		service := NewAgentService(mockRepo)
+		service := NewAgentService(mockRepo, new(MockTxManager), acceptingAudit())
 
 		// Existing agent
 		existingAgent := &domain.Agent{
@@ -170,22 +333,23 @@ func TestAgentService_UpdateAgent(t *testing.T) {
 
 		mockRepo.On("Update", ctx, mock.MatchedBy(func(a *domain.Agent) bool {
 			return a.Name == newName && string(a.Configuration) == string(newConfig)
-		})).Return(nil)
+		})).Return(true, nil)
 
-		mockRepo.On("CreateVersion", ctx, mock.MatchedBy(func(v *domain.AgentVersion) bool {
-			return v.VersionNumber == 2 && string(v.ConfigurationSnapshot) == string(newConfig)
+		mockRepo.On("CreateNextVersion", ctx, mock.MatchedBy(func(v *domain.AgentVersion) bool {
+			return v.AgentID == agentID && string(v.ConfigurationSnapshot) == string(newConfig)
 		})).Return(nil)
 
-		agent, err := service.UpdateAgent(ctx, agentID, userID, newName, newConfig, domain.AgentStatusActive)
+		agent, changeRequest, err := service.UpdateAgent(ctx, agentID, userID, newName, newConfig, "")
 
 		assert.NoError(t, err)
+		assert.Nil(t, changeRequest)
 		assert.Equal(t, newName, agent.Name)
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("Success - No Config Change", func(t *testing.T) {
 		mockRepo := new(MockAgentRepository)
-		service := NewAgentService(mockRepo)
+		service := NewAgentService(mockRepo, new(MockTxManager), acceptingAudit())
 
 		// Existing agent
 		config := json.RawMessage(`{"model": "gpt-4"}`)
@@ -205,35 +369,68 @@ func TestAgentService_UpdateAgent(t *testing.T) {
 
 		mockRepo.On("Update", ctx, mock.MatchedBy(func(a *domain.Agent) bool {
 			return a.Name == newName
-		})).Return(nil)
+		})).Return(true, nil)
 
-		// CreateVersion should NOT be called
+		// CreateNextVersion should NOT be called
 
-		agent, err := service.UpdateAgent(ctx, agentID, userID, newName, config, domain.AgentStatusActive)
+		agent, changeRequest, err := service.UpdateAgent(ctx, agentID, userID, newName, config, "")
 
 		assert.NoError(t, err)
+		assert.Nil(t, changeRequest)
 		assert.Equal(t, newName, agent.Name)
-		mockRepo.AssertNotCalled(t, "CreateVersion")
+		mockRepo.AssertNotCalled(t, "CreateNextVersion")
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("Version Error", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		txManager := new(MockTxManager)
+		service := NewAgentService(mockRepo, txManager, acceptingAudit())
+
+		existingAgent := &domain.Agent{ID: agentID, OrganizationID: orgID, Configuration: json.RawMessage(`{}`)}
+		mockRepo.On("GetByID", ctx, agentID).Return(existingAgent, nil)
+		mockRepo.On("Update", ctx, mock.Anything).Return(true, nil)
+		mockRepo.On("CreateNextVersion", ctx, mock.Anything).Return(errors.New("db error"))
+
+		// The update is rolled back with the failed version instead of being kept silently.
+		_, _, err := service.UpdateAgent(ctx, agentID, userID, "Name", json.RawMessage(`{"model": "gpt-4"}`), "")
+		assert.EqualError(t, err, "db error")
+		assert.Equal(t, 1, txManager.calls)
+	})
+
+	t.Run("Transitioned Concurrently", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), acceptingAudit())
+
+		// The agent is read as active, then moved to maintenance before the write.
+		existingAgent := &domain.Agent{ID: agentID, OrganizationID: orgID, Status: domain.AgentStatusActive, Configuration: json.RawMessage(`{}`)}
+		mockRepo.On("GetByID", ctx, agentID).Return(existingAgent, nil)
+		mockRepo.On("Update", ctx, mock.MatchedBy(func(a *domain.Agent) bool {
+			return a.Status == domain.AgentStatusActive
+		})).Return(false, nil)
+
+		_, _, err := service.UpdateAgent(ctx, agentID, userID, "Name", json.RawMessage(`{"model": "gpt-4"}`), "")
+		assert.ErrorIs(t, err, ErrAgentChanged)
+		mockRepo.AssertNotCalled(t, "CreateNextVersion", mock.Anything, mock.Anything)
+	})
+
 	t.Run("Not Found", func(t *testing.T) {
 		mockRepo := new(MockAgentRepository)
-		service := NewAgentService(mockRepo)
+		service := NewAgentService(mockRepo, new(MockTxManager), acceptingAudit())
 
 		mockRepo.On("GetByID", ctx, agentID).Return(nil, nil)
-		_, err := service.UpdateAgent(ctx, agentID, userID, "name", nil, domain.AgentStatusActive)
+		_, _, err := service.UpdateAgent(ctx, agentID, userID, "name", nil, "")
 		assert.Error(t, err)
 	})
 }
 
 func TestAgentService_GetAgent(t *testing.T) {
-	ctx := context.Background()
+	ctx := principalContext(domain.UserRoleAdmin)
 	agentID := uuid.New()
 
 	t.Run("Success", func(t *testing.T) {
 		mockRepo := new(MockAgentRepository)
-		service := NewAgentService(mockRepo)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
 
 		mockRepo.On("GetByID", ctx, agentID).Return(&domain.Agent{ID: agentID}, nil)
 		agent, err := service.GetAgent(ctx, agentID)
@@ -243,7 +440,7 @@ func TestAgentService_GetAgent(t *testing.T) {
 
 	t.Run("Not Found", func(t *testing.T) {
 		mockRepo := new(MockAgentRepository)
-		service := NewAgentService(mockRepo)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
 
 		mockRepo.On("GetByID", ctx, agentID).Return(nil, nil)
 		_, err := service.GetAgent(ctx, agentID)
@@ -253,12 +450,12 @@ func TestAgentService_GetAgent(t *testing.T) {
 }
 
 func TestAgentService_ListAgentResources(t *testing.T) {
-	ctx := context.Background()
+	ctx := principalContext(domain.UserRoleAdmin)
 	agentID := uuid.New()
 
 	t.Run("Success", func(t *testing.T) {
 		mockRepo := new(MockAgentRepository)
-		service := NewAgentService(mockRepo)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
 
 		expectedResources := []domain.Resource{
 			{ID: uuid.New(), Name: "Resource 1"},
@@ -276,7 +473,7 @@ func TestAgentService_ListAgentResources(t *testing.T) {
 
 	t.Run("Error", func(t *testing.T) {
 		mockRepo := new(MockAgentRepository)
-		service := NewAgentService(mockRepo)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
 
 		mockRepo.On("GetResources", ctx, agentID).Return(nil, errors.New("db error"))
 
@@ -288,12 +485,12 @@ func TestAgentService_ListAgentResources(t *testing.T) {
 }
 
 func TestAgentService_ListAssignedUsers(t *testing.T) {
-	ctx := context.Background()
+	ctx := principalContext(domain.UserRoleAdmin)
 	agentID := uuid.New()
 
 	t.Run("Success", func(t *testing.T) {
 		mockRepo := new(MockAgentRepository)
-		service := NewAgentService(mockRepo)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
 
 		expectedUsers := []domain.User{
 			{ID: uuid.New(), Email: "user1@example.com"},
@@ -311,7 +508,7 @@ func TestAgentService_ListAssignedUsers(t *testing.T) {
 
 	t.Run("Error", func(t *testing.T) {
 		mockRepo := new(MockAgentRepository)
-		service := NewAgentService(mockRepo)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
 
 		mockRepo.On("GetAssignedUsers", ctx, agentID).Return([]domain.User{}, errors.New("db error"))
 
@@ -323,12 +520,12 @@ func TestAgentService_ListAssignedUsers(t *testing.T) {
 }
 
 func TestAgentService_GetAgentLLMs(t *testing.T) {
-	ctx := context.Background()
+	ctx := principalContext(domain.UserRoleAdmin)
 	agentID := uuid.New()
 
 	t.Run("Success", func(t *testing.T) {
 		mockRepo := new(MockAgentRepository)
-		service := NewAgentService(mockRepo)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
 
 		expectedLLMs := []domain.AgentLLM{
 			{ID: uuid.New(), AgentID: agentID, LLMModel: domain.LLMModel{FamilyName: "GPT-4"}},
@@ -343,12 +540,12 @@ func TestAgentService_GetAgentLLMs(t *testing.T) {
 }
 
 func TestAgentService_ListAssignedApplications(t *testing.T) {
-	ctx := context.Background()
+	ctx := principalContext(domain.UserRoleAdmin)
 	agentID := uuid.New()
 
 	t.Run("Success", func(t *testing.T) {
 		mockRepo := new(MockAgentRepository)
-		service := NewAgentService(mockRepo)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
 
 		expectedApps := []domain.Application{
 			{Name: "App A"},
@@ -363,7 +560,7 @@ func TestAgentService_ListAssignedApplications(t *testing.T) {
 
 	t.Run("Error", func(t *testing.T) {
 		mockRepo := new(MockAgentRepository)
-		service := NewAgentService(mockRepo)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
 
 		mockRepo.On("GetAssignedApplications", ctx, agentID).Return([]domain.Application{}, errors.New("db error"))
 
@@ -375,12 +572,12 @@ func TestAgentService_ListAssignedApplications(t *testing.T) {
 }
 
 func TestAgentService_ListAssignedAgents(t *testing.T) {
-	ctx := context.Background()
+	ctx := principalContext(domain.UserRoleAdmin)
 	userID := uuid.New()
 
 	t.Run("Success", func(t *testing.T) {
 		mockRepo := new(MockAgentRepository)
-		service := NewAgentService(mockRepo)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
 
 		expectedAgents := []domain.Agent{
 			{Name: "Agent X"},
@@ -395,7 +592,7 @@ func TestAgentService_ListAssignedAgents(t *testing.T) {
 
 	t.Run("Error", func(t *testing.T) {
 		mockRepo := new(MockAgentRepository)
-		service := NewAgentService(mockRepo)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
 
 		mockRepo.On("GetAssignedAgents", ctx, userID).Return([]domain.Agent{}, errors.New("db error"))
 
@@ -407,12 +604,12 @@ func TestAgentService_ListAssignedAgents(t *testing.T) {
 }
 
 func TestAgentService_ListAgentsByStatus(t *testing.T) {
-	ctx := context.Background()
+	ctx := principalContext(domain.UserRoleAdmin)
 	orgID := uuid.New()
 
 	t.Run("Success", func(t *testing.T) {
 		mockRepo := new(MockAgentRepository)
-		service := NewAgentService(mockRepo)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
 
 		expectedAgents := []domain.Agent{
 			{Name: "Active Agent", Status: domain.AgentStatusActive},
@@ -427,12 +624,12 @@ func TestAgentService_ListAgentsByStatus(t *testing.T) {
 }
 
 func TestAgentService_GetActiveMonthlyCost(t *testing.T) {
-	ctx := context.Background()
+	ctx := principalContext(domain.UserRoleAdmin)
 	orgID := uuid.New()
 
 	t.Run("Success", func(t *testing.T) {
 		mockRepo := new(MockAgentRepository)
-		service := NewAgentService(mockRepo)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
 
 		activeAgents := []domain.Agent{
 			{Name: "Monthly Agent", Status: domain.AgentStatusActive, BillingCycle: domain.BillingCycleMonthly, CostAmount: 100.0},
@@ -451,12 +648,12 @@ func TestAgentService_GetActiveMonthlyCost(t *testing.T) {
 }
 
 func TestAgentService_ListAgentCertifications(t *testing.T) {
-	ctx := context.Background()
+	ctx := principalContext(domain.UserRoleAdmin)
 	agentID := uuid.New()
 
 	t.Run("Success", func(t *testing.T) {
 		mockRepo := new(MockAgentRepository)
-		service := NewAgentService(mockRepo)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
 
 		expectedCerts := []domain.Certification{
 			{Name: "ISO 27001"},
@@ -469,3 +666,731 @@ func TestAgentService_ListAgentCertifications(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestAgentService_DeleteAgent(t *testing.T) {
+	ctx := principalContext(domain.UserRoleAdmin)
+	agentID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), acceptingAudit())
+
+		mockRepo.On("GetByID", ctx, agentID).Return(&domain.Agent{ID: agentID}, nil)
+		mockRepo.On("Delete", ctx, agentID).Return(nil)
+
+		assert.NoError(t, service.DeleteAgent(ctx, agentID))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), acceptingAudit())
+
+		// Agents of other organizations are invisible to the tenant-scoped repository.
+		mockRepo.On("GetByID", ctx, agentID).Return(nil, nil)
+
+		err := service.DeleteAgent(ctx, agentID)
+		assert.ErrorIs(t, err, ErrAgentNotFound)
+		mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAgentService_Authorization(t *testing.T) {
+	agentID := uuid.New()
+
+	t.Run("No principal", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
+
+		_, err := service.GetAgent(context.Background(), agentID)
+		assert.ErrorIs(t, err, policy.ErrUnauthenticated)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("User cannot create", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
+
+		_, err := service.CreateAgent(principalContext(domain.UserRoleUser), uuid.New(), uuid.New(), "Agent", nil)
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Manager cannot delete", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
+
+		err := service.DeleteAgent(principalContext(domain.UserRoleManager), agentID)
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("User reads assigned agent", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
+		ctx := principalContext(domain.UserRoleUser)
+		caller, _ := domain.PrincipalFromContext(ctx)
+
+		mockRepo.On("IsAssigned", ctx, agentID, caller.UserID).Return(true, nil)
+		mockRepo.On("GetByID", ctx, agentID).Return(&domain.Agent{ID: agentID}, nil)
+
+		agent, err := service.GetAgent(ctx, agentID)
+		assert.NoError(t, err)
+		assert.Equal(t, agentID, agent.ID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("User cannot read unassigned agent", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
+		ctx := principalContext(domain.UserRoleUser)
+		caller, _ := domain.PrincipalFromContext(ctx)
+
+		mockRepo.On("IsAssigned", ctx, agentID, caller.UserID).Return(false, nil)
+
+		_, err := service.ListAgentResources(ctx, agentID)
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("User lists only assigned agents of the org", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
+		ctx := principalContext(domain.UserRoleUser)
+		caller, _ := domain.PrincipalFromContext(ctx)
+
+		mockRepo.On("GetAssignedAgents", ctx, caller.UserID).Return([]domain.Agent{
+			{Name: "Mine", OrganizationID: caller.OrganizationID, Status: domain.AgentStatusActive},
+			{Name: "Mine inactive", OrganizationID: caller.OrganizationID, Status: domain.AgentStatusInactive},
+			{Name: "Other org", OrganizationID: uuid.New(), Status: domain.AgentStatusActive},
+		}, nil)
+
+		agents, err := service.ListAgents(ctx, caller.OrganizationID)
+		assert.NoError(t, err)
+		assert.Len(t, agents, 2)
+
+		agents, err = service.ListAgentsByStatus(ctx, caller.OrganizationID, domain.AgentStatusActive)
+		assert.NoError(t, err)
+		assert.Len(t, agents, 1)
+		assert.Equal(t, "Mine", agents[0].Name)
+		mockRepo.AssertNotCalled(t, "ListByOrg", mock.Anything, mock.Anything)
+	})
+
+	t.Run("User cannot list another user's assignments", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
+
+		_, err := service.ListAssignedAgents(principalContext(domain.UserRoleUser), uuid.New())
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+	})
+
+	t.Run("User cannot read costs", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
+
+		_, err := service.GetActiveMonthlyCost(principalContext(domain.UserRoleUser), uuid.New())
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+	})
+}
+
+func TestAgentService_ListVersions(t *testing.T) {
+	ctx := principalContext(domain.UserRoleAdmin)
+	agentID := uuid.New()
+	mockRepo := new(MockAgentRepository)
+	service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
+
+	mockRepo.On("ListVersions", ctx, agentID, 10, 0).Return([]domain.AgentVersion{{VersionNumber: 2}, {VersionNumber: 1}}, int64(2), nil)
+
+	versions, total, err := service.ListVersions(ctx, agentID, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, versions, 2)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAgentService_DiffVersions(t *testing.T) {
+	ctx := principalContext(domain.UserRoleAdmin)
+	agentID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
+
+		mockRepo.On("GetVersion", ctx, agentID, 1).Return(&domain.AgentVersion{
+			VersionNumber:         1,
+			ConfigurationSnapshot: json.RawMessage(`{"model": "gpt-3.5", "temperature": 0.2, "tools": ["search"]}`),
+		}, nil)
+		mockRepo.On("GetVersion", ctx, agentID, 2).Return(&domain.AgentVersion{
+			VersionNumber:         2,
+			ConfigurationSnapshot: json.RawMessage(`{"model": "gpt-4", "tools": ["search", "mail"], "prompt": {"lang": "fr"}}`),
+		}, nil)
+
+		diff, err := service.DiffVersions(ctx, agentID, 1, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, diff.FromVersion)
+		assert.Equal(t, 2, diff.ToVersion)
+		assert.Equal(t, []ConfigChange{
+			{Path: "/model", Op: ChangeChanged, From: "gpt-3.5", To: "gpt-4"},
+			{Path: "/prompt", Op: ChangeAdded, To: map[string]any{"lang": "fr"}},
+			{Path: "/temperature", Op: ChangeRemoved, From: 0.2},
+			{Path: "/tools/1", Op: ChangeAdded, To: "mail"},
+		}, diff.Changes)
+	})
+
+	t.Run("Unknown Version", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
+
+		mockRepo.On("GetVersion", ctx, agentID, 1).Return(&domain.AgentVersion{ConfigurationSnapshot: json.RawMessage(`{}`)}, nil)
+		mockRepo.On("GetVersion", ctx, agentID, 9).Return(nil, nil)
+
+		_, err := service.DiffVersions(ctx, agentID, 1, 9)
+		assert.ErrorIs(t, err, ErrAgentVersionNotFound)
+	})
+}
+
+func TestDiffConfigurations(t *testing.T) {
+	t.Run("Identical", func(t *testing.T) {
+		changes, err := DiffConfigurations(json.RawMessage(`{"a": [1, {"b": true}]}`), json.RawMessage(`{"a":[1,{"b":true}]}`))
+		assert.NoError(t, err)
+		assert.Empty(t, changes)
+	})
+
+	t.Run("Escaped Keys And Type Change", func(t *testing.T) {
+		changes, err := DiffConfigurations(json.RawMessage(`{"a/b": {"c": 1}}`), json.RawMessage(`{"a/b": [1]}`))
+		assert.NoError(t, err)
+		assert.Equal(t, []ConfigChange{
+			{Path: "/a~1b", Op: ChangeChanged, From: map[string]any{"c": 1.0}, To: []any{1.0}},
+		}, changes)
+	})
+
+	t.Run("Invalid JSON", func(t *testing.T) {
+		_, err := DiffConfigurations(json.RawMessage(`{`), json.RawMessage(`{}`))
+		assert.Error(t, err)
+	})
+}
+
+func TestAgentService_RollbackAgent(t *testing.T) {
+	ctx := principalContext(domain.UserRoleAdmin)
+	agentID := uuid.New()
+	userID := uuid.New()
+	oldConfig := json.RawMessage(`{"model": "gpt-3.5"}`)
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		txManager := new(MockTxManager)
+		service := NewAgentService(mockRepo, txManager, acceptingAudit())
+
+		mockRepo.On("GetByID", ctx, agentID).Return(&domain.Agent{ID: agentID, Configuration: json.RawMessage(`{"model": "gpt-4"}`)}, nil)
+		mockRepo.On("GetVersion", ctx, agentID, 1).Return(&domain.AgentVersion{VersionNumber: 1, ConfigurationSnapshot: oldConfig}, nil)
+		mockRepo.On("Update", ctx, mock.MatchedBy(func(a *domain.Agent) bool {
+			return string(a.Configuration) == string(oldConfig)
+		})).Return(true, nil)
+		mockRepo.On("CreateNextVersion", ctx, mock.MatchedBy(func(v *domain.AgentVersion) bool {
+			return string(v.ConfigurationSnapshot) == string(oldConfig) &&
+				v.ReasonForChange == "Rollback to version 1: regression in v3" &&
+				*v.CreatedBy == userID
+		})).Return(nil)
+
+		agent, changeRequest, err := service.RollbackAgent(ctx, agentID, userID, 1, "regression in v3")
+		assert.NoError(t, err)
+		assert.Nil(t, changeRequest)
+		assert.Equal(t, string(oldConfig), string(agent.Configuration))
+		assert.Equal(t, 1, txManager.calls)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Reason Required", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), acceptingAudit())
+
+		_, _, err := service.RollbackAgent(ctx, agentID, userID, 1, "")
+		assert.ErrorIs(t, err, ErrRollbackReasonRequired)
+	})
+
+	t.Run("Unknown Version", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), acceptingAudit())
+
+		mockRepo.On("GetByID", ctx, agentID).Return(&domain.Agent{ID: agentID}, nil)
+		mockRepo.On("GetVersion", ctx, agentID, 7).Return(nil, nil)
+
+		_, _, err := service.RollbackAgent(ctx, agentID, userID, 7, "why")
+		assert.ErrorIs(t, err, ErrAgentVersionNotFound)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Users cannot roll back", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), acceptingAudit())
+
+		_, _, err := service.RollbackAgent(principalContext(domain.UserRoleUser), agentID, userID, 1, "why")
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+	})
+}
+
+func TestAgentService_UpdateHighRiskAgent(t *testing.T) {
+	ctx := principalContext(domain.UserRoleManager)
+	orgID := uuid.New()
+	userID := uuid.New()
+	agentID := uuid.New()
+	oldConfig := json.RawMessage(`{"model": "gpt-3.5"}`)
+	newConfig := json.RawMessage(`{"model": "gpt-4"}`)
+
+	highRiskAgent := func() *domain.Agent {
+		return &domain.Agent{ID: agentID, OrganizationID: orgID, Name: "Scoring", RiskLevel: domain.AgentRiskLevelHigh, Configuration: oldConfig}
+	}
+
+	t.Run("Config Change Creates Pending Request", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		auditService := new(MockAuditService)
+		txManager := new(MockTxManager)
+		service := NewAgentService(mockRepo, txManager, auditService)
+
+		mockRepo.On("GetByID", ctx, agentID).Return(highRiskAgent(), nil)
+		// The rename is applied, the configuration is not.
+		mockRepo.On("Update", ctx, mock.MatchedBy(func(a *domain.Agent) bool {
+			return a.Name == "Renamed" && string(a.Configuration) == string(oldConfig)
+		})).Return(true, nil)
+		mockRepo.On("GetLatestVersion", ctx, agentID).Return(&domain.AgentVersion{VersionNumber: 3}, nil)
+		mockRepo.On("CreateChangeRequest", ctx, mock.MatchedBy(func(r *domain.AgentChangeRequest) bool {
+			return r.AgentID == agentID && string(r.ProposedConfiguration) == string(newConfig) && r.BaseVersionNumber == 3 &&
+				r.Reason == "needs gpt-4" && *r.RequestedBy == userID && r.Status == domain.ChangeRequestStatusPending
+		})).Return(nil)
+		auditService.On("LogAction", ctx, orgID, (*uuid.UUID)(nil), "agent_change_request", mock.Anything, domain.AuditActionCreate, mock.Anything, "").Return(nil)
+		auditService.On("LogAction", ctx, orgID, (*uuid.UUID)(nil), "agent", agentID, domain.AuditActionUpdate, mock.MatchedBy(func(changes json.RawMessage) bool {
+			return string(changes) == `{"after":{"name":"Renamed"},"before":{"name":"Scoring"}}`
+		}), "").Return(nil)
+
+		agent, changeRequest, err := service.UpdateAgent(ctx, agentID, userID, "Renamed", newConfig, "needs gpt-4")
+		assert.NoError(t, err)
+		assert.Equal(t, string(oldConfig), string(agent.Configuration))
+		if assert.NotNil(t, changeRequest) {
+			assert.Equal(t, domain.ChangeRequestStatusPending, changeRequest.Status)
+		}
+		assert.Equal(t, 1, txManager.calls)
+		mockRepo.AssertNotCalled(t, "CreateNextVersion", mock.Anything, mock.Anything)
+		mockRepo.AssertExpectations(t)
+		auditService.AssertExpectations(t)
+	})
+
+	t.Run("Reason Required", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
+
+		mockRepo.On("GetByID", ctx, agentID).Return(highRiskAgent(), nil)
+
+		_, _, err := service.UpdateAgent(ctx, agentID, userID, "Scoring", newConfig, "")
+		assert.ErrorIs(t, err, ErrChangeReasonRequired)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rollback Creates Pending Request", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		auditService := new(MockAuditService)
+		service := NewAgentService(mockRepo, new(MockTxManager), auditService)
+
+		mockRepo.On("GetByID", ctx, agentID).Return(highRiskAgent(), nil)
+		mockRepo.On("GetVersion", ctx, agentID, 1).Return(&domain.AgentVersion{VersionNumber: 1, ConfigurationSnapshot: newConfig}, nil)
+		mockRepo.On("GetLatestVersion", ctx, agentID).Return(&domain.AgentVersion{VersionNumber: 3}, nil)
+		mockRepo.On("CreateChangeRequest", ctx, mock.MatchedBy(func(r *domain.AgentChangeRequest) bool {
+			return r.Reason == "Rollback to version 1: incident 42" && r.BaseVersionNumber == 3
+		})).Return(nil)
+		auditService.On("LogAction", ctx, orgID, (*uuid.UUID)(nil), "agent_change_request", mock.Anything, domain.AuditActionCreate, mock.Anything, "").Return(nil)
+
+		_, changeRequest, err := service.RollbackAgent(ctx, agentID, userID, 1, "incident 42")
+		assert.NoError(t, err)
+		assert.NotNil(t, changeRequest)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Raised To High Risk Concurrently", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), acceptingAudit())
+
+		// The agent is read as minimal risk, then classified high before the write:
+		// the configuration must not be applied without approval.
+		agent := highRiskAgent()
+		agent.RiskLevel = domain.AgentRiskLevelMinimal
+		mockRepo.On("GetByID", ctx, agentID).Return(agent, nil)
+		mockRepo.On("Update", ctx, mock.MatchedBy(func(a *domain.Agent) bool {
+			return a.RiskLevel == domain.AgentRiskLevelMinimal
+		})).Return(false, nil)
+
+		_, _, err := service.UpdateAgent(ctx, agentID, userID, "Scoring", newConfig, "")
+		assert.ErrorIs(t, err, ErrAgentChanged)
+		mockRepo.AssertNotCalled(t, "CreateNextVersion", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAgentService_ApproveChangeRequest(t *testing.T) {
+	ctx := principalContext(domain.UserRoleManager)
+	orgID := uuid.New()
+	requesterID := uuid.New()
+	reviewerID := uuid.New()
+	agentID := uuid.New()
+	requestID := uuid.New()
+	proposed := json.RawMessage(`{"model": "gpt-4"}`)
+
+	pending := func() *domain.AgentChangeRequest {
+		return &domain.AgentChangeRequest{
+			ID: requestID, AgentID: agentID, ProposedConfiguration: proposed, BaseVersionNumber: 3, Reason: "needs gpt-4",
+			Status: domain.ChangeRequestStatusPending, RequestedBy: &requesterID,
+		}
+	}
+	agent := func() *domain.Agent {
+		return &domain.Agent{ID: agentID, OrganizationID: orgID, RiskLevel: domain.AgentRiskLevelHigh, Configuration: json.RawMessage(`{}`)}
+	}
+	// allocate makes CreateNextVersion assign versionNumber.
+	allocate := func(versionNumber int) func(mock.Arguments) {
+		return func(args mock.Arguments) {
+			args.Get(1).(*domain.AgentVersion).VersionNumber = versionNumber
+		}
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		auditService := new(MockAuditService)
+		service := NewAgentService(mockRepo, new(MockTxManager), auditService)
+
+		mockRepo.On("GetChangeRequest", ctx, requestID).Return(pending(), nil)
+		mockRepo.On("GetByID", ctx, agentID).Return(agent(), nil)
+		mockRepo.On("Update", ctx, mock.MatchedBy(func(a *domain.Agent) bool {
+			return string(a.Configuration) == string(proposed) && *a.UpdatedBy == reviewerID
+		})).Return(true, nil)
+		mockRepo.On("CreateNextVersion", ctx, mock.MatchedBy(func(v *domain.AgentVersion) bool {
+			return string(v.ConfigurationSnapshot) == string(proposed) && v.ReasonForChange == "needs gpt-4" && *v.CreatedBy == requesterID
+		})).Run(allocate(4)).Return(nil)
+		mockRepo.On("ResolveChangeRequest", ctx, mock.MatchedBy(func(r *domain.AgentChangeRequest) bool {
+			return r.Status == domain.ChangeRequestStatusApproved && *r.ReviewedBy == reviewerID && r.AppliedVersionID != nil
+		})).Return(true, nil)
+		auditService.On("LogAction", ctx, orgID, (*uuid.UUID)(nil), "agent_change_request", requestID, domain.AuditActionApprove, mock.Anything, "").Return(nil)
+		auditService.On("LogAction", ctx, orgID, (*uuid.UUID)(nil), "agent", agentID, domain.AuditActionUpdate, mock.MatchedBy(func(changes json.RawMessage) bool {
+			return string(changes) == `{"after":{"configuration":{"model":"gpt-4"}},"before":{"configuration":{}}}`
+		}), "").Return(nil)
+
+		req, err := service.ApproveChangeRequest(ctx, requestID, reviewerID, "ok")
+		assert.NoError(t, err)
+		assert.Equal(t, domain.ChangeRequestStatusApproved, req.Status)
+		assert.Equal(t, "ok", req.ReviewComment)
+		mockRepo.AssertExpectations(t)
+		auditService.AssertExpectations(t)
+	})
+
+	t.Run("Requester Cannot Approve", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
+
+		mockRepo.On("GetChangeRequest", ctx, requestID).Return(pending(), nil)
+		mockRepo.On("GetByID", ctx, agentID).Return(agent(), nil)
+
+		_, err := service.ApproveChangeRequest(ctx, requestID, requesterID, "")
+		assert.ErrorIs(t, err, ErrSelfApproval)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Already Resolved", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
+
+		resolved := pending()
+		resolved.Status = domain.ChangeRequestStatusRejected
+		mockRepo.On("GetChangeRequest", ctx, requestID).Return(resolved, nil)
+
+		_, err := service.ApproveChangeRequest(ctx, requestID, reviewerID, "")
+		assert.ErrorIs(t, err, ErrChangeRequestNotPending)
+	})
+
+	t.Run("Concurrent Review", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		auditService := new(MockAuditService)
+		service := NewAgentService(mockRepo, new(MockTxManager), auditService)
+
+		mockRepo.On("GetChangeRequest", ctx, requestID).Return(pending(), nil)
+		mockRepo.On("GetByID", ctx, agentID).Return(agent(), nil)
+		mockRepo.On("Update", ctx, mock.Anything).Return(true, nil)
+		mockRepo.On("CreateNextVersion", ctx, mock.Anything).Run(allocate(4)).Return(nil)
+		mockRepo.On("ResolveChangeRequest", ctx, mock.Anything).Return(false, nil)
+
+		_, err := service.ApproveChangeRequest(ctx, requestID, reviewerID, "")
+		assert.ErrorIs(t, err, ErrChangeRequestNotPending)
+		auditService.AssertNotCalled(t, "LogAction")
+	})
+
+	t.Run("Agent Moved On", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		auditService := new(MockAuditService)
+		service := NewAgentService(mockRepo, new(MockTxManager), auditService)
+
+		// Version 4 was recorded after the request was based on version 3.
+		mockRepo.On("GetChangeRequest", ctx, requestID).Return(pending(), nil)
+		mockRepo.On("GetByID", ctx, agentID).Return(agent(), nil)
+		mockRepo.On("Update", ctx, mock.Anything).Return(true, nil)
+		mockRepo.On("CreateNextVersion", ctx, mock.Anything).Run(allocate(5)).Return(nil)
+
+		_, err := service.ApproveChangeRequest(ctx, requestID, reviewerID, "")
+		assert.ErrorIs(t, err, ErrChangeRequestStale)
+		mockRepo.AssertNotCalled(t, "ResolveChangeRequest", mock.Anything, mock.Anything)
+		auditService.AssertNotCalled(t, "LogAction")
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
+
+		mockRepo.On("GetChangeRequest", ctx, requestID).Return(nil, nil)
+
+		_, err := service.ApproveChangeRequest(ctx, requestID, reviewerID, "")
+		assert.ErrorIs(t, err, ErrChangeRequestNotFound)
+	})
+
+	t.Run("Users Cannot Review", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
+
+		_, err := service.ApproveChangeRequest(principalContext(domain.UserRoleUser), requestID, reviewerID, "")
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+	})
+}
+
+func TestAgentService_RejectChangeRequest(t *testing.T) {
+	ctx := principalContext(domain.UserRoleAdmin)
+	orgID := uuid.New()
+	requesterID := uuid.New()
+	agentID := uuid.New()
+	requestID := uuid.New()
+
+	mockRepo := new(MockAgentRepository)
+	auditService := new(MockAuditService)
+	service := NewAgentService(mockRepo, new(MockTxManager), auditService)
+
+	mockRepo.On("GetChangeRequest", ctx, requestID).Return(&domain.AgentChangeRequest{
+		ID: requestID, AgentID: agentID, Status: domain.ChangeRequestStatusPending, RequestedBy: &requesterID,
+	}, nil)
+	mockRepo.On("GetByID", ctx, agentID).Return(&domain.Agent{ID: agentID, OrganizationID: orgID}, nil)
+	mockRepo.On("ResolveChangeRequest", ctx, mock.MatchedBy(func(r *domain.AgentChangeRequest) bool {
+		return r.Status == domain.ChangeRequestStatusRejected && r.ReviewComment == "not compliant" && r.AppliedVersionID == nil
+	})).Return(true, nil)
+	auditService.On("LogAction", ctx, orgID, (*uuid.UUID)(nil), "agent_change_request", requestID, domain.AuditActionReject, mock.Anything, "").Return(nil)
+
+	// Requesters may withdraw their own request.
+	req, err := service.RejectChangeRequest(ctx, requestID, requesterID, "not compliant")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ChangeRequestStatusRejected, req.Status)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "CreateNextVersion", mock.Anything, mock.Anything)
+	auditService.AssertExpectations(t)
+}
+
+func TestAgentService_SetRiskLevel(t *testing.T) {
+	orgID := uuid.New()
+	userID := uuid.New()
+	agentID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		ctx := principalContext(domain.UserRoleAdmin)
+		mockRepo := new(MockAgentRepository)
+		auditService := new(MockAuditService)
+		service := NewAgentService(mockRepo, new(MockTxManager), auditService)
+
+		mockRepo.On("GetByID", ctx, agentID).Return(&domain.Agent{ID: agentID, OrganizationID: orgID, RiskLevel: domain.AgentRiskLevelMinimal}, nil)
+		mockRepo.On("UpdateRiskLevel", ctx, agentID, domain.AgentRiskLevelMinimal, domain.AgentRiskLevelHigh, userID).Return(true, nil)
+		auditService.On("LogAction", ctx, orgID, (*uuid.UUID)(nil), "agent", agentID, domain.AuditActionUpdate,
+			json.RawMessage(`{"after":{"risk_level":"high"},"before":{"risk_level":"minimal"}}`), "").Return(nil)
+
+		agent, err := service.SetRiskLevel(ctx, agentID, userID, domain.AgentRiskLevelHigh)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.AgentRiskLevelHigh, agent.RiskLevel)
+		auditService.AssertExpectations(t)
+	})
+
+	t.Run("Reclassified Concurrently", func(t *testing.T) {
+		ctx := principalContext(domain.UserRoleAdmin)
+		mockRepo := new(MockAgentRepository)
+		auditService := new(MockAuditService)
+		service := NewAgentService(mockRepo, new(MockTxManager), auditService)
+
+		mockRepo.On("GetByID", ctx, agentID).Return(&domain.Agent{ID: agentID, OrganizationID: orgID, RiskLevel: domain.AgentRiskLevelMinimal}, nil)
+		mockRepo.On("UpdateRiskLevel", ctx, agentID, domain.AgentRiskLevelMinimal, domain.AgentRiskLevelLimited, userID).Return(false, nil)
+
+		_, err := service.SetRiskLevel(ctx, agentID, userID, domain.AgentRiskLevelLimited)
+		assert.ErrorIs(t, err, ErrAgentChanged)
+		auditService.AssertNotCalled(t, "LogAction")
+	})
+
+	t.Run("Managers Cannot Classify", func(t *testing.T) {
+		service := NewAgentService(new(MockAgentRepository), new(MockTxManager), new(MockAuditService))
+
+		_, err := service.SetRiskLevel(principalContext(domain.UserRoleManager), agentID, userID, domain.AgentRiskLevelMinimal)
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+	})
+
+	t.Run("Invalid Level", func(t *testing.T) {
+		service := NewAgentService(new(MockAgentRepository), new(MockTxManager), new(MockAuditService))
+
+		_, err := service.SetRiskLevel(principalContext(domain.UserRoleAdmin), agentID, userID, "extreme")
+		assert.ErrorIs(t, err, ErrInvalidRiskLevel)
+	})
+}
+
+func TestCanTransition(t *testing.T) {
+	const (
+		active      = domain.AgentStatusActive
+		inactive    = domain.AgentStatusInactive
+		maintenance = domain.AgentStatusMaintenance
+		deprecated  = domain.AgentStatusDeprecated
+	)
+
+	tests := []struct {
+		from, to domain.AgentStatus
+		want     bool
+	}{
+		{active, inactive, true},
+		{active, maintenance, true},
+		{active, deprecated, true},
+		{inactive, active, true},
+		{maintenance, active, true},
+		{maintenance, inactive, true},
+		{active, active, false},
+		{deprecated, active, false},
+		{deprecated, inactive, false},
+		{active, "garbage", false},
+		{"garbage", active, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			assert.Equal(t, tt.want, CanTransition(tt.from, tt.to))
+		})
+	}
+}
+
+func TestAgentService_TransitionAgent(t *testing.T) {
+	ctx := principalContext(domain.UserRoleManager)
+	userID := uuid.New()
+	agentID := uuid.New()
+
+	agentIn := func(status domain.AgentStatus) *domain.Agent {
+		return &domain.Agent{ID: agentID, Status: status}
+	}
+
+	t.Run("Maintenance", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		txManager := new(MockTxManager)
+		service := NewAgentService(mockRepo, txManager, acceptingAudit())
+
+		mockRepo.On("GetByID", ctx, agentID).Return(agentIn(domain.AgentStatusActive), nil)
+		mockRepo.On("UpdateStatus", ctx, agentID, domain.AgentStatusActive, domain.AgentStatusMaintenance, userID).Return(true, nil)
+		mockRepo.On("CreateStatusTransition", ctx, mock.MatchedBy(func(tr *domain.AgentStatusTransition) bool {
+			return tr.FromStatus == domain.AgentStatusActive && tr.ToStatus == domain.AgentStatusMaintenance &&
+				tr.Reason == "reindexing" && *tr.ChangedBy == userID
+		})).Return(nil)
+
+		agent, err := service.TransitionAgent(ctx, agentID, userID, domain.AgentStatusMaintenance, "reindexing")
+		assert.NoError(t, err)
+		assert.Equal(t, domain.AgentStatusMaintenance, agent.Status)
+		assert.Equal(t, 1, txManager.calls)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Maintenance Requires Reason", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), acceptingAudit())
+
+		mockRepo.On("GetByID", ctx, agentID).Return(agentIn(domain.AgentStatusActive), nil)
+
+		_, err := service.TransitionAgent(ctx, agentID, userID, domain.AgentStatusMaintenance, "")
+		assert.ErrorIs(t, err, ErrTransitionReasonRequired)
+	})
+
+	t.Run("Deprecated Is Terminal", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), acceptingAudit())
+
+		mockRepo.On("GetByID", ctx, agentID).Return(agentIn(domain.AgentStatusDeprecated), nil)
+
+		_, err := service.TransitionAgent(ctx, agentID, userID, domain.AgentStatusInactive, "")
+		assert.ErrorIs(t, err, ErrInvalidTransition)
+		mockRepo.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown Status", func(t *testing.T) {
+		service := NewAgentService(new(MockAgentRepository), new(MockTxManager), acceptingAudit())
+
+		_, err := service.TransitionAgent(ctx, agentID, userID, "garbage", "")
+		assert.ErrorIs(t, err, ErrInvalidAgentStatus)
+	})
+
+	t.Run("Reactivation Requires Certification", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), acceptingAudit())
+
+		mockRepo.On("GetByID", ctx, agentID).Return(agentIn(domain.AgentStatusMaintenance), nil)
+		mockRepo.On("GetAgentCertifications", ctx, agentID).Return([]domain.AgentCertification{}, nil)
+
+		_, err := service.TransitionAgent(ctx, agentID, userID, domain.AgentStatusActive, "")
+		assert.ErrorIs(t, err, ErrValidCertificationRequired)
+	})
+
+	t.Run("Reactivation Rejects Expired Certification", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), acceptingAudit())
+
+		expired := time.Now().Add(-time.Hour)
+		mockRepo.On("GetByID", ctx, agentID).Return(agentIn(domain.AgentStatusInactive), nil)
+		mockRepo.On("GetAgentCertifications", ctx, agentID).Return([]domain.AgentCertification{
+			{CertificationID: uuid.New()},
+			{CertificationID: uuid.New(), ExpiresAt: &expired},
+		}, nil)
+
+		_, err := service.TransitionAgent(ctx, agentID, userID, domain.AgentStatusActive, "")
+		assert.ErrorIs(t, err, ErrValidCertificationRequired)
+	})
+
+	t.Run("Reactivation", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), acceptingAudit())
+
+		valid := time.Now().Add(24 * time.Hour)
+		mockRepo.On("GetByID", ctx, agentID).Return(agentIn(domain.AgentStatusInactive), nil)
+		mockRepo.On("GetAgentCertifications", ctx, agentID).Return([]domain.AgentCertification{{ExpiresAt: &valid}}, nil)
+		mockRepo.On("UpdateStatus", ctx, agentID, domain.AgentStatusInactive, domain.AgentStatusActive, userID).Return(true, nil)
+		mockRepo.On("CreateStatusTransition", ctx, mock.Anything).Return(nil)
+
+		agent, err := service.TransitionAgent(ctx, agentID, userID, domain.AgentStatusActive, "")
+		assert.NoError(t, err)
+		assert.Equal(t, domain.AgentStatusActive, agent.Status)
+	})
+
+	t.Run("Concurrent Transition", func(t *testing.T) {
+		mockRepo := new(MockAgentRepository)
+		service := NewAgentService(mockRepo, new(MockTxManager), acceptingAudit())
+
+		mockRepo.On("GetByID", ctx, agentID).Return(agentIn(domain.AgentStatusActive), nil)
+		mockRepo.On("UpdateStatus", ctx, agentID, domain.AgentStatusActive, domain.AgentStatusInactive, userID).Return(false, nil)
+
+		_, err := service.TransitionAgent(ctx, agentID, userID, domain.AgentStatusInactive, "")
+		assert.ErrorIs(t, err, ErrAgentStatusChanged)
+		mockRepo.AssertNotCalled(t, "CreateStatusTransition", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Managers Cannot Deprecate", func(t *testing.T) {
+		service := NewAgentService(new(MockAgentRepository), new(MockTxManager), acceptingAudit())
+
+		_, err := service.TransitionAgent(ctx, agentID, userID, domain.AgentStatusDeprecated, "end of life")
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+	})
+}
+
+func TestAgentService_UpdateDeprecatedAgent(t *testing.T) {
+	ctx := principalContext(domain.UserRoleAdmin)
+	agentID := uuid.New()
+	mockRepo := new(MockAgentRepository)
+	service := NewAgentService(mockRepo, new(MockTxManager), new(MockAuditService))
+
+	mockRepo.On("GetByID", ctx, agentID).Return(&domain.Agent{ID: agentID, Status: domain.AgentStatusDeprecated}, nil)
+
+	_, _, err := service.UpdateAgent(ctx, agentID, uuid.New(), "Name", json.RawMessage(`{}`), "")
+	assert.ErrorIs(t, err, ErrAgentDeprecated)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
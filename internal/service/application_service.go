@@ -2,15 +2,44 @@ package service
 
 import (
 	"agentXmap/internal/domain"
+	"agentXmap/internal/policy"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Raw API keys look like "sk-live-" + 16 hex lookup chars + 48 hex secret chars.
+// The lookup part is stored in clear (indexed) so a key row is found in one query;
+// the full key is then verified against its bcrypt hash.
+const (
+	apiKeyPrefix       = "sk-live-"
+	apiKeyLookupBytes  = 8
+	apiKeySecretBytes  = 24
+	apiKeyLookupLen    = apiKeyLookupBytes * 2
+	apiKeyRawLen       = len(apiKeyPrefix) + apiKeyLookupLen + apiKeySecretBytes*2
+	keyUsageResolution = time.Minute
+)
+
+var (
+	ErrInvalidAPIKey       = errors.New("invalid api key")
+	ErrAPIKeyExpired       = errors.New("api key expired")
+	ErrApplicationInactive = errors.New("application is inactive")
+	ErrApplicationNotFound = errors.New("application not found")
+)
+
+const (
+	// auditEntityApplication is the SystemAuditLog entity type of applications.
+	auditEntityApplication = "application"
+	// auditEntityAPIKey is the SystemAuditLog entity type of application keys.
+	auditEntityAPIKey = "api_key"
 )
 
 type ApplicationService interface {
@@ -19,17 +48,23 @@ type ApplicationService interface {
 	ListAssignedAgents(ctx context.Context, appID uuid.UUID) ([]domain.Agent, error)
 	ListApplicationCertifications(ctx context.Context, appID uuid.UUID) ([]domain.Certification, error)
 	CreateAPIKey(ctx context.Context, appID uuid.UUID, name string) (string, *domain.ApplicationKey, error)
+	AuthenticateKey(ctx context.Context, rawKey string) (*domain.ApplicationKey, error)
 }
 
 type DefaultApplicationService struct {
-	appRepo domain.ApplicationRepository
+	appRepo      domain.ApplicationRepository
+	txManager    domain.TxManager
+	auditService AuditService
 }
 
-func NewApplicationService(appRepo domain.ApplicationRepository) *DefaultApplicationService {
-	return &DefaultApplicationService{appRepo: appRepo}
+func NewApplicationService(appRepo domain.ApplicationRepository, txManager domain.TxManager, auditService AuditService) *DefaultApplicationService {
+	return &DefaultApplicationService{appRepo: appRepo, txManager: txManager, auditService: auditService}
 }
 
 func (s *DefaultApplicationService) CreateApplication(ctx context.Context, ownerID uuid.UUID, name, description string) (*domain.Application, error) {
+	if err := policy.Authorize(ctx, policy.ResourceApplication, policy.ActionCreate); err != nil {
+		return nil, err
+	}
 	if name == "" {
 		return nil, errors.New("application name is required")
 	}
@@ -41,17 +76,33 @@ func (s *DefaultApplicationService) CreateApplication(ctx context.Context, owner
 		IsActive:    true,
 	}
 
-	if err := s.appRepo.Create(ctx, app); err != nil {
+	err := s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.appRepo.Create(ctx, app); err != nil {
+			return err
+		}
+		return s.auditCreated(ctx, auditEntityApplication, app.ID, auditSnapshot{
+			"name":        app.Name,
+			"description": app.Description,
+			"is_active":   app.IsActive,
+			"owner_id":    app.OwnerID,
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
-
 	return app, nil
 }
 
 func (s *DefaultApplicationService) GetApplication(ctx context.Context, id uuid.UUID) (*domain.Application, error) {
+	if err := policy.Authorize(ctx, policy.ResourceApplication, policy.ActionRead); err != nil {
+		return nil, err
+	}
 	app, err := s.appRepo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && app == nil) {
+		return nil, ErrApplicationNotFound
+	}
 	if err != nil {
-		return nil, errors.New("application not found")
+		return nil, fmt.Errorf("failed to load application: %w", err)
 	}
 	return app, nil
 }
@@ -65,12 +116,16 @@ func (s *DefaultApplicationService) ListApplicationCertifications(ctx context.Co
 }
 
 func (s *DefaultApplicationService) CreateAPIKey(ctx context.Context, appID uuid.UUID, name string) (string, *domain.ApplicationKey, error) {
-	// Generate a secure random key
-	keyBytes := make([]byte, 32)
+	if err := policy.Authorize(ctx, policy.ResourceAPIKey, policy.ActionCreate); err != nil {
+		return "", nil, err
+	}
+	// Generate a secure random key: public lookup part + secret part
+	keyBytes := make([]byte, apiKeyLookupBytes+apiKeySecretBytes)
 	if _, err := rand.Read(keyBytes); err != nil {
 		return "", nil, fmt.Errorf("failed to generate random key: %w", err)
 	}
-	rawKey := "sk-live-" + hex.EncodeToString(keyBytes)
+	rawKey := apiKeyPrefix + hex.EncodeToString(keyBytes)
+	lookupID := rawKey[len(apiKeyPrefix) : len(apiKeyPrefix)+apiKeyLookupLen]
 
 	// Hash the key for storage
 	hashedKey, err := bcrypt.GenerateFromPassword([]byte(rawKey), bcrypt.DefaultCost)
@@ -78,30 +133,95 @@ func (s *DefaultApplicationService) CreateAPIKey(ctx context.Context, appID uuid
 		return "", nil, fmt.Errorf("failed to hash key: %w", err)
 	}
 
-	// Create the key record
+	// Store "sk-live-" + first 4 chars of hex as display prefix (12 chars).
 	key := &domain.ApplicationKey{
 		ApplicationID: appID,
 		KeyHash:       string(hashedKey),
-		KeyPrefix:     rawKey[:16] + "...", // Store prefix for display
+		LookupID:      &lookupID,
+		KeyPrefix:     rawKey[:12],
 		Name:          name,
 	}
-	// Correct prefix storage (sk-live-...)
-	// Actually typical pattern is to store "sk-live-" + first few chars.
-	// rawKey is "sk-live-" (8 chars) + 64 hex chars.
-	// Let's store "sk-live-" + first 4 chars of hex as prefix.
-	// Total prefix length: 8+4 = 12 chars.
-	key.KeyPrefix = rawKey[:12]
 
-	if err := s.appRepo.CreateKey(ctx, key); err != nil {
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.appRepo.CreateKey(ctx, key); err != nil {
+			return err
+		}
+		// Neither the raw key nor its hash are recorded.
+		return s.auditCreated(ctx, auditEntityAPIKey, key.ID, auditSnapshot{
+			"name":           key.Name,
+			"key_prefix":     key.KeyPrefix,
+			"application_id": key.ApplicationID,
+			"expires_at":     key.ExpiresAt,
+		})
+	})
+	if err != nil {
 		return "", nil, err
 	}
-
 	return rawKey, key, nil
 }
 
-// ValidateKey (future helper) checks if a provided raw API key matches the stored hash
+// auditCreated records the creation of an entity in the caller's organization.
+func (s *DefaultApplicationService) auditCreated(ctx context.Context, entityType string, entityID uuid.UUID, after auditSnapshot) error {
+	orgID, _ := domain.OrganizationFromContext(ctx)
+	return logMutation(ctx, s.auditService, orgID, entityType, entityID, domain.AuditActionCreate, nil, after)
+}
+
+// parseAPIKey extracts the lookup identifier from a raw key.
+func parseAPIKey(rawKey string) (string, bool) {
+	if len(rawKey) != apiKeyRawLen || !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return "", false
+	}
+	if _, err := hex.DecodeString(rawKey[len(apiKeyPrefix):]); err != nil {
+		return "", false
+	}
+	return rawKey[len(apiKeyPrefix) : len(apiKeyPrefix)+apiKeyLookupLen], true
+}
+
+// AuthenticateKey resolves a raw API key to its ApplicationKey (with Application preloaded),
+// rejecting expired keys and inactive applications. LastUsedAt is updated asynchronously.
+func (s *DefaultApplicationService) AuthenticateKey(ctx context.Context, rawKey string) (*domain.ApplicationKey, error) {
+	lookupID, ok := parseAPIKey(rawKey)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.appRepo.GetKeyByLookupID(ctx, lookupID)
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+	if !s.ValidateKey(rawKey, key.KeyHash) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now().UTC()
+	if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+		return nil, ErrAPIKeyExpired
+	}
+	if !key.Application.IsActive {
+		return nil, ErrApplicationInactive
+	}
+
+	s.touchKey(key, now)
+	return key, nil
+}
+
+// touchKey records key usage in the background, at most once per keyUsageResolution,
+// so authentication never waits on a write.
+func (s *DefaultApplicationService) touchKey(key *domain.ApplicationKey, usedAt time.Time) {
+	if key.LastUsedAt != nil && usedAt.Sub(*key.LastUsedAt) < keyUsageResolution {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		// Best effort: a missed update only delays LastUsedAt.
+		_ = s.appRepo.UpdateKeyLastUsed(ctx, key.ID, usedAt)
+	}()
+}
+
+// ValidateKey checks if a provided raw API key matches the stored hash
 func (s *DefaultApplicationService) ValidateKey(rawKey, storedHash string) bool {
-	if !strings.HasPrefix(rawKey, "sk-live-") {
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
 		return false
 	}
 	err := bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(rawKey))
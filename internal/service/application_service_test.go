@@ -2,14 +2,18 @@ package service
 
 import (
 	"agentXmap/internal/domain"
+	"agentXmap/internal/policy"
 	"context"
+	"encoding/json"
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
 // MockApplicationRepository is a mock implementation of domain.ApplicationRepository
@@ -35,6 +39,14 @@ func (m *MockApplicationRepository) GetAssignedAgents(ctx context.Context, appID
 	return args.Get(0).([]domain.Agent), args.Error(1)
 }
 
+func (m *MockApplicationRepository) GetAgentAccess(ctx context.Context, appID, agentID uuid.UUID) (*domain.ApplicationAgentAccess, error) {
+	args := m.Called(ctx, appID, agentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ApplicationAgentAccess), args.Error(1)
+}
+
 func (m *MockApplicationRepository) GetCertifications(ctx context.Context, appID uuid.UUID) ([]domain.Certification, error) {
 	args := m.Called(ctx, appID)
 	return args.Get(0).([]domain.Certification), args.Error(1)
@@ -45,15 +57,32 @@ func (m *MockApplicationRepository) CreateKey(ctx context.Context, key *domain.A
 	return args.Error(0)
 }
 
+func (m *MockApplicationRepository) GetKeyByLookupID(ctx context.Context, lookupID string) (*domain.ApplicationKey, error) {
+	args := m.Called(ctx, lookupID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ApplicationKey), args.Error(1)
+}
+
+func (m *MockApplicationRepository) UpdateKeyLastUsed(ctx context.Context, keyID uuid.UUID, usedAt time.Time) error {
+	args := m.Called(ctx, keyID, usedAt)
+	return args.Error(0)
+}
+
 func TestApplicationService_CreateApplication(t *testing.T) {
-	ctx := context.Background()
+	ctx := principalContext(domain.UserRoleAdmin)
 	ownerID := uuid.New()
 
 	t.Run("Success", func(t *testing.T) {
 		mockRepo := new(MockApplicationRepository)
-		service := NewApplicationService(mockRepo)
+		mockAudit := new(MockAuditService)
+		service := NewApplicationService(mockRepo, new(MockTxManager), mockAudit)
 
+		p, _ := domain.PrincipalFromContext(ctx)
 		mockRepo.On("Create", ctx, mock.AnythingOfType("*domain.Application")).Return(nil)
+		mockAudit.On("LogAction", ctx, p.OrganizationID, (*uuid.UUID)(nil), "application", mock.Anything, domain.AuditActionCreate,
+			json.RawMessage(`{"after":{"description":"Description","is_active":true,"name":"Test App","owner_id":"`+ownerID.String()+`"}}`), "").Return(nil)
 
 		app, err := service.CreateApplication(ctx, ownerID, "Test App", "Description")
 		assert.NoError(t, err)
@@ -61,11 +90,12 @@ func TestApplicationService_CreateApplication(t *testing.T) {
 		assert.Equal(t, "Test App", app.Name)
 		assert.Equal(t, ownerID, app.OwnerID)
 		mockRepo.AssertExpectations(t)
+		mockAudit.AssertExpectations(t)
 	})
 
 	t.Run("Empty Name", func(t *testing.T) {
 		mockRepo := new(MockApplicationRepository)
-		service := NewApplicationService(mockRepo)
+		service := NewApplicationService(mockRepo, new(MockTxManager), acceptingAudit())
 
 		_, err := service.CreateApplication(ctx, ownerID, "", "Description")
 		assert.Error(t, err)
@@ -74,12 +104,12 @@ func TestApplicationService_CreateApplication(t *testing.T) {
 }
 
 func TestApplicationService_GetApplication(t *testing.T) {
-	ctx := context.Background()
+	ctx := principalContext(domain.UserRoleAdmin)
 	appID := uuid.New()
 
 	t.Run("Success", func(t *testing.T) {
 		mockRepo := new(MockApplicationRepository)
-		service := NewApplicationService(mockRepo)
+		service := NewApplicationService(mockRepo, new(MockTxManager), new(MockAuditService))
 
 		expectedApp := &domain.Application{ID: appID, Name: "Test App"}
 		mockRepo.On("GetByID", ctx, appID).Return(expectedApp, nil)
@@ -92,25 +122,41 @@ func TestApplicationService_GetApplication(t *testing.T) {
 
 	t.Run("Not Found", func(t *testing.T) {
 		mockRepo := new(MockApplicationRepository)
-		service := NewApplicationService(mockRepo)
+		service := NewApplicationService(mockRepo, new(MockTxManager), new(MockAuditService))
 
-		mockRepo.On("GetByID", ctx, appID).Return(nil, errors.New("db error"))
+		mockRepo.On("GetByID", ctx, appID).Return(nil, gorm.ErrRecordNotFound)
 
 		_, err := service.GetApplication(ctx, appID)
-		assert.Error(t, err)
-		assert.Equal(t, "application not found", err.Error())
+		assert.ErrorIs(t, err, ErrApplicationNotFound)
+	})
+
+	t.Run("Repository Error", func(t *testing.T) {
+		mockRepo := new(MockApplicationRepository)
+		service := NewApplicationService(mockRepo, new(MockTxManager), new(MockAuditService))
+		dbErr := errors.New("connection reset")
+
+		mockRepo.On("GetByID", ctx, appID).Return(nil, dbErr)
+
+		_, err := service.GetApplication(ctx, appID)
+		assert.ErrorIs(t, err, dbErr)
+		assert.NotErrorIs(t, err, ErrApplicationNotFound)
 	})
 }
 
 func TestApplicationService_CreateAPIKey(t *testing.T) {
-	ctx := context.Background()
+	ctx := principalContext(domain.UserRoleAdmin)
 	appID := uuid.New()
 
 	t.Run("Success", func(t *testing.T) {
 		mockRepo := new(MockApplicationRepository)
-		service := NewApplicationService(mockRepo)
+		mockAudit := new(MockAuditService)
+		service := NewApplicationService(mockRepo, new(MockTxManager), mockAudit)
 
+		var recorded json.RawMessage
 		mockRepo.On("CreateKey", ctx, mock.AnythingOfType("*domain.ApplicationKey")).Return(nil)
+		mockAudit.On("LogAction", ctx, mock.Anything, (*uuid.UUID)(nil), "api_key", mock.Anything, domain.AuditActionCreate, mock.Anything, "").
+			Run(func(args mock.Arguments) { recorded = args.Get(6).(json.RawMessage) }).
+			Return(nil)
 
 		rawKey, key, err := service.CreateAPIKey(ctx, appID, "Test Key")
 		assert.NoError(t, err)
@@ -120,7 +166,124 @@ func TestApplicationService_CreateAPIKey(t *testing.T) {
 		assert.NotEmpty(t, key.KeyHash)
 		assert.Equal(t, "Test Key", key.Name)
 		assert.Equal(t, appID, key.ApplicationID)
+		if assert.NotNil(t, key.LookupID) {
+			assert.Equal(t, rawKey[8:24], *key.LookupID)
+		}
 		mockRepo.AssertExpectations(t)
+		mockAudit.AssertExpectations(t)
+		assert.Contains(t, string(recorded), `"key_prefix":"`+key.KeyPrefix+`"`)
+		assert.NotContains(t, string(recorded), rawKey)
+		assert.NotContains(t, string(recorded), key.KeyHash)
+	})
+
+	t.Run("Forbidden for managers", func(t *testing.T) {
+		mockRepo := new(MockApplicationRepository)
+		service := NewApplicationService(mockRepo, new(MockTxManager), acceptingAudit())
+
+		_, _, err := service.CreateAPIKey(principalContext(domain.UserRoleManager), appID, "Test Key")
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "CreateKey", mock.Anything, mock.Anything)
+	})
+}
+
+func TestApplicationService_AuthenticateKey(t *testing.T) {
+	ctx := context.Background()
+
+	// newStoredKey issues a real key through CreateAPIKey and returns it with its stored row.
+	newStoredKey := func(t *testing.T) (string, *domain.ApplicationKey) {
+		adminCtx := principalContext(domain.UserRoleAdmin)
+		mockRepo := new(MockApplicationRepository)
+		mockRepo.On("CreateKey", adminCtx, mock.AnythingOfType("*domain.ApplicationKey")).Return(nil)
+		rawKey, key, err := NewApplicationService(mockRepo, new(MockTxManager), acceptingAudit()).CreateAPIKey(adminCtx, uuid.New(), "k")
+		assert.NoError(t, err)
+		key.ID = uuid.New()
+		key.Application = domain.Application{ID: key.ApplicationID, IsActive: true}
+		return rawKey, key
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		rawKey, stored := newStoredKey(t)
+		mockRepo := new(MockApplicationRepository)
+		service := NewApplicationService(mockRepo, new(MockTxManager), new(MockAuditService))
+
+		touched := make(chan struct{})
+		mockRepo.On("GetKeyByLookupID", ctx, *stored.LookupID).Return(stored, nil)
+		mockRepo.On("UpdateKeyLastUsed", mock.Anything, stored.ID, mock.AnythingOfType("time.Time")).
+			Run(func(mock.Arguments) { close(touched) }).
+			Return(nil)
+
+		key, err := service.AuthenticateKey(ctx, rawKey)
+		assert.NoError(t, err)
+		assert.Equal(t, stored.ID, key.ID)
+
+		select {
+		case <-touched:
+		case <-time.After(time.Second):
+			t.Fatal("LastUsedAt was not updated")
+		}
+	})
+
+	t.Run("Recently Used Skips Update", func(t *testing.T) {
+		rawKey, stored := newStoredKey(t)
+		recent := time.Now()
+		stored.LastUsedAt = &recent
+		mockRepo := new(MockApplicationRepository)
+		service := NewApplicationService(mockRepo, new(MockTxManager), new(MockAuditService))
+
+		mockRepo.On("GetKeyByLookupID", ctx, *stored.LookupID).Return(stored, nil)
+
+		_, err := service.AuthenticateKey(ctx, rawKey)
+		assert.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "UpdateKeyLastUsed", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Wrong Secret", func(t *testing.T) {
+		rawKey, stored := newStoredKey(t)
+		mockRepo := new(MockApplicationRepository)
+		service := NewApplicationService(mockRepo, new(MockTxManager), new(MockAuditService))
+
+		mockRepo.On("GetKeyByLookupID", ctx, *stored.LookupID).Return(stored, nil)
+
+		tampered := rawKey[:len(rawKey)-1] + "0"
+		if tampered == rawKey {
+			tampered = rawKey[:len(rawKey)-1] + "1"
+		}
+		_, err := service.AuthenticateKey(ctx, tampered)
+		assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		rawKey, stored := newStoredKey(t)
+		past := time.Now().Add(-time.Hour)
+		stored.ExpiresAt = &past
+		mockRepo := new(MockApplicationRepository)
+		service := NewApplicationService(mockRepo, new(MockTxManager), new(MockAuditService))
+
+		mockRepo.On("GetKeyByLookupID", ctx, *stored.LookupID).Return(stored, nil)
+
+		_, err := service.AuthenticateKey(ctx, rawKey)
+		assert.ErrorIs(t, err, ErrAPIKeyExpired)
+	})
+
+	t.Run("Inactive Application", func(t *testing.T) {
+		rawKey, stored := newStoredKey(t)
+		stored.Application.IsActive = false
+		mockRepo := new(MockApplicationRepository)
+		service := NewApplicationService(mockRepo, new(MockTxManager), new(MockAuditService))
+
+		mockRepo.On("GetKeyByLookupID", ctx, *stored.LookupID).Return(stored, nil)
+
+		_, err := service.AuthenticateKey(ctx, rawKey)
+		assert.ErrorIs(t, err, ErrApplicationInactive)
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		mockRepo := new(MockApplicationRepository)
+		service := NewApplicationService(mockRepo, new(MockTxManager), new(MockAuditService))
+
+		_, err := service.AuthenticateKey(ctx, "sk-live-short")
+		assert.ErrorIs(t, err, ErrInvalidAPIKey)
+		mockRepo.AssertNotCalled(t, "GetKeyByLookupID", mock.Anything, mock.Anything)
 	})
 }
 
@@ -130,7 +293,7 @@ func TestApplicationService_ListAssignedAgents(t *testing.T) {
 
 	t.Run("Success", func(t *testing.T) {
 		mockRepo := new(MockApplicationRepository)
-		service := NewApplicationService(mockRepo)
+		service := NewApplicationService(mockRepo, new(MockTxManager), new(MockAuditService))
 
 		expectedAgents := []domain.Agent{
 			{Name: "Agent Y"},
@@ -145,7 +308,7 @@ func TestApplicationService_ListAssignedAgents(t *testing.T) {
 
 	t.Run("Error", func(t *testing.T) {
 		mockRepo := new(MockApplicationRepository)
-		service := NewApplicationService(mockRepo)
+		service := NewApplicationService(mockRepo, new(MockTxManager), new(MockAuditService))
 
 		mockRepo.On("GetAssignedAgents", ctx, appID).Return([]domain.Agent{}, errors.New("db error"))
 
@@ -162,7 +325,7 @@ func TestApplicationService_ListApplicationCertifications(t *testing.T) {
 
 	t.Run("Success", func(t *testing.T) {
 		mockRepo := new(MockApplicationRepository)
-		service := NewApplicationService(mockRepo)
+		service := NewApplicationService(mockRepo, new(MockTxManager), new(MockAuditService))
 
 		expectedCerts := []domain.Certification{
 			{Name: "SOC2"},
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"agentXmap/internal/domain"
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// auditChainPageSize is how many entries VerifyChain reads at a time.
+const auditChainPageSize = 500
+
+// AuditChainBreak is an entry that does not follow from the one before it.
+type AuditChainBreak struct {
+	Seq     int64     `json:"seq"`
+	EntryID uuid.UUID `json:"entry_id"`
+	Reason  string    `json:"reason" example:"hash mismatch"`
+}
+
+// AuditChainReport is the result of verifying one organization's audit chain.
+type AuditChainReport struct {
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Entries        int64     `json:"entries"`
+	// HeadSeq and HeadHash identify the last entry. Recording them elsewhere is
+	// what lets a later run notice entries removed from the end of the chain.
+	HeadSeq  int64             `json:"head_seq"`
+	HeadHash string            `json:"head_hash,omitempty"`
+	Breaks   []AuditChainBreak `json:"breaks,omitempty"`
+}
+
+// Intact reports whether the chain verified without breaks.
+func (r *AuditChainReport) Intact() bool {
+	return len(r.Breaks) == 0
+}
+
+// AuditChainService verifies the hash chains of SystemAuditLog. It runs as
+// operator tooling across all organizations, outside any Principal.
+type AuditChainService interface {
+	// VerifyChain recomputes every entry of orgID in Seq order and reports each
+	// one whose Seq, PrevHash or Hash does not follow from its predecessor.
+	// Verification carries on past a break, from the entry as stored.
+	VerifyChain(ctx context.Context, orgID uuid.UUID) (*AuditChainReport, error)
+	// VerifyAll runs VerifyChain for every organization with audit entries.
+	VerifyAll(ctx context.Context) ([]AuditChainReport, error)
+}
+
+type DefaultAuditChainService struct {
+	repo domain.AuditChainRepository
+}
+
+func NewAuditChainService(repo domain.AuditChainRepository) *DefaultAuditChainService {
+	return &DefaultAuditChainService{repo: repo}
+}
+
+func (s *DefaultAuditChainService) VerifyChain(ctx context.Context, orgID uuid.UUID) (*AuditChainReport, error) {
+	report := &AuditChainReport{OrganizationID: orgID}
+	for {
+		logs, err := s.repo.ListChain(ctx, orgID, report.HeadSeq, auditChainPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for i := range logs {
+			entry := &logs[i]
+			for _, reason := range s.check(report, entry) {
+				report.Breaks = append(report.Breaks, AuditChainBreak{Seq: entry.Seq, EntryID: entry.ID, Reason: reason})
+			}
+			report.Entries++
+			report.HeadSeq = entry.Seq
+			report.HeadHash = entry.Hash
+		}
+		if len(logs) < auditChainPageSize {
+			return report, nil
+		}
+	}
+}
+
+func (s *DefaultAuditChainService) VerifyAll(ctx context.Context) ([]AuditChainReport, error) {
+	orgIDs, err := s.repo.ListChainOrganizations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reports := make([]AuditChainReport, 0, len(orgIDs))
+	for _, orgID := range orgIDs {
+		report, err := s.VerifyChain(ctx, orgID)
+		if err != nil {
+			return reports, err
+		}
+		reports = append(reports, *report)
+	}
+	return reports, nil
+}
+
+// check compares entry with the head of the chain verified so far.
+func (s *DefaultAuditChainService) check(report *AuditChainReport, entry *domain.SystemAuditLog) []string {
+	var reasons []string
+	if entry.Seq != report.HeadSeq+1 {
+		reasons = append(reasons, fmt.Sprintf("sequence gap: expected %d", report.HeadSeq+1))
+	}
+	if entry.PrevHash != report.HeadHash {
+		reasons = append(reasons, "previous hash mismatch")
+	}
+	hash, err := entry.ChainHash()
+	switch {
+	case err != nil:
+		reasons = append(reasons, "unreadable entry: "+err.Error())
+	case hash != entry.Hash:
+		reasons = append(reasons, "hash mismatch")
+	}
+	return reasons
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"agentXmap/internal/domain"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockAuditChainRepository is a mock implementation of domain.AuditChainRepository
+type MockAuditChainRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditChainRepository) ListChainOrganizations(ctx context.Context) ([]uuid.UUID, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockAuditChainRepository) ListChain(ctx context.Context, orgID uuid.UUID, afterSeq int64, limit int) ([]domain.SystemAuditLog, error) {
+	args := m.Called(ctx, orgID, afterSeq, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.SystemAuditLog), args.Error(1)
+}
+
+// auditChain builds a valid chain of n entries for orgID.
+func auditChain(t *testing.T, orgID uuid.UUID, n int) []domain.SystemAuditLog {
+	logs := make([]domain.SystemAuditLog, n)
+	prev := ""
+	for i := range logs {
+		logs[i] = domain.SystemAuditLog{
+			ID:             uuid.New(),
+			OrganizationID: orgID,
+			Seq:            int64(i + 1),
+			EntityType:     "agent",
+			EntityID:       uuid.New(),
+			Action:         domain.AuditActionUpdate,
+			Changes:        json.RawMessage(`{"name": "v` + string(rune('a'+i)) + `"}`),
+			OccurredAt:     time.Date(2026, 1, 1, 0, i, 0, 0, time.UTC),
+			PrevHash:       prev,
+		}
+		hash, err := logs[i].ChainHash()
+		require.NoError(t, err)
+		logs[i].Hash = hash
+		prev = hash
+	}
+	return logs
+}
+
+func TestAuditChainService_VerifyChain(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+
+	verify := func(logs []domain.SystemAuditLog) *AuditChainReport {
+		repo := new(MockAuditChainRepository)
+		repo.On("ListChain", ctx, orgID, int64(0), auditChainPageSize).Return(logs, nil)
+		report, err := NewAuditChainService(repo).VerifyChain(ctx, orgID)
+		require.NoError(t, err)
+		return report
+	}
+
+	t.Run("Intact", func(t *testing.T) {
+		logs := auditChain(t, orgID, 3)
+		report := verify(logs)
+		assert.True(t, report.Intact())
+		assert.Equal(t, int64(3), report.Entries)
+		assert.Equal(t, logs[2].Hash, report.HeadHash)
+	})
+
+	t.Run("Changes Reformatted By jsonb", func(t *testing.T) {
+		logs := auditChain(t, orgID, 1)
+		logs[0].Changes = json.RawMessage(`{"name":"va"}`)
+		assert.True(t, verify(logs).Intact())
+	})
+
+	t.Run("Edited Entry", func(t *testing.T) {
+		logs := auditChain(t, orgID, 3)
+		logs[1].Changes = json.RawMessage(`{"name": "forged"}`)
+
+		report := verify(logs)
+		require.Len(t, report.Breaks, 1)
+		assert.Equal(t, int64(2), report.Breaks[0].Seq)
+		assert.Equal(t, "hash mismatch", report.Breaks[0].Reason)
+	})
+
+	t.Run("Rehashed Entry Breaks The Next Link", func(t *testing.T) {
+		logs := auditChain(t, orgID, 3)
+		logs[1].Action = domain.AuditActionDelete
+		logs[1].Hash, _ = logs[1].ChainHash()
+
+		report := verify(logs)
+		require.Len(t, report.Breaks, 1)
+		assert.Equal(t, int64(3), report.Breaks[0].Seq)
+		assert.Equal(t, "previous hash mismatch", report.Breaks[0].Reason)
+	})
+
+	t.Run("Deleted Entry", func(t *testing.T) {
+		logs := auditChain(t, orgID, 4)
+		logs = append(logs[:1], logs[2:]...)
+
+		report := verify(logs)
+		require.Len(t, report.Breaks, 2)
+		assert.Equal(t, int64(3), report.Breaks[0].Seq)
+		assert.Equal(t, "sequence gap: expected 2", report.Breaks[0].Reason)
+		assert.Equal(t, "previous hash mismatch", report.Breaks[1].Reason)
+		assert.Equal(t, int64(3), report.Entries)
+	})
+}
+
+func TestAuditChainService_VerifyAll(t *testing.T) {
+	ctx := context.Background()
+	intact, broken := uuid.New(), uuid.New()
+	brokenLogs := auditChain(t, broken, 2)
+	brokenLogs[0].IPAddress = "10.0.0.1"
+
+	repo := new(MockAuditChainRepository)
+	repo.On("ListChainOrganizations", ctx).Return([]uuid.UUID{intact, broken}, nil)
+	repo.On("ListChain", ctx, intact, int64(0), auditChainPageSize).Return(auditChain(t, intact, 2), nil)
+	repo.On("ListChain", ctx, broken, int64(0), auditChainPageSize).Return(brokenLogs, nil)
+
+	reports, err := NewAuditChainService(repo).VerifyAll(ctx)
+	assert.NoError(t, err)
+	require.Len(t, reports, 2)
+	assert.True(t, reports[0].Intact())
+	assert.False(t, reports[1].Intact())
+	assert.Equal(t, brokenLogs[0].ID, reports[1].Breaks[0].EntryID)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"agentXmap/internal/domain"
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/google/uuid"
+)
+
+// auditSnapshot is the audited state of an entity, keyed by JSON field name.
+// Only fields worth tracking are listed: no timestamps, relations or secrets.
+type auditSnapshot map[string]any
+
+// auditChanges builds the SystemAuditLog.Changes of a mutation as
+// {"before": {...}, "after": {...}}, keeping only the fields that differ.
+// A nil before stands for a creation and a nil after for a deletion. ok is
+// false when nothing changed.
+func auditChanges(before, after auditSnapshot) (changes json.RawMessage, ok bool, err error) {
+	b, err := normalizeSnapshot(before)
+	if err != nil {
+		return nil, false, err
+	}
+	a, err := normalizeSnapshot(after)
+	if err != nil {
+		return nil, false, err
+	}
+	if b != nil && a != nil {
+		for field, value := range b {
+			if reflect.DeepEqual(value, a[field]) {
+				delete(b, field)
+				delete(a, field)
+			}
+		}
+		if len(b) == 0 && len(a) == 0 {
+			return nil, false, nil
+		}
+	}
+
+	diff := map[string]map[string]any{}
+	if b != nil {
+		diff["before"] = b
+	}
+	if a != nil {
+		diff["after"] = a
+	}
+	changes, err = json.Marshal(diff)
+	return changes, err == nil, err
+}
+
+// normalizeSnapshot round-trips s through JSON, so that values compare as
+// they will be stored: json.RawMessage documents regardless of formatting,
+// typed strings as strings.
+func normalizeSnapshot(s auditSnapshot) (map[string]any, error) {
+	if s == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// logMutation audits a mutation of an entity with the fields it changed, and
+// records nothing for a no-op update. Every mutation is audited through it, so
+// the actor is always resolved the same way: AuditService takes the Principal
+// carried by ctx, and background jobs without one are recorded as the system.
+func logMutation(ctx context.Context, audit AuditService, orgID uuid.UUID, entityType string, entityID uuid.UUID, action domain.AuditAction, before, after auditSnapshot) error {
+	changes, ok, err := auditChanges(before, after)
+	if err != nil || !ok {
+		return err
+	}
+	return audit.LogAction(ctx, orgID, nil, entityType, entityID, action, changes, "")
+}
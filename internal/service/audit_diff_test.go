@@ -0,0 +1,43 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditChanges(t *testing.T) {
+	t.Run("Create Records Every Field", func(t *testing.T) {
+		changes, ok, err := auditChanges(nil, auditSnapshot{"name": "Scoring", "configuration": json.RawMessage(`{"model":"gpt-4"}`)})
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.JSONEq(t, `{"after":{"name":"Scoring","configuration":{"model":"gpt-4"}}}`, string(changes))
+	})
+
+	t.Run("Delete Records Every Field", func(t *testing.T) {
+		changes, ok, err := auditChanges(auditSnapshot{"name": "Scoring"}, nil)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.JSONEq(t, `{"before":{"name":"Scoring"}}`, string(changes))
+	})
+
+	t.Run("Update Keeps Changed Fields", func(t *testing.T) {
+		changes, ok, err := auditChanges(
+			auditSnapshot{"name": "Scoring", "status": "active"},
+			auditSnapshot{"name": "Scoring", "status": "maintenance"},
+		)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.JSONEq(t, `{"before":{"status":"active"},"after":{"status":"maintenance"}}`, string(changes))
+	})
+
+	t.Run("Document Formatting Is Not A Change", func(t *testing.T) {
+		_, ok, err := auditChanges(
+			auditSnapshot{"configuration": json.RawMessage(`{"model": "gpt-4", "temperature": 0.2}`)},
+			auditSnapshot{"configuration": json.RawMessage(`{"temperature":0.2,"model":"gpt-4"}`)},
+		)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
@@ -2,15 +2,69 @@ package service
 
 import (
 	"agentXmap/internal/domain"
+	"agentXmap/internal/policy"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"strconv"
 
 	"github.com/google/uuid"
 )
 
+var (
+	ErrInvalidAuditCursor = errors.New("invalid cursor")
+	ErrInvalidAuditAction = errors.New("invalid audit action")
+	ErrInvalidTimeRange   = errors.New("from must be before to")
+)
+
+// auditEntityAuditLog is the SystemAuditLog entity type of audit trail exports.
+const auditEntityAuditLog = "audit_log"
+
+// auditExportPageSize is how many entries ExportLogs reads at a time.
+const auditExportPageSize = 500
+
+// auditQueryDefaultLimit and auditQueryMaxLimit bound the page size of QueryLogs.
+const (
+	auditQueryDefaultLimit = 20
+	auditQueryMaxLimit     = 100
+)
+
+// auditActions lists every AuditAction a filter may name.
+var auditActions = map[domain.AuditAction]bool{
+	domain.AuditActionCreate:      true,
+	domain.AuditActionUpdate:      true,
+	domain.AuditActionDelete:      true,
+	domain.AuditActionLogin:       true,
+	domain.AuditActionExportData:  true,
+	domain.AuditActionApprove:     true,
+	domain.AuditActionReject:      true,
+	domain.AuditActionReadSecret:  true,
+	domain.AuditActionRestore:     true,
+	domain.AuditActionIssueLease:  true,
+	domain.AuditActionExpireLease: true,
+}
+
+// AuditLogPage is one page of an organization's audit trail, newest first.
+type AuditLogPage struct {
+	Items []domain.SystemAuditLog `json:"items"`
+	// NextCursor fetches the next, older page. It is empty on the last page.
+	NextCursor string `json:"next_cursor,omitempty" example:"MTI4"`
+}
+
 type AuditService interface {
+	// LogAction appends an entry to the audit trail. A nil actorUserID defaults
+	// to the Principal in ctx and an empty ipAddress to domain.ClientIPFromContext.
 	LogAction(ctx context.Context, orgID uuid.UUID, actorUserID *uuid.UUID, entityType string, entityID uuid.UUID, action domain.AuditAction, changes json.RawMessage, ipAddress string) error
 	RecordExecution(ctx context.Context, exec *domain.AgentExecution) error
+	// QueryLogs returns up to limit entries of the caller's organization matching
+	// filter, newest first, starting after cursor when it is not empty. A limit
+	// below 1 means 20 and one above 100 means 100.
+	QueryLogs(ctx context.Context, filter domain.AuditLogFilter, cursor string, limit int) (*AuditLogPage, error)
+	// ExportLogs calls fn with every entry of the caller's organization matching
+	// filter, newest first, stopping at the first error fn returns. The export
+	// itself is audited as export_data before the first entry is read.
+	ExportLogs(ctx context.Context, filter domain.AuditLogFilter, fn func(*domain.SystemAuditLog) error) error
 }
 
 type DefaultAuditService struct {
@@ -22,6 +76,14 @@ func NewAuditService(auditRepo domain.AuditRepository) *DefaultAuditService {
 }
 
 func (s *DefaultAuditService) LogAction(ctx context.Context, orgID uuid.UUID, actorUserID *uuid.UUID, entityType string, entityID uuid.UUID, action domain.AuditAction, changes json.RawMessage, ipAddress string) error {
+	if actorUserID == nil {
+		if p, ok := domain.PrincipalFromContext(ctx); ok {
+			actorUserID = &p.UserID
+		}
+	}
+	if ipAddress == "" {
+		ipAddress = domain.ClientIPFromContext(ctx)
+	}
 	log := &domain.SystemAuditLog{
 		OrganizationID: orgID,
 		ActorUserID:    actorUserID,
@@ -44,3 +106,103 @@ func (s *DefaultAuditService) RecordExecution(ctx context.Context, exec *domain.
 	}
 	return s.auditRepo.CreateExecution(ctx, exec)
 }
+
+func (s *DefaultAuditService) QueryLogs(ctx context.Context, filter domain.AuditLogFilter, cursor string, limit int) (*AuditLogPage, error) {
+	if err := policy.Authorize(ctx, policy.ResourceAuditLog, policy.ActionRead); err != nil {
+		return nil, err
+	}
+	if err := checkAuditFilter(filter); err != nil {
+		return nil, err
+	}
+	beforeSeq, err := decodeAuditCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = auditQueryDefaultLimit
+	}
+	limit = min(limit, auditQueryMaxLimit)
+
+	// One extra entry tells whether an older page exists.
+	logs, err := s.auditRepo.ListLogs(ctx, filter, beforeSeq, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &AuditLogPage{Items: logs}
+	if len(logs) > limit {
+		page.Items = logs[:limit]
+		page.NextCursor = encodeAuditCursor(page.Items[limit-1].Seq)
+	}
+	return page, nil
+}
+
+func (s *DefaultAuditService) ExportLogs(ctx context.Context, filter domain.AuditLogFilter, fn func(*domain.SystemAuditLog) error) error {
+	if err := policy.Authorize(ctx, policy.ResourceAuditLog, policy.ActionRead); err != nil {
+		return err
+	}
+	if err := checkAuditFilter(filter); err != nil {
+		return err
+	}
+	caller, _ := domain.PrincipalFromContext(ctx)
+	changes, err := json.Marshal(map[string]any{
+		"actor_user_id": filter.ActorUserID,
+		"entity_type":   filter.EntityType,
+		"entity_id":     filter.EntityID,
+		"action":        filter.Action,
+		"from":          filter.From,
+		"to":            filter.To,
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.LogAction(ctx, caller.OrganizationID, &caller.UserID, auditEntityAuditLog, caller.OrganizationID, domain.AuditActionExportData, changes, ""); err != nil {
+		return err
+	}
+
+	var beforeSeq int64
+	for {
+		logs, err := s.auditRepo.ListLogs(ctx, filter, beforeSeq, auditExportPageSize)
+		if err != nil {
+			return err
+		}
+		for i := range logs {
+			if err := fn(&logs[i]); err != nil {
+				return err
+			}
+		}
+		if len(logs) < auditExportPageSize {
+			return nil
+		}
+		beforeSeq = logs[len(logs)-1].Seq
+	}
+}
+
+func checkAuditFilter(filter domain.AuditLogFilter) error {
+	if filter.Action != "" && !auditActions[filter.Action] {
+		return ErrInvalidAuditAction
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return ErrInvalidTimeRange
+	}
+	return nil
+}
+
+// Cursors are opaque to clients: the Seq of the last entry they received.
+func encodeAuditCursor(seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(seq, 10)))
+}
+
+func decodeAuditCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidAuditCursor
+	}
+	seq, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || seq < 1 {
+		return 0, ErrInvalidAuditCursor
+	}
+	return seq, nil
+}
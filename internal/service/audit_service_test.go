@@ -2,9 +2,11 @@ package service
 
 import (
 	"agentXmap/internal/domain"
+	"agentXmap/internal/policy"
 	"context"
 	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -23,6 +25,14 @@ func (m *MockAuditRepository) CreateLog(ctx context.Context, log *domain.SystemA
 	return args.Error(0)
 }
 
+func (m *MockAuditRepository) ListLogs(ctx context.Context, filter domain.AuditLogFilter, beforeSeq int64, limit int) ([]domain.SystemAuditLog, error) {
+	args := m.Called(ctx, filter, beforeSeq, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.SystemAuditLog), args.Error(1)
+}
+
 func (m *MockAuditRepository) CreateExecution(ctx context.Context, exec *domain.AgentExecution) error {
 	args := m.Called(ctx, exec)
 	return args.Error(0)
@@ -47,6 +57,21 @@ func TestAuditService_LogAction(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("Actor And IP From Context", func(t *testing.T) {
+		mockRepo := new(MockAuditRepository)
+		service := NewAuditService(mockRepo)
+		caller := &domain.Principal{UserID: userID, OrganizationID: orgID, Role: domain.UserRoleManager}
+		reqCtx := domain.WithClientIP(domain.WithPrincipal(ctx, caller), "203.0.113.7")
+
+		mockRepo.On("CreateLog", reqCtx, mock.MatchedBy(func(l *domain.SystemAuditLog) bool {
+			return l.ActorUserID != nil && *l.ActorUserID == userID && l.IPAddress == "203.0.113.7"
+		})).Return(nil)
+
+		err := service.LogAction(reqCtx, orgID, nil, "agent", entityID, domain.AuditActionUpdate, nil, "")
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
 	t.Run("Error", func(t *testing.T) {
 		mockRepo := new(MockAuditRepository)
 		service := NewAuditService(mockRepo)
@@ -76,3 +101,134 @@ func TestAuditService_RecordExecution(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+// auditEntries returns entries with the given Seqs, newest first.
+func auditEntries(seqs ...int64) []domain.SystemAuditLog {
+	logs := make([]domain.SystemAuditLog, len(seqs))
+	for i, seq := range seqs {
+		logs[i] = domain.SystemAuditLog{ID: uuid.New(), Seq: seq}
+	}
+	return logs
+}
+
+func TestAuditService_QueryLogs(t *testing.T) {
+	ctx := principalContext(domain.UserRoleAdmin)
+	filter := domain.AuditLogFilter{EntityType: "agent"}
+
+	t.Run("Pages With A Cursor", func(t *testing.T) {
+		mockRepo := new(MockAuditRepository)
+		service := NewAuditService(mockRepo)
+		mockRepo.On("ListLogs", ctx, filter, int64(0), 3).Return(auditEntries(9, 8, 7), nil)
+
+		page, err := service.QueryLogs(ctx, filter, "", 2)
+		assert.NoError(t, err)
+		assert.Len(t, page.Items, 2)
+		assert.NotEmpty(t, page.NextCursor)
+
+		mockRepo.On("ListLogs", ctx, filter, int64(8), 3).Return(auditEntries(7), nil)
+		page, err = service.QueryLogs(ctx, filter, page.NextCursor, 2)
+		assert.NoError(t, err)
+		assert.Len(t, page.Items, 1)
+		assert.Empty(t, page.NextCursor)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Clamps The Limit", func(t *testing.T) {
+		mockRepo := new(MockAuditRepository)
+		service := NewAuditService(mockRepo)
+		mockRepo.On("ListLogs", ctx, filter, int64(0), 21).Return(auditEntries(9, 8), nil)
+		mockRepo.On("ListLogs", ctx, filter, int64(0), 101).Return(auditEntries(9), nil)
+
+		for _, limit := range []int{0, -5, 1000} {
+			page, err := service.QueryLogs(ctx, filter, "", limit)
+			assert.NoError(t, err, limit)
+			assert.NotEmpty(t, page.Items, limit)
+			assert.Empty(t, page.NextCursor, limit)
+		}
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid Cursor", func(t *testing.T) {
+		service := NewAuditService(new(MockAuditRepository))
+		for _, cursor := range []string{"%%%", encodeAuditCursor(0), "YWJj"} {
+			_, err := service.QueryLogs(ctx, filter, cursor, 2)
+			assert.ErrorIs(t, err, ErrInvalidAuditCursor, cursor)
+		}
+	})
+
+	t.Run("Invalid Filter", func(t *testing.T) {
+		service := NewAuditService(new(MockAuditRepository))
+		_, err := service.QueryLogs(ctx, domain.AuditLogFilter{Action: "drop"}, "", 2)
+		assert.ErrorIs(t, err, ErrInvalidAuditAction)
+
+		now := time.Now()
+		_, err = service.QueryLogs(ctx, domain.AuditLogFilter{From: &now, To: &now}, "", 2)
+		assert.ErrorIs(t, err, ErrInvalidTimeRange)
+	})
+
+	t.Run("Admins Only", func(t *testing.T) {
+		mockRepo := new(MockAuditRepository)
+		service := NewAuditService(mockRepo)
+
+		_, err := service.QueryLogs(principalContext(domain.UserRoleManager), filter, "", 2)
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "ListLogs", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestAuditService_ExportLogs(t *testing.T) {
+	ctx := principalContext(domain.UserRoleAdmin)
+	caller, _ := domain.PrincipalFromContext(ctx)
+	filter := domain.AuditLogFilter{Action: domain.AuditActionDelete}
+
+	t.Run("Audits Then Streams Every Page", func(t *testing.T) {
+		mockRepo := new(MockAuditRepository)
+		service := NewAuditService(mockRepo)
+
+		first := make([]domain.SystemAuditLog, auditExportPageSize)
+		for i := range first {
+			first[i] = domain.SystemAuditLog{Seq: int64(auditExportPageSize + 10 - i)}
+		}
+		mockRepo.On("CreateLog", ctx, mock.MatchedBy(func(l *domain.SystemAuditLog) bool {
+			return l.Action == domain.AuditActionExportData && *l.ActorUserID == caller.UserID &&
+				l.OrganizationID == caller.OrganizationID && strings.Contains(string(l.Changes), `"action":"delete"`)
+		})).Return(nil)
+		mockRepo.On("ListLogs", ctx, filter, int64(0), auditExportPageSize).Return(first, nil)
+		mockRepo.On("ListLogs", ctx, filter, int64(11), auditExportPageSize).Return(auditEntries(10, 9), nil)
+
+		var seqs []int64
+		err := service.ExportLogs(ctx, filter, func(l *domain.SystemAuditLog) error {
+			seqs = append(seqs, l.Seq)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Len(t, seqs, auditExportPageSize+2)
+		assert.Equal(t, int64(9), seqs[len(seqs)-1])
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Nothing Exported If The Audit Fails", func(t *testing.T) {
+		mockRepo := new(MockAuditRepository)
+		service := NewAuditService(mockRepo)
+		mockRepo.On("CreateLog", ctx, mock.Anything).Return(errors.New("db error"))
+
+		err := service.ExportLogs(ctx, filter, func(*domain.SystemAuditLog) error { return nil })
+		assert.Error(t, err)
+		mockRepo.AssertNotCalled(t, "ListLogs", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Stops On Writer Error", func(t *testing.T) {
+		mockRepo := new(MockAuditRepository)
+		service := NewAuditService(mockRepo)
+		mockRepo.On("CreateLog", ctx, mock.Anything).Return(nil)
+		mockRepo.On("ListLogs", ctx, filter, int64(0), auditExportPageSize).Return(auditEntries(3, 2, 1), nil)
+
+		calls := 0
+		err := service.ExportLogs(ctx, filter, func(*domain.SystemAuditLog) error {
+			calls++
+			return errors.New("client gone")
+		})
+		assert.EqualError(t, err, "client gone")
+		assert.Equal(t, 1, calls)
+	})
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Change operations reported by DiffConfigurations.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// ConfigChange is one difference between two agent configurations.
+// Path is a JSON Pointer (RFC 6901) to the value, "" for the document root.
+type ConfigChange struct {
+	Path string `json:"path" example:"/model"`
+	Op   string `json:"op" example:"changed"`
+	From any    `json:"from,omitempty" swaggertype:"string" example:"gpt-3.5"`
+	To   any    `json:"to,omitempty" swaggertype:"string" example:"gpt-4"`
+}
+
+// DiffConfigurations compares two JSON documents and returns their
+// differences, objects being compared key by key and arrays index by index.
+func DiffConfigurations(from, to json.RawMessage) ([]ConfigChange, error) {
+	a, err := decodeConfig(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source configuration: %w", err)
+	}
+	b, err := decodeConfig(to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target configuration: %w", err)
+	}
+
+	changes := []ConfigChange{}
+	diffValues("", a, b, &changes)
+	return changes, nil
+}
+
+func decodeConfig(raw json.RawMessage) (any, error) {
+	if len(raw) == 0 {
+		return map[string]any{}, nil
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func diffValues(path string, a, b any, out *[]ConfigChange) {
+	switch av := a.(type) {
+	case map[string]any:
+		if bv, ok := b.(map[string]any); ok {
+			diffObjects(path, av, bv, out)
+			return
+		}
+	case []any:
+		if bv, ok := b.([]any); ok {
+			diffArrays(path, av, bv, out)
+			return
+		}
+	}
+	if !reflect.DeepEqual(a, b) {
+		*out = append(*out, ConfigChange{Path: path, Op: ChangeChanged, From: a, To: b})
+	}
+}
+
+func diffObjects(path string, a, b map[string]any, out *[]ConfigChange) {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		child := path + "/" + escapePointer(k)
+		av, inA := a[k]
+		bv, inB := b[k]
+		switch {
+		case !inB:
+			*out = append(*out, ConfigChange{Path: child, Op: ChangeRemoved, From: av})
+		case !inA:
+			*out = append(*out, ConfigChange{Path: child, Op: ChangeAdded, To: bv})
+		default:
+			diffValues(child, av, bv, out)
+		}
+	}
+}
+
+func diffArrays(path string, a, b []any, out *[]ConfigChange) {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		child := fmt.Sprintf("%s/%d", path, i)
+		switch {
+		case i >= len(b):
+			*out = append(*out, ConfigChange{Path: child, Op: ChangeRemoved, From: a[i]})
+		case i >= len(a):
+			*out = append(*out, ConfigChange{Path: child, Op: ChangeAdded, To: b[i]})
+		default:
+			diffValues(child, a[i], b[i], out)
+		}
+	}
+}
+
+// escapePointer escapes a key for use as a JSON Pointer reference token.
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"agentXmap/internal/connector"
+	"agentXmap/internal/domain"
+	"agentXmap/internal/policy"
+	"agentXmap/internal/secrets"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAgentNotInvocable = errors.New("application may not invoke this agent")
+	ErrAgentNotActive    = errors.New("agent is not active")
+	// ErrLeaseNotSupported is returned for resources whose type has no driver
+	// able to issue expiring credentials; their stored ones are never leased.
+	ErrLeaseNotSupported = errors.New("resource type cannot issue expiring credentials")
+	ErrIssueFailed       = errors.New("resource did not issue credentials")
+)
+
+// issueTimeout bounds the issuance of credentials by a driver.
+const issueTimeout = 10 * time.Second
+
+// auditEntityCredentialLease is the SystemAuditLog entity type of credential leases.
+const auditEntityCredentialLease = "credential_lease"
+
+// LeaseConfig sets how long leased credentials may be used, per AccessLevel.
+type LeaseConfig struct {
+	ReadOnlyTTL  time.Duration
+	ReadWriteTTL time.Duration
+}
+
+// IssuedLease is a lease together with the credentials it covers. The
+// credentials are only returned once, at issuance. They are minted for the
+// lease by the resource's driver and stop working when it expires.
+type IssuedLease struct {
+	domain.CredentialLease
+	Credentials json.RawMessage `json:"credentials" swaggertype:"object"`
+}
+
+// CredentialLeaseService hands agents credentials of a resource that expire.
+type CredentialLeaseService interface {
+	// IssueLease mints credentials of a resource for the Application in ctx,
+	// acting for agentID. The application must be allowed to invoke the agent,
+	// the agent must be active and hold an AgentResourceAccess to the resource,
+	// whose driver must be a connector.Issuer. The credentials carry that access
+	// level and expire after its TTL. The lease is audited as issue_lease before
+	// they are returned.
+	IssueLease(ctx context.Context, agentID, resourceID uuid.UUID) (*IssuedLease, error)
+	// ExpireLeases audits, as expire_lease, every lease of every organization
+	// whose TTL has run out by now, batchSize at a time. It returns how many it
+	// recorded.
+	ExpireLeases(ctx context.Context, now time.Time, batchSize int) (int, error)
+}
+
+type DefaultCredentialLeaseService struct {
+	leaseRepo    domain.CredentialLeaseRepository
+	resRepo      domain.ResourceRepository
+	agentRepo    domain.AgentRepository
+	appRepo      domain.ApplicationRepository
+	txManager    domain.TxManager
+	auditService AuditService
+	cipher       *secrets.Cipher
+	drivers      *connector.Registry
+	cfg          LeaseConfig
+}
+
+// NewCredentialLeaseService creates a new instance of DefaultCredentialLeaseService.
+// read_write leases default to 5 minutes and read_only ones to 15.
+func NewCredentialLeaseService(leaseRepo domain.CredentialLeaseRepository, resRepo domain.ResourceRepository, agentRepo domain.AgentRepository, appRepo domain.ApplicationRepository, txManager domain.TxManager, auditService AuditService, cipher *secrets.Cipher, drivers *connector.Registry, cfg LeaseConfig) *DefaultCredentialLeaseService {
+	if cfg.ReadOnlyTTL <= 0 {
+		cfg.ReadOnlyTTL = 15 * time.Minute
+	}
+	if cfg.ReadWriteTTL <= 0 {
+		cfg.ReadWriteTTL = 5 * time.Minute
+	}
+	return &DefaultCredentialLeaseService{
+		leaseRepo:    leaseRepo,
+		resRepo:      resRepo,
+		agentRepo:    agentRepo,
+		appRepo:      appRepo,
+		txManager:    txManager,
+		auditService: auditService,
+		cipher:       cipher,
+		drivers:      drivers,
+		cfg:          cfg,
+	}
+}
+
+func (s *DefaultCredentialLeaseService) IssueLease(ctx context.Context, agentID, resourceID uuid.UUID) (*IssuedLease, error) {
+	app, ok := domain.ApplicationPrincipalFromContext(ctx)
+	if !ok {
+		return nil, policy.ErrUnauthenticated
+	}
+
+	invocation, err := s.appRepo.GetAgentAccess(ctx, app.ApplicationID, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if invocation == nil || !invocation.CanInvoke {
+		return nil, ErrAgentNotInvocable
+	}
+	agent, err := s.agentRepo.GetByID(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if agent == nil {
+		return nil, ErrAgentNotFound
+	}
+	if agent.Status != domain.AgentStatusActive {
+		return nil, ErrAgentNotActive
+	}
+
+	res, err := findResource(ctx, s.resRepo, resourceID)
+	if err != nil {
+		return nil, err
+	}
+	access, err := s.resRepo.GetAccess(ctx, res.ID, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if access == nil {
+		return nil, ErrAccessNotGranted
+	}
+	ttl, err := s.ttl(access.Permission)
+	if err != nil {
+		return nil, err
+	}
+	driver, _ := s.drivers.Get(res.TypeID)
+	issuer, ok := driver.(connector.Issuer)
+	if !ok {
+		return nil, ErrLeaseNotSupported
+	}
+	secret, err := s.resRepo.GetSecret(ctx, res.ID)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, ErrResourceSecretNotFound
+	}
+	plaintext, err := s.cipher.Open(ctx, secret.EncryptedCredentials, secret.KeyVersionID, res.ID[:])
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	lease := &domain.CredentialLease{
+		OrganizationID: res.OrganizationID,
+		ResourceID:     res.ID,
+		AgentID:        agentID,
+		ApplicationID:  &app.ApplicationID,
+		AccessLevel:    access.Permission,
+		KeyVersionID:   secret.KeyVersionID,
+		IssuedAt:       now,
+		ExpiresAt:      now.Add(ttl),
+	}
+	// Credentials are only minted once the lease is recorded, and the record
+	// is rolled back if minting fails.
+	var issued json.RawMessage
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.leaseRepo.Create(ctx, lease); err != nil {
+			return err
+		}
+		if err := logMutation(ctx, s.auditService, lease.OrganizationID, auditEntityCredentialLease, lease.ID, domain.AuditActionIssueLease, nil, leaseSnapshot(lease)); err != nil {
+			return err
+		}
+		issueCtx, cancel := context.WithTimeout(ctx, issueTimeout)
+		defer cancel()
+		credentials, err := issuer.IssueCredentials(issueCtx, res.ConnectionDetails, plaintext, lease.AccessLevel == domain.AccessLevelReadOnly, lease.ExpiresAt)
+		if err != nil {
+			return issueError(err)
+		}
+		issued = credentials
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &IssuedLease{CredentialLease: *lease, Credentials: issued}, nil
+}
+
+func (s *DefaultCredentialLeaseService) ExpireLeases(ctx context.Context, now time.Time, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	expired := 0
+	for {
+		leases, err := s.leaseRepo.ListExpired(ctx, now, batchSize)
+		if err != nil {
+			return expired, err
+		}
+		for i := range leases {
+			lease := &leases[i]
+			recorded := false
+			err := s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+				ok, err := s.leaseRepo.MarkExpired(ctx, lease.ID, now)
+				if err != nil || !ok {
+					// Another instance recorded it first.
+					return err
+				}
+				recorded = true
+				before := leaseSnapshot(lease)
+				lease.ExpiredAt = &now
+				return logMutation(ctx, s.auditService, lease.OrganizationID, auditEntityCredentialLease, lease.ID, domain.AuditActionExpireLease, before, leaseSnapshot(lease))
+			})
+			if err != nil {
+				return expired, err
+			}
+			if recorded {
+				expired++
+			}
+		}
+		if len(leases) < batchSize {
+			return expired, nil
+		}
+	}
+}
+
+// issueError reports a driver failure as ErrIssueFailed with the driver's
+// message, which unlike the underlying error never quotes the credentials.
+func issueError(err error) error {
+	var cerr *connector.Error
+	if errors.As(err, &cerr) {
+		return fmt.Errorf("%w: %s: %s", ErrIssueFailed, cerr.Code, cerr.Message)
+	}
+	return fmt.Errorf("%w: %v", ErrIssueFailed, err)
+}
+
+func (s *DefaultCredentialLeaseService) ttl(level domain.AccessLevel) (time.Duration, error) {
+	switch level {
+	case domain.AccessLevelReadOnly:
+		return s.cfg.ReadOnlyTTL, nil
+	case domain.AccessLevelReadWrite:
+		return s.cfg.ReadWriteTTL, nil
+	default:
+		return 0, ErrInvalidAccessLevel
+	}
+}
+
+// leaseSnapshot is the audited state of a lease. It names the agent,
+// application and key version, never the credentials.
+func leaseSnapshot(lease *domain.CredentialLease) auditSnapshot {
+	return auditSnapshot{
+		"resource_id":    lease.ResourceID,
+		"agent_id":       lease.AgentID,
+		"application_id": lease.ApplicationID,
+		"access_level":   lease.AccessLevel,
+		"key_version_id": lease.KeyVersionID,
+		"expires_at":     lease.ExpiresAt,
+		"expired_at":     lease.ExpiredAt,
+	}
+}
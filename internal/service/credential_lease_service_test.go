@@ -0,0 +1,228 @@
+package service
+
+import (
+	"agentXmap/internal/connector"
+	"agentXmap/internal/domain"
+	"agentXmap/internal/policy"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockCredentialLeaseRepository is a mock implementation of domain.CredentialLeaseRepository
+type MockCredentialLeaseRepository struct {
+	mock.Mock
+}
+
+func (m *MockCredentialLeaseRepository) Create(ctx context.Context, lease *domain.CredentialLease) error {
+	args := m.Called(ctx, lease)
+	return args.Error(0)
+}
+
+func (m *MockCredentialLeaseRepository) ListExpired(ctx context.Context, now time.Time, limit int) ([]domain.CredentialLease, error) {
+	args := m.Called(ctx, now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.CredentialLease), args.Error(1)
+}
+
+func (m *MockCredentialLeaseRepository) MarkExpired(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	args := m.Called(ctx, id, at)
+	return args.Bool(0), args.Error(1)
+}
+
+// stubIssuer is a stubDriver that also issues credentials.
+type stubIssuer struct {
+	stubDriver
+	issued    json.RawMessage
+	readOnly  bool
+	expiresAt time.Time
+}
+
+func (d *stubIssuer) IssueCredentials(ctx context.Context, config, credentials json.RawMessage, readOnly bool, expiresAt time.Time) (json.RawMessage, error) {
+	d.config, d.credentials, d.readOnly, d.expiresAt = config, credentials, readOnly, expiresAt
+	return d.issued, d.err
+}
+
+func TestCredentialLeaseService_IssueLease(t *testing.T) {
+	app := &domain.ApplicationPrincipal{ApplicationID: uuid.New(), KeyID: uuid.New(), OrganizationID: uuid.New()}
+	ctx := domain.WithApplicationPrincipal(context.Background(), app)
+	agent := &domain.Agent{ID: uuid.New(), OrganizationID: app.OrganizationID, Status: domain.AgentStatusActive}
+	res := &domain.Resource{ID: uuid.New(), OrganizationID: app.OrganizationID, TypeID: "postgres_db",
+		ConnectionDetails: json.RawMessage(`{"host":"db"}`)}
+	credentials := `{"username":"app","password":"s3cret"}`
+	minted := `{"username":"axm_lease_1","password":"temporary"}`
+
+	sealed, version, err := testCipher().Seal(ctx, []byte(credentials), res.ID[:])
+	assert.NoError(t, err)
+	stored := &domain.ResourceSecret{ResourceID: res.ID, EncryptedCredentials: sealed, KeyVersionID: version}
+	invocable := &domain.ApplicationAgentAccess{ApplicationID: app.ApplicationID, AgentID: agent.ID, CanInvoke: true}
+	cfg := LeaseConfig{ReadOnlyTTL: 10 * time.Minute, ReadWriteTTL: 2 * time.Minute}
+
+	type mocks struct {
+		leases *MockCredentialLeaseRepository
+		res    *MockResourceRepository
+		agents *MockAgentRepository
+		apps   *MockApplicationRepository
+		audit  *MockAuditService
+		issuer *stubIssuer
+	}
+	setup := func() (*DefaultCredentialLeaseService, mocks) {
+		m := mocks{new(MockCredentialLeaseRepository), new(MockResourceRepository), new(MockAgentRepository), new(MockApplicationRepository), new(MockAuditService),
+			&stubIssuer{issued: json.RawMessage(minted)}}
+		drivers := connector.NewRegistry()
+		drivers.Register("postgres_db", m.issuer)
+		drivers.Register("rest_api", &stubDriver{})
+		return NewCredentialLeaseService(m.leases, m.res, m.agents, m.apps, new(MockTxManager), m.audit, testCipher(), drivers, cfg), m
+	}
+	granted := func(m mocks, level domain.AccessLevel) {
+		m.apps.On("GetAgentAccess", ctx, app.ApplicationID, agent.ID).Return(invocable, nil)
+		m.agents.On("GetByID", ctx, agent.ID).Return(agent, nil)
+		m.res.On("GetByID", ctx, res.ID).Return(res, nil)
+		m.res.On("GetAccess", ctx, res.ID, agent.ID).Return(&domain.AgentResourceAccess{AgentID: agent.ID, ResourceID: res.ID, Permission: level}, nil)
+		m.res.On("GetSecret", ctx, res.ID).Return(stored, nil)
+	}
+
+	t.Run("Success Scopes TTL To Access Level", func(t *testing.T) {
+		for level, ttl := range map[domain.AccessLevel]time.Duration{
+			domain.AccessLevelReadOnly:  cfg.ReadOnlyTTL,
+			domain.AccessLevelReadWrite: cfg.ReadWriteTTL,
+		} {
+			service, m := setup()
+			granted(m, level)
+			m.leases.On("Create", ctx, mock.AnythingOfType("*domain.CredentialLease")).Return(nil)
+			m.audit.On("LogAction", ctx, app.OrganizationID, (*uuid.UUID)(nil), "credential_lease", mock.Anything, domain.AuditActionIssueLease,
+				mock.MatchedBy(func(changes json.RawMessage) bool {
+					return strings.Contains(string(changes), `"access_level":"`+string(level)+`"`) && !strings.Contains(string(changes), "s3cret")
+				}), "").Return(nil)
+
+			lease, err := service.IssueLease(ctx, agent.ID, res.ID)
+			assert.NoError(t, err)
+			assert.JSONEq(t, minted, string(lease.Credentials))
+			assert.JSONEq(t, credentials, string(m.issuer.credentials))
+			assert.JSONEq(t, `{"host":"db"}`, string(m.issuer.config))
+			assert.Equal(t, level == domain.AccessLevelReadOnly, m.issuer.readOnly)
+			assert.Equal(t, lease.ExpiresAt, m.issuer.expiresAt)
+			assert.Equal(t, level, lease.AccessLevel)
+			assert.Equal(t, ttl, lease.ExpiresAt.Sub(lease.IssuedAt))
+			assert.Equal(t, time.UTC, lease.IssuedAt.Location())
+			assert.Equal(t, app.ApplicationID, *lease.ApplicationID)
+			m.audit.AssertExpectations(t)
+		}
+	})
+
+	t.Run("Audit Failure Withholds The Credentials", func(t *testing.T) {
+		service, m := setup()
+		granted(m, domain.AccessLevelReadOnly)
+		m.leases.On("Create", ctx, mock.Anything).Return(nil)
+		m.audit.On("LogAction", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(errors.New("audit down"))
+
+		lease, err := service.IssueLease(ctx, agent.ID, res.ID)
+		assert.Error(t, err)
+		assert.Nil(t, lease)
+		assert.Nil(t, m.issuer.credentials, "nothing is minted before the lease is audited")
+	})
+
+	t.Run("Issue Failure Withholds The Lease", func(t *testing.T) {
+		service, m := setup()
+		granted(m, domain.AccessLevelReadWrite)
+		m.issuer.err = &connector.Error{Code: connector.ErrorAuthFailed, Message: "permission denied", Err: errors.New("role app s3cret")}
+		m.leases.On("Create", ctx, mock.Anything).Return(nil)
+		m.audit.On("LogAction", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		lease, err := service.IssueLease(ctx, agent.ID, res.ID)
+		assert.ErrorIs(t, err, ErrIssueFailed)
+		assert.NotContains(t, err.Error(), "s3cret")
+		assert.Nil(t, lease)
+	})
+
+	t.Run("Type Cannot Issue Credentials", func(t *testing.T) {
+		service, m := setup()
+		restAPI := &domain.Resource{ID: res.ID, OrganizationID: res.OrganizationID, TypeID: "rest_api"}
+		m.apps.On("GetAgentAccess", ctx, app.ApplicationID, agent.ID).Return(invocable, nil)
+		m.agents.On("GetByID", ctx, agent.ID).Return(agent, nil)
+		m.res.On("GetByID", ctx, res.ID).Return(restAPI, nil)
+		m.res.On("GetAccess", ctx, res.ID, agent.ID).Return(&domain.AgentResourceAccess{Permission: domain.AccessLevelReadOnly}, nil)
+
+		_, err := service.IssueLease(ctx, agent.ID, res.ID)
+		assert.ErrorIs(t, err, ErrLeaseNotSupported)
+		m.res.AssertNotCalled(t, "GetSecret", mock.Anything, mock.Anything)
+		m.leases.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Application May Not Invoke Agent", func(t *testing.T) {
+		service, m := setup()
+		m.apps.On("GetAgentAccess", ctx, app.ApplicationID, agent.ID).Return(&domain.ApplicationAgentAccess{CanInvoke: false}, nil)
+
+		_, err := service.IssueLease(ctx, agent.ID, res.ID)
+		assert.ErrorIs(t, err, ErrAgentNotInvocable)
+		m.res.AssertNotCalled(t, "GetSecret", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Inactive Agent", func(t *testing.T) {
+		service, m := setup()
+		m.apps.On("GetAgentAccess", ctx, app.ApplicationID, agent.ID).Return(invocable, nil)
+		m.agents.On("GetByID", ctx, agent.ID).Return(&domain.Agent{ID: agent.ID, Status: domain.AgentStatusMaintenance}, nil)
+
+		_, err := service.IssueLease(ctx, agent.ID, res.ID)
+		assert.ErrorIs(t, err, ErrAgentNotActive)
+	})
+
+	t.Run("No Access Grant", func(t *testing.T) {
+		service, m := setup()
+		m.apps.On("GetAgentAccess", ctx, app.ApplicationID, agent.ID).Return(invocable, nil)
+		m.agents.On("GetByID", ctx, agent.ID).Return(agent, nil)
+		m.res.On("GetByID", ctx, res.ID).Return(res, nil)
+		m.res.On("GetAccess", ctx, res.ID, agent.ID).Return(nil, nil)
+
+		_, err := service.IssueLease(ctx, agent.ID, res.ID)
+		assert.ErrorIs(t, err, ErrAccessNotGranted)
+		m.res.AssertNotCalled(t, "GetSecret", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Requires An Application", func(t *testing.T) {
+		service, _ := setup()
+
+		_, err := service.IssueLease(principalContext(domain.UserRoleAdmin), agent.ID, res.ID)
+		assert.ErrorIs(t, err, policy.ErrUnauthenticated)
+	})
+}
+
+func TestCredentialLeaseService_ExpireLeases(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	batch := []domain.CredentialLease{
+		{ID: uuid.New(), OrganizationID: uuid.New(), AccessLevel: domain.AccessLevelReadOnly},
+		{ID: uuid.New(), OrganizationID: uuid.New(), AccessLevel: domain.AccessLevelReadWrite},
+	}
+
+	leaseRepo := new(MockCredentialLeaseRepository)
+	mockAudit := new(MockAuditService)
+	service := NewCredentialLeaseService(leaseRepo, new(MockResourceRepository), new(MockAgentRepository), new(MockApplicationRepository), new(MockTxManager), mockAudit, testCipher(), connector.NewRegistry(), LeaseConfig{})
+
+	leaseRepo.On("ListExpired", ctx, now, 2).Return(batch, nil).Once()
+	leaseRepo.On("ListExpired", ctx, now, 2).Return([]domain.CredentialLease{}, nil).Once()
+	leaseRepo.On("MarkExpired", ctx, batch[0].ID, now).Return(true, nil)
+	// Already recorded by another instance: not audited twice.
+	leaseRepo.On("MarkExpired", ctx, batch[1].ID, now).Return(false, nil)
+	mockAudit.On("LogAction", ctx, batch[0].OrganizationID, (*uuid.UUID)(nil), "credential_lease", batch[0].ID, domain.AuditActionExpireLease,
+		mock.MatchedBy(func(changes json.RawMessage) bool {
+			return strings.Contains(string(changes), `"before":{"expired_at":null}`)
+		}), "").Return(nil)
+
+	expired, err := service.ExpireLeases(ctx, now, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	leaseRepo.AssertExpectations(t)
+	mockAudit.AssertExpectations(t)
+	mockAudit.AssertNumberOfCalls(t, "LogAction", 1)
+}
@@ -2,31 +2,130 @@ package service
 
 import (
 	"agentXmap/internal/domain"
+	"agentXmap/internal/policy"
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"net/mail"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidResetToken  = errors.New("invalid or expired password reset token")
+	ErrPasswordTooShort   = errors.New("password is too short")
+	ErrResetQueueFull     = errors.New("too many password reset requests, try again later")
+
+	ErrInvalidInvitation    = errors.New("invalid invitation token")
+	ErrInvitationNotFound   = errors.New("invitation not found")
+	ErrInvitationNotPending = errors.New("invitation is not pending")
+	ErrInvitationExpired    = errors.New("invitation expired")
+	ErrInvitationClosed     = errors.New("invitation was already accepted or revoked")
+	ErrEmailRegistered      = errors.New("email is already registered")
+)
+
+// SystemAuditLog entity types of the identity mutations.
+const (
+	auditEntityOrganization = "organization"
+	auditEntityUser         = "user"
+	auditEntityInvitation   = "invitation"
+)
+
+const (
+	// passwordResetTTL is how long a password reset token can be used.
+	passwordResetTTL = time.Hour
+	// minPasswordLength applies to passwords chosen through a reset.
+	minPasswordLength = 8
+	// invitationTTL is how long an invitation, or a resent one, can be accepted.
+	invitationTTL = 48 * time.Hour
+	// resetQueueSize bounds the password reset requests waiting for
+	// ProcessPasswordResets.
+	resetQueueSize = 256
+)
+
+// InvitationOutcomeStatus is what InviteUsers did with one email.
+type InvitationOutcomeStatus string
+
+const (
+	InvitationOutcomeInvited           InvitationOutcomeStatus = "invited"
+	InvitationOutcomeAlreadyRegistered InvitationOutcomeStatus = "already_registered"
+	InvitationOutcomeAlreadyInvited    InvitationOutcomeStatus = "already_invited"
+	InvitationOutcomeDuplicate         InvitationOutcomeStatus = "duplicate"
+	InvitationOutcomeInvalid           InvitationOutcomeStatus = "invalid"
+	InvitationOutcomeFailed            InvitationOutcomeStatus = "failed"
+)
+
+// InvitationOutcome reports the result of inviting one email. Invitation is
+// the new invitation, or the pending one when already_invited.
+type InvitationOutcome struct {
+	Email      string                  `json:"email" example:"john.doe@acme.com"`
+	Status     InvitationOutcomeStatus `json:"status" example:"invited"`
+	Invitation *domain.Invitation      `json:"invitation,omitempty"`
+	Error      string                  `json:"error,omitempty"`
+}
+
+// IdentityMailer emails users the tokens the Identity Service issues.
+// MailService implements it.
+type IdentityMailer interface {
+	SendInvitation(ctx context.Context, invitation *domain.Invitation, invitor *domain.User) error
+	SendPasswordReset(ctx context.Context, user *domain.User, token string, expiresAt time.Time) error
+}
+
 // IdentityService defines the interface for user identity management.
 type IdentityService interface {
 	SignUp(ctx context.Context, orgName, email, password string) (*domain.User, error)
-	Login(ctx context.Context, email, password string) (*domain.User, error)
-	InviteUsers(ctx context.Context, invitorID uuid.UUID, emails []string, role domain.UserRole) ([]*domain.Invitation, error)
+	Login(ctx context.Context, email, password string) (*domain.User, *TokenPair, error)
+	// InviteUsers invites every email to the organization of the invitor and
+	// reports, in order, what became of each. Members of the organization,
+	// emails with a pending invitation, repeats and malformed addresses are
+	// not invited; an email whose invitation could not be stored is reported
+	// failed without stopping the others.
+	InviteUsers(ctx context.Context, invitorID uuid.UUID, emails []string, role domain.UserRole) ([]InvitationOutcome, error)
 	AcceptInvitation(ctx context.Context, token, password, firstName, lastName string) (*domain.User, error)
+	// ListInvitations lists the invitations of an organization, optionally
+	// only those with status.
+	ListInvitations(ctx context.Context, orgID uuid.UUID, status domain.InvitationStatus) ([]domain.Invitation, error)
+	// ResendInvitation emails a pending or expired invitation again under a new
+	// token, valid for another 48 hours, on behalf of the caller. The previous
+	// token stops working.
+	ResendInvitation(ctx context.Context, id uuid.UUID) (*domain.Invitation, error)
+	// RevokeInvitation withdraws a pending invitation.
+	RevokeInvitation(ctx context.Context, id uuid.UUID) error
+	// ExpireInvitations marks every pending invitation of every organization
+	// that expired by now as expired. It returns how many it marked.
+	ExpireInvitations(ctx context.Context, now time.Time) (int, error)
+	// RequestPasswordReset queues a request to send a single-use reset token
+	// to the user with this email, superseding earlier ones, and returns
+	// without looking the email up. Registered and unknown addresses therefore
+	// get the same answer in the same time; it only fails with
+	// ErrResetQueueFull.
+	RequestPasswordReset(ctx context.Context, email string) error
+	// ProcessPasswordResets handles the queued password reset requests until
+	// ctx is done, passing each failure to onError.
+	ProcessPasswordResets(ctx context.Context, onError func(error))
+	// ResetPassword sets a new password with a token from RequestPasswordReset
+	// and ends every session of the user.
+	ResetPassword(ctx context.Context, token, newPassword string) error
 }
 
 type DefaultIdentityService struct {
 	userRepo       domain.UserRepository
 	orgRepo        domain.OrganizationRepository
 	invitationRepo domain.InvitationRepository
-	// In a real app we would have a PasswordHasher and EmailService interface here
+	resetRepo      domain.PasswordResetTokenRepository
+	txManager      domain.TxManager
+	auditService   AuditService
+	sessions       SessionService
+	mailer         IdentityMailer
+	resetQueue     chan string
 }
 
 // NewIdentityService creates a new instance of DefaultIdentityService.
@@ -34,11 +133,22 @@ func NewIdentityService(
 	userRepo domain.UserRepository,
 	orgRepo domain.OrganizationRepository,
 	invitationRepo domain.InvitationRepository,
+	resetRepo domain.PasswordResetTokenRepository,
+	txManager domain.TxManager,
+	auditService AuditService,
+	sessions SessionService,
+	mailer IdentityMailer,
 ) *DefaultIdentityService {
 	return &DefaultIdentityService{
 		userRepo:       userRepo,
 		orgRepo:        orgRepo,
 		invitationRepo: invitationRepo,
+		resetRepo:      resetRepo,
+		txManager:      txManager,
+		auditService:   auditService,
+		sessions:       sessions,
+		mailer:         mailer,
+		resetQueue:     make(chan string, resetQueueSize),
 	}
 }
 
@@ -78,30 +188,32 @@ func (s *DefaultIdentityService) SignUp(ctx context.Context, orgName, email, pas
 	// Slug generation (simplistic for now)
 	slug := Slugify(orgName)
 
-	// We should run this in a transaction.
-	// Gorm doesn't easily expose "RunTransaction" on Repos unless we share the DB instance or pass it around.
-	// For now we do it sequentially. If Org creation succeeds but User fails, we have an orphan Org.
-	// Ideally Repos should accept a DB/Tx interface.
-
 	org := &domain.Organization{
 		Name: orgName,
 		Slug: slug,
 	}
-
-	if err := s.orgRepo.Create(ctx, org); err != nil {
-		return nil, err
-	}
-
 	user := &domain.User{
-		OrganizationID: org.ID,
-		Email:          email,
-		PasswordHash:   hashedPassword,
-		Role:           domain.UserRoleAdmin, // First user is Admin
+		Email:        email,
+		PasswordHash: hashedPassword,
+		Role:         domain.UserRoleAdmin, // First user is Admin
 	}
 
-	if err := s.userRepo.Create(ctx, user); err != nil {
-		// Rollback Org?
-		// s.orgRepo.Delete(ctx, org.ID)
+	// Organization and admin are created together so a failed user insert leaves no orphan Org.
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.orgRepo.Create(ctx, org); err != nil {
+			return err
+		}
+		user.OrganizationID = org.ID
+		if err := s.userRepo.Create(ctx, user); err != nil {
+			return err
+		}
+		ctx = actingAs(ctx, user)
+		if err := logMutation(ctx, s.auditService, org.ID, auditEntityOrganization, org.ID, domain.AuditActionCreate, nil, auditSnapshot{"name": org.Name, "slug": org.Slug}); err != nil {
+			return err
+		}
+		return logMutation(ctx, s.auditService, org.ID, auditEntityUser, user.ID, domain.AuditActionCreate, nil, userSnapshot(user))
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -109,78 +221,139 @@ func (s *DefaultIdentityService) SignUp(ctx context.Context, orgName, email, pas
 	return user, nil
 }
 
-func (s *DefaultIdentityService) Login(ctx context.Context, email, password string) (*domain.User, error) {
+// Login authenticates the user and opens a new session (access + refresh token).
+func (s *DefaultIdentityService) Login(ctx context.Context, email, password string) (*domain.User, *TokenPair, error) {
 	user, err := s.userRepo.GetByEmail(ctx, email)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, nil, ErrInvalidCredentials
 	}
 
 	if !checkPasswordHash(password, user.PasswordHash) {
-		return nil, errors.New("invalid credentials")
+		return nil, nil, ErrInvalidCredentials
 	}
 
-	return user, nil
+	tokens, err := s.sessions.IssueTokens(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, tokens, nil
 }
 
-func (s *DefaultIdentityService) InviteUsers(ctx context.Context, invitorID uuid.UUID, emails []string, role domain.UserRole) ([]*domain.Invitation, error) {
+func (s *DefaultIdentityService) InviteUsers(ctx context.Context, invitorID uuid.UUID, emails []string, role domain.UserRole) ([]InvitationOutcome, error) {
 	invitor, err := s.userRepo.GetByID(ctx, invitorID)
 	if err != nil {
 		return nil, errors.New("invitor not found")
 	}
 
-	if invitor.Role != domain.UserRoleAdmin && invitor.Role != domain.UserRoleManager {
+	if policy.Decide(invitor.Role, policy.ResourceInvitation, policy.ActionCreate) != policy.Allow {
 		return nil, errors.New("insufficient permissions to invite users")
 	}
+	// Managers may invite, but not into a role above their own.
+	if role == domain.UserRoleAdmin && policy.Decide(invitor.Role, policy.ResourceAdminRole, policy.ActionCreate) != policy.Allow {
+		return nil, policy.ErrForbidden
+	}
 
-	var invitations []*domain.Invitation
-
+	outcomes := make([]InvitationOutcome, 0, len(emails))
+	seen := map[string]bool{}
 	for _, email := range emails {
-		// Skip existing users? or allow re-invite?
-		// For now simple check:
-		if _, err := s.userRepo.GetByEmail(ctx, email); err == nil {
-			// User exists, skip or error? Let's skip and continue.
-			// Or maybe return error for partial failure?
+		email = strings.TrimSpace(email)
+		if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+			outcomes = append(outcomes, InvitationOutcome{Email: email, Status: InvitationOutcomeInvalid})
 			continue
 		}
+		key := strings.ToLower(email)
+		if seen[key] {
+			outcomes = append(outcomes, InvitationOutcome{Email: email, Status: InvitationOutcomeDuplicate})
+			continue
+		}
+		seen[key] = true
 
-		token, err := generateToken()
+		status, invitation, err := s.invite(ctx, invitor, email, role)
+		outcome := InvitationOutcome{Email: email, Status: status, Invitation: invitation}
 		if err != nil {
-			return nil, err
+			outcome.Status = InvitationOutcomeFailed
+			outcome.Error = err.Error()
 		}
+		outcomes = append(outcomes, outcome)
+	}
 
-		invitation := &domain.Invitation{
-			OrganizationID: invitor.OrganizationID,
-			InvitorID:      invitor.ID,
-			Email:          email,
-			Token:          token,
-			Role:           role,
-			Status:         domain.InvitationStatusPending,
-			ExpiresAt:      time.Now().Add(48 * time.Hour),
-		}
+	return outcomes, nil
+}
 
+// invite creates and emails the invitation of one email, unless it belongs to
+// a member of the invitor's organization or already has a pending invitation.
+// Users of other organizations are invited like unknown addresses, so the
+// outcome reveals nothing about them; their acceptance fails.
+func (s *DefaultIdentityService) invite(ctx context.Context, invitor *domain.User, email string, role domain.UserRole) (InvitationOutcomeStatus, *domain.Invitation, error) {
+	existing, err := s.userRepo.GetByEmail(ctx, email)
+	switch {
+	case err == nil && existing.OrganizationID == invitor.OrganizationID:
+		return InvitationOutcomeAlreadyRegistered, nil, nil
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		return "", nil, err
+	}
+	now := time.Now().UTC()
+	pending, err := s.invitationRepo.GetPendingByEmail(ctx, invitor.OrganizationID, email, now)
+	if err != nil {
+		return "", nil, err
+	}
+	if pending != nil {
+		return InvitationOutcomeAlreadyInvited, pending, nil
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return "", nil, err
+	}
+	invitation := &domain.Invitation{
+		OrganizationID: invitor.OrganizationID,
+		InvitorID:      invitor.ID,
+		Email:          email,
+		Token:          token,
+		Role:           role,
+		Status:         domain.InvitationStatusPending,
+		ExpiresAt:      now.Add(invitationTTL),
+	}
+
+	// The invitation, its audit entry and its email are committed together.
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
 		if err := s.invitationRepo.Create(ctx, invitation); err != nil {
-			return nil, err
+			return err
+		}
+		if err := logMutation(ctx, s.auditService, invitation.OrganizationID, auditEntityInvitation, invitation.ID, domain.AuditActionCreate, nil, invitationSnapshot(invitation)); err != nil {
+			return err
 		}
-		invitations = append(invitations, invitation)
+		return s.mailer.SendInvitation(ctx, invitation, invitor)
+	})
+	if err != nil {
+		return "", nil, err
 	}
-
-	return invitations, nil
+	return InvitationOutcomeInvited, invitation, nil
 }
 
 func (s *DefaultIdentityService) AcceptInvitation(ctx context.Context, token, password, firstName, lastName string) (*domain.User, error) {
 	invitation, err := s.invitationRepo.GetByToken(ctx, token)
 	if err != nil {
-		return nil, errors.New("invalid invitation token")
+		return nil, ErrInvalidInvitation
 	}
 
 	if invitation.Status != domain.InvitationStatusPending {
-		return nil, errors.New("invitation is not pending")
+		return nil, ErrInvitationNotPending
 	}
 
-	if time.Now().After(invitation.ExpiresAt) {
-		invitation.Status = domain.InvitationStatusExpired
-		_ = s.invitationRepo.Update(ctx, invitation)
-		return nil, errors.New("invitation expired")
+	if time.Now().UTC().After(invitation.ExpiresAt) {
+		_ = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+			return s.updateInvitation(ctx, invitation, func() { invitation.Status = domain.InvitationStatusExpired })
+		})
+		return nil, ErrInvitationExpired
+	}
+
+	// The address may belong to a user of another organization.
+	if _, err := s.userRepo.GetByEmail(ctx, invitation.Email); err == nil {
+		return nil, ErrEmailRegistered
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
 	}
 
 	hashedPassword, err := hashPassword(password)
@@ -197,17 +370,263 @@ func (s *DefaultIdentityService) AcceptInvitation(ctx context.Context, token, pa
 		LastName:       lastName,
 	}
 
-	// This should also be transactional
-	if err := s.userRepo.Create(ctx, user); err != nil {
+	// The user only exists if the invitation is marked accepted with it. The
+	// new user is the actor of both entries.
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Create(ctx, user); err != nil {
+			return err
+		}
+		ctx = actingAs(ctx, user)
+		if err := logMutation(ctx, s.auditService, user.OrganizationID, auditEntityUser, user.ID, domain.AuditActionCreate, nil, userSnapshot(user)); err != nil {
+			return err
+		}
+		return s.updateInvitation(ctx, invitation, func() { invitation.Status = domain.InvitationStatusAccepted })
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *DefaultIdentityService) ListInvitations(ctx context.Context, orgID uuid.UUID, status domain.InvitationStatus) ([]domain.Invitation, error) {
+	if err := policy.Authorize(ctx, policy.ResourceInvitation, policy.ActionRead); err != nil {
+		return nil, err
+	}
+	return s.invitationRepo.ListByOrganization(ctx, orgID, status)
+}
+
+func (s *DefaultIdentityService) ResendInvitation(ctx context.Context, id uuid.UUID) (*domain.Invitation, error) {
+	if err := policy.Authorize(ctx, policy.ResourceInvitation, policy.ActionUpdate); err != nil {
 		return nil, err
 	}
+	caller, invitation, err := s.callerInvitation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	resender, err := s.userRepo.GetByID(ctx, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if invitation.Status != domain.InvitationStatusPending && invitation.Status != domain.InvitationStatusExpired {
+		return nil, ErrInvitationClosed
+	}
 
-	invitation.Status = domain.InvitationStatusAccepted
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := s.updateInvitation(ctx, invitation, func() {
+			invitation.Token = token
+			invitation.Status = domain.InvitationStatusPending
+			invitation.ExpiresAt = time.Now().UTC().Add(invitationTTL)
+		})
+		if err != nil {
+			return err
+		}
+		return s.mailer.SendInvitation(ctx, invitation, resender)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+func (s *DefaultIdentityService) RevokeInvitation(ctx context.Context, id uuid.UUID) error {
+	if err := policy.Authorize(ctx, policy.ResourceInvitation, policy.ActionDelete); err != nil {
+		return err
+	}
+	_, invitation, err := s.callerInvitation(ctx, id)
+	if err != nil {
+		return err
+	}
+	if invitation.Status != domain.InvitationStatusPending {
+		return ErrInvitationNotPending
+	}
+	return s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		return s.updateInvitation(ctx, invitation, func() { invitation.Status = domain.InvitationStatusRevoked })
+	})
+}
+
+func (s *DefaultIdentityService) ExpireInvitations(ctx context.Context, now time.Time) (int, error) {
+	var expired []domain.Invitation
+	err := s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if expired, err = s.invitationRepo.ExpireOverdue(ctx, now); err != nil {
+			return err
+		}
+		for i := range expired {
+			invitation := &expired[i]
+			after := invitationSnapshot(invitation)
+			before := invitationSnapshot(invitation)
+			before["status"] = domain.InvitationStatusPending
+			if err := logMutation(ctx, s.auditService, invitation.OrganizationID, auditEntityInvitation, invitation.ID, domain.AuditActionUpdate, before, after); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(expired), nil
+}
+
+// updateInvitation applies change to invitation, then stores it and its audit
+// entry. Callers run it in a transaction.
+func (s *DefaultIdentityService) updateInvitation(ctx context.Context, invitation *domain.Invitation, change func()) error {
+	before := invitationSnapshot(invitation)
+	change()
 	if err := s.invitationRepo.Update(ctx, invitation); err != nil {
-		// Log error, but user is created
+		return err
 	}
+	return logMutation(ctx, s.auditService, invitation.OrganizationID, auditEntityInvitation, invitation.ID, domain.AuditActionUpdate, before, invitationSnapshot(invitation))
+}
 
-	return user, nil
+// invitationSnapshot is the audited state of an invitation, without its token.
+func invitationSnapshot(invitation *domain.Invitation) auditSnapshot {
+	return auditSnapshot{
+		"email":      invitation.Email,
+		"role":       invitation.Role,
+		"status":     invitation.Status,
+		"invitor_id": invitation.InvitorID,
+		"expires_at": invitation.ExpiresAt,
+	}
+}
+
+// userSnapshot is the audited state of a user, without its password hash.
+func userSnapshot(user *domain.User) auditSnapshot {
+	return auditSnapshot{
+		"email":      user.Email,
+		"role":       user.Role,
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+	}
+}
+
+// actingAs returns ctx with user as the Principal, for the entries of a user
+// who is created without being signed in.
+func actingAs(ctx context.Context, user *domain.User) context.Context {
+	return domain.WithPrincipal(ctx, &domain.Principal{UserID: user.ID, OrganizationID: user.OrganizationID, Role: user.Role})
+}
+
+// callerInvitation returns the caller and an invitation of their organization.
+// Invitations of other organizations read as not found.
+func (s *DefaultIdentityService) callerInvitation(ctx context.Context, id uuid.UUID) (*domain.Principal, *domain.Invitation, error) {
+	caller, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, nil, policy.ErrUnauthenticated
+	}
+	invitation, err := s.invitationRepo.GetByID(ctx, id)
+	if err != nil || invitation.OrganizationID != caller.OrganizationID {
+		return nil, nil, ErrInvitationNotFound
+	}
+	return caller, invitation, nil
+}
+
+// hashResetToken returns the hex SHA-256 digest under which a reset token is stored.
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *DefaultIdentityService) RequestPasswordReset(ctx context.Context, email string) error {
+	select {
+	case s.resetQueue <- email:
+		return nil
+	default:
+		return ErrResetQueueFull
+	}
+}
+
+func (s *DefaultIdentityService) ProcessPasswordResets(ctx context.Context, onError func(error)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case email := <-s.resetQueue:
+			if err := s.sendPasswordReset(ctx, email); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// sendPasswordReset issues a reset token to the user with email, if any, and
+// queues its email.
+func (s *DefaultIdentityService) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	record := &domain.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashResetToken(token),
+		ExpiresAt: now.Add(passwordResetTTL),
+	}
+
+	// The token is only kept if its email was queued.
+	return s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.resetRepo.ConsumeAllForUser(ctx, user.ID, now); err != nil {
+			return err
+		}
+		if err := s.resetRepo.Create(ctx, record); err != nil {
+			return err
+		}
+		return s.mailer.SendPasswordReset(ctx, user, token, record.ExpiresAt)
+	})
+}
+
+func (s *DefaultIdentityService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if len(newPassword) < minPasswordLength {
+		return ErrPasswordTooShort
+	}
+	record, err := s.resetRepo.GetByHash(ctx, hashResetToken(token))
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+	now := time.Now().UTC()
+	if record.ConsumedAt != nil || !now.Before(record.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+	user, err := s.userRepo.GetByID(ctx, record.UserID)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+	hashedPassword, err := hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	return s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		consumed, err := s.resetRepo.Consume(ctx, record.ID, now)
+		if err != nil {
+			return err
+		}
+		if !consumed {
+			// Used concurrently.
+			return ErrInvalidResetToken
+		}
+		if err := s.resetRepo.ConsumeAllForUser(ctx, user.ID, now); err != nil {
+			return err
+		}
+		user.PasswordHash = hashedPassword
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return err
+		}
+		return s.sessions.RevokeAllForUser(ctx, user.ID)
+	})
 }
 
 // Simple slugify helper
@@ -2,15 +2,19 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"testing"
 	"time"
 
 	"agentXmap/internal/domain"
+	"agentXmap/internal/policy"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // Mock Repositories
@@ -99,13 +103,86 @@ func (m *MockInvitationRepository) Update(ctx context.Context, invitation *domai
 	return args.Error(0)
 }
 
+func (m *MockInvitationRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Invitation, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Invitation), args.Error(1)
+}
+
+func (m *MockInvitationRepository) GetPendingByEmail(ctx context.Context, orgID uuid.UUID, email string, now time.Time) (*domain.Invitation, error) {
+	args := m.Called(ctx, orgID, email, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Invitation), args.Error(1)
+}
+
+func (m *MockInvitationRepository) ListByOrganization(ctx context.Context, orgID uuid.UUID, status domain.InvitationStatus) ([]domain.Invitation, error) {
+	args := m.Called(ctx, orgID, status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Invitation), args.Error(1)
+}
+
+func (m *MockInvitationRepository) ExpireOverdue(ctx context.Context, now time.Time) ([]domain.Invitation, error) {
+	args := m.Called(ctx, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Invitation), args.Error(1)
+}
+
+type MockPasswordResetTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockPasswordResetTokenRepository) Create(ctx context.Context, token *domain.PasswordResetToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockPasswordResetTokenRepository) GetByHash(ctx context.Context, hash string) (*domain.PasswordResetToken, error) {
+	args := m.Called(ctx, hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.PasswordResetToken), args.Error(1)
+}
+
+func (m *MockPasswordResetTokenRepository) Consume(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	args := m.Called(ctx, id, at)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPasswordResetTokenRepository) ConsumeAllForUser(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	args := m.Called(ctx, userID, at)
+	return args.Error(0)
+}
+
+type MockIdentityMailer struct {
+	mock.Mock
+}
+
+func (m *MockIdentityMailer) SendInvitation(ctx context.Context, invitation *domain.Invitation, invitor *domain.User) error {
+	args := m.Called(ctx, invitation, invitor)
+	return args.Error(0)
+}
+
+func (m *MockIdentityMailer) SendPasswordReset(ctx context.Context, user *domain.User, token string, expiresAt time.Time) error {
+	args := m.Called(ctx, user, token, expiresAt)
+	return args.Error(0)
+}
+
 // Tests
 
 func TestIdentityService_SignUp(t *testing.T) {
 	mockUserRepo := new(MockUserRepository)
 	mockOrgRepo := new(MockOrganizationRepository)
 	mockInvRepo := new(MockInvitationRepository)
-	service := NewIdentityService(mockUserRepo, mockOrgRepo, mockInvRepo)
+	service := NewIdentityService(mockUserRepo, mockOrgRepo, mockInvRepo, new(MockPasswordResetTokenRepository), new(MockTxManager), acceptingAudit(), nil, nil)
 
 	ctx := context.Background()
 
@@ -131,38 +208,179 @@ func TestIdentityService_SignUp(t *testing.T) {
 		assert.Nil(t, user)
 		assert.Equal(t, "user already exists", err.Error())
 	})
+
+	t.Run("UserCreationFails", func(t *testing.T) {
+		txManager := new(MockTxManager)
+		service := NewIdentityService(mockUserRepo, mockOrgRepo, mockInvRepo, new(MockPasswordResetTokenRepository), txManager, acceptingAudit(), nil, nil)
+
+		mockUserRepo.On("GetByEmail", ctx, "admin2@test.com").Return(nil, errors.New("not found")).Once()
+		mockOrgRepo.On("Create", ctx, mock.AnythingOfType("*domain.Organization")).Return(nil).Once()
+		mockUserRepo.On("Create", ctx, mock.AnythingOfType("*domain.User")).Return(errors.New("db error")).Once()
+
+		// The error is returned from inside the unit of work, so the Org insert is rolled back.
+		user, err := service.SignUp(ctx, "Test Org", "admin2@test.com", "password123")
+		assert.EqualError(t, err, "db error")
+		assert.Nil(t, user)
+		assert.Equal(t, 1, txManager.calls)
+	})
 }
 
-func TestIdentityService_InviteUsers(t *testing.T) {
-	mockUserRepo := new(MockUserRepository)
-	mockOrgRepo := new(MockOrganizationRepository)
-	mockInvRepo := new(MockInvitationRepository)
-	service := NewIdentityService(mockUserRepo, mockOrgRepo, mockInvRepo)
+func TestIdentityService_Login(t *testing.T) {
+	ctx := context.Background()
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user := &domain.User{ID: uuid.New(), OrganizationID: uuid.New(), Email: "admin@test.com", PasswordHash: string(hash), Role: domain.UserRoleAdmin}
 
+	t.Run("Success", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		mockTokenRepo := new(MockRefreshTokenRepository)
+		sessions := newTestSessionService(t, mockTokenRepo, mockUserRepo)
+		service := NewIdentityService(mockUserRepo, new(MockOrganizationRepository), new(MockInvitationRepository), new(MockPasswordResetTokenRepository), new(MockTxManager), acceptingAudit(), sessions, nil)
+
+		mockUserRepo.On("GetByEmail", ctx, "admin@test.com").Return(user, nil).Once()
+		mockTokenRepo.On("Create", ctx, mock.AnythingOfType("*domain.RefreshToken")).Return(nil).Once()
+
+		got, tokens, err := service.Login(ctx, "admin@test.com", "password123")
+		assert.NoError(t, err)
+		assert.Equal(t, user.ID, got.ID)
+		assert.NotEmpty(t, tokens.AccessToken)
+		assert.NotEmpty(t, tokens.RefreshToken)
+	})
+
+	t.Run("WrongPassword", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		service := NewIdentityService(mockUserRepo, new(MockOrganizationRepository), new(MockInvitationRepository), new(MockPasswordResetTokenRepository), new(MockTxManager), acceptingAudit(), nil, nil)
+
+		mockUserRepo.On("GetByEmail", ctx, "admin@test.com").Return(user, nil).Once()
+
+		_, tokens, err := service.Login(ctx, "admin@test.com", "wrong")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		assert.Nil(t, tokens)
+	})
+
+	t.Run("UnknownEmail", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		service := NewIdentityService(mockUserRepo, new(MockOrganizationRepository), new(MockInvitationRepository), new(MockPasswordResetTokenRepository), new(MockTxManager), acceptingAudit(), nil, nil)
+
+		mockUserRepo.On("GetByEmail", ctx, "ghost@test.com").Return(nil, errors.New("not found")).Once()
+
+		_, _, err := service.Login(ctx, "ghost@test.com", "password123")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+}
+
+func TestIdentityService_InviteUsers(t *testing.T) {
 	ctx := context.Background()
 	invitorID := uuid.New()
 	orgID := uuid.New()
+	admin := &domain.User{ID: invitorID, OrganizationID: orgID, Role: domain.UserRoleAdmin}
+
+	setup := func() (*DefaultIdentityService, *MockUserRepository, *MockInvitationRepository, *MockIdentityMailer) {
+		mockUserRepo := new(MockUserRepository)
+		mockInvRepo := new(MockInvitationRepository)
+		mailer := new(MockIdentityMailer)
+		return NewIdentityService(mockUserRepo, new(MockOrganizationRepository), mockInvRepo, new(MockPasswordResetTokenRepository), new(MockTxManager), acceptingAudit(), nil, mailer),
+			mockUserRepo, mockInvRepo, mailer
+	}
 
 	t.Run("Success", func(t *testing.T) {
-		invitor := &domain.User{
-			ID:             invitorID,
-			OrganizationID: orgID,
-			Role:           domain.UserRoleAdmin,
+		service, mockUserRepo, mockInvRepo, mailer := setup()
+
+		mockUserRepo.On("GetByID", ctx, invitorID).Return(admin, nil).Once()
+		mockUserRepo.On("GetByEmail", ctx, "invitee@test.com").Return(nil, gorm.ErrRecordNotFound).Once()
+		mockInvRepo.On("GetPendingByEmail", ctx, orgID, "invitee@test.com", mock.AnythingOfType("time.Time")).Return(nil, nil).Once()
+		mockInvRepo.On("Create", ctx, mock.AnythingOfType("*domain.Invitation")).Return(nil).Once()
+		mailer.On("SendInvitation", ctx, mock.AnythingOfType("*domain.Invitation"), admin).Return(nil).Once()
+
+		outcomes, err := service.InviteUsers(ctx, invitorID, []string{"invitee@test.com"}, domain.UserRoleUser)
+		assert.NoError(t, err)
+		assert.Len(t, outcomes, 1)
+		assert.Equal(t, InvitationOutcomeInvited, outcomes[0].Status)
+		assert.Equal(t, "invitee@test.com", outcomes[0].Invitation.Email)
+		assert.Equal(t, domain.InvitationStatusPending, outcomes[0].Invitation.Status)
+		mailer.AssertExpectations(t)
+	})
+
+	t.Run("Reports Every Email", func(t *testing.T) {
+		service, mockUserRepo, mockInvRepo, mailer := setup()
+		pending := &domain.Invitation{ID: uuid.New(), Email: "pending@test.com"}
+
+		mockUserRepo.On("GetByID", ctx, invitorID).Return(admin, nil).Once()
+		mockUserRepo.On("GetByEmail", ctx, "member@test.com").Return(&domain.User{Email: "member@test.com", OrganizationID: orgID}, nil)
+		mockUserRepo.On("GetByEmail", ctx, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+		mockInvRepo.On("GetPendingByEmail", ctx, orgID, "pending@test.com", mock.Anything).Return(pending, nil)
+		mockInvRepo.On("GetPendingByEmail", ctx, orgID, mock.Anything, mock.Anything).Return(nil, nil)
+		mockInvRepo.On("Create", ctx, mock.MatchedBy(func(i *domain.Invitation) bool { return i.Email == "broken@test.com" })).Return(errors.New("db error"))
+		mockInvRepo.On("Create", ctx, mock.Anything).Return(nil)
+		mailer.On("SendInvitation", ctx, mock.Anything, admin).Return(nil)
+
+		outcomes, err := service.InviteUsers(ctx, invitorID,
+			[]string{"new@test.com", "member@test.com", "pending@test.com", " NEW@test.com", "broken@test.com", "John <john@test.com>", "not-an-email"}, domain.UserRoleUser)
+		assert.NoError(t, err)
+		statuses := make([]InvitationOutcomeStatus, len(outcomes))
+		for i, o := range outcomes {
+			statuses[i] = o.Status
 		}
+		assert.Equal(t, []InvitationOutcomeStatus{
+			InvitationOutcomeInvited,
+			InvitationOutcomeAlreadyRegistered,
+			InvitationOutcomeAlreadyInvited,
+			InvitationOutcomeDuplicate,
+			InvitationOutcomeFailed,
+			InvitationOutcomeInvalid,
+			InvitationOutcomeInvalid,
+		}, statuses)
+		assert.Equal(t, "NEW@test.com", outcomes[3].Email)
+		assert.Equal(t, pending, outcomes[2].Invitation)
+		assert.Equal(t, "db error", outcomes[4].Error)
+		mailer.AssertNumberOfCalls(t, "SendInvitation", 1)
+	})
 
-		mockUserRepo.On("GetByID", ctx, invitorID).Return(invitor, nil).Once()
-		// Mock GetByEmail for each email (assuming they don't exist)
-		mockUserRepo.On("GetByEmail", ctx, "invitee@test.com").Return(nil, errors.New("not found")).Once()
+	t.Run("Member Of Another Organization", func(t *testing.T) {
+		service, mockUserRepo, mockInvRepo, mailer := setup()
+
+		mockUserRepo.On("GetByID", ctx, invitorID).Return(admin, nil).Once()
+		mockUserRepo.On("GetByEmail", ctx, "elsewhere@test.com").Return(&domain.User{Email: "elsewhere@test.com", OrganizationID: uuid.New()}, nil).Once()
+		mockInvRepo.On("GetPendingByEmail", ctx, orgID, "elsewhere@test.com", mock.Anything).Return(nil, nil).Once()
 		mockInvRepo.On("Create", ctx, mock.AnythingOfType("*domain.Invitation")).Return(nil).Once()
+		mailer.On("SendInvitation", ctx, mock.AnythingOfType("*domain.Invitation"), admin).Return(nil).Once()
 
-		invitations, err := service.InviteUsers(ctx, invitorID, []string{"invitee@test.com"}, domain.UserRoleUser)
+		// Reported like an unknown address, so other tenants' users are not disclosed.
+		outcomes, err := service.InviteUsers(ctx, invitorID, []string{"elsewhere@test.com"}, domain.UserRoleUser)
 		assert.NoError(t, err)
-		assert.Len(t, invitations, 1)
-		assert.Equal(t, "invitee@test.com", invitations[0].Email)
-		assert.Equal(t, domain.InvitationStatusPending, invitations[0].Status)
+		assert.Equal(t, InvitationOutcomeInvited, outcomes[0].Status)
+	})
+
+	t.Run("User Lookup Fails", func(t *testing.T) {
+		service, mockUserRepo, mockInvRepo, _ := setup()
+
+		mockUserRepo.On("GetByID", ctx, invitorID).Return(admin, nil).Once()
+		mockUserRepo.On("GetByEmail", ctx, "invitee@test.com").Return(nil, errors.New("connection refused")).Once()
+
+		outcomes, err := service.InviteUsers(ctx, invitorID, []string{"invitee@test.com"}, domain.UserRoleUser)
+		assert.NoError(t, err)
+		assert.Equal(t, InvitationOutcomeFailed, outcomes[0].Status)
+		assert.Equal(t, "connection refused", outcomes[0].Error)
+		mockInvRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Email Not Queued", func(t *testing.T) {
+		service, mockUserRepo, mockInvRepo, mailer := setup()
+
+		mockUserRepo.On("GetByID", ctx, invitorID).Return(admin, nil).Once()
+		mockUserRepo.On("GetByEmail", ctx, "invitee@test.com").Return(nil, gorm.ErrRecordNotFound).Once()
+		mockInvRepo.On("GetPendingByEmail", ctx, orgID, "invitee@test.com", mock.Anything).Return(nil, nil).Once()
+		mockInvRepo.On("Create", ctx, mock.AnythingOfType("*domain.Invitation")).Return(nil).Once()
+		mailer.On("SendInvitation", ctx, mock.AnythingOfType("*domain.Invitation"), admin).Return(errors.New("outbox down")).Once()
+
+		// The invitation is rolled back with its email.
+		outcomes, err := service.InviteUsers(ctx, invitorID, []string{"invitee@test.com"}, domain.UserRoleUser)
+		assert.NoError(t, err)
+		assert.Equal(t, InvitationOutcomeFailed, outcomes[0].Status)
+		assert.Nil(t, outcomes[0].Invitation)
 	})
 
 	t.Run("InsufficientPermissions", func(t *testing.T) {
+		service, mockUserRepo, _, _ := setup()
 		invitor := &domain.User{
 			ID:             invitorID,
 			OrganizationID: orgID,
@@ -171,32 +389,214 @@ func TestIdentityService_InviteUsers(t *testing.T) {
 
 		mockUserRepo.On("GetByID", ctx, invitorID).Return(invitor, nil).Once()
 
-		invitations, err := service.InviteUsers(ctx, invitorID, []string{"invitee@test.com"}, domain.UserRoleUser)
+		outcomes, err := service.InviteUsers(ctx, invitorID, []string{"invitee@test.com"}, domain.UserRoleUser)
 		assert.Error(t, err)
-		assert.Nil(t, invitations)
+		assert.Nil(t, outcomes)
 		assert.Equal(t, "insufficient permissions to invite users", err.Error())
 	})
 
+	t.Run("Manager Cannot Invite Admin", func(t *testing.T) {
+		service, mockUserRepo, mockInvRepo, _ := setup()
+		manager := &domain.User{ID: invitorID, OrganizationID: orgID, Role: domain.UserRoleManager}
+		mockUserRepo.On("GetByID", ctx, invitorID).Return(manager, nil).Once()
+
+		outcomes, err := service.InviteUsers(ctx, invitorID, []string{"invitee@test.com"}, domain.UserRoleAdmin)
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+		assert.Nil(t, outcomes)
+		mockInvRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Manager Invites Manager", func(t *testing.T) {
+		service, mockUserRepo, mockInvRepo, mailer := setup()
+		manager := &domain.User{ID: invitorID, OrganizationID: orgID, Role: domain.UserRoleManager}
+		mockUserRepo.On("GetByID", ctx, invitorID).Return(manager, nil).Once()
+		mockUserRepo.On("GetByEmail", ctx, "invitee@test.com").Return(nil, gorm.ErrRecordNotFound).Once()
+		mockInvRepo.On("GetPendingByEmail", ctx, orgID, "invitee@test.com", mock.AnythingOfType("time.Time")).Return(nil, nil).Once()
+		mockInvRepo.On("Create", ctx, mock.AnythingOfType("*domain.Invitation")).Return(nil).Once()
+		mailer.On("SendInvitation", ctx, mock.AnythingOfType("*domain.Invitation"), manager).Return(nil).Once()
+
+		outcomes, err := service.InviteUsers(ctx, invitorID, []string{"invitee@test.com"}, domain.UserRoleManager)
+		assert.NoError(t, err)
+		assert.Equal(t, InvitationOutcomeInvited, outcomes[0].Status)
+		assert.Equal(t, domain.UserRoleManager, outcomes[0].Invitation.Role)
+	})
+
 	t.Run("InvitorNotFound", func(t *testing.T) {
+		service, mockUserRepo, _, _ := setup()
 		mockUserRepo.On("GetByID", ctx, invitorID).Return(nil, errors.New("not found")).Once()
 
-		invitations, err := service.InviteUsers(ctx, invitorID, []string{"invitee@test.com"}, domain.UserRoleUser)
+		outcomes, err := service.InviteUsers(ctx, invitorID, []string{"invitee@test.com"}, domain.UserRoleUser)
 		assert.Error(t, err)
-		assert.Nil(t, invitations)
+		assert.Nil(t, outcomes)
 	})
 }
 
+func TestIdentityService_ListInvitations(t *testing.T) {
+	orgID := uuid.New()
+
+	t.Run("Manager", func(t *testing.T) {
+		mockInvRepo := new(MockInvitationRepository)
+		service := NewIdentityService(new(MockUserRepository), new(MockOrganizationRepository), mockInvRepo, new(MockPasswordResetTokenRepository), new(MockTxManager), acceptingAudit(), nil, nil)
+		ctx := principalContext(domain.UserRoleManager)
+
+		mockInvRepo.On("ListByOrganization", ctx, orgID, domain.InvitationStatusPending).Return([]domain.Invitation{{Email: "john@acme.com"}}, nil)
+
+		invitations, err := service.ListInvitations(ctx, orgID, domain.InvitationStatusPending)
+		assert.NoError(t, err)
+		assert.Len(t, invitations, 1)
+	})
+
+	t.Run("Forbidden For Users", func(t *testing.T) {
+		mockInvRepo := new(MockInvitationRepository)
+		service := NewIdentityService(new(MockUserRepository), new(MockOrganizationRepository), mockInvRepo, new(MockPasswordResetTokenRepository), new(MockTxManager), acceptingAudit(), nil, nil)
+
+		_, err := service.ListInvitations(principalContext(domain.UserRoleUser), orgID, "")
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+		mockInvRepo.AssertNotCalled(t, "ListByOrganization", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestIdentityService_ResendInvitation(t *testing.T) {
+	orgID := uuid.New()
+	resender := &domain.User{ID: uuid.New(), OrganizationID: orgID, Role: domain.UserRoleAdmin}
+	ctx := domain.WithPrincipal(context.Background(), &domain.Principal{UserID: resender.ID, OrganizationID: orgID, Role: resender.Role})
+
+	setup := func() (*DefaultIdentityService, *MockInvitationRepository, *MockIdentityMailer) {
+		mockUserRepo := new(MockUserRepository)
+		mockInvRepo := new(MockInvitationRepository)
+		mailer := new(MockIdentityMailer)
+		mockUserRepo.On("GetByID", ctx, resender.ID).Return(resender, nil)
+		return NewIdentityService(mockUserRepo, new(MockOrganizationRepository), mockInvRepo, new(MockPasswordResetTokenRepository), new(MockTxManager), acceptingAudit(), nil, mailer),
+			mockInvRepo, mailer
+	}
+
+	for _, status := range []domain.InvitationStatus{domain.InvitationStatusPending, domain.InvitationStatusExpired} {
+		t.Run("Rotates Token Of "+string(status), func(t *testing.T) {
+			service, mockInvRepo, mailer := setup()
+			invitation := &domain.Invitation{ID: uuid.New(), OrganizationID: orgID, Token: "old", Status: status, ExpiresAt: time.Now().Add(-time.Hour)}
+
+			mockInvRepo.On("GetByID", ctx, invitation.ID).Return(invitation, nil)
+			mockInvRepo.On("Update", ctx, invitation).Return(nil)
+			mailer.On("SendInvitation", ctx, invitation, resender).Return(nil)
+
+			resent, err := service.ResendInvitation(ctx, invitation.ID)
+			assert.NoError(t, err)
+			assert.NotEqual(t, "old", resent.Token)
+			assert.Equal(t, domain.InvitationStatusPending, resent.Status)
+			assert.WithinDuration(t, time.Now().Add(invitationTTL), resent.ExpiresAt, time.Minute)
+			mailer.AssertExpectations(t)
+		})
+	}
+
+	t.Run("Accepted", func(t *testing.T) {
+		service, mockInvRepo, mailer := setup()
+		invitation := &domain.Invitation{ID: uuid.New(), OrganizationID: orgID, Status: domain.InvitationStatusAccepted}
+
+		mockInvRepo.On("GetByID", ctx, invitation.ID).Return(invitation, nil)
+
+		_, err := service.ResendInvitation(ctx, invitation.ID)
+		assert.ErrorIs(t, err, ErrInvitationClosed)
+		mailer.AssertNotCalled(t, "SendInvitation", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Other Organization", func(t *testing.T) {
+		service, mockInvRepo, _ := setup()
+		invitation := &domain.Invitation{ID: uuid.New(), OrganizationID: uuid.New(), Status: domain.InvitationStatusPending}
+
+		mockInvRepo.On("GetByID", ctx, invitation.ID).Return(invitation, nil)
+
+		_, err := service.ResendInvitation(ctx, invitation.ID)
+		assert.ErrorIs(t, err, ErrInvitationNotFound)
+	})
+}
+
+func TestIdentityService_RevokeInvitation(t *testing.T) {
+	ctx := principalContext(domain.UserRoleManager)
+	caller, _ := domain.PrincipalFromContext(ctx)
+
+	t.Run("Pending", func(t *testing.T) {
+		mockInvRepo := new(MockInvitationRepository)
+		audit := new(MockAuditService)
+		txManager := new(MockTxManager)
+		service := NewIdentityService(new(MockUserRepository), new(MockOrganizationRepository), mockInvRepo, new(MockPasswordResetTokenRepository), txManager, audit, nil, nil)
+		invitation := &domain.Invitation{ID: uuid.New(), OrganizationID: caller.OrganizationID, Status: domain.InvitationStatusPending}
+
+		mockInvRepo.On("GetByID", ctx, invitation.ID).Return(invitation, nil)
+		mockInvRepo.On("Update", ctx, mock.MatchedBy(func(i *domain.Invitation) bool {
+			return i.Status == domain.InvitationStatusRevoked
+		})).Return(nil)
+		audit.On("LogAction", ctx, caller.OrganizationID, (*uuid.UUID)(nil), "invitation", invitation.ID, domain.AuditActionUpdate, mock.MatchedBy(func(changes json.RawMessage) bool {
+			return string(changes) == `{"after":{"status":"revoked"},"before":{"status":"pending"}}`
+		}), "").Return(nil)
+
+		assert.NoError(t, service.RevokeInvitation(ctx, invitation.ID))
+		mockInvRepo.AssertExpectations(t)
+		audit.AssertExpectations(t)
+		assert.Equal(t, 1, txManager.calls)
+	})
+
+	t.Run("Not Pending", func(t *testing.T) {
+		mockInvRepo := new(MockInvitationRepository)
+		service := NewIdentityService(new(MockUserRepository), new(MockOrganizationRepository), mockInvRepo, new(MockPasswordResetTokenRepository), new(MockTxManager), acceptingAudit(), nil, nil)
+		invitation := &domain.Invitation{ID: uuid.New(), OrganizationID: caller.OrganizationID, Status: domain.InvitationStatusAccepted}
+
+		mockInvRepo.On("GetByID", ctx, invitation.ID).Return(invitation, nil)
+
+		assert.ErrorIs(t, service.RevokeInvitation(ctx, invitation.ID), ErrInvitationNotPending)
+		mockInvRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Forbidden For Users", func(t *testing.T) {
+		service := NewIdentityService(new(MockUserRepository), new(MockOrganizationRepository), new(MockInvitationRepository), new(MockPasswordResetTokenRepository), new(MockTxManager), acceptingAudit(), nil, nil)
+
+		assert.ErrorIs(t, service.RevokeInvitation(principalContext(domain.UserRoleUser), uuid.New()), policy.ErrForbidden)
+	})
+}
+
+func TestIdentityService_ExpireInvitations(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	mockInvRepo := new(MockInvitationRepository)
+	audit := new(MockAuditService)
+	service := NewIdentityService(new(MockUserRepository), new(MockOrganizationRepository), mockInvRepo, new(MockPasswordResetTokenRepository), new(MockTxManager), audit, nil, nil)
+
+	orgID := uuid.New()
+	invitations := []domain.Invitation{
+		{ID: uuid.New(), OrganizationID: orgID, Status: domain.InvitationStatusExpired},
+		{ID: uuid.New(), OrganizationID: orgID, Status: domain.InvitationStatusExpired},
+	}
+	mockInvRepo.On("ExpireOverdue", ctx, now).Return(invitations, nil)
+	for _, invitation := range invitations {
+		audit.On("LogAction", ctx, orgID, (*uuid.UUID)(nil), "invitation", invitation.ID, domain.AuditActionUpdate, mock.MatchedBy(func(changes json.RawMessage) bool {
+			return string(changes) == `{"after":{"status":"expired"},"before":{"status":"pending"}}`
+		}), "").Return(nil).Once()
+	}
+
+	expired, err := service.ExpireInvitations(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, expired)
+	audit.AssertExpectations(t)
+}
+
 func TestIdentityService_AcceptInvitation(t *testing.T) {
 	mockUserRepo := new(MockUserRepository)
 	mockOrgRepo := new(MockOrganizationRepository)
 	mockInvRepo := new(MockInvitationRepository)
-	service := NewIdentityService(mockUserRepo, mockOrgRepo, mockInvRepo)
+	service := NewIdentityService(mockUserRepo, mockOrgRepo, mockInvRepo, new(MockPasswordResetTokenRepository), new(MockTxManager), acceptingAudit(), nil, nil)
 
 	ctx := context.Background()
 	token := "valid-token"
+	// The accepted invitation is recorded as the new user's action.
+	asNewUser := mock.MatchedBy(func(ctx context.Context) bool {
+		p, ok := domain.PrincipalFromContext(ctx)
+		return ok && p.UserID != uuid.Nil
+	})
+	// assignUserID stands in for the database generating the new user's ID.
+	assignUserID := func(args mock.Arguments) { args.Get(1).(*domain.User).ID = uuid.New() }
 
 	t.Run("Success", func(t *testing.T) {
 		invitation := &domain.Invitation{
+			ID:        uuid.New(),
 			Token:     token,
 			Status:    domain.InvitationStatusPending,
 			ExpiresAt: time.Now().Add(1 * time.Hour),
@@ -205,15 +605,23 @@ func TestIdentityService_AcceptInvitation(t *testing.T) {
 		}
 
 		mockInvRepo.On("GetByToken", ctx, token).Return(invitation, nil).Once()
-		mockUserRepo.On("Create", ctx, mock.AnythingOfType("*domain.User")).Return(nil).Once()
-		mockInvRepo.On("Update", ctx, mock.MatchedBy(func(inv *domain.Invitation) bool {
+		mockUserRepo.On("GetByEmail", ctx, "newuser@test.com").Return(nil, gorm.ErrRecordNotFound).Once()
+		mockUserRepo.On("Create", ctx, mock.AnythingOfType("*domain.User")).Run(assignUserID).Return(nil).Once()
+		mockInvRepo.On("Update", asNewUser, mock.MatchedBy(func(inv *domain.Invitation) bool {
 			return inv.Status == domain.InvitationStatusAccepted
 		})).Return(nil).Once()
+		audit := new(MockAuditService)
+		audit.On("LogAction", asNewUser, mock.Anything, (*uuid.UUID)(nil), "user", mock.Anything, domain.AuditActionCreate, mock.Anything, "").Return(nil).Once()
+		audit.On("LogAction", asNewUser, mock.Anything, (*uuid.UUID)(nil), "invitation", invitation.ID, domain.AuditActionUpdate, mock.MatchedBy(func(changes json.RawMessage) bool {
+			return string(changes) == `{"after":{"status":"accepted"},"before":{"status":"pending"}}`
+		}), "").Return(nil).Once()
+		service := NewIdentityService(mockUserRepo, mockOrgRepo, mockInvRepo, new(MockPasswordResetTokenRepository), new(MockTxManager), audit, nil, nil)
 
 		user, err := service.AcceptInvitation(ctx, token, "password123", "John", "Doe")
 		assert.NoError(t, err)
 		assert.NotNil(t, user)
 		assert.Equal(t, "newuser@test.com", user.Email)
+		audit.AssertExpectations(t)
 	})
 
 	t.Run("ExpiredToken", func(t *testing.T) {
@@ -234,6 +642,45 @@ func TestIdentityService_AcceptInvitation(t *testing.T) {
 		assert.Equal(t, "invitation expired", err.Error())
 	})
 
+	t.Run("InvitationUpdateFails", func(t *testing.T) {
+		txManager := new(MockTxManager)
+		service := NewIdentityService(mockUserRepo, mockOrgRepo, mockInvRepo, new(MockPasswordResetTokenRepository), txManager, acceptingAudit(), nil, nil)
+		invitation := &domain.Invitation{
+			Token:     token,
+			Status:    domain.InvitationStatusPending,
+			ExpiresAt: time.Now().Add(1 * time.Hour),
+			Email:     "newuser@test.com",
+			Role:      domain.UserRoleUser,
+		}
+
+		mockInvRepo.On("GetByToken", ctx, token).Return(invitation, nil).Once()
+		mockUserRepo.On("GetByEmail", ctx, "newuser@test.com").Return(nil, gorm.ErrRecordNotFound).Once()
+		mockUserRepo.On("Create", ctx, mock.AnythingOfType("*domain.User")).Run(assignUserID).Return(nil).Once()
+		mockInvRepo.On("Update", asNewUser, mock.AnythingOfType("*domain.Invitation")).Return(errors.New("db error")).Once()
+
+		user, err := service.AcceptInvitation(ctx, token, "password123", "John", "Doe")
+		assert.EqualError(t, err, "db error")
+		assert.Nil(t, user)
+		assert.Equal(t, 1, txManager.calls)
+	})
+
+	t.Run("EmailRegisteredElsewhere", func(t *testing.T) {
+		invitation := &domain.Invitation{
+			Token:     token,
+			Status:    domain.InvitationStatusPending,
+			ExpiresAt: time.Now().Add(1 * time.Hour),
+			Email:     "taken@test.com",
+			Role:      domain.UserRoleUser,
+		}
+
+		mockInvRepo.On("GetByToken", ctx, token).Return(invitation, nil).Once()
+		mockUserRepo.On("GetByEmail", ctx, "taken@test.com").Return(&domain.User{Email: "taken@test.com", OrganizationID: uuid.New()}, nil).Once()
+
+		user, err := service.AcceptInvitation(ctx, token, "password123", "John", "Doe")
+		assert.ErrorIs(t, err, ErrEmailRegistered)
+		assert.Nil(t, user)
+	})
+
 	t.Run("InvalidToken", func(t *testing.T) {
 		mockInvRepo.On("GetByToken", ctx, "invalid").Return(nil, errors.New("not found")).Once()
 
@@ -242,3 +689,186 @@ func TestIdentityService_AcceptInvitation(t *testing.T) {
 		assert.Nil(t, user)
 	})
 }
+
+func TestIdentityService_RequestPasswordReset(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Queued Without Lookup", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		service := NewIdentityService(mockUserRepo, new(MockOrganizationRepository), new(MockInvitationRepository), new(MockPasswordResetTokenRepository), new(MockTxManager), acceptingAudit(), nil, nil)
+
+		assert.NoError(t, service.RequestPasswordReset(ctx, "ghost@test.com"))
+		mockUserRepo.AssertNotCalled(t, "GetByEmail", mock.Anything, mock.Anything)
+		assert.Equal(t, "ghost@test.com", <-service.resetQueue)
+	})
+
+	t.Run("Queue Full", func(t *testing.T) {
+		service := NewIdentityService(new(MockUserRepository), new(MockOrganizationRepository), new(MockInvitationRepository), new(MockPasswordResetTokenRepository), new(MockTxManager), acceptingAudit(), nil, nil)
+		for range resetQueueSize {
+			assert.NoError(t, service.RequestPasswordReset(ctx, "admin@test.com"))
+		}
+
+		assert.ErrorIs(t, service.RequestPasswordReset(ctx, "admin@test.com"), ErrResetQueueFull)
+	})
+}
+
+func TestIdentityService_ProcessPasswordResets(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mockUserRepo := new(MockUserRepository)
+	service := NewIdentityService(mockUserRepo, new(MockOrganizationRepository), new(MockInvitationRepository), new(MockPasswordResetTokenRepository), new(MockTxManager), acceptingAudit(), nil, nil)
+
+	mockUserRepo.On("GetByEmail", ctx, "admin@test.com").Return(nil, errors.New("connection refused"))
+	failures := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		service.ProcessPasswordResets(ctx, func(err error) { failures <- err })
+		close(done)
+	}()
+
+	assert.NoError(t, service.RequestPasswordReset(ctx, "admin@test.com"))
+	assert.EqualError(t, <-failures, "connection refused")
+	cancel()
+	<-done
+}
+
+func TestIdentityService_SendPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	user := &domain.User{ID: uuid.New(), Email: "admin@test.com"}
+
+	t.Run("Known Email", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		resetRepo := new(MockPasswordResetTokenRepository)
+		sender := new(MockIdentityMailer)
+		txManager := new(MockTxManager)
+		service := NewIdentityService(mockUserRepo, new(MockOrganizationRepository), new(MockInvitationRepository), resetRepo, txManager, acceptingAudit(), nil, sender)
+
+		var stored *domain.PasswordResetToken
+		mockUserRepo.On("GetByEmail", ctx, "admin@test.com").Return(user, nil)
+		resetRepo.On("ConsumeAllForUser", ctx, user.ID, mock.AnythingOfType("time.Time")).Return(nil)
+		resetRepo.On("Create", ctx, mock.AnythingOfType("*domain.PasswordResetToken")).
+			Run(func(args mock.Arguments) { stored = args.Get(1).(*domain.PasswordResetToken) }).
+			Return(nil)
+		sender.On("SendPasswordReset", ctx, user, mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(nil)
+
+		assert.NoError(t, service.sendPasswordReset(ctx, "admin@test.com"))
+		sender.AssertExpectations(t)
+		assert.Equal(t, 1, txManager.calls)
+
+		// Only the hash of the emailed token is stored.
+		token := sender.Calls[0].Arguments.String(2)
+		assert.Equal(t, hashResetToken(token), stored.TokenHash)
+		assert.NotEqual(t, token, stored.TokenHash)
+		assert.Equal(t, stored.ExpiresAt, sender.Calls[0].Arguments.Get(3))
+		assert.WithinDuration(t, time.Now().Add(passwordResetTTL), stored.ExpiresAt, time.Minute)
+	})
+
+	t.Run("Unknown Email", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		resetRepo := new(MockPasswordResetTokenRepository)
+		sender := new(MockIdentityMailer)
+		service := NewIdentityService(mockUserRepo, new(MockOrganizationRepository), new(MockInvitationRepository), resetRepo, new(MockTxManager), acceptingAudit(), nil, sender)
+
+		mockUserRepo.On("GetByEmail", ctx, "ghost@test.com").Return(nil, gorm.ErrRecordNotFound)
+
+		assert.NoError(t, service.sendPasswordReset(ctx, "ghost@test.com"))
+		resetRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		sender.AssertNotCalled(t, "SendPasswordReset", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("User Lookup Fails", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		sender := new(MockIdentityMailer)
+		service := NewIdentityService(mockUserRepo, new(MockOrganizationRepository), new(MockInvitationRepository), new(MockPasswordResetTokenRepository), new(MockTxManager), acceptingAudit(), nil, sender)
+
+		mockUserRepo.On("GetByEmail", ctx, "admin@test.com").Return(nil, errors.New("connection refused"))
+
+		assert.EqualError(t, service.sendPasswordReset(ctx, "admin@test.com"), "connection refused")
+		sender.AssertNotCalled(t, "SendPasswordReset", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestIdentityService_ResetPassword(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	token := "reset-token"
+	valid := func() *domain.PasswordResetToken {
+		return &domain.PasswordResetToken{ID: uuid.New(), UserID: userID, TokenHash: hashResetToken(token), ExpiresAt: time.Now().Add(time.Hour)}
+	}
+	setup := func() (*DefaultIdentityService, *MockUserRepository, *MockPasswordResetTokenRepository, *MockRefreshTokenRepository) {
+		mockUserRepo := new(MockUserRepository)
+		resetRepo := new(MockPasswordResetTokenRepository)
+		tokenRepo := new(MockRefreshTokenRepository)
+		sessions := newTestSessionService(t, tokenRepo, mockUserRepo)
+		return NewIdentityService(mockUserRepo, new(MockOrganizationRepository), new(MockInvitationRepository), resetRepo, new(MockTxManager), acceptingAudit(), sessions, nil),
+			mockUserRepo, resetRepo, tokenRepo
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		service, mockUserRepo, resetRepo, tokenRepo := setup()
+		record := valid()
+
+		resetRepo.On("GetByHash", ctx, hashResetToken(token)).Return(record, nil)
+		mockUserRepo.On("GetByID", ctx, userID).Return(&domain.User{ID: userID, PasswordHash: "old"}, nil)
+		resetRepo.On("Consume", ctx, record.ID, mock.AnythingOfType("time.Time")).Return(true, nil)
+		resetRepo.On("ConsumeAllForUser", ctx, userID, mock.AnythingOfType("time.Time")).Return(nil)
+		mockUserRepo.On("Update", ctx, mock.MatchedBy(func(u *domain.User) bool {
+			return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte("n3w-password")) == nil
+		})).Return(nil)
+		tokenRepo.On("RevokeAllForUser", ctx, userID).Return(nil)
+
+		assert.NoError(t, service.ResetPassword(ctx, token, "n3w-password"))
+		mockUserRepo.AssertExpectations(t)
+		tokenRepo.AssertExpectations(t)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		service, mockUserRepo, resetRepo, _ := setup()
+		record := valid()
+		record.ExpiresAt = time.Now().Add(-time.Minute)
+
+		resetRepo.On("GetByHash", ctx, hashResetToken(token)).Return(record, nil)
+
+		assert.ErrorIs(t, service.ResetPassword(ctx, token, "n3w-password"), ErrInvalidResetToken)
+		mockUserRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Already Used", func(t *testing.T) {
+		service, _, resetRepo, _ := setup()
+		record := valid()
+		usedAt := time.Now().Add(-time.Minute)
+		record.ConsumedAt = &usedAt
+
+		resetRepo.On("GetByHash", ctx, hashResetToken(token)).Return(record, nil)
+
+		assert.ErrorIs(t, service.ResetPassword(ctx, token, "n3w-password"), ErrInvalidResetToken)
+	})
+
+	t.Run("Used Concurrently", func(t *testing.T) {
+		service, mockUserRepo, resetRepo, tokenRepo := setup()
+		record := valid()
+
+		resetRepo.On("GetByHash", ctx, hashResetToken(token)).Return(record, nil)
+		mockUserRepo.On("GetByID", ctx, userID).Return(&domain.User{ID: userID}, nil)
+		resetRepo.On("Consume", ctx, record.ID, mock.AnythingOfType("time.Time")).Return(false, nil)
+
+		assert.ErrorIs(t, service.ResetPassword(ctx, token, "n3w-password"), ErrInvalidResetToken)
+		mockUserRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		tokenRepo.AssertNotCalled(t, "RevokeAllForUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown Token", func(t *testing.T) {
+		service, _, resetRepo, _ := setup()
+
+		resetRepo.On("GetByHash", ctx, hashResetToken("bogus")).Return(nil, errors.New("not found"))
+
+		assert.ErrorIs(t, service.ResetPassword(ctx, "bogus", "n3w-password"), ErrInvalidResetToken)
+	})
+
+	t.Run("Password Too Short", func(t *testing.T) {
+		service, _, resetRepo, _ := setup()
+
+		assert.ErrorIs(t, service.ResetPassword(ctx, token, "short"), ErrPasswordTooShort)
+		resetRepo.AssertNotCalled(t, "GetByHash", mock.Anything, mock.Anything)
+	})
+}
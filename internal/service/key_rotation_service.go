@@ -0,0 +1,147 @@
+package service
+
+import (
+	"agentXmap/internal/domain"
+	"agentXmap/internal/secrets"
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// DefaultRotationBatchSize is used when RotateSecrets is given no batch size.
+const DefaultRotationBatchSize = 100
+
+var ErrInvalidBatchSize = errors.New("batch size must be positive")
+
+// KeyRotationStatus reports which master key versions wrap the stored secrets.
+type KeyRotationStatus struct {
+	CurrentKeyVersion string           `json:"current_key_version" example:"v2"`
+	SecretsByVersion  map[string]int64 `json:"secrets_by_version"`
+	// Pending counts the secrets not yet wrapped by the current version.
+	Pending int64 `json:"pending" example:"12"`
+}
+
+// KeyRotationFailure is a secret the rotation could not rewrap.
+type KeyRotationFailure struct {
+	SecretID     uuid.UUID `json:"secret_id"`
+	KeyVersionID string    `json:"key_version_id"`
+	Error        string    `json:"error"`
+}
+
+// KeyRotationProgress is the running tally of a rotation, reported after each batch.
+type KeyRotationProgress struct {
+	TargetKeyVersion string `json:"target_key_version" example:"v2"`
+	// Total is the number of secrets pending when the rotation started.
+	Total     int64 `json:"total"`
+	Rewrapped int   `json:"rewrapped"`
+	// Skipped counts secrets replaced while being rewrapped; the new value is already on the target version.
+	Skipped  int                  `json:"skipped"`
+	Failures []KeyRotationFailure `json:"failures,omitempty"`
+	Batches  int                  `json:"batches"`
+}
+
+// KeyRotationService moves every ResourceSecret onto the current master key.
+// It runs as operator tooling across all organizations, outside any Principal.
+type KeyRotationService interface {
+	Status(ctx context.Context) (*KeyRotationStatus, error)
+	// RotateSecrets rewraps, batchSize at a time, the data key of every secret not
+	// on the current master key version, calling onBatch after each batch. Secrets
+	// that cannot be unwrapped are reported and left unchanged. Rows keep working
+	// throughout, since each one names the version that wraps it.
+	RotateSecrets(ctx context.Context, batchSize int, onBatch func(KeyRotationProgress)) (*KeyRotationProgress, error)
+}
+
+type DefaultKeyRotationService struct {
+	repo   domain.SecretRotationRepository
+	cipher *secrets.Cipher
+}
+
+func NewKeyRotationService(repo domain.SecretRotationRepository, cipher *secrets.Cipher) *DefaultKeyRotationService {
+	return &DefaultKeyRotationService{repo: repo, cipher: cipher}
+}
+
+func (s *DefaultKeyRotationService) Status(ctx context.Context) (*KeyRotationStatus, error) {
+	current, err := s.cipher.CurrentKeyVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.repo.CountSecretsByKeyVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &KeyRotationStatus{CurrentKeyVersion: current, SecretsByVersion: counts}
+	for version, n := range counts {
+		if version != current {
+			status.Pending += n
+		}
+	}
+	return status, nil
+}
+
+func (s *DefaultKeyRotationService) RotateSecrets(ctx context.Context, batchSize int, onBatch func(KeyRotationProgress)) (*KeyRotationProgress, error) {
+	if batchSize == 0 {
+		batchSize = DefaultRotationBatchSize
+	}
+	if batchSize < 0 {
+		return nil, ErrInvalidBatchSize
+	}
+	status, err := s.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := &KeyRotationProgress{TargetKeyVersion: status.CurrentKeyVersion, Total: status.Pending}
+	afterID := uuid.Nil
+	for {
+		if err := ctx.Err(); err != nil {
+			return progress, err
+		}
+		batch, err := s.repo.ListSecretsToRotate(ctx, progress.TargetKeyVersion, afterID, batchSize)
+		if err != nil {
+			return progress, err
+		}
+		if len(batch) == 0 {
+			return progress, nil
+		}
+
+		for _, secret := range batch {
+			if err := s.rotateSecret(ctx, &secret, progress); err != nil {
+				return progress, err
+			}
+		}
+		afterID = batch[len(batch)-1].ID
+		progress.Batches++
+		if onBatch != nil {
+			onBatch(*progress)
+		}
+	}
+}
+
+// rotateSecret rewraps one secret, returning an error only when the rotation must stop.
+func (s *DefaultKeyRotationService) rotateSecret(ctx context.Context, secret *domain.ResourceSecret, progress *KeyRotationProgress) error {
+	resealed, version, err := s.cipher.Rewrap(ctx, secret.EncryptedCredentials, secret.KeyVersionID)
+	if err != nil {
+		progress.Failures = append(progress.Failures, KeyRotationFailure{
+			SecretID:     secret.ID,
+			KeyVersionID: secret.KeyVersionID,
+			Error:        err.Error(),
+		})
+		return nil
+	}
+	if version != progress.TargetKeyVersion {
+		return errors.New("current master key changed during rotation")
+	}
+
+	stored, err := s.repo.ReplaceSecretEnvelope(ctx, secret.ID, secret.EncryptedCredentials, resealed, version)
+	if err != nil {
+		return err
+	}
+	if stored {
+		progress.Rewrapped++
+	} else {
+		progress.Skipped++
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"agentXmap/internal/domain"
+	"agentXmap/internal/secrets"
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockSecretRotationRepository is a mock implementation of domain.SecretRotationRepository
+type MockSecretRotationRepository struct {
+	mock.Mock
+}
+
+func (m *MockSecretRotationRepository) ListSecretsToRotate(ctx context.Context, keyVersion string, afterID uuid.UUID, limit int) ([]domain.ResourceSecret, error) {
+	args := m.Called(ctx, keyVersion, afterID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.ResourceSecret), args.Error(1)
+}
+
+func (m *MockSecretRotationRepository) CountSecretsByKeyVersion(ctx context.Context) (map[string]int64, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]int64), args.Error(1)
+}
+
+func (m *MockSecretRotationRepository) ReplaceSecretEnvelope(ctx context.Context, id uuid.UUID, oldEnvelope, newEnvelope, keyVersion string) (bool, error) {
+	args := m.Called(ctx, id, oldEnvelope, newEnvelope, keyVersion)
+	return args.Bool(0), args.Error(1)
+}
+
+// rotatedCipher knows the "v1" key of testCipher and wraps with a new "v2".
+func rotatedCipher(t *testing.T) *secrets.Cipher {
+	provider, err := secrets.NewLocalKeyProvider(map[string][]byte{
+		"v1": bytes.Repeat([]byte{7}, 32),
+		"v2": bytes.Repeat([]byte{8}, 32),
+	}, "v2")
+	require.NoError(t, err)
+	return secrets.NewCipher(provider)
+}
+
+func sealedSecret(t *testing.T, resourceID uuid.UUID) domain.ResourceSecret {
+	sealed, version, err := testCipher().Seal(context.Background(), []byte(`{"password":"s3cret"}`), resourceID[:])
+	require.NoError(t, err)
+	return domain.ResourceSecret{ID: uuid.New(), ResourceID: resourceID, EncryptedCredentials: sealed, KeyVersionID: version}
+}
+
+func TestKeyRotationService_Status(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockSecretRotationRepository)
+	service := NewKeyRotationService(repo, rotatedCipher(t))
+
+	repo.On("CountSecretsByKeyVersion", ctx).Return(map[string]int64{"v1": 3, "v2": 4, "": 1}, nil)
+
+	status, err := service.Status(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", status.CurrentKeyVersion)
+	assert.Equal(t, int64(4), status.Pending)
+}
+
+func TestKeyRotationService_RotateSecrets(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Rewraps In Batches", func(t *testing.T) {
+		repo := new(MockSecretRotationRepository)
+		cipher := rotatedCipher(t)
+		service := NewKeyRotationService(repo, cipher)
+
+		first, second, third := sealedSecret(t, uuid.New()), sealedSecret(t, uuid.New()), sealedSecret(t, uuid.New())
+		repo.On("CountSecretsByKeyVersion", ctx).Return(map[string]int64{"v1": 3}, nil)
+		repo.On("ListSecretsToRotate", ctx, "v2", uuid.Nil, 2).Return([]domain.ResourceSecret{first, second}, nil)
+		repo.On("ListSecretsToRotate", ctx, "v2", second.ID, 2).Return([]domain.ResourceSecret{third}, nil)
+		repo.On("ListSecretsToRotate", ctx, "v2", third.ID, 2).Return([]domain.ResourceSecret{}, nil)
+
+		var rewrapped []string
+		repo.On("ReplaceSecretEnvelope", ctx, mock.Anything, mock.Anything, mock.Anything, "v2").
+			Run(func(args mock.Arguments) { rewrapped = append(rewrapped, args.String(3)) }).
+			Return(true, nil).Times(2)
+		// The third secret was replaced while being rewrapped.
+		repo.On("ReplaceSecretEnvelope", ctx, third.ID, mock.Anything, mock.Anything, "v2").Return(false, nil).Once()
+
+		var reports []KeyRotationProgress
+		progress, err := service.RotateSecrets(ctx, 2, func(p KeyRotationProgress) { reports = append(reports, p) })
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), progress.Total)
+		assert.Equal(t, 2, progress.Rewrapped)
+		assert.Equal(t, 1, progress.Skipped)
+		assert.Empty(t, progress.Failures)
+		if assert.Len(t, reports, 2) {
+			assert.Equal(t, 2, reports[0].Rewrapped)
+			assert.Equal(t, 2, reports[1].Batches)
+		}
+
+		// Rewrapped envelopes open with the new version and the same binding.
+		if assert.Len(t, rewrapped, 2) {
+			plaintext, err := cipher.Open(ctx, rewrapped[0], "v2", first.ResourceID[:])
+			assert.NoError(t, err)
+			assert.Equal(t, `{"password":"s3cret"}`, string(plaintext))
+		}
+	})
+
+	t.Run("Reports Undecryptable Secrets", func(t *testing.T) {
+		repo := new(MockSecretRotationRepository)
+		service := NewKeyRotationService(repo, rotatedCipher(t))
+
+		orphan := sealedSecret(t, uuid.New())
+		orphan.KeyVersionID = "v0"
+		repo.On("CountSecretsByKeyVersion", ctx).Return(map[string]int64{"v0": 1}, nil)
+		repo.On("ListSecretsToRotate", ctx, "v2", uuid.Nil, DefaultRotationBatchSize).Return([]domain.ResourceSecret{orphan}, nil)
+		repo.On("ListSecretsToRotate", ctx, "v2", orphan.ID, DefaultRotationBatchSize).Return([]domain.ResourceSecret{}, nil)
+
+		progress, err := service.RotateSecrets(ctx, 0, nil)
+		assert.NoError(t, err)
+		if assert.Len(t, progress.Failures, 1) {
+			assert.Equal(t, orphan.ID, progress.Failures[0].SecretID)
+		}
+		repo.AssertNotCalled(t, "ReplaceSecretEnvelope", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Stops On Database Errors", func(t *testing.T) {
+		repo := new(MockSecretRotationRepository)
+		service := NewKeyRotationService(repo, rotatedCipher(t))
+
+		secret := sealedSecret(t, uuid.New())
+		repo.On("CountSecretsByKeyVersion", ctx).Return(map[string]int64{"v1": 1}, nil)
+		repo.On("ListSecretsToRotate", ctx, "v2", uuid.Nil, 10).Return([]domain.ResourceSecret{secret}, nil)
+		repo.On("ReplaceSecretEnvelope", ctx, secret.ID, mock.Anything, mock.Anything, "v2").Return(false, errors.New("db error"))
+
+		_, err := service.RotateSecrets(ctx, 10, nil)
+		assert.EqualError(t, err, "db error")
+	})
+
+	t.Run("Invalid Batch Size", func(t *testing.T) {
+		service := NewKeyRotationService(new(MockSecretRotationRepository), rotatedCipher(t))
+
+		_, err := service.RotateSecrets(ctx, -1, nil)
+		assert.ErrorIs(t, err, ErrInvalidBatchSize)
+	})
+}
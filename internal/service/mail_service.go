@@ -0,0 +1,177 @@
+package service
+
+import (
+	"agentXmap/internal/domain"
+	"agentXmap/internal/mail"
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxRetryBackoff caps the wait between two delivery attempts.
+const maxRetryBackoff = time.Hour
+
+// MailConfig sets the links in emails and how delivery is retried.
+type MailConfig struct {
+	// BaseURL is the web app that serves the invitation and reset pages.
+	BaseURL string
+	// MaxAttempts is how often an email is tried before it is marked failed.
+	MaxAttempts int
+	// RetryBackoff is the wait after the first failed attempt; it doubles
+	// with every further one, up to an hour.
+	RetryBackoff time.Duration
+}
+
+// MailService queues the emails of the platform in the outbox and delivers
+// them. Queuing joins the transaction in ctx, so an email exists exactly when
+// what it announces was committed.
+type MailService interface {
+	// SendInvitation queues the email inviting invitation.Email to accept it.
+	SendInvitation(ctx context.Context, invitation *domain.Invitation, invitor *domain.User) error
+	// SendPasswordReset queues the email carrying a password reset token.
+	SendPasswordReset(ctx context.Context, user *domain.User, token string, expiresAt time.Time) error
+	// SendNotification queues a plain notification to a user.
+	SendNotification(ctx context.Context, user *domain.User, subject, message string) error
+	// DeliverPending sends the queued emails due by now, batchSize at a time.
+	// Failed sends are retried with exponential backoff until MaxAttempts. It
+	// returns how many emails were sent.
+	DeliverPending(ctx context.Context, now time.Time, batchSize int) (int, error)
+}
+
+type DefaultMailService struct {
+	outbox    domain.OutboundEmailRepository
+	mailer    mail.Mailer
+	templates *mail.Templates
+	cfg       MailConfig
+}
+
+// NewMailService creates a new instance of DefaultMailService.
+// Emails are tried 8 times, 1 minute apart at first, by default.
+func NewMailService(outbox domain.OutboundEmailRepository, mailer mail.Mailer, templates *mail.Templates, cfg MailConfig) *DefaultMailService {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 8
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = time.Minute
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	return &DefaultMailService{outbox: outbox, mailer: mailer, templates: templates, cfg: cfg}
+}
+
+func (s *DefaultMailService) SendInvitation(ctx context.Context, invitation *domain.Invitation, invitor *domain.User) error {
+	data := mail.InvitationData{
+		Role:      string(invitation.Role),
+		AcceptURL: s.link("/invitations/accept", invitation.Token),
+		ExpiresAt: invitation.ExpiresAt,
+	}
+	if invitor != nil {
+		data.OrganizationName = invitor.Organization.Name
+		data.InvitorName = displayName(invitor)
+	}
+	return s.enqueue(ctx, &invitation.OrganizationID, invitation.Email, mail.TemplateInvitation, data)
+}
+
+func (s *DefaultMailService) SendPasswordReset(ctx context.Context, user *domain.User, token string, expiresAt time.Time) error {
+	return s.enqueue(ctx, &user.OrganizationID, user.Email, mail.TemplatePasswordReset, mail.PasswordResetData{
+		Name:      user.FirstName,
+		ResetURL:  s.link("/reset-password", token),
+		ExpiresAt: expiresAt,
+	})
+}
+
+func (s *DefaultMailService) SendNotification(ctx context.Context, user *domain.User, subject, message string) error {
+	return s.enqueue(ctx, &user.OrganizationID, user.Email, mail.TemplateNotification, mail.NotificationData{
+		Name:    user.FirstName,
+		Subject: subject,
+		Message: message,
+	})
+}
+
+func (s *DefaultMailService) DeliverPending(ctx context.Context, now time.Time, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	sent := 0
+	for {
+		emails, err := s.outbox.ListDue(ctx, now, batchSize)
+		if err != nil {
+			return sent, err
+		}
+		for i := range emails {
+			ok, err := s.deliver(ctx, &emails[i], now)
+			if err != nil {
+				return sent, err
+			}
+			if ok {
+				sent++
+			}
+		}
+		// Claimed emails are no longer due, so the next batch holds new ones.
+		if len(emails) < batchSize {
+			return sent, nil
+		}
+	}
+}
+
+// deliver makes one attempt at email. The attempt is claimed before sending,
+// so a worker that dies mid-send leaves the email to be retried, and two
+// workers never send it concurrently. Send errors are recorded on the email,
+// not returned.
+func (s *DefaultMailService) deliver(ctx context.Context, email *domain.OutboundEmail, now time.Time) (bool, error) {
+	attempt := email.Attempts + 1
+	claimed, err := s.outbox.Claim(ctx, email.ID, now, now.Add(s.backoff(attempt)))
+	if err != nil || !claimed {
+		return false, err
+	}
+	sendErr := s.mailer.Send(ctx, mail.Message{
+		To:      email.Recipient,
+		Subject: email.Subject,
+		Text:    email.TextBody,
+		HTML:    email.HTMLBody,
+	})
+	if sendErr != nil {
+		return false, s.outbox.MarkFailed(ctx, email.ID, sendErr.Error(), attempt >= s.cfg.MaxAttempts)
+	}
+	return true, s.outbox.MarkSent(ctx, email.ID, now)
+}
+
+// backoff is the wait after the given failed attempt.
+func (s *DefaultMailService) backoff(attempt int) time.Duration {
+	wait := s.cfg.RetryBackoff
+	for i := 1; i < attempt && wait < maxRetryBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, maxRetryBackoff)
+}
+
+func (s *DefaultMailService) enqueue(ctx context.Context, orgID *uuid.UUID, to, template string, data any) error {
+	msg, err := s.templates.Render(template, data)
+	if err != nil {
+		return err
+	}
+	return s.outbox.Create(ctx, &domain.OutboundEmail{
+		OrganizationID: orgID,
+		Recipient:      to,
+		Template:       template,
+		Subject:        msg.Subject,
+		TextBody:       msg.Text,
+		HTMLBody:       msg.HTML,
+		Status:         domain.EmailStatusPending,
+		NextAttemptAt:  time.Now().UTC(),
+	})
+}
+
+// link is a page of the web app carrying token in its query.
+func (s *DefaultMailService) link(path, token string) string {
+	return s.cfg.BaseURL + path + "?" + url.Values{"token": {token}}.Encode()
+}
+
+func displayName(user *domain.User) string {
+	if name := strings.TrimSpace(user.FirstName + " " + user.LastName); name != "" {
+		return name
+	}
+	return user.Email
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"agentXmap/internal/domain"
+	"agentXmap/internal/mail"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockOutboundEmailRepository is a mock implementation of domain.OutboundEmailRepository
+type MockOutboundEmailRepository struct {
+	mock.Mock
+}
+
+func (m *MockOutboundEmailRepository) Create(ctx context.Context, email *domain.OutboundEmail) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *MockOutboundEmailRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]domain.OutboundEmail, error) {
+	args := m.Called(ctx, now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.OutboundEmail), args.Error(1)
+}
+
+func (m *MockOutboundEmailRepository) Claim(ctx context.Context, id uuid.UUID, now, retryAt time.Time) (bool, error) {
+	args := m.Called(ctx, id, now, retryAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockOutboundEmailRepository) MarkSent(ctx context.Context, id uuid.UUID, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func (m *MockOutboundEmailRepository) MarkFailed(ctx context.Context, id uuid.UUID, lastError string, giveUp bool) error {
+	args := m.Called(ctx, id, lastError, giveUp)
+	return args.Error(0)
+}
+
+// MockMailer is a mock implementation of mail.Mailer
+type MockMailer struct {
+	mock.Mock
+}
+
+func (m *MockMailer) Send(ctx context.Context, msg mail.Message) error {
+	args := m.Called(ctx, msg)
+	return args.Error(0)
+}
+
+func newTestMailService(t *testing.T, outbox *MockOutboundEmailRepository, mailer *MockMailer) *DefaultMailService {
+	t.Helper()
+	templates, err := mail.LoadTemplates()
+	require.NoError(t, err)
+	return NewMailService(outbox, mailer, templates, MailConfig{BaseURL: "https://app.agentxmap.io/", MaxAttempts: 3, RetryBackoff: time.Minute})
+}
+
+func TestMailService_SendInvitation(t *testing.T) {
+	ctx := context.Background()
+	outbox := new(MockOutboundEmailRepository)
+	service := newTestMailService(t, outbox, new(MockMailer))
+
+	invitor := &domain.User{FirstName: "Jane", LastName: "Doe", Organization: domain.Organization{Name: "Acme"}}
+	invitation := &domain.Invitation{OrganizationID: uuid.New(), Email: "john@acme.com", Token: "tok/en", Role: domain.UserRoleUser, ExpiresAt: time.Now().Add(48 * time.Hour)}
+
+	var queued *domain.OutboundEmail
+	outbox.On("Create", ctx, mock.AnythingOfType("*domain.OutboundEmail")).
+		Run(func(args mock.Arguments) { queued = args.Get(1).(*domain.OutboundEmail) }).
+		Return(nil)
+
+	assert.NoError(t, service.SendInvitation(ctx, invitation, invitor))
+	assert.Equal(t, "john@acme.com", queued.Recipient)
+	assert.Equal(t, mail.TemplateInvitation, queued.Template)
+	assert.Equal(t, invitation.OrganizationID, *queued.OrganizationID)
+	assert.Equal(t, domain.EmailStatusPending, queued.Status)
+	assert.Equal(t, "You are invited to join Acme", queued.Subject)
+	assert.Contains(t, queued.TextBody, "Jane Doe invited you")
+	assert.Contains(t, queued.TextBody, "https://app.agentxmap.io/invitations/accept?token=tok%2Fen")
+	assert.NotEmpty(t, queued.HTMLBody)
+}
+
+func TestMailService_SendPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	outbox := new(MockOutboundEmailRepository)
+	service := newTestMailService(t, outbox, new(MockMailer))
+	user := &domain.User{ID: uuid.New(), OrganizationID: uuid.New(), Email: "john@acme.com", FirstName: "John"}
+
+	outbox.On("Create", ctx, mock.MatchedBy(func(e *domain.OutboundEmail) bool {
+		return e.Recipient == "john@acme.com" && e.Template == mail.TemplatePasswordReset
+	})).Return(nil)
+
+	assert.NoError(t, service.SendPasswordReset(ctx, user, "secret", time.Now().Add(time.Hour)))
+	queued := outbox.Calls[0].Arguments.Get(1).(*domain.OutboundEmail)
+	assert.Contains(t, queued.TextBody, "https://app.agentxmap.io/reset-password?token=secret")
+}
+
+func TestMailService_DeliverPending(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	due := func(attempts int) domain.OutboundEmail {
+		return domain.OutboundEmail{ID: uuid.New(), Recipient: "john@acme.com", Subject: "Hi", TextBody: "hello", Attempts: attempts}
+	}
+
+	t.Run("Sent", func(t *testing.T) {
+		outbox, mailer := new(MockOutboundEmailRepository), new(MockMailer)
+		service := newTestMailService(t, outbox, mailer)
+		email := due(0)
+
+		outbox.On("ListDue", ctx, now, 10).Return([]domain.OutboundEmail{email}, nil)
+		outbox.On("Claim", ctx, email.ID, now, now.Add(time.Minute)).Return(true, nil)
+		mailer.On("Send", ctx, mail.Message{To: "john@acme.com", Subject: "Hi", Text: "hello"}).Return(nil)
+		outbox.On("MarkSent", ctx, email.ID, now).Return(nil)
+
+		sent, err := service.DeliverPending(ctx, now, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, sent)
+		outbox.AssertExpectations(t)
+	})
+
+	t.Run("Failure Is Retried With Backoff", func(t *testing.T) {
+		outbox, mailer := new(MockOutboundEmailRepository), new(MockMailer)
+		service := newTestMailService(t, outbox, mailer)
+		email := due(1)
+
+		outbox.On("ListDue", ctx, now, 10).Return([]domain.OutboundEmail{email}, nil)
+		// Second attempt: the next one waits twice the base backoff.
+		outbox.On("Claim", ctx, email.ID, now, now.Add(2*time.Minute)).Return(true, nil)
+		mailer.On("Send", ctx, mock.Anything).Return(errors.New("connection refused"))
+		outbox.On("MarkFailed", ctx, email.ID, "connection refused", false).Return(nil)
+
+		sent, err := service.DeliverPending(ctx, now, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, sent)
+		outbox.AssertExpectations(t)
+	})
+
+	t.Run("Last Attempt Gives Up", func(t *testing.T) {
+		outbox, mailer := new(MockOutboundEmailRepository), new(MockMailer)
+		service := newTestMailService(t, outbox, mailer)
+		email := due(2)
+
+		outbox.On("ListDue", ctx, now, 10).Return([]domain.OutboundEmail{email}, nil)
+		outbox.On("Claim", ctx, email.ID, now, mock.Anything).Return(true, nil)
+		mailer.On("Send", ctx, mock.Anything).Return(errors.New("mailbox unavailable"))
+		outbox.On("MarkFailed", ctx, email.ID, "mailbox unavailable", true).Return(nil)
+
+		_, err := service.DeliverPending(ctx, now, 10)
+		assert.NoError(t, err)
+		outbox.AssertExpectations(t)
+	})
+
+	t.Run("Claimed By Another Worker", func(t *testing.T) {
+		outbox, mailer := new(MockOutboundEmailRepository), new(MockMailer)
+		service := newTestMailService(t, outbox, mailer)
+		email := due(0)
+
+		outbox.On("ListDue", ctx, now, 10).Return([]domain.OutboundEmail{email}, nil)
+		outbox.On("Claim", ctx, email.ID, now, mock.Anything).Return(false, nil)
+
+		sent, err := service.DeliverPending(ctx, now, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, sent)
+		mailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	})
+}
+
+func TestMailService_Backoff(t *testing.T) {
+	service := NewMailService(nil, nil, nil, MailConfig{RetryBackoff: time.Minute})
+
+	assert.Equal(t, time.Minute, service.backoff(1))
+	assert.Equal(t, 4*time.Minute, service.backoff(3))
+	assert.Equal(t, time.Hour, service.backoff(20))
+}
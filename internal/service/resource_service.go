@@ -1,34 +1,124 @@
 package service
 
 import (
+	"agentXmap/internal/connector"
 	"agentXmap/internal/domain"
+	"agentXmap/internal/policy"
+	"agentXmap/internal/secrets"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"sort"
+	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+var (
+	ErrResourceNotFound           = errors.New("resource not found")
+	ErrResourceNameRequired       = errors.New("resource name is required")
+	ErrResourceTypeRequired       = errors.New("resource type is required")
+	ErrResourceTypeNotFound       = errors.New("resource type not found")
+	ErrResourceTypeInactive       = errors.New("resource type is not active")
+	ErrInvalidConnectionDetails   = errors.New("invalid connection details")
+	ErrInvalidResourceCredentials = errors.New("invalid resource credentials")
+	ErrResourceSecretNotFound     = errors.New("resource has no credentials")
+	ErrConnectionTestUnsupported  = errors.New("resource type does not support connection testing")
+
+	ErrInvalidAccessLevel   = errors.New("invalid access level")
+	ErrAccessAlreadyGranted = errors.New("agent already has access to this resource")
+	ErrAccessNotGranted     = errors.New("agent has no access to this resource")
+	ErrAccessChanged        = errors.New("resource access was changed concurrently")
+)
+
+const (
+	// auditEntityResource is the SystemAuditLog entity type of resources.
+	auditEntityResource = "resource"
+	// auditEntityResourceSecret is the SystemAuditLog entity type of resource credentials.
+	auditEntityResourceSecret = "resource_secret"
+	// auditEntityResourceAccess is the SystemAuditLog entity type of agent access grants.
+	auditEntityResourceAccess = "agent_resource_access"
+)
+
+// connectionTestTimeout bounds a single connection test.
+const connectionTestTimeout = 10 * time.Second
+
+// SecretMetadata describes stored credentials without disclosing them.
+type SecretMetadata struct {
+	ResourceID   uuid.UUID `json:"resource_id"`
+	KeyVersionID string    `json:"key_version_id" example:"v1"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// RevealedSecret is the plaintext of stored credentials.
+type RevealedSecret struct {
+	SecretMetadata
+	Credentials json.RawMessage `json:"credentials" swaggertype:"string"`
+}
+
 type ResourceService interface {
+	// CreateResource and UpdateResource reject connection details that do not match
+	// the ConfigSchema of the resource type with a *SchemaValidationError.
 	CreateResource(ctx context.Context, orgID uuid.UUID, typeID, name string, config json.RawMessage) (*domain.Resource, error)
 	GetResource(ctx context.Context, id uuid.UUID) (*domain.Resource, error)
+	// ListResources lists the resources of an organization; listing deleted ones requires an admin.
+	ListResources(ctx context.Context, orgID uuid.UUID, filter domain.ResourceFilter) ([]domain.Resource, error)
+	UpdateResource(ctx context.Context, id uuid.UUID, name string, config json.RawMessage) (*domain.Resource, error)
+	// DeleteResource soft-deletes a resource, suspending the access grants of its agents.
+	DeleteResource(ctx context.Context, id uuid.UUID) error
+	// RestoreResource undeletes a resource together with the grants suspended by its deletion.
+	RestoreResource(ctx context.Context, id uuid.UUID) (*domain.Resource, error)
+	// ValidateCredentials checks credentials against the SecretSchema of the resource type.
+	ValidateCredentials(ctx context.Context, typeID string, credentials json.RawMessage) error
+	// SetSecret validates credentials against the SecretSchema of the resource type,
+	// then stores them encrypted, replacing any previous ones.
+	SetSecret(ctx context.Context, resourceID uuid.UUID, credentials json.RawMessage) (*SecretMetadata, error)
+	// RevealSecret decrypts the stored credentials; every call is audited.
+	RevealSecret(ctx context.Context, resourceID uuid.UUID) (*RevealedSecret, error)
+	// TestConnection reaches the resource with its stored credentials through the
+	// driver of its type and records the outcome as its last connection test. A
+	// failed test is a result, not an error.
+	TestConnection(ctx context.Context, resourceID uuid.UUID) (*domain.ConnectionTestResult, error)
 	ListAgentsWithAccess(ctx context.Context, resourceID uuid.UUID) ([]domain.Agent, error)
+	// GrantAccess gives an agent access to a resource. Granting read_write requires an admin.
+	GrantAccess(ctx context.Context, resourceID, agentID uuid.UUID, level domain.AccessLevel) (*domain.AgentResourceAccess, error)
+	// ChangeAccess upgrades or downgrades an existing grant. Upgrading to read_write requires an admin.
+	ChangeAccess(ctx context.Context, resourceID, agentID uuid.UUID, level domain.AccessLevel) (*domain.AgentResourceAccess, error)
+	RevokeAccess(ctx context.Context, resourceID, agentID uuid.UUID) error
 }
 
 type DefaultResourceService struct {
-	resRepo domain.ResourceRepository
+	resRepo      domain.ResourceRepository
+	agentRepo    domain.AgentRepository
+	txManager    domain.TxManager
+	auditService AuditService
+	cipher       *secrets.Cipher
+	drivers      *connector.Registry
 }
 
-func NewResourceService(resRepo domain.ResourceRepository) *DefaultResourceService {
-	return &DefaultResourceService{resRepo: resRepo}
+func NewResourceService(resRepo domain.ResourceRepository, agentRepo domain.AgentRepository, txManager domain.TxManager, auditService AuditService, cipher *secrets.Cipher, drivers *connector.Registry) *DefaultResourceService {
+	return &DefaultResourceService{resRepo: resRepo, agentRepo: agentRepo, txManager: txManager, auditService: auditService, cipher: cipher, drivers: drivers}
 }
 
 func (s *DefaultResourceService) CreateResource(ctx context.Context, orgID uuid.UUID, typeID, name string, config json.RawMessage) (*domain.Resource, error) {
+	if err := policy.Authorize(ctx, policy.ResourceResource, policy.ActionCreate); err != nil {
+		return nil, err
+	}
 	if name == "" {
-		return nil, errors.New("resource name is required")
+		return nil, ErrResourceNameRequired
 	}
 	if typeID == "" {
-		return nil, errors.New("resource type is required")
+		return nil, ErrResourceTypeRequired
+	}
+
+	rt, err := s.activeType(ctx, typeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateConnectionDetails(rt, config); err != nil {
+		return nil, err
 	}
 
 	res := &domain.Resource{
@@ -38,17 +128,504 @@ func (s *DefaultResourceService) CreateResource(ctx context.Context, orgID uuid.
 		ConnectionDetails: config,
 	}
 
-	if err := s.resRepo.Create(ctx, res); err != nil {
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.resRepo.Create(ctx, res); err != nil {
+			return err
+		}
+		return logMutation(ctx, s.auditService, res.OrganizationID, auditEntityResource, res.ID, domain.AuditActionCreate, nil, resourceSnapshot(res))
+	})
+	if err != nil {
 		return nil, err
 	}
-
 	return res, nil
 }
 
 func (s *DefaultResourceService) GetResource(ctx context.Context, id uuid.UUID) (*domain.Resource, error) {
-	res, err := s.resRepo.GetByID(ctx, id)
+	if err := policy.Authorize(ctx, policy.ResourceResource, policy.ActionRead); err != nil {
+		return nil, err
+	}
+	res, err := findResource(ctx, s.resRepo, id)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *DefaultResourceService) ListResources(ctx context.Context, orgID uuid.UUID, filter domain.ResourceFilter) ([]domain.Resource, error) {
+	action := policy.ActionRead
+	if filter.Deleted {
+		action = policy.ActionDelete
+	}
+	if err := policy.Authorize(ctx, policy.ResourceResource, action); err != nil {
+		return nil, err
+	}
+	return s.resRepo.ListByOrg(ctx, orgID, filter)
+}
+
+// UpdateResource renames a resource and replaces its connection details. They are
+// validated against the current schema of the type, even if it was deactivated.
+func (s *DefaultResourceService) UpdateResource(ctx context.Context, id uuid.UUID, name string, config json.RawMessage) (*domain.Resource, error) {
+	if err := policy.Authorize(ctx, policy.ResourceResource, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, ErrResourceNameRequired
+	}
+
+	res, err := findResource(ctx, s.resRepo, id)
+	if err != nil {
+		return nil, err
+	}
+	rt, err := s.resRepo.GetType(ctx, res.TypeID)
+	if err != nil {
+		return nil, err
+	}
+	if rt == nil {
+		return nil, ErrResourceTypeNotFound
+	}
+	if err := validateConnectionDetails(rt, config); err != nil {
+		return nil, err
+	}
+
+	before := resourceSnapshot(res)
+	res.Name = name
+	res.ConnectionDetails = config
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.resRepo.Update(ctx, res); err != nil {
+			return err
+		}
+		return logMutation(ctx, s.auditService, res.OrganizationID, auditEntityResource, res.ID, domain.AuditActionUpdate, before, resourceSnapshot(res))
+	})
 	if err != nil {
-		return nil, errors.New("resource not found")
+		return nil, err
 	}
 	return res, nil
 }
+
+func (s *DefaultResourceService) DeleteResource(ctx context.Context, id uuid.UUID) error {
+	if err := policy.Authorize(ctx, policy.ResourceResource, policy.ActionDelete); err != nil {
+		return err
+	}
+	res, err := findResource(ctx, s.resRepo, id)
+	if err != nil {
+		return err
+	}
+	return s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.resRepo.Delete(ctx, res.ID); err != nil {
+			return err
+		}
+		return logMutation(ctx, s.auditService, res.OrganizationID, auditEntityResource, res.ID, domain.AuditActionDelete, resourceSnapshot(res), nil)
+	})
+}
+
+func (s *DefaultResourceService) RestoreResource(ctx context.Context, id uuid.UUID) (*domain.Resource, error) {
+	if err := policy.Authorize(ctx, policy.ResourceResource, policy.ActionDelete); err != nil {
+		return nil, err
+	}
+	var res *domain.Resource
+	err := s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		restored, err := s.resRepo.Restore(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !restored {
+			return ErrResourceNotFound
+		}
+		if res, err = s.resRepo.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return logMutation(ctx, s.auditService, res.OrganizationID, auditEntityResource, res.ID, domain.AuditActionRestore, nil, resourceSnapshot(res))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// resourceSnapshot is the audited state of res. Credentials live in
+// ResourceSecret and are never part of it.
+func resourceSnapshot(res *domain.Resource) auditSnapshot {
+	return auditSnapshot{
+		"name":               res.Name,
+		"type_id":            res.TypeID,
+		"connection_details": res.ConnectionDetails,
+	}
+}
+
+func (s *DefaultResourceService) ValidateCredentials(ctx context.Context, typeID string, credentials json.RawMessage) error {
+	if err := policy.Authorize(ctx, policy.ResourceResource, policy.ActionUpdate); err != nil {
+		return err
+	}
+	rt, err := s.resRepo.GetType(ctx, typeID)
+	if err != nil {
+		return err
+	}
+	if rt == nil {
+		return ErrResourceTypeNotFound
+	}
+	return validateCredentials(rt, credentials)
+}
+
+func (s *DefaultResourceService) SetSecret(ctx context.Context, resourceID uuid.UUID, credentials json.RawMessage) (*SecretMetadata, error) {
+	if err := policy.Authorize(ctx, policy.ResourceResourceSecret, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+	res, err := findResource(ctx, s.resRepo, resourceID)
+	if err != nil {
+		return nil, err
+	}
+	rt, err := s.resRepo.GetType(ctx, res.TypeID)
+	if err != nil {
+		return nil, err
+	}
+	if rt == nil {
+		return nil, ErrResourceTypeNotFound
+	}
+	if err := validateCredentials(rt, credentials); err != nil {
+		return nil, err
+	}
+
+	sealed, keyVersion, err := s.cipher.Seal(ctx, credentials, res.ID[:])
+	if err != nil {
+		return nil, err
+	}
+	secret := &domain.ResourceSecret{
+		ResourceID:           res.ID,
+		EncryptedCredentials: sealed,
+		KeyVersionID:         keyVersion,
+		UpdatedAt:            time.Now().UTC(),
+	}
+
+	// The fields of the replaced credentials are unknown without decrypting
+	// them, so an update records the previous key version only.
+	action, before := domain.AuditActionCreate, auditSnapshot(nil)
+	if res.Secret.ID != uuid.Nil {
+		action, before = domain.AuditActionUpdate, auditSnapshot{"key_version_id": res.Secret.KeyVersionID}
+	}
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.resRepo.SaveSecret(ctx, secret); err != nil {
+			return err
+		}
+		return s.auditSecret(ctx, res, action, before, secret.KeyVersionID, credentials)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &SecretMetadata{ResourceID: res.ID, KeyVersionID: secret.KeyVersionID, UpdatedAt: secret.UpdatedAt}, nil
+}
+
+func (s *DefaultResourceService) RevealSecret(ctx context.Context, resourceID uuid.UUID) (*RevealedSecret, error) {
+	if err := policy.Authorize(ctx, policy.ResourceResourceSecret, policy.ActionRead); err != nil {
+		return nil, err
+	}
+	res, err := findResource(ctx, s.resRepo, resourceID)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := s.resRepo.GetSecret(ctx, res.ID)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, ErrResourceSecretNotFound
+	}
+
+	plaintext, err := s.cipher.Open(ctx, secret.EncryptedCredentials, secret.KeyVersionID, res.ID[:])
+	if err != nil {
+		return nil, err
+	}
+	// Nothing is disclosed unless the disclosure was recorded.
+	if err := s.auditSecret(ctx, res, domain.AuditActionReadSecret, nil, secret.KeyVersionID, plaintext); err != nil {
+		return nil, err
+	}
+	return &RevealedSecret{
+		SecretMetadata: SecretMetadata{ResourceID: res.ID, KeyVersionID: secret.KeyVersionID, UpdatedAt: secret.UpdatedAt},
+		Credentials:    plaintext,
+	}, nil
+}
+
+func (s *DefaultResourceService) TestConnection(ctx context.Context, resourceID uuid.UUID) (*domain.ConnectionTestResult, error) {
+	if err := policy.Authorize(ctx, policy.ResourceResource, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+	res, err := findResource(ctx, s.resRepo, resourceID)
+	if err != nil {
+		return nil, err
+	}
+	driver, ok := s.drivers.Get(res.TypeID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrConnectionTestUnsupported, res.TypeID)
+	}
+
+	var credentials json.RawMessage
+	secret, err := s.resRepo.GetSecret(ctx, res.ID)
+	if err != nil {
+		return nil, err
+	}
+	if secret != nil {
+		// The test sends the plaintext credentials to whatever host the
+		// connection details name, so it discloses them like RevealSecret.
+		if err := policy.Authorize(ctx, policy.ResourceResourceSecret, policy.ActionRead); err != nil {
+			return nil, err
+		}
+		if credentials, err = s.cipher.Open(ctx, secret.EncryptedCredentials, secret.KeyVersionID, res.ID[:]); err != nil {
+			return nil, err
+		}
+		// Nothing is sent unless the disclosure was recorded.
+		if err := s.auditSecret(ctx, res, domain.AuditActionReadSecret, nil, secret.KeyVersionID, credentials); err != nil {
+			return nil, err
+		}
+	}
+
+	testCtx, cancel := context.WithTimeout(ctx, connectionTestTimeout)
+	defer cancel()
+	start := time.Now()
+	testErr := driver.TestConnection(testCtx, res.ConnectionDetails, credentials)
+	result := &domain.ConnectionTestResult{
+		Success:   testErr == nil,
+		LatencyMS: time.Since(start).Milliseconds(),
+		TestedAt:  start.UTC(),
+	}
+	if testErr != nil {
+		var cerr *connector.Error
+		if errors.As(testErr, &cerr) {
+			result.ErrorCode, result.ErrorMessage = string(cerr.Code), cerr.Message
+		} else {
+			result.ErrorCode, result.ErrorMessage = string(connector.ErrorUnknown), "connection failed"
+		}
+	}
+
+	recorded, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	after := auditSnapshot{
+		"connection_details": res.ConnectionDetails,
+		"success":            result.Success,
+		"error_code":         result.ErrorCode,
+	}
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.resRepo.RecordConnectionTest(ctx, res.ID, recorded); err != nil {
+			return err
+		}
+		return logMutation(ctx, s.auditService, res.OrganizationID, auditEntityResource, res.ID, domain.AuditActionTestConnection, nil, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// auditSecret records an operation on the credentials of res, whose previous
+// state is before. Only the names of the credential fields are logged, never
+// their values.
+func (s *DefaultResourceService) auditSecret(ctx context.Context, res *domain.Resource, action domain.AuditAction, before auditSnapshot, keyVersion string, credentials json.RawMessage) error {
+	after := auditSnapshot{"fields": credentialFields(credentials), "key_version_id": keyVersion}
+	return logMutation(ctx, s.auditService, res.OrganizationID, auditEntityResourceSecret, res.ID, action, before, after)
+}
+
+// credentialFields returns the sorted names of the fields of credentials.
+func credentialFields(credentials json.RawMessage) []string {
+	var fields map[string]json.RawMessage
+	_ = json.Unmarshal(credentials, &fields)
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *DefaultResourceService) ListAgentsWithAccess(ctx context.Context, resourceID uuid.UUID) ([]domain.Agent, error) {
+	if err := policy.Authorize(ctx, policy.ResourceResource, policy.ActionRead); err != nil {
+		return nil, err
+	}
+	return s.resRepo.ListAgentsWithAccess(ctx, resourceID)
+}
+
+func (s *DefaultResourceService) GrantAccess(ctx context.Context, resourceID, agentID uuid.UUID, level domain.AccessLevel) (*domain.AgentResourceAccess, error) {
+	if err := policy.Authorize(ctx, policy.ResourceResourceAccess, policy.ActionCreate); err != nil {
+		return nil, err
+	}
+	if err := authorizeAccessLevel(ctx, level); err != nil {
+		return nil, err
+	}
+
+	res, err := findResource(ctx, s.resRepo, resourceID)
+	if err != nil {
+		return nil, err
+	}
+	agent, err := s.agentRepo.GetByID(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if agent == nil {
+		return nil, ErrAgentNotFound
+	}
+	existing, err := s.resRepo.GetAccess(ctx, res.ID, agent.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrAccessAlreadyGranted
+	}
+
+	access := &domain.AgentResourceAccess{
+		ID:         uuid.New(),
+		AgentID:    agent.ID,
+		ResourceID: res.ID,
+		Permission: level,
+		GrantedAt:  time.Now().UTC(),
+	}
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		created, err := s.resRepo.CreateAccess(ctx, access)
+		if err != nil {
+			return err
+		}
+		if !created {
+			return ErrAccessAlreadyGranted
+		}
+		return logMutation(ctx, s.auditService, res.OrganizationID, auditEntityResourceAccess, access.ID, domain.AuditActionCreate, nil, accessSnapshot(access))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return access, nil
+}
+
+func (s *DefaultResourceService) ChangeAccess(ctx context.Context, resourceID, agentID uuid.UUID, level domain.AccessLevel) (*domain.AgentResourceAccess, error) {
+	if err := policy.Authorize(ctx, policy.ResourceResourceAccess, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+	if err := authorizeAccessLevel(ctx, level); err != nil {
+		return nil, err
+	}
+
+	res, access, err := s.grant(ctx, resourceID, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if access.Permission == level {
+		return access, nil
+	}
+
+	before := accessSnapshot(access)
+	from := access.Permission
+	access.Permission = level
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		ok, err := s.resRepo.UpdateAccessPermission(ctx, access.ID, from, level)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrAccessChanged
+		}
+		return logMutation(ctx, s.auditService, res.OrganizationID, auditEntityResourceAccess, access.ID, domain.AuditActionUpdate, before, accessSnapshot(access))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return access, nil
+}
+
+func (s *DefaultResourceService) RevokeAccess(ctx context.Context, resourceID, agentID uuid.UUID) error {
+	if err := policy.Authorize(ctx, policy.ResourceResourceAccess, policy.ActionDelete); err != nil {
+		return err
+	}
+	res, access, err := s.grant(ctx, resourceID, agentID)
+	if err != nil {
+		return err
+	}
+
+	return s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		ok, err := s.resRepo.DeleteAccess(ctx, access.ID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrAccessChanged
+		}
+		return logMutation(ctx, s.auditService, res.OrganizationID, auditEntityResourceAccess, access.ID, domain.AuditActionDelete, accessSnapshot(access), nil)
+	})
+}
+
+// grant loads a resource and the access agentID has to it.
+func (s *DefaultResourceService) grant(ctx context.Context, resourceID, agentID uuid.UUID) (*domain.Resource, *domain.AgentResourceAccess, error) {
+	res, err := findResource(ctx, s.resRepo, resourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	access, err := s.resRepo.GetAccess(ctx, res.ID, agentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if access == nil {
+		return nil, nil, ErrAccessNotGranted
+	}
+	return res, access, nil
+}
+
+// findResource loads a resource of the caller's organization. Only a missing
+// one is ErrResourceNotFound; other failures are returned as such.
+func findResource(ctx context.Context, resRepo domain.ResourceRepository, id uuid.UUID) (*domain.Resource, error) {
+	res, err := resRepo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && res == nil) {
+		return nil, ErrResourceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load resource: %w", err)
+	}
+	return res, nil
+}
+
+// accessSnapshot is the audited state of a grant.
+func accessSnapshot(access *domain.AgentResourceAccess) auditSnapshot {
+	return auditSnapshot{
+		"agent_id":    access.AgentID,
+		"resource_id": access.ResourceID,
+		"permission":  access.Permission,
+	}
+}
+
+// authorizeAccessLevel validates level and requires an admin to hand out read_write.
+func authorizeAccessLevel(ctx context.Context, level domain.AccessLevel) error {
+	switch level {
+	case domain.AccessLevelReadOnly:
+		return nil
+	case domain.AccessLevelReadWrite:
+		return policy.Authorize(ctx, policy.ResourceResourceWriteAccess, policy.ActionCreate)
+	default:
+		return ErrInvalidAccessLevel
+	}
+}
+
+// activeType loads a resource type that new resources may use.
+func (s *DefaultResourceService) activeType(ctx context.Context, typeID string) (*domain.ResourceType, error) {
+	rt, err := s.resRepo.GetType(ctx, typeID)
+	if err != nil {
+		return nil, err
+	}
+	if rt == nil {
+		return nil, ErrResourceTypeNotFound
+	}
+	if !rt.IsActive {
+		return nil, ErrResourceTypeInactive
+	}
+	return rt, nil
+}
+
+func validateConnectionDetails(rt *domain.ResourceType, config json.RawMessage) error {
+	return validateAgainstType(rt, rt.ConfigSchema, config, ErrInvalidConnectionDetails)
+}
+
+func validateCredentials(rt *domain.ResourceType, credentials json.RawMessage) error {
+	return validateAgainstType(rt, rt.SecretSchema, credentials, ErrInvalidResourceCredentials)
+}
+
+func validateAgainstType(rt *domain.ResourceType, schema, doc json.RawMessage, invalid error) error {
+	err := validateDocument(schema, doc, invalid)
+	if errors.Is(err, ErrInvalidSchema) {
+		return fmt.Errorf("resource type %s: %w", rt.ID, err)
+	}
+	return err
+}
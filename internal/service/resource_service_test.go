@@ -1,7 +1,11 @@
 package service
 
 import (
+	"agentXmap/internal/connector"
 	"agentXmap/internal/domain"
+	"agentXmap/internal/policy"
+	"agentXmap/internal/secrets"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -10,6 +14,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
 // MockResourceRepository is a mock implementation of domain.ResourceRepository
@@ -30,6 +35,55 @@ func (m *MockResourceRepository) GetByID(ctx context.Context, id uuid.UUID) (*do
 	return args.Get(0).(*domain.Resource), args.Error(1)
 }
 
+func (m *MockResourceRepository) Update(ctx context.Context, res *domain.Resource) error {
+	args := m.Called(ctx, res)
+	return args.Error(0)
+}
+
+func (m *MockResourceRepository) ListByOrg(ctx context.Context, orgID uuid.UUID, filter domain.ResourceFilter) ([]domain.Resource, error) {
+	args := m.Called(ctx, orgID, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Resource), args.Error(1)
+}
+
+func (m *MockResourceRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockResourceRepository) Restore(ctx context.Context, id uuid.UUID) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockResourceRepository) GetType(ctx context.Context, id string) (*domain.ResourceType, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ResourceType), args.Error(1)
+}
+
+func (m *MockResourceRepository) GetSecret(ctx context.Context, resourceID uuid.UUID) (*domain.ResourceSecret, error) {
+	args := m.Called(ctx, resourceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ResourceSecret), args.Error(1)
+}
+
+func (m *MockResourceRepository) SaveSecret(ctx context.Context, secret *domain.ResourceSecret) error {
+	args := m.Called(ctx, secret)
+	return args.Error(0)
+}
+
+func (m *MockResourceRepository) RecordConnectionTest(ctx context.Context, resourceID uuid.UUID, result json.RawMessage) error {
+	args := m.Called(ctx, resourceID, result)
+	return args.Error(0)
+}
+
 func (m *MockResourceRepository) ListAgentsWithAccess(ctx context.Context, resourceID uuid.UUID) ([]domain.Agent, error) {
 	args := m.Called(ctx, resourceID)
 	if args.Get(0) == nil {
@@ -38,16 +92,52 @@ func (m *MockResourceRepository) ListAgentsWithAccess(ctx context.Context, resou
 	return args.Get(0).([]domain.Agent), args.Error(1)
 }
 
+// testCipher seals with a fixed local master key "v1".
+func testCipher() *secrets.Cipher {
+	provider, err := secrets.NewLocalKeyProvider(map[string][]byte{"v1": bytes.Repeat([]byte{7}, 32)}, "v1")
+	if err != nil {
+		panic(err)
+	}
+	return secrets.NewCipher(provider)
+}
+
+// postgresType mirrors the seeded postgres_db resource type.
+func postgresType() *domain.ResourceType {
+	return &domain.ResourceType{
+		ID:       "postgres-db",
+		Name:     "PostgreSQL Database",
+		IsActive: true,
+		ConfigSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"host": {"type": "string", "minLength": 1},
+				"port": {"type": "integer", "minimum": 1, "maximum": 65535}
+			},
+			"required": ["host"],
+			"additionalProperties": false
+		}`),
+		SecretSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {"username": {"type": "string"}, "password": {"type": "string"}},
+			"required": ["username", "password"]
+		}`),
+	}
+}
+
 func TestResourceService_CreateResource(t *testing.T) {
-	ctx := context.Background()
+	ctx := principalContext(domain.UserRoleAdmin)
 	orgID := uuid.New()
 	config := json.RawMessage(`{"host":"localhost"}`)
 
 	t.Run("Success", func(t *testing.T) {
 		mockRepo := new(MockResourceRepository)
-		service := NewResourceService(mockRepo)
+		mockAudit := new(MockAuditService)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), mockAudit, testCipher(), connector.NewRegistry())
 
+		mockRepo.On("GetType", ctx, "postgres-db").Return(postgresType(), nil)
 		mockRepo.On("Create", ctx, mock.AnythingOfType("*domain.Resource")).Return(nil)
+		mockAudit.On("LogAction", ctx, orgID, (*uuid.UUID)(nil), "resource", mock.Anything, domain.AuditActionCreate,
+			json.RawMessage(`{"after":{"connection_details":{"host":"localhost"},"name":"Test DB","type_id":"postgres-db"}}`), "").Return(nil)
 
 		res, err := service.CreateResource(ctx, orgID, "postgres-db", "Test DB", config)
 		assert.NoError(t, err)
@@ -56,11 +146,12 @@ func TestResourceService_CreateResource(t *testing.T) {
 		assert.Equal(t, "postgres-db", res.TypeID)
 		assert.Equal(t, orgID, res.OrganizationID)
 		mockRepo.AssertExpectations(t)
+		mockAudit.AssertExpectations(t)
 	})
 
 	t.Run("Validation Error - Empty Name", func(t *testing.T) {
 		mockRepo := new(MockResourceRepository)
-		service := NewResourceService(mockRepo)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), acceptingAudit(), testCipher(), connector.NewRegistry())
 
 		_, err := service.CreateResource(ctx, orgID, "postgres-db", "", config)
 		assert.Error(t, err)
@@ -69,17 +160,93 @@ func TestResourceService_CreateResource(t *testing.T) {
 
 	t.Run("Validation Error - Empty Type", func(t *testing.T) {
 		mockRepo := new(MockResourceRepository)
-		service := NewResourceService(mockRepo)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), acceptingAudit(), testCipher(), connector.NewRegistry())
 
 		_, err := service.CreateResource(ctx, orgID, "", "Test DB", config)
 		assert.Error(t, err)
 		assert.Equal(t, "resource type is required", err.Error())
 	})
 
+	t.Run("Invalid Connection Details", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), acceptingAudit(), testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetType", ctx, "postgres-db").Return(postgresType(), nil)
+
+		_, err := service.CreateResource(ctx, orgID, "postgres-db", "Test DB", json.RawMessage(`{"port":"5432","user":"x"}`))
+		assert.ErrorIs(t, err, ErrInvalidConnectionDetails)
+		var verr *SchemaValidationError
+		if assert.ErrorAs(t, err, &verr) {
+			assert.Equal(t, []FieldError{
+				{Field: "/host", Message: "is required"},
+				{Field: "/port", Message: "got string, want integer"},
+				{Field: "/user", Message: "is not allowed"},
+			}, verr.Fields)
+		}
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Malformed Connection Details", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), acceptingAudit(), testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetType", ctx, "postgres-db").Return(postgresType(), nil)
+
+		_, err := service.CreateResource(ctx, orgID, "postgres-db", "Test DB", json.RawMessage(`{"host":`))
+		assert.ErrorIs(t, err, ErrInvalidConnectionDetails)
+	})
+
+	t.Run("Unknown Type", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), acceptingAudit(), testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetType", ctx, "mongo").Return(nil, nil)
+
+		_, err := service.CreateResource(ctx, orgID, "mongo", "Test DB", config)
+		assert.ErrorIs(t, err, ErrResourceTypeNotFound)
+	})
+
+	t.Run("Inactive Type", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), acceptingAudit(), testCipher(), connector.NewRegistry())
+
+		rt := postgresType()
+		rt.IsActive = false
+		mockRepo.On("GetType", ctx, "postgres-db").Return(rt, nil)
+
+		_, err := service.CreateResource(ctx, orgID, "postgres-db", "Test DB", config)
+		assert.ErrorIs(t, err, ErrResourceTypeInactive)
+	})
+
+	t.Run("Type Without Schema", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), acceptingAudit(), testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetType", ctx, "custom").Return(&domain.ResourceType{ID: "custom", IsActive: true}, nil)
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*domain.Resource")).Return(nil)
+
+		_, err := service.CreateResource(ctx, orgID, "custom", "Anything", json.RawMessage(`{"any":"thing"}`))
+		assert.NoError(t, err)
+	})
+
+	t.Run("Broken Type Schema", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), acceptingAudit(), testCipher(), connector.NewRegistry())
+
+		rt := postgresType()
+		rt.ConfigSchema = json.RawMessage(`{"type": 42}`)
+		mockRepo.On("GetType", ctx, "postgres-db").Return(rt, nil)
+
+		_, err := service.CreateResource(ctx, orgID, "postgres-db", "Test DB", config)
+		assert.ErrorIs(t, err, ErrInvalidSchema)
+		assert.NotErrorIs(t, err, ErrInvalidConnectionDetails)
+	})
+
 	t.Run("Repo Error", func(t *testing.T) {
 		mockRepo := new(MockResourceRepository)
-		service := NewResourceService(mockRepo)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), acceptingAudit(), testCipher(), connector.NewRegistry())
 
+		mockRepo.On("GetType", ctx, "postgres-db").Return(postgresType(), nil)
 		mockRepo.On("Create", ctx, mock.AnythingOfType("*domain.Resource")).Return(errors.New("db error"))
 
 		_, err := service.CreateResource(ctx, orgID, "postgres-db", "Test DB", config)
@@ -87,15 +254,24 @@ func TestResourceService_CreateResource(t *testing.T) {
 		assert.Equal(t, "db error", err.Error())
 		mockRepo.AssertExpectations(t)
 	})
+
+	t.Run("Forbidden for users", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), acceptingAudit(), testCipher(), connector.NewRegistry())
+
+		_, err := service.CreateResource(principalContext(domain.UserRoleUser), orgID, "postgres-db", "Test DB", config)
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
 }
 
 func TestResourceService_GetResource(t *testing.T) {
-	ctx := context.Background()
+	ctx := principalContext(domain.UserRoleAdmin)
 	resID := uuid.New()
 
 	t.Run("Success", func(t *testing.T) {
 		mockRepo := new(MockResourceRepository)
-		service := NewResourceService(mockRepo)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), acceptingAudit(), testCipher(), connector.NewRegistry())
 
 		expectedRes := &domain.Resource{ID: resID, Name: "Test Resource"}
 		mockRepo.On("GetByID", ctx, resID).Return(expectedRes, nil)
@@ -108,13 +284,712 @@ func TestResourceService_GetResource(t *testing.T) {
 
 	t.Run("Not Found", func(t *testing.T) {
 		mockRepo := new(MockResourceRepository)
-		service := NewResourceService(mockRepo)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), acceptingAudit(), testCipher(), connector.NewRegistry())
 
-		mockRepo.On("GetByID", ctx, resID).Return(nil, errors.New("db error"))
+		mockRepo.On("GetByID", ctx, resID).Return(nil, gorm.ErrRecordNotFound)
 
 		_, err := service.GetResource(ctx, resID)
 		assert.Error(t, err)
 		assert.Equal(t, "resource not found", err.Error())
 		mockRepo.AssertExpectations(t)
 	})
+
+	t.Run("Repository Error", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), acceptingAudit(), testCipher(), connector.NewRegistry())
+		dbErr := errors.New("connection reset")
+
+		mockRepo.On("GetByID", ctx, resID).Return(nil, dbErr)
+
+		_, err := service.GetResource(ctx, resID)
+		assert.ErrorIs(t, err, dbErr)
+		assert.NotErrorIs(t, err, ErrResourceNotFound)
+	})
+}
+
+func TestResourceService_UpdateResource(t *testing.T) {
+	ctx := principalContext(domain.UserRoleManager)
+	resID := uuid.New()
+	existing := func() *domain.Resource {
+		return &domain.Resource{ID: resID, TypeID: "postgres-db", Name: "Old", ConnectionDetails: json.RawMessage(`{"host":"old"}`)}
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		mockAudit := new(MockAuditService)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), mockAudit, testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetByID", ctx, resID).Return(existing(), nil)
+		mockRepo.On("GetType", ctx, "postgres-db").Return(postgresType(), nil)
+		mockRepo.On("Update", ctx, mock.MatchedBy(func(res *domain.Resource) bool {
+			return res.Name == "New" && string(res.ConnectionDetails) == `{"host":"db","port":5432}`
+		})).Return(nil)
+		// Only the changed fields are recorded, the type is left out.
+		mockAudit.On("LogAction", ctx, uuid.Nil, (*uuid.UUID)(nil), "resource", resID, domain.AuditActionUpdate,
+			json.RawMessage(`{"after":{"connection_details":{"host":"db","port":5432},"name":"New"},"before":{"connection_details":{"host":"old"},"name":"Old"}}`), "").Return(nil)
+
+		res, err := service.UpdateResource(ctx, resID, "New", json.RawMessage(`{"host":"db","port":5432}`))
+		assert.NoError(t, err)
+		assert.Equal(t, "New", res.Name)
+		mockRepo.AssertExpectations(t)
+		mockAudit.AssertExpectations(t)
+	})
+
+	t.Run("Invalid Connection Details", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), acceptingAudit(), testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetByID", ctx, resID).Return(existing(), nil)
+		mockRepo.On("GetType", ctx, "postgres-db").Return(postgresType(), nil)
+
+		_, err := service.UpdateResource(ctx, resID, "New", json.RawMessage(`{"host":"db","port":70000}`))
+		var verr *SchemaValidationError
+		if assert.ErrorAs(t, err, &verr) {
+			assert.Len(t, verr.Fields, 1)
+			assert.Equal(t, "/port", verr.Fields[0].Field)
+		}
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), acceptingAudit(), testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetByID", ctx, resID).Return(nil, gorm.ErrRecordNotFound)
+
+		_, err := service.UpdateResource(ctx, resID, "New", nil)
+		assert.ErrorIs(t, err, ErrResourceNotFound)
+	})
+
+	t.Run("Forbidden for users", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), acceptingAudit(), testCipher(), connector.NewRegistry())
+
+		_, err := service.UpdateResource(principalContext(domain.UserRoleUser), resID, "New", nil)
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	})
+}
+
+func TestResourceService_ValidateCredentials(t *testing.T) {
+	ctx := principalContext(domain.UserRoleAdmin)
+
+	t.Run("Valid", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), new(MockAuditService), testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetType", ctx, "postgres-db").Return(postgresType(), nil)
+
+		err := service.ValidateCredentials(ctx, "postgres-db", json.RawMessage(`{"username":"app","password":"s3cret"}`))
+		assert.NoError(t, err)
+	})
+
+	t.Run("Missing Password", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), new(MockAuditService), testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetType", ctx, "postgres-db").Return(postgresType(), nil)
+
+		err := service.ValidateCredentials(ctx, "postgres-db", json.RawMessage(`{"username":"app"}`))
+		assert.ErrorIs(t, err, ErrInvalidResourceCredentials)
+		assert.EqualError(t, err, "invalid resource credentials: /password: is required")
+	})
+}
+
+func TestResourceService_SetSecret(t *testing.T) {
+	ctx := principalContext(domain.UserRoleManager)
+	res := &domain.Resource{ID: uuid.New(), OrganizationID: uuid.New(), TypeID: "postgres-db"}
+	credentials := json.RawMessage(`{"username":"app","password":"s3cret"}`)
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		mockAudit := new(MockAuditService)
+		txm := new(MockTxManager)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), txm, mockAudit, testCipher(), connector.NewRegistry())
+
+		var saved *domain.ResourceSecret
+		mockRepo.On("GetByID", ctx, res.ID).Return(res, nil)
+		mockRepo.On("GetType", ctx, "postgres-db").Return(postgresType(), nil)
+		mockRepo.On("SaveSecret", ctx, mock.AnythingOfType("*domain.ResourceSecret")).
+			Run(func(args mock.Arguments) { saved = args.Get(1).(*domain.ResourceSecret) }).
+			Return(nil)
+		mockAudit.On("LogAction", ctx, res.OrganizationID, (*uuid.UUID)(nil), "resource_secret", res.ID, domain.AuditActionCreate,
+			json.RawMessage(`{"after":{"fields":["password","username"],"key_version_id":"v1"}}`), "").Return(nil)
+
+		meta, err := service.SetSecret(ctx, res.ID, credentials)
+		assert.NoError(t, err)
+		assert.Equal(t, "v1", meta.KeyVersionID)
+		assert.Equal(t, 1, txm.calls)
+		if assert.NotNil(t, saved) {
+			assert.NotContains(t, saved.EncryptedCredentials, "s3cret")
+			plaintext, err := testCipher().Open(ctx, saved.EncryptedCredentials, saved.KeyVersionID, res.ID[:])
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(credentials), string(plaintext))
+		}
+		mockRepo.AssertExpectations(t)
+		mockAudit.AssertExpectations(t)
+	})
+
+	t.Run("Invalid Credentials", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), new(MockAuditService), testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetByID", ctx, res.ID).Return(res, nil)
+		mockRepo.On("GetType", ctx, "postgres-db").Return(postgresType(), nil)
+
+		_, err := service.SetSecret(ctx, res.ID, json.RawMessage(`{"username":"app"}`))
+		assert.ErrorIs(t, err, ErrInvalidResourceCredentials)
+		mockRepo.AssertNotCalled(t, "SaveSecret", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Forbidden for users", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), new(MockAuditService), testCipher(), connector.NewRegistry())
+
+		_, err := service.SetSecret(principalContext(domain.UserRoleUser), res.ID, credentials)
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+	})
+}
+
+func TestResourceService_RevealSecret(t *testing.T) {
+	ctx := principalContext(domain.UserRoleAdmin)
+	res := &domain.Resource{ID: uuid.New(), OrganizationID: uuid.New(), TypeID: "postgres-db"}
+	credentials := `{"username":"app","password":"s3cret"}`
+
+	sealed, version, err := testCipher().Seal(ctx, []byte(credentials), res.ID[:])
+	assert.NoError(t, err)
+	stored := &domain.ResourceSecret{ResourceID: res.ID, EncryptedCredentials: sealed, KeyVersionID: version}
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		mockAudit := new(MockAuditService)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), mockAudit, testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetByID", ctx, res.ID).Return(res, nil)
+		mockRepo.On("GetSecret", ctx, res.ID).Return(stored, nil)
+		mockAudit.On("LogAction", ctx, res.OrganizationID, (*uuid.UUID)(nil), "resource_secret", res.ID, domain.AuditActionReadSecret,
+			json.RawMessage(`{"after":{"fields":["password","username"],"key_version_id":"v1"}}`), "").Return(nil)
+
+		revealed, err := service.RevealSecret(ctx, res.ID)
+		assert.NoError(t, err)
+		assert.JSONEq(t, credentials, string(revealed.Credentials))
+		mockAudit.AssertExpectations(t)
+	})
+
+	t.Run("Audit Failure Withholds The Secret", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		mockAudit := new(MockAuditService)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), mockAudit, testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetByID", ctx, res.ID).Return(res, nil)
+		mockRepo.On("GetSecret", ctx, res.ID).Return(stored, nil)
+		mockAudit.On("LogAction", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(errors.New("audit down"))
+
+		revealed, err := service.RevealSecret(ctx, res.ID)
+		assert.Error(t, err)
+		assert.Nil(t, revealed)
+	})
+
+	t.Run("No Credentials", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), new(MockAuditService), testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetByID", ctx, res.ID).Return(res, nil)
+		mockRepo.On("GetSecret", ctx, res.ID).Return(nil, nil)
+
+		_, err := service.RevealSecret(ctx, res.ID)
+		assert.ErrorIs(t, err, ErrResourceSecretNotFound)
+	})
+
+	t.Run("Forbidden for managers", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), new(MockAuditService), testCipher(), connector.NewRegistry())
+
+		_, err := service.RevealSecret(principalContext(domain.UserRoleManager), res.ID)
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "GetSecret", mock.Anything, mock.Anything)
+	})
+}
+
+func (m *MockResourceRepository) GetAccess(ctx context.Context, resourceID, agentID uuid.UUID) (*domain.AgentResourceAccess, error) {
+	args := m.Called(ctx, resourceID, agentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AgentResourceAccess), args.Error(1)
+}
+
+func (m *MockResourceRepository) CreateAccess(ctx context.Context, access *domain.AgentResourceAccess) (bool, error) {
+	args := m.Called(ctx, access)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockResourceRepository) UpdateAccessPermission(ctx context.Context, id uuid.UUID, from, to domain.AccessLevel) (bool, error) {
+	args := m.Called(ctx, id, from, to)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockResourceRepository) DeleteAccess(ctx context.Context, id uuid.UUID) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+// stubDriver is a connector.Driver that records what it was given.
+type stubDriver struct {
+	err         error
+	config      json.RawMessage
+	credentials json.RawMessage
+}
+
+func (d *stubDriver) TestConnection(ctx context.Context, config, credentials json.RawMessage) error {
+	d.config, d.credentials = config, credentials
+	return d.err
+}
+
+func TestResourceService_TestConnection(t *testing.T) {
+	ctx := principalContext(domain.UserRoleAdmin)
+	manager := principalContext(domain.UserRoleManager)
+	res := &domain.Resource{ID: uuid.New(), OrganizationID: uuid.New(), TypeID: "postgres-db",
+		ConnectionDetails: json.RawMessage(`{"host":"db"}`)}
+	credentials := `{"username":"app","password":"s3cret"}`
+	sealed, version, err := testCipher().Seal(ctx, []byte(credentials), res.ID[:])
+	assert.NoError(t, err)
+	stored := &domain.ResourceSecret{ResourceID: res.ID, EncryptedCredentials: sealed, KeyVersionID: version}
+
+	registry := func(d connector.Driver) *connector.Registry {
+		r := connector.NewRegistry()
+		r.Register("postgres-db", d)
+		return r
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		driver := &stubDriver{}
+		mockAudit := new(MockAuditService)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), mockAudit, testCipher(), registry(driver))
+
+		var recorded json.RawMessage
+		mockRepo.On("GetByID", ctx, res.ID).Return(res, nil)
+		mockRepo.On("GetSecret", ctx, res.ID).Return(stored, nil)
+		mockAudit.On("LogAction", ctx, res.OrganizationID, (*uuid.UUID)(nil), "resource_secret", res.ID, domain.AuditActionReadSecret,
+			json.RawMessage(`{"after":{"fields":["password","username"],"key_version_id":"`+version+`"}}`), "").Return(nil)
+		mockAudit.On("LogAction", ctx, res.OrganizationID, (*uuid.UUID)(nil), "resource", res.ID, domain.AuditActionTestConnection,
+			json.RawMessage(`{"after":{"connection_details":{"host":"db"},"error_code":"","success":true}}`), "").Return(nil)
+		mockRepo.On("RecordConnectionTest", ctx, res.ID, mock.Anything).
+			Run(func(args mock.Arguments) { recorded = args.Get(2).(json.RawMessage) }).Return(nil)
+
+		result, err := service.TestConnection(ctx, res.ID)
+		assert.NoError(t, err)
+		assert.True(t, result.Success)
+		assert.Empty(t, result.ErrorCode)
+		assert.JSONEq(t, `{"host":"db"}`, string(driver.config))
+		assert.JSONEq(t, credentials, string(driver.credentials))
+		mockAudit.AssertExpectations(t)
+
+		var stored domain.ConnectionTestResult
+		assert.NoError(t, json.Unmarshal(recorded, &stored))
+		assert.True(t, stored.Success)
+		assert.False(t, stored.TestedAt.IsZero())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Stored Credentials Need Secret Access", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		driver := &stubDriver{}
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), new(MockAuditService), testCipher(), registry(driver))
+
+		mockRepo.On("GetByID", manager, res.ID).Return(res, nil)
+		mockRepo.On("GetSecret", manager, res.ID).Return(stored, nil)
+
+		_, err := service.TestConnection(manager, res.ID)
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+		assert.Nil(t, driver.credentials)
+		mockRepo.AssertNotCalled(t, "RecordConnectionTest", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Not Sent Unless Audited", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		mockAudit := new(MockAuditService)
+		driver := &stubDriver{}
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), mockAudit, testCipher(), registry(driver))
+
+		mockRepo.On("GetByID", ctx, res.ID).Return(res, nil)
+		mockRepo.On("GetSecret", ctx, res.ID).Return(stored, nil)
+		mockAudit.On("LogAction", ctx, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(errors.New("audit down"))
+
+		_, err := service.TestConnection(ctx, res.ID)
+		assert.EqualError(t, err, "audit down")
+		assert.Nil(t, driver.credentials)
+	})
+
+	t.Run("Failure Is Recorded", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		driver := &stubDriver{err: &connector.Error{Code: connector.ErrorAuthFailed, Message: "authentication failed", Err: errors.New("password for app")}}
+		mockAudit := new(MockAuditService)
+		txManager := new(MockTxManager)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), txManager, mockAudit, testCipher(), registry(driver))
+
+		// Without stored credentials, managers may test.
+		var recorded json.RawMessage
+		mockRepo.On("GetByID", manager, res.ID).Return(res, nil)
+		mockRepo.On("GetSecret", manager, res.ID).Return(nil, nil)
+		mockRepo.On("RecordConnectionTest", manager, res.ID, mock.Anything).
+			Run(func(args mock.Arguments) { recorded = args.Get(2).(json.RawMessage) }).Return(nil)
+		mockAudit.On("LogAction", manager, res.OrganizationID, (*uuid.UUID)(nil), "resource", res.ID, domain.AuditActionTestConnection,
+			json.RawMessage(`{"after":{"connection_details":{"host":"db"},"error_code":"auth_failed","success":false}}`), "").Return(nil)
+
+		result, err := service.TestConnection(manager, res.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, txManager.calls)
+		mockAudit.AssertExpectations(t)
+		assert.False(t, result.Success)
+		assert.Equal(t, "auth_failed", result.ErrorCode)
+		assert.Equal(t, "authentication failed", result.ErrorMessage)
+		assert.Nil(t, driver.credentials)
+		assert.NotContains(t, string(recorded), "password for app")
+	})
+
+	t.Run("Unsupported Type", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), new(MockAuditService), testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetByID", ctx, res.ID).Return(res, nil)
+
+		_, err := service.TestConnection(ctx, res.ID)
+		assert.ErrorIs(t, err, ErrConnectionTestUnsupported)
+		mockRepo.AssertNotCalled(t, "RecordConnectionTest", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Forbidden for users", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), new(MockAuditService), testCipher(), registry(&stubDriver{}))
+
+		_, err := service.TestConnection(principalContext(domain.UserRoleUser), res.ID)
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+	})
+}
+
+func TestResourceService_GrantAccess(t *testing.T) {
+	admin := principalContext(domain.UserRoleAdmin)
+	manager := principalContext(domain.UserRoleManager)
+	res := &domain.Resource{ID: uuid.New(), OrganizationID: uuid.New(), TypeID: "postgres-db"}
+	agent := &domain.Agent{ID: uuid.New(), OrganizationID: res.OrganizationID}
+
+	t.Run("Read Only By Manager", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		mockAgentRepo := new(MockAgentRepository)
+		mockAudit := new(MockAuditService)
+		txm := new(MockTxManager)
+		service := NewResourceService(mockRepo, mockAgentRepo, txm, mockAudit, testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetByID", manager, res.ID).Return(res, nil)
+		mockAgentRepo.On("GetByID", manager, agent.ID).Return(agent, nil)
+		mockRepo.On("GetAccess", manager, res.ID, agent.ID).Return(nil, nil)
+		mockRepo.On("CreateAccess", manager, mock.MatchedBy(func(a *domain.AgentResourceAccess) bool {
+			return a.AgentID == agent.ID && a.ResourceID == res.ID && a.Permission == domain.AccessLevelReadOnly
+		})).Return(true, nil)
+		mockAudit.On("LogAction", manager, res.OrganizationID, (*uuid.UUID)(nil), "agent_resource_access", mock.Anything, domain.AuditActionCreate,
+			json.RawMessage(`{"after":{"agent_id":"`+agent.ID.String()+`","permission":"read_only","resource_id":"`+res.ID.String()+`"}}`), "").Return(nil)
+
+		access, err := service.GrantAccess(manager, res.ID, agent.ID, domain.AccessLevelReadOnly)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.AccessLevelReadOnly, access.Permission)
+		assert.Equal(t, 1, txm.calls)
+		mockRepo.AssertExpectations(t)
+		mockAudit.AssertExpectations(t)
+	})
+
+	t.Run("Read Write Requires Admin", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), new(MockAuditService), testCipher(), connector.NewRegistry())
+
+		_, err := service.GrantAccess(manager, res.ID, agent.ID, domain.AccessLevelReadWrite)
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "CreateAccess", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Read Write By Admin", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		mockAgentRepo := new(MockAgentRepository)
+		mockAudit := new(MockAuditService)
+		service := NewResourceService(mockRepo, mockAgentRepo, new(MockTxManager), mockAudit, testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetByID", admin, res.ID).Return(res, nil)
+		mockAgentRepo.On("GetByID", admin, agent.ID).Return(agent, nil)
+		mockRepo.On("GetAccess", admin, res.ID, agent.ID).Return(nil, nil)
+		mockRepo.On("CreateAccess", admin, mock.Anything).Return(true, nil)
+		mockAudit.On("LogAction", admin, mock.Anything, mock.Anything, mock.Anything, mock.Anything, domain.AuditActionCreate, mock.Anything, "").Return(nil)
+
+		access, err := service.GrantAccess(admin, res.ID, agent.ID, domain.AccessLevelReadWrite)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.AccessLevelReadWrite, access.Permission)
+	})
+
+	t.Run("Already Granted", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		mockAgentRepo := new(MockAgentRepository)
+		service := NewResourceService(mockRepo, mockAgentRepo, new(MockTxManager), new(MockAuditService), testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetByID", manager, res.ID).Return(res, nil)
+		mockAgentRepo.On("GetByID", manager, agent.ID).Return(agent, nil)
+		mockRepo.On("GetAccess", manager, res.ID, agent.ID).Return(&domain.AgentResourceAccess{ID: uuid.New()}, nil)
+
+		_, err := service.GrantAccess(manager, res.ID, agent.ID, domain.AccessLevelReadOnly)
+		assert.ErrorIs(t, err, ErrAccessAlreadyGranted)
+	})
+
+	t.Run("Granted Concurrently", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		mockAgentRepo := new(MockAgentRepository)
+		mockAudit := new(MockAuditService)
+		service := NewResourceService(mockRepo, mockAgentRepo, new(MockTxManager), mockAudit, testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetByID", manager, res.ID).Return(res, nil)
+		mockAgentRepo.On("GetByID", manager, agent.ID).Return(agent, nil)
+		mockRepo.On("GetAccess", manager, res.ID, agent.ID).Return(nil, nil)
+		mockRepo.On("CreateAccess", manager, mock.Anything).Return(false, nil)
+
+		_, err := service.GrantAccess(manager, res.ID, agent.ID, domain.AccessLevelReadOnly)
+		assert.ErrorIs(t, err, ErrAccessAlreadyGranted)
+		mockAudit.AssertNotCalled(t, "LogAction", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown Agent", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		mockAgentRepo := new(MockAgentRepository)
+		service := NewResourceService(mockRepo, mockAgentRepo, new(MockTxManager), new(MockAuditService), testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetByID", manager, res.ID).Return(res, nil)
+		mockAgentRepo.On("GetByID", manager, agent.ID).Return(nil, nil)
+
+		_, err := service.GrantAccess(manager, res.ID, agent.ID, domain.AccessLevelReadOnly)
+		assert.ErrorIs(t, err, ErrAgentNotFound)
+	})
+
+	t.Run("Invalid Level", func(t *testing.T) {
+		service := NewResourceService(new(MockResourceRepository), new(MockAgentRepository), new(MockTxManager), new(MockAuditService), testCipher(), connector.NewRegistry())
+
+		_, err := service.GrantAccess(admin, res.ID, agent.ID, domain.AccessLevel("owner"))
+		assert.ErrorIs(t, err, ErrInvalidAccessLevel)
+	})
+}
+
+func TestResourceService_ChangeAccess(t *testing.T) {
+	admin := principalContext(domain.UserRoleAdmin)
+	manager := principalContext(domain.UserRoleManager)
+	res := &domain.Resource{ID: uuid.New(), OrganizationID: uuid.New()}
+	agentID := uuid.New()
+	grant := func(level domain.AccessLevel) *domain.AgentResourceAccess {
+		return &domain.AgentResourceAccess{ID: uuid.New(), AgentID: agentID, ResourceID: res.ID, Permission: level}
+	}
+
+	t.Run("Upgrade By Admin", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		mockAudit := new(MockAuditService)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), mockAudit, testCipher(), connector.NewRegistry())
+		access := grant(domain.AccessLevelReadOnly)
+
+		mockRepo.On("GetByID", admin, res.ID).Return(res, nil)
+		mockRepo.On("GetAccess", admin, res.ID, agentID).Return(access, nil)
+		mockRepo.On("UpdateAccessPermission", admin, access.ID, domain.AccessLevelReadOnly, domain.AccessLevelReadWrite).Return(true, nil)
+		mockAudit.On("LogAction", admin, res.OrganizationID, (*uuid.UUID)(nil), "agent_resource_access", access.ID, domain.AuditActionUpdate,
+			json.RawMessage(`{"after":{"permission":"read_write"},"before":{"permission":"read_only"}}`), "").Return(nil)
+
+		updated, err := service.ChangeAccess(admin, res.ID, agentID, domain.AccessLevelReadWrite)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.AccessLevelReadWrite, updated.Permission)
+		mockAudit.AssertExpectations(t)
+	})
+
+	t.Run("Upgrade Forbidden For Managers", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), new(MockAuditService), testCipher(), connector.NewRegistry())
+
+		_, err := service.ChangeAccess(manager, res.ID, agentID, domain.AccessLevelReadWrite)
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "UpdateAccessPermission", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Downgrade By Manager", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		mockAudit := new(MockAuditService)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), mockAudit, testCipher(), connector.NewRegistry())
+		access := grant(domain.AccessLevelReadWrite)
+
+		mockRepo.On("GetByID", manager, res.ID).Return(res, nil)
+		mockRepo.On("GetAccess", manager, res.ID, agentID).Return(access, nil)
+		mockRepo.On("UpdateAccessPermission", manager, access.ID, domain.AccessLevelReadWrite, domain.AccessLevelReadOnly).Return(true, nil)
+		mockAudit.On("LogAction", manager, mock.Anything, mock.Anything, mock.Anything, mock.Anything, domain.AuditActionUpdate, mock.Anything, "").Return(nil)
+
+		updated, err := service.ChangeAccess(manager, res.ID, agentID, domain.AccessLevelReadOnly)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.AccessLevelReadOnly, updated.Permission)
+	})
+
+	t.Run("Unchanged Level Is A No-op", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		txm := new(MockTxManager)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), txm, new(MockAuditService), testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetByID", manager, res.ID).Return(res, nil)
+		mockRepo.On("GetAccess", manager, res.ID, agentID).Return(grant(domain.AccessLevelReadOnly), nil)
+
+		_, err := service.ChangeAccess(manager, res.ID, agentID, domain.AccessLevelReadOnly)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, txm.calls)
+	})
+
+	t.Run("Changed Concurrently", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), new(MockAuditService), testCipher(), connector.NewRegistry())
+		access := grant(domain.AccessLevelReadWrite)
+
+		mockRepo.On("GetByID", manager, res.ID).Return(res, nil)
+		mockRepo.On("GetAccess", manager, res.ID, agentID).Return(access, nil)
+		mockRepo.On("UpdateAccessPermission", manager, access.ID, domain.AccessLevelReadWrite, domain.AccessLevelReadOnly).Return(false, nil)
+
+		_, err := service.ChangeAccess(manager, res.ID, agentID, domain.AccessLevelReadOnly)
+		assert.ErrorIs(t, err, ErrAccessChanged)
+	})
+
+	t.Run("Not Granted", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), new(MockAuditService), testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetByID", manager, res.ID).Return(res, nil)
+		mockRepo.On("GetAccess", manager, res.ID, agentID).Return(nil, nil)
+
+		_, err := service.ChangeAccess(manager, res.ID, agentID, domain.AccessLevelReadOnly)
+		assert.ErrorIs(t, err, ErrAccessNotGranted)
+	})
+}
+
+func TestResourceService_RevokeAccess(t *testing.T) {
+	manager := principalContext(domain.UserRoleManager)
+	res := &domain.Resource{ID: uuid.New(), OrganizationID: uuid.New()}
+	access := &domain.AgentResourceAccess{ID: uuid.New(), AgentID: uuid.New(), ResourceID: res.ID, Permission: domain.AccessLevelReadWrite}
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		mockAudit := new(MockAuditService)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), mockAudit, testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetByID", manager, res.ID).Return(res, nil)
+		mockRepo.On("GetAccess", manager, res.ID, access.AgentID).Return(access, nil)
+		mockRepo.On("DeleteAccess", manager, access.ID).Return(true, nil)
+		mockAudit.On("LogAction", manager, res.OrganizationID, (*uuid.UUID)(nil), "agent_resource_access", access.ID, domain.AuditActionDelete,
+			json.RawMessage(`{"before":{"agent_id":"`+access.AgentID.String()+`","permission":"read_write","resource_id":"`+res.ID.String()+`"}}`), "").Return(nil)
+
+		assert.NoError(t, service.RevokeAccess(manager, res.ID, access.AgentID))
+		mockRepo.AssertExpectations(t)
+		mockAudit.AssertExpectations(t)
+	})
+
+	t.Run("Forbidden for users", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), new(MockAuditService), testCipher(), connector.NewRegistry())
+
+		err := service.RevokeAccess(principalContext(domain.UserRoleUser), res.ID, access.AgentID)
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "DeleteAccess", mock.Anything, mock.Anything)
+	})
+}
+
+func TestResourceService_ListResources(t *testing.T) {
+	orgID := uuid.New()
+
+	t.Run("By Type", func(t *testing.T) {
+		ctx := principalContext(domain.UserRoleUser)
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), new(MockAuditService), testCipher(), connector.NewRegistry())
+		filter := domain.ResourceFilter{TypeID: "postgres-db"}
+
+		mockRepo.On("ListByOrg", ctx, orgID, filter).Return([]domain.Resource{{Name: "DB"}}, nil)
+
+		resources, err := service.ListResources(ctx, orgID, filter)
+		assert.NoError(t, err)
+		assert.Len(t, resources, 1)
+	})
+
+	t.Run("Deleted Forbidden For Managers", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), new(MockAuditService), testCipher(), connector.NewRegistry())
+
+		_, err := service.ListResources(principalContext(domain.UserRoleManager), orgID, domain.ResourceFilter{Deleted: true})
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "ListByOrg", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestResourceService_DeleteResource(t *testing.T) {
+	ctx := principalContext(domain.UserRoleAdmin)
+	res := &domain.Resource{ID: uuid.New(), OrganizationID: uuid.New(), TypeID: "postgres-db", Name: "DB"}
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		mockAudit := new(MockAuditService)
+		txm := new(MockTxManager)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), txm, mockAudit, testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetByID", ctx, res.ID).Return(res, nil)
+		mockRepo.On("Delete", ctx, res.ID).Return(nil)
+		mockAudit.On("LogAction", ctx, res.OrganizationID, (*uuid.UUID)(nil), "resource", res.ID, domain.AuditActionDelete,
+			json.RawMessage(`{"before":{"connection_details":null,"name":"DB","type_id":"postgres-db"}}`), "").Return(nil)
+
+		assert.NoError(t, service.DeleteResource(ctx, res.ID))
+		assert.Equal(t, 1, txm.calls)
+		mockRepo.AssertExpectations(t)
+		mockAudit.AssertExpectations(t)
+	})
+
+	t.Run("Forbidden for managers", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), new(MockAuditService), testCipher(), connector.NewRegistry())
+
+		err := service.DeleteResource(principalContext(domain.UserRoleManager), res.ID)
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), new(MockAuditService), testCipher(), connector.NewRegistry())
+
+		mockRepo.On("GetByID", ctx, res.ID).Return(nil, gorm.ErrRecordNotFound)
+
+		assert.ErrorIs(t, service.DeleteResource(ctx, res.ID), ErrResourceNotFound)
+	})
+}
+
+func TestResourceService_RestoreResource(t *testing.T) {
+	ctx := principalContext(domain.UserRoleAdmin)
+	res := &domain.Resource{ID: uuid.New(), OrganizationID: uuid.New(), TypeID: "postgres-db", Name: "DB"}
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		mockAudit := new(MockAuditService)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), mockAudit, testCipher(), connector.NewRegistry())
+
+		mockRepo.On("Restore", ctx, res.ID).Return(true, nil)
+		mockRepo.On("GetByID", ctx, res.ID).Return(res, nil)
+		mockAudit.On("LogAction", ctx, res.OrganizationID, (*uuid.UUID)(nil), "resource", res.ID, domain.AuditActionRestore,
+			json.RawMessage(`{"after":{"connection_details":null,"name":"DB","type_id":"postgres-db"}}`), "").Return(nil)
+
+		restored, err := service.RestoreResource(ctx, res.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, res, restored)
+		mockAudit.AssertExpectations(t)
+	})
+
+	t.Run("Not Deleted", func(t *testing.T) {
+		mockRepo := new(MockResourceRepository)
+		service := NewResourceService(mockRepo, new(MockAgentRepository), new(MockTxManager), new(MockAuditService), testCipher(), connector.NewRegistry())
+
+		mockRepo.On("Restore", ctx, res.ID).Return(false, nil)
+
+		_, err := service.RestoreResource(ctx, res.ID)
+		assert.ErrorIs(t, err, ErrResourceNotFound)
+	})
 }
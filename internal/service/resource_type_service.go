@@ -0,0 +1,399 @@
+package service
+
+import (
+	"agentXmap/internal/domain"
+	"agentXmap/internal/policy"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidResourceTypeID    = errors.New("resource type id must be 2-50 lowercase letters, digits or underscores, starting with a letter")
+	ErrResourceTypeNameRequired = errors.New("resource type name is required")
+	ErrResourceTypeExists       = errors.New("resource type already exists")
+	ErrBuiltinResourceType      = errors.New("built-in resource types are managed by the catalog")
+	ErrResourceTypeChanged      = errors.New("resource type was changed concurrently")
+)
+
+// resourceTypeIDPattern mirrors resource_types.id VARCHAR(50).
+var resourceTypeIDPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+const auditEntityResourceType = "resource_type"
+
+// resourceTypeAuditNamespace derives the SystemAuditLog entity ID of a
+// ResourceType, whose own ID is a name rather than a UUID.
+var resourceTypeAuditNamespace = uuid.MustParse("0f6f4f0e-6a0b-4d1c-9a57-3c2f1e0b7d21")
+
+// ResourceTypeInput carries the editable fields of a ResourceType. A nil schema
+// means "accept any document" on create and "keep the current one" on update.
+type ResourceTypeInput struct {
+	Name         string
+	ConfigSchema json.RawMessage
+	SecretSchema json.RawMessage
+}
+
+// CatalogSyncReport lists what SyncBuiltinTypes did to each built-in type.
+type CatalogSyncReport struct {
+	Created   []string `json:"created"`
+	Updated   []string `json:"updated"`
+	Unchanged []string `json:"unchanged"`
+}
+
+// CatalogConfig names who may change the ResourceType catalog.
+type CatalogConfig struct {
+	// OperatorOrganizationID is the organization running the platform. The
+	// catalog is shared by every organization, so only the admins of this one
+	// may change it; when unset, it only changes through SyncBuiltinTypes.
+	OperatorOrganizationID uuid.UUID
+}
+
+// ResourceTypeService manages the ResourceType catalog shared by every organization.
+type ResourceTypeService interface {
+	ListResourceTypes(ctx context.Context, includeInactive bool) ([]domain.ResourceType, error)
+	GetResourceType(ctx context.Context, id string) (*domain.ResourceType, error)
+	// CreateResourceType adds an active custom type at schema version 1.
+	CreateResourceType(ctx context.Context, id string, userID uuid.UUID, input ResourceTypeInput) (*domain.ResourceType, error)
+	// UpdateResourceType renames a custom type and replaces its schemas. A schema
+	// change bumps SchemaVersion and is kept in the version history; existing
+	// resources are validated against the new schemas on their next update.
+	UpdateResourceType(ctx context.Context, id string, userID uuid.UUID, input ResourceTypeInput) (*domain.ResourceType, error)
+	// SetResourceTypeActive deactivates or reactivates a type. Inactive types
+	// accept no new resources; existing ones keep working.
+	SetResourceTypeActive(ctx context.Context, id string, active bool) (*domain.ResourceType, error)
+	ListSchemaVersions(ctx context.Context, id string) ([]domain.ResourceTypeSchemaVersion, error)
+	// SyncBuiltinTypes reconciles the resource_types table with the built-in
+	// catalog. It runs at startup, outside any Principal, and never changes
+	// whether a type is active.
+	SyncBuiltinTypes(ctx context.Context, builtins []domain.ResourceType) (*CatalogSyncReport, error)
+}
+
+type DefaultResourceTypeService struct {
+	typeRepo     domain.ResourceTypeRepository
+	txManager    domain.TxManager
+	auditService AuditService
+	config       CatalogConfig
+}
+
+func NewResourceTypeService(typeRepo domain.ResourceTypeRepository, txManager domain.TxManager, auditService AuditService, config CatalogConfig) *DefaultResourceTypeService {
+	return &DefaultResourceTypeService{typeRepo: typeRepo, txManager: txManager, auditService: auditService, config: config}
+}
+
+func (s *DefaultResourceTypeService) ListResourceTypes(ctx context.Context, includeInactive bool) ([]domain.ResourceType, error) {
+	if err := policy.Authorize(ctx, policy.ResourceResourceType, policy.ActionRead); err != nil {
+		return nil, err
+	}
+	return s.typeRepo.List(ctx, includeInactive)
+}
+
+func (s *DefaultResourceTypeService) GetResourceType(ctx context.Context, id string) (*domain.ResourceType, error) {
+	if err := policy.Authorize(ctx, policy.ResourceResourceType, policy.ActionRead); err != nil {
+		return nil, err
+	}
+	return s.getType(ctx, id)
+}
+
+func (s *DefaultResourceTypeService) CreateResourceType(ctx context.Context, id string, userID uuid.UUID, input ResourceTypeInput) (*domain.ResourceType, error) {
+	if err := s.authorizeChange(ctx, policy.ActionCreate); err != nil {
+		return nil, err
+	}
+	if !resourceTypeIDPattern.MatchString(id) {
+		return nil, ErrInvalidResourceTypeID
+	}
+	if input.Name == "" {
+		return nil, ErrResourceTypeNameRequired
+	}
+	configSchema, secretSchema, err := checkSchemas(orEmptySchema(input.ConfigSchema), orEmptySchema(input.SecretSchema))
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.typeRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrResourceTypeExists
+	}
+
+	rt := &domain.ResourceType{
+		ID:            id,
+		Name:          input.Name,
+		ConfigSchema:  configSchema,
+		SecretSchema:  secretSchema,
+		SchemaVersion: 1,
+		IsActive:      true,
+	}
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.typeRepo.Create(ctx, rt); err != nil {
+			return err
+		}
+		if err := s.typeRepo.CreateSchemaVersion(ctx, schemaVersionOf(rt, &userID)); err != nil {
+			return err
+		}
+		return s.logTypeMutation(ctx, rt, domain.AuditActionCreate, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rt, nil
+}
+
+func (s *DefaultResourceTypeService) UpdateResourceType(ctx context.Context, id string, userID uuid.UUID, input ResourceTypeInput) (*domain.ResourceType, error) {
+	if err := s.authorizeChange(ctx, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+	if input.Name == "" {
+		return nil, ErrResourceTypeNameRequired
+	}
+	rt, err := s.getType(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rt.IsBuiltin {
+		return nil, ErrBuiltinResourceType
+	}
+
+	configSchema, secretSchema := rt.ConfigSchema, rt.SecretSchema
+	if input.ConfigSchema != nil {
+		configSchema = input.ConfigSchema
+	}
+	if input.SecretSchema != nil {
+		secretSchema = input.SecretSchema
+	}
+	if configSchema, secretSchema, err = checkSchemas(configSchema, secretSchema); err != nil {
+		return nil, err
+	}
+
+	before := resourceTypeSnapshot(rt)
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.apply(ctx, rt, input.Name, configSchema, secretSchema, &userID); err != nil {
+			return err
+		}
+		return s.logTypeMutation(ctx, rt, domain.AuditActionUpdate, before)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rt, nil
+}
+
+func (s *DefaultResourceTypeService) SetResourceTypeActive(ctx context.Context, id string, active bool) (*domain.ResourceType, error) {
+	if err := s.authorizeChange(ctx, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+	rt, err := s.getType(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rt.IsActive == active {
+		return rt, nil
+	}
+
+	before := resourceTypeSnapshot(rt)
+	rt.IsActive = active
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		ok, err := s.typeRepo.Update(ctx, rt, rt.SchemaVersion)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrResourceTypeChanged
+		}
+		return s.logTypeMutation(ctx, rt, domain.AuditActionUpdate, before)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rt, nil
+}
+
+func (s *DefaultResourceTypeService) ListSchemaVersions(ctx context.Context, id string) ([]domain.ResourceTypeSchemaVersion, error) {
+	if err := policy.Authorize(ctx, policy.ResourceResourceType, policy.ActionRead); err != nil {
+		return nil, err
+	}
+	if _, err := s.getType(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.typeRepo.ListSchemaVersions(ctx, id)
+}
+
+func (s *DefaultResourceTypeService) SyncBuiltinTypes(ctx context.Context, builtins []domain.ResourceType) (*CatalogSyncReport, error) {
+	report := &CatalogSyncReport{}
+	err := s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		for i := range builtins {
+			builtin := builtins[i]
+			configSchema, secretSchema, err := checkSchemas(orEmptySchema(builtin.ConfigSchema), orEmptySchema(builtin.SecretSchema))
+			if err != nil {
+				return fmt.Errorf("built-in type %s: %w", builtin.ID, err)
+			}
+
+			current, err := s.typeRepo.GetByID(ctx, builtin.ID)
+			if err != nil {
+				return err
+			}
+			if current == nil {
+				rt := &domain.ResourceType{
+					ID:            builtin.ID,
+					Name:          builtin.Name,
+					ConfigSchema:  configSchema,
+					SecretSchema:  secretSchema,
+					SchemaVersion: 1,
+					IsActive:      true,
+					IsBuiltin:     true,
+				}
+				if err := s.typeRepo.Create(ctx, rt); err != nil {
+					return err
+				}
+				if err := s.typeRepo.CreateSchemaVersion(ctx, schemaVersionOf(rt, nil)); err != nil {
+					return err
+				}
+				report.Created = append(report.Created, rt.ID)
+				continue
+			}
+
+			if current.IsBuiltin && current.Name == builtin.Name &&
+				sameJSON(current.ConfigSchema, configSchema) && sameJSON(current.SecretSchema, secretSchema) {
+				report.Unchanged = append(report.Unchanged, current.ID)
+				continue
+			}
+			// A row with a built-in ID, e.g. from an older seed, is adopted by the catalog.
+			current.IsBuiltin = true
+			if err := s.apply(ctx, current, builtin.Name, configSchema, secretSchema, nil); err != nil {
+				return err
+			}
+			report.Updated = append(report.Updated, current.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// apply writes a new name and schemas to rt, bumping its schema version and
+// recording the new schemas when they changed.
+func (s *DefaultResourceTypeService) apply(ctx context.Context, rt *domain.ResourceType, name string, configSchema, secretSchema json.RawMessage, userID *uuid.UUID) error {
+	expected := rt.SchemaVersion
+	schemaChanged := !sameJSON(rt.ConfigSchema, configSchema) || !sameJSON(rt.SecretSchema, secretSchema)
+	rt.Name = name
+	if schemaChanged {
+		rt.ConfigSchema, rt.SecretSchema = configSchema, secretSchema
+		rt.SchemaVersion++
+	}
+
+	return s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		ok, err := s.typeRepo.Update(ctx, rt, expected)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrResourceTypeChanged
+		}
+		if !schemaChanged {
+			return nil
+		}
+		return s.typeRepo.CreateSchemaVersion(ctx, schemaVersionOf(rt, userID))
+	})
+}
+
+// authorizeChange checks that the caller may change the catalog: the policy
+// allows admins, and only those of the operator organization act for the platform.
+func (s *DefaultResourceTypeService) authorizeChange(ctx context.Context, action policy.Action) error {
+	if err := policy.Authorize(ctx, policy.ResourceResourceType, action); err != nil {
+		return err
+	}
+	p, _ := domain.PrincipalFromContext(ctx)
+	if s.config.OperatorOrganizationID == uuid.Nil || p.OrganizationID != s.config.OperatorOrganizationID {
+		return policy.ErrForbidden
+	}
+	return nil
+}
+
+// logTypeMutation audits a change to the catalog in the operator organization,
+// the organization of every caller allowed to make one. SyncBuiltinTypes runs
+// without a caller and is reported by its CatalogSyncReport instead.
+func (s *DefaultResourceTypeService) logTypeMutation(ctx context.Context, rt *domain.ResourceType, action domain.AuditAction, before auditSnapshot) error {
+	entityID := uuid.NewSHA1(resourceTypeAuditNamespace, []byte(rt.ID))
+	return logMutation(ctx, s.auditService, s.config.OperatorOrganizationID, auditEntityResourceType, entityID, action, before, resourceTypeSnapshot(rt))
+}
+
+// resourceTypeSnapshot is the audited state of a ResourceType. The schemas are
+// tracked by their version, whose history keeps the documents themselves.
+func resourceTypeSnapshot(rt *domain.ResourceType) auditSnapshot {
+	return auditSnapshot{
+		"id":             rt.ID,
+		"name":           rt.Name,
+		"schema_version": rt.SchemaVersion,
+		"is_active":      rt.IsActive,
+		"is_builtin":     rt.IsBuiltin,
+	}
+}
+
+func (s *DefaultResourceTypeService) getType(ctx context.Context, id string) (*domain.ResourceType, error) {
+	rt, err := s.typeRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rt == nil {
+		return nil, ErrResourceTypeNotFound
+	}
+	return rt, nil
+}
+
+func schemaVersionOf(rt *domain.ResourceType, userID *uuid.UUID) *domain.ResourceTypeSchemaVersion {
+	return &domain.ResourceTypeSchemaVersion{
+		TypeID:       rt.ID,
+		Version:      rt.SchemaVersion,
+		ConfigSchema: rt.ConfigSchema,
+		SecretSchema: rt.SecretSchema,
+		CreatedBy:    userID,
+	}
+}
+
+// checkSchemas compiles both schemas, returning them compacted.
+func checkSchemas(configSchema, secretSchema json.RawMessage) (json.RawMessage, json.RawMessage, error) {
+	config, err := compactSchema(configSchema, "config schema")
+	if err != nil {
+		return nil, nil, err
+	}
+	secret, err := compactSchema(secretSchema, "secret schema")
+	if err != nil {
+		return nil, nil, err
+	}
+	return config, secret, nil
+}
+
+func compactSchema(schema json.RawMessage, what string) (json.RawMessage, error) {
+	if _, err := compileSchema(schema); err != nil {
+		return nil, fmt.Errorf("%s: %w", what, err)
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, schema); err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", what, ErrInvalidSchema, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func orEmptySchema(schema json.RawMessage) json.RawMessage {
+	if len(schema) == 0 {
+		return json.RawMessage(`{}`)
+	}
+	return schema
+}
+
+// sameJSON compares two documents by value, since jsonb does not preserve key order or spacing.
+func sameJSON(a, b json.RawMessage) bool {
+	var va, vb any
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
@@ -0,0 +1,412 @@
+package service
+
+import (
+	"agentXmap/internal/catalog"
+	"agentXmap/internal/domain"
+	"agentXmap/internal/policy"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockResourceTypeRepository is a mock implementation of domain.ResourceTypeRepository
+type MockResourceTypeRepository struct {
+	mock.Mock
+}
+
+func (m *MockResourceTypeRepository) List(ctx context.Context, includeInactive bool) ([]domain.ResourceType, error) {
+	args := m.Called(ctx, includeInactive)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.ResourceType), args.Error(1)
+}
+
+func (m *MockResourceTypeRepository) GetByID(ctx context.Context, id string) (*domain.ResourceType, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ResourceType), args.Error(1)
+}
+
+func (m *MockResourceTypeRepository) Create(ctx context.Context, rt *domain.ResourceType) error {
+	args := m.Called(ctx, rt)
+	return args.Error(0)
+}
+
+func (m *MockResourceTypeRepository) Update(ctx context.Context, rt *domain.ResourceType, expectedVersion int) (bool, error) {
+	args := m.Called(ctx, rt, expectedVersion)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockResourceTypeRepository) CreateSchemaVersion(ctx context.Context, version *domain.ResourceTypeSchemaVersion) error {
+	args := m.Called(ctx, version)
+	return args.Error(0)
+}
+
+func (m *MockResourceTypeRepository) ListSchemaVersions(ctx context.Context, typeID string) ([]domain.ResourceTypeSchemaVersion, error) {
+	args := m.Called(ctx, typeID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.ResourceTypeSchemaVersion), args.Error(1)
+}
+
+func customType() *domain.ResourceType {
+	return &domain.ResourceType{
+		ID:            "ftp_server",
+		Name:          "FTP Server",
+		ConfigSchema:  json.RawMessage(`{"type":"object","properties":{"host":{"type":"string"}}}`),
+		SecretSchema:  json.RawMessage(`{}`),
+		SchemaVersion: 2,
+		IsActive:      true,
+	}
+}
+
+// operatorOrg is the organization whose admins may change the catalog in these tests.
+var operatorOrg = uuid.New()
+
+func newTypeService(typeRepo domain.ResourceTypeRepository, txManager domain.TxManager) *DefaultResourceTypeService {
+	return NewResourceTypeService(typeRepo, txManager, acceptingAudit(), CatalogConfig{OperatorOrganizationID: operatorOrg})
+}
+
+// operatorContext returns the context of an admin of operatorOrg.
+func operatorContext() context.Context {
+	return domain.WithPrincipal(context.Background(), &domain.Principal{
+		UserID:         uuid.New(),
+		OrganizationID: operatorOrg,
+		Role:           domain.UserRoleAdmin,
+	})
+}
+
+func TestResourceTypeService_CreateResourceType(t *testing.T) {
+	ctx := operatorContext()
+	userID := uuid.New()
+	input := ResourceTypeInput{Name: "FTP Server", ConfigSchema: json.RawMessage(`{"type": "object"}`)}
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockResourceTypeRepository)
+		txManager := new(MockTxManager)
+		service := newTypeService(mockRepo, txManager)
+
+		mockRepo.On("GetByID", ctx, "ftp_server").Return(nil, nil)
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*domain.ResourceType")).Return(nil)
+		mockRepo.On("CreateSchemaVersion", ctx, mock.MatchedBy(func(v *domain.ResourceTypeSchemaVersion) bool {
+			return v.TypeID == "ftp_server" && v.Version == 1 && *v.CreatedBy == userID
+		})).Return(nil)
+
+		rt, err := service.CreateResourceType(ctx, "ftp_server", userID, input)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, rt.SchemaVersion)
+		assert.True(t, rt.IsActive)
+		assert.False(t, rt.IsBuiltin)
+		assert.JSONEq(t, `{"type":"object"}`, string(rt.ConfigSchema))
+		assert.JSONEq(t, `{}`, string(rt.SecretSchema))
+		assert.Equal(t, 1, txManager.calls)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		service := newTypeService(new(MockResourceTypeRepository), new(MockTxManager))
+		for _, id := range []string{"", "f", "FTP", "1ftp", "ftp-server"} {
+			_, err := service.CreateResourceType(ctx, id, userID, input)
+			assert.ErrorIs(t, err, ErrInvalidResourceTypeID, id)
+		}
+	})
+
+	t.Run("Invalid Schema", func(t *testing.T) {
+		mockRepo := new(MockResourceTypeRepository)
+		service := newTypeService(mockRepo, new(MockTxManager))
+
+		_, err := service.CreateResourceType(ctx, "ftp_server", userID, ResourceTypeInput{
+			Name:         "FTP Server",
+			SecretSchema: json.RawMessage(`{"type": 12}`),
+		})
+		assert.ErrorIs(t, err, ErrInvalidSchema)
+		mockRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Already Exists", func(t *testing.T) {
+		mockRepo := new(MockResourceTypeRepository)
+		service := newTypeService(mockRepo, new(MockTxManager))
+
+		mockRepo.On("GetByID", ctx, "ftp_server").Return(customType(), nil)
+
+		_, err := service.CreateResourceType(ctx, "ftp_server", userID, input)
+		assert.ErrorIs(t, err, ErrResourceTypeExists)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Forbidden", func(t *testing.T) {
+		service := newTypeService(new(MockResourceTypeRepository), new(MockTxManager))
+
+		_, err := service.CreateResourceType(principalContext(domain.UserRoleManager), "ftp_server", userID, input)
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+	})
+
+	t.Run("Admin Of Another Organization", func(t *testing.T) {
+		mockRepo := new(MockResourceTypeRepository)
+		service := newTypeService(mockRepo, new(MockTxManager))
+
+		_, err := service.CreateResourceType(principalContext(domain.UserRoleAdmin), "ftp_server", userID, input)
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("No Operator Organization", func(t *testing.T) {
+		service := NewResourceTypeService(new(MockResourceTypeRepository), new(MockTxManager), acceptingAudit(), CatalogConfig{})
+
+		_, err := service.CreateResourceType(ctx, "ftp_server", userID, input)
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+	})
+}
+
+func TestResourceTypeService_UpdateResourceType(t *testing.T) {
+	ctx := operatorContext()
+	userID := uuid.New()
+
+	t.Run("Rename Keeps Version", func(t *testing.T) {
+		mockRepo := new(MockResourceTypeRepository)
+		service := newTypeService(mockRepo, new(MockTxManager))
+
+		mockRepo.On("GetByID", ctx, "ftp_server").Return(customType(), nil)
+		mockRepo.On("Update", ctx, mock.AnythingOfType("*domain.ResourceType"), 2).Return(true, nil)
+
+		rt, err := service.UpdateResourceType(ctx, "ftp_server", userID, ResourceTypeInput{Name: "SFTP Server"})
+		assert.NoError(t, err)
+		assert.Equal(t, "SFTP Server", rt.Name)
+		assert.Equal(t, 2, rt.SchemaVersion)
+		mockRepo.AssertNotCalled(t, "CreateSchemaVersion", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Reformatted Schema Keeps Version", func(t *testing.T) {
+		mockRepo := new(MockResourceTypeRepository)
+		service := newTypeService(mockRepo, new(MockTxManager))
+
+		mockRepo.On("GetByID", ctx, "ftp_server").Return(customType(), nil)
+		mockRepo.On("Update", ctx, mock.AnythingOfType("*domain.ResourceType"), 2).Return(true, nil)
+
+		rt, err := service.UpdateResourceType(ctx, "ftp_server", userID, ResourceTypeInput{
+			Name:         "FTP Server",
+			ConfigSchema: json.RawMessage(`{"properties": {"host": {"type": "string"}}, "type": "object"}`),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, rt.SchemaVersion)
+		mockRepo.AssertNotCalled(t, "CreateSchemaVersion", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Schema Change Bumps Version", func(t *testing.T) {
+		mockRepo := new(MockResourceTypeRepository)
+		service := newTypeService(mockRepo, new(MockTxManager))
+
+		mockRepo.On("GetByID", ctx, "ftp_server").Return(customType(), nil)
+		mockRepo.On("Update", ctx, mock.MatchedBy(func(rt *domain.ResourceType) bool {
+			return rt.SchemaVersion == 3
+		}), 2).Return(true, nil)
+		mockRepo.On("CreateSchemaVersion", ctx, mock.MatchedBy(func(v *domain.ResourceTypeSchemaVersion) bool {
+			return v.Version == 3 && string(v.SecretSchema) == `{"required":["password"]}`
+		})).Return(nil)
+
+		rt, err := service.UpdateResourceType(ctx, "ftp_server", userID, ResourceTypeInput{
+			Name:         "FTP Server",
+			SecretSchema: json.RawMessage(`{"required": ["password"]}`),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, rt.SchemaVersion)
+		assert.JSONEq(t, string(customType().ConfigSchema), string(rt.ConfigSchema))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Concurrent Change", func(t *testing.T) {
+		mockRepo := new(MockResourceTypeRepository)
+		service := newTypeService(mockRepo, new(MockTxManager))
+
+		mockRepo.On("GetByID", ctx, "ftp_server").Return(customType(), nil)
+		mockRepo.On("Update", ctx, mock.Anything, 2).Return(false, nil)
+
+		_, err := service.UpdateResourceType(ctx, "ftp_server", userID, ResourceTypeInput{
+			Name:         "FTP Server",
+			SecretSchema: json.RawMessage(`{"required": ["password"]}`),
+		})
+		assert.ErrorIs(t, err, ErrResourceTypeChanged)
+		mockRepo.AssertNotCalled(t, "CreateSchemaVersion", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Builtin", func(t *testing.T) {
+		mockRepo := new(MockResourceTypeRepository)
+		service := newTypeService(mockRepo, new(MockTxManager))
+		builtin := postgresType()
+		builtin.IsBuiltin = true
+
+		mockRepo.On("GetByID", ctx, "postgres-db").Return(builtin, nil)
+
+		_, err := service.UpdateResourceType(ctx, "postgres-db", userID, ResourceTypeInput{Name: "Postgres"})
+		assert.ErrorIs(t, err, ErrBuiltinResourceType)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		mockRepo := new(MockResourceTypeRepository)
+		service := newTypeService(mockRepo, new(MockTxManager))
+
+		mockRepo.On("GetByID", ctx, "mongo").Return(nil, nil)
+
+		_, err := service.UpdateResourceType(ctx, "mongo", userID, ResourceTypeInput{Name: "Mongo"})
+		assert.ErrorIs(t, err, ErrResourceTypeNotFound)
+	})
+
+	t.Run("Admin Of Another Organization", func(t *testing.T) {
+		mockRepo := new(MockResourceTypeRepository)
+		service := newTypeService(mockRepo, new(MockTxManager))
+
+		_, err := service.UpdateResourceType(principalContext(domain.UserRoleAdmin), "ftp_server", userID, ResourceTypeInput{Name: "FTP"})
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	})
+}
+
+func TestResourceTypeService_SetResourceTypeActive(t *testing.T) {
+	ctx := operatorContext()
+
+	t.Run("Deactivate Builtin", func(t *testing.T) {
+		mockRepo := new(MockResourceTypeRepository)
+		audit := new(MockAuditService)
+		service := NewResourceTypeService(mockRepo, new(MockTxManager), audit, CatalogConfig{OperatorOrganizationID: operatorOrg})
+		builtin := postgresType()
+		builtin.IsBuiltin = true
+		builtin.IsActive = true
+		builtin.SchemaVersion = 1
+
+		mockRepo.On("GetByID", ctx, "postgres-db").Return(builtin, nil)
+		mockRepo.On("Update", ctx, mock.MatchedBy(func(rt *domain.ResourceType) bool { return !rt.IsActive }), 1).Return(true, nil)
+		audit.On("LogAction", ctx, operatorOrg, (*uuid.UUID)(nil), "resource_type", uuid.NewSHA1(resourceTypeAuditNamespace, []byte("postgres-db")), domain.AuditActionUpdate, mock.MatchedBy(func(changes json.RawMessage) bool {
+			return string(changes) == `{"after":{"is_active":false},"before":{"is_active":true}}`
+		}), "").Return(nil)
+
+		rt, err := service.SetResourceTypeActive(ctx, "postgres-db", false)
+		assert.NoError(t, err)
+		assert.False(t, rt.IsActive)
+		mockRepo.AssertExpectations(t)
+		audit.AssertExpectations(t)
+	})
+
+	t.Run("Already Active", func(t *testing.T) {
+		mockRepo := new(MockResourceTypeRepository)
+		service := newTypeService(mockRepo, new(MockTxManager))
+
+		mockRepo.On("GetByID", ctx, "ftp_server").Return(customType(), nil)
+
+		_, err := service.SetResourceTypeActive(ctx, "ftp_server", true)
+		assert.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Forbidden", func(t *testing.T) {
+		service := newTypeService(new(MockResourceTypeRepository), new(MockTxManager))
+
+		_, err := service.SetResourceTypeActive(principalContext(domain.UserRoleManager), "ftp_server", false)
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+	})
+
+	t.Run("Admin Of Another Organization", func(t *testing.T) {
+		mockRepo := new(MockResourceTypeRepository)
+		service := newTypeService(mockRepo, new(MockTxManager))
+
+		_, err := service.SetResourceTypeActive(principalContext(domain.UserRoleAdmin), "postgres_db", false)
+		assert.ErrorIs(t, err, policy.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestResourceTypeService_SyncBuiltinTypes(t *testing.T) {
+	ctx := context.Background()
+	builtin := domain.ResourceType{
+		ID:           "postgres_db",
+		Name:         "PostgreSQL Database",
+		ConfigSchema: json.RawMessage(`{"type": "object", "required": ["host"]}`),
+		SecretSchema: json.RawMessage(`{"type": "object"}`),
+	}
+
+	t.Run("Creates Missing", func(t *testing.T) {
+		mockRepo := new(MockResourceTypeRepository)
+		service := newTypeService(mockRepo, new(MockTxManager))
+
+		mockRepo.On("GetByID", ctx, "postgres_db").Return(nil, nil)
+		mockRepo.On("Create", ctx, mock.MatchedBy(func(rt *domain.ResourceType) bool {
+			return rt.IsBuiltin && rt.IsActive && rt.SchemaVersion == 1
+		})).Return(nil)
+		mockRepo.On("CreateSchemaVersion", ctx, mock.MatchedBy(func(v *domain.ResourceTypeSchemaVersion) bool {
+			return v.Version == 1 && v.CreatedBy == nil
+		})).Return(nil)
+
+		report, err := service.SyncBuiltinTypes(ctx, []domain.ResourceType{builtin})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"postgres_db"}, report.Created)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Unchanged Ignores Key Order", func(t *testing.T) {
+		mockRepo := new(MockResourceTypeRepository)
+		service := newTypeService(mockRepo, new(MockTxManager))
+
+		mockRepo.On("GetByID", ctx, "postgres_db").Return(&domain.ResourceType{
+			ID:            "postgres_db",
+			Name:          "PostgreSQL Database",
+			ConfigSchema:  json.RawMessage(`{"required": ["host"], "type": "object"}`),
+			SecretSchema:  json.RawMessage(`{"type": "object"}`),
+			SchemaVersion: 4,
+			IsBuiltin:     true,
+		}, nil)
+
+		report, err := service.SyncBuiltinTypes(ctx, []domain.ResourceType{builtin})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"postgres_db"}, report.Unchanged)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Upgrades Schema And Keeps Active Flag", func(t *testing.T) {
+		mockRepo := new(MockResourceTypeRepository)
+		service := newTypeService(mockRepo, new(MockTxManager))
+
+		mockRepo.On("GetByID", ctx, "postgres_db").Return(&domain.ResourceType{
+			ID:            "postgres_db",
+			Name:          "PostgreSQL",
+			ConfigSchema:  json.RawMessage(`{"type": "object"}`),
+			SecretSchema:  json.RawMessage(`{"type": "object"}`),
+			SchemaVersion: 1,
+		}, nil)
+		mockRepo.On("Update", ctx, mock.MatchedBy(func(rt *domain.ResourceType) bool {
+			return rt.IsBuiltin && !rt.IsActive && rt.SchemaVersion == 2 && rt.Name == "PostgreSQL Database"
+		}), 1).Return(true, nil)
+		mockRepo.On("CreateSchemaVersion", ctx, mock.AnythingOfType("*domain.ResourceTypeSchemaVersion")).Return(nil)
+
+		report, err := service.SyncBuiltinTypes(ctx, []domain.ResourceType{builtin})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"postgres_db"}, report.Updated)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid Builtin Schema", func(t *testing.T) {
+		mockRepo := new(MockResourceTypeRepository)
+		service := newTypeService(mockRepo, new(MockTxManager))
+		broken := builtin
+		broken.ConfigSchema = json.RawMessage(`{"type": "nothing"}`)
+
+		_, err := service.SyncBuiltinTypes(ctx, []domain.ResourceType{broken})
+		assert.ErrorIs(t, err, ErrInvalidSchema)
+		mockRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Catalog Schemas Compile", func(t *testing.T) {
+		builtins, err := catalog.BuiltinTypes()
+		assert.NoError(t, err)
+		for _, rt := range builtins {
+			_, _, err := checkSchemas(rt.ConfigSchema, rt.SecretSchema)
+			assert.NoError(t, err, rt.ID)
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// ErrInvalidSchema reports a ResourceType schema that is not a valid JSON Schema.
+var ErrInvalidSchema = errors.New("invalid JSON schema")
+
+// FieldError explains why one value of a document failed validation.
+// Field is a JSON Pointer (RFC 6901) to the value, "" for the document root.
+type FieldError struct {
+	Field   string `json:"field" example:"/port"`
+	Message string `json:"message" example:"got string, want integer"`
+}
+
+// SchemaValidationError lists every field of a document that violates its
+// JSON Schema. Err is the sentinel identifying the document.
+type SchemaValidationError struct {
+	Err    error
+	Fields []FieldError
+}
+
+func (e *SchemaValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		field := f.Field
+		if field == "" {
+			field = "/"
+		}
+		msgs = append(msgs, field+": "+f.Message)
+	}
+	return e.Err.Error() + ": " + strings.Join(msgs, "; ")
+}
+
+func (e *SchemaValidationError) Unwrap() error {
+	return e.Err
+}
+
+var schemaPrinter = message.NewPrinter(language.English)
+
+// validateDocument checks doc against schema, reporting violations as a
+// *SchemaValidationError wrapping invalid. An empty schema or document
+// stands for {}, so types without a schema accept any object.
+func validateDocument(schema, doc json.RawMessage, invalid error) error {
+	compiled, err := compileSchema(schema)
+	if err != nil {
+		return err
+	}
+	instance, err := decodeDocument(doc)
+	if err != nil {
+		return &SchemaValidationError{Err: invalid, Fields: []FieldError{{Message: "must be a valid JSON document"}}}
+	}
+
+	err = compiled.Validate(instance)
+	var verr *jsonschema.ValidationError
+	if errors.As(err, &verr) {
+		return &SchemaValidationError{Err: invalid, Fields: fieldErrors(verr)}
+	}
+	return err
+}
+
+func compileSchema(raw json.RawMessage) (*jsonschema.Schema, error) {
+	doc, err := decodeDocument(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	const url = "resource-type.json"
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource(url, doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	compiled, err := c.Compile(url)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	return compiled, nil
+}
+
+// decodeDocument parses raw the way the validator expects, numbers as json.Number.
+func decodeDocument(raw json.RawMessage) (any, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return map[string]any{}, nil
+	}
+	return jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+}
+
+// fieldErrors flattens the leaves of a validation error tree, one entry per
+// offending field, ordered by field.
+func fieldErrors(verr *jsonschema.ValidationError) []FieldError {
+	var out []FieldError
+	collectFieldErrors(verr, &out)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Field < out[j].Field })
+	return out
+}
+
+func collectFieldErrors(e *jsonschema.ValidationError, out *[]FieldError) {
+	if len(e.Causes) > 0 {
+		for _, cause := range e.Causes {
+			collectFieldErrors(cause, out)
+		}
+		return
+	}
+
+	at := pointerTo(e.InstanceLocation)
+	switch k := e.ErrorKind.(type) {
+	case *kind.Required:
+		for _, name := range k.Missing {
+			*out = append(*out, FieldError{Field: at + "/" + escapePointer(name), Message: "is required"})
+		}
+	case *kind.AdditionalProperties:
+		for _, name := range k.Properties {
+			*out = append(*out, FieldError{Field: at + "/" + escapePointer(name), Message: "is not allowed"})
+		}
+	default:
+		*out = append(*out, FieldError{Field: at, Message: e.ErrorKind.LocalizedString(schemaPrinter)})
+	}
+}
+
+func pointerTo(location []string) string {
+	var b strings.Builder
+	for _, token := range location {
+		b.WriteString("/")
+		b.WriteString(escapePointer(token))
+	}
+	return b.String()
+}
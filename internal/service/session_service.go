@@ -0,0 +1,246 @@
+package service
+
+import (
+	"agentXmap/internal/domain"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidToken        = errors.New("invalid or expired token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrSessionSecretNeeded = errors.New("session signing secret is required")
+)
+
+// TokenPair is returned to clients after a successful login or refresh.
+type TokenPair struct {
+	AccessToken           string    `json:"access_token"`
+	RefreshToken          string    `json:"refresh_token"`
+	TokenType             string    `json:"token_type" example:"Bearer"`
+	AccessTokenExpiresAt  time.Time `json:"access_token_expires_at"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
+// AccessClaims are the JWT claims carried by an access token. SessionID ties
+// the token to the refresh tokens of its session, so that it stops working
+// when the session is revoked rather than when it expires.
+type AccessClaims struct {
+	OrganizationID uuid.UUID       `json:"org"`
+	Role           domain.UserRole `json:"role"`
+	SessionID      uuid.UUID       `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// SessionConfig holds the signing material and lifetimes used by DefaultSessionService.
+type SessionConfig struct {
+	Secret          []byte
+	Issuer          string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+// SessionService issues and validates access tokens and rotates refresh tokens.
+type SessionService interface {
+	IssueTokens(ctx context.Context, user *domain.User) (*TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*TokenPair, error)
+	// ValidateAccessToken checks the signature and expiry of an access token
+	// and that its session was not revoked by a logout, a password reset or a
+	// refresh token reuse.
+	ValidateAccessToken(ctx context.Context, accessToken string) (*domain.Principal, error)
+	Revoke(ctx context.Context, refreshToken string) error
+	RevokeAllForUser(ctx context.Context, userID uuid.UUID) error
+}
+
+type DefaultSessionService struct {
+	tokenRepo domain.RefreshTokenRepository
+	userRepo  domain.UserRepository
+	txManager domain.TxManager
+	cfg       SessionConfig
+}
+
+// NewSessionService creates a new instance of DefaultSessionService.
+func NewSessionService(tokenRepo domain.RefreshTokenRepository, userRepo domain.UserRepository, txManager domain.TxManager, cfg SessionConfig) (*DefaultSessionService, error) {
+	if len(cfg.Secret) == 0 {
+		return nil, ErrSessionSecretNeeded
+	}
+	if cfg.AccessTokenTTL <= 0 {
+		cfg.AccessTokenTTL = 15 * time.Minute
+	}
+	if cfg.RefreshTokenTTL <= 0 {
+		cfg.RefreshTokenTTL = 30 * 24 * time.Hour
+	}
+	return &DefaultSessionService{
+		tokenRepo: tokenRepo,
+		userRepo:  userRepo,
+		txManager: txManager,
+		cfg:       cfg,
+	}, nil
+}
+
+// hashRefreshToken returns the hex SHA-256 digest under which a refresh token is stored.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *DefaultSessionService) IssueTokens(ctx context.Context, user *domain.User) (*TokenPair, error) {
+	// A new session is named after its first refresh token.
+	tokenID := uuid.New()
+	return s.issue(ctx, user, tokenID, tokenID)
+}
+
+// issue signs a new access token and persists a fresh refresh token for user
+// under tokenID, both in sessionID.
+func (s *DefaultSessionService) issue(ctx context.Context, user *domain.User, tokenID, sessionID uuid.UUID) (*TokenPair, error) {
+	now := time.Now().UTC()
+	accessExpiresAt := now.Add(s.cfg.AccessTokenTTL)
+
+	claims := AccessClaims{
+		OrganizationID: user.OrganizationID,
+		Role:           user.Role,
+		SessionID:      sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   user.ID.String(),
+			Issuer:    s.cfg.Issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(accessExpiresAt),
+		},
+	}
+	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.cfg.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign access token: %w", err)
+	}
+
+	rawRefresh, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	record := &domain.RefreshToken{
+		ID:        tokenID,
+		UserID:    user.ID,
+		SessionID: sessionID,
+		TokenHash: hashRefreshToken(rawRefresh),
+		ExpiresAt: now.Add(s.cfg.RefreshTokenTTL),
+	}
+	if err := s.tokenRepo.Create(ctx, record); err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:           accessToken,
+		RefreshToken:          rawRefresh,
+		TokenType:             "Bearer",
+		AccessTokenExpiresAt:  accessExpiresAt,
+		RefreshTokenExpiresAt: record.ExpiresAt,
+	}, nil
+}
+
+// Refresh exchanges a valid refresh token for a new token pair and revokes the old one.
+// Presenting an already rotated token revokes every session of its owner.
+func (s *DefaultSessionService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	current, err := s.tokenRepo.GetByHash(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if current.RevokedAt != nil {
+		if current.ReplacedByID != nil {
+			return nil, s.revokeFamily(ctx, current.UserID)
+		}
+		return nil, ErrInvalidToken
+	}
+	if time.Now().UTC().After(current.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, current.UserID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// The old token is revoked before its successor is issued, so of two
+	// concurrent refreshes with the same token only one gets a new pair.
+	var pair *TokenPair
+	nextID := uuid.New()
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		revoked, err := s.tokenRepo.Revoke(ctx, current.ID, time.Now().UTC(), &nextID)
+		if err != nil {
+			return err
+		}
+		if !revoked {
+			return ErrRefreshTokenReused
+		}
+		pair, err = s.issue(ctx, user, nextID, current.SessionID)
+		return err
+	})
+	if errors.Is(err, ErrRefreshTokenReused) {
+		return nil, s.revokeFamily(ctx, current.UserID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// revokeFamily ends every session of a user whose rotated refresh token was
+// presented again, and reports the reuse.
+func (s *DefaultSessionService) revokeFamily(ctx context.Context, userID uuid.UUID) error {
+	_ = s.tokenRepo.RevokeAllForUser(ctx, userID)
+	return ErrRefreshTokenReused
+}
+
+func (s *DefaultSessionService) ValidateAccessToken(ctx context.Context, accessToken string) (*domain.Principal, error) {
+	var claims AccessClaims
+	_, err := jwt.ParseWithClaims(accessToken, &claims, func(t *jwt.Token) (interface{}, error) {
+		return s.cfg.Secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(s.cfg.Issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil || claims.SessionID == uuid.Nil {
+		return nil, ErrInvalidToken
+	}
+	// Revoking a session revokes its refresh tokens; the access tokens issued
+	// with them end at the same time.
+	active, err := s.tokenRepo.SessionActive(ctx, claims.SessionID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, ErrInvalidToken
+	}
+
+	return &domain.Principal{
+		UserID:         userID,
+		OrganizationID: claims.OrganizationID,
+		Role:           claims.Role,
+	}, nil
+}
+
+func (s *DefaultSessionService) Revoke(ctx context.Context, refreshToken string) error {
+	current, err := s.tokenRepo.GetByHash(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		return ErrInvalidToken
+	}
+	// Revoking a token that was revoked concurrently is not an error.
+	_, err = s.tokenRepo.Revoke(ctx, current.ID, time.Now().UTC(), nil)
+	return err
+}
+
+func (s *DefaultSessionService) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	return s.tokenRepo.RevokeAllForUser(ctx, userID)
+}
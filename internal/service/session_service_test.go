@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"agentXmap/internal/domain"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockRefreshTokenRepository is a mock implementation of domain.RefreshTokenRepository
+type MockRefreshTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockRefreshTokenRepository) Create(ctx context.Context, token *domain.RefreshToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) GetByHash(ctx context.Context, hash string) (*domain.RefreshToken, error) {
+	args := m.Called(ctx, hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.RefreshToken), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) Revoke(ctx context.Context, id uuid.UUID, at time.Time, replacedByID *uuid.UUID) (bool, error) {
+	args := m.Called(ctx, id, at, replacedByID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) SessionActive(ctx context.Context, sessionID uuid.UUID, now time.Time) (bool, error) {
+	args := m.Called(ctx, sessionID, now)
+	return args.Bool(0), args.Error(1)
+}
+
+func newTestSessionService(t *testing.T, tokenRepo domain.RefreshTokenRepository, userRepo domain.UserRepository) *DefaultSessionService {
+	svc, err := NewSessionService(tokenRepo, userRepo, new(MockTxManager), SessionConfig{
+		Secret:          []byte("test-secret"),
+		Issuer:          "agentXmap-test",
+		AccessTokenTTL:  time.Minute,
+		RefreshTokenTTL: time.Hour,
+	})
+	require.NoError(t, err)
+	return svc
+}
+
+func TestNewSessionService_RequiresSecret(t *testing.T) {
+	_, err := NewSessionService(nil, nil, nil, SessionConfig{})
+	assert.ErrorIs(t, err, ErrSessionSecretNeeded)
+}
+
+func TestSessionService_IssueAndValidate(t *testing.T) {
+	ctx := context.Background()
+	tokenRepo := new(MockRefreshTokenRepository)
+	svc := newTestSessionService(t, tokenRepo, nil)
+
+	user := &domain.User{ID: uuid.New(), OrganizationID: uuid.New(), Role: domain.UserRoleManager}
+	var stored *domain.RefreshToken
+	tokenRepo.On("Create", ctx, mock.AnythingOfType("*domain.RefreshToken")).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(*domain.RefreshToken) }).
+		Return(nil).Once()
+
+	pair, err := svc.IssueTokens(ctx, user)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer", pair.TokenType)
+	assert.NotEmpty(t, pair.RefreshToken)
+	assert.Equal(t, hashRefreshToken(pair.RefreshToken), stored.TokenHash)
+	assert.NotEqual(t, pair.RefreshToken, stored.TokenHash)
+	assert.Equal(t, stored.ID, stored.SessionID)
+
+	tokenRepo.On("SessionActive", ctx, stored.SessionID, mock.AnythingOfType("time.Time")).Return(true, nil).Once()
+	principal, err := svc.ValidateAccessToken(ctx, pair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, principal.UserID)
+	assert.Equal(t, user.OrganizationID, principal.OrganizationID)
+	assert.Equal(t, domain.UserRoleManager, principal.Role)
+}
+
+func TestSessionService_ValidateAccessToken(t *testing.T) {
+	ctx := context.Background()
+	tokenRepo := new(MockRefreshTokenRepository)
+	svc := newTestSessionService(t, tokenRepo, nil)
+
+	sign := func(secret string, method jwt.SigningMethod, claims AccessClaims) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString([]byte(secret))
+		require.NoError(t, err)
+		return token
+	}
+	valid := AccessClaims{
+		SessionID: uuid.New(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   uuid.NewString(),
+			Issuer:    "agentXmap-test",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+
+	t.Run("Wrong Secret", func(t *testing.T) {
+		_, err := svc.ValidateAccessToken(ctx, sign("other", jwt.SigningMethodHS256, valid))
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		expired := valid
+		expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+		_, err := svc.ValidateAccessToken(ctx, sign("test-secret", jwt.SigningMethodHS256, expired))
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Wrong Issuer", func(t *testing.T) {
+		other := valid
+		other.Issuer = "someone-else"
+		_, err := svc.ValidateAccessToken(ctx, sign("test-secret", jwt.SigningMethodHS256, other))
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Garbage", func(t *testing.T) {
+		_, err := svc.ValidateAccessToken(ctx, "not-a-jwt")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Without Session", func(t *testing.T) {
+		legacy := valid
+		legacy.SessionID = uuid.Nil
+		_, err := svc.ValidateAccessToken(ctx, sign("test-secret", jwt.SigningMethodHS256, legacy))
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Revoked Session", func(t *testing.T) {
+		tokenRepo.On("SessionActive", ctx, valid.SessionID, mock.AnythingOfType("time.Time")).Return(false, nil).Once()
+		_, err := svc.ValidateAccessToken(ctx, sign("test-secret", jwt.SigningMethodHS256, valid))
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Session Lookup Fails", func(t *testing.T) {
+		tokenRepo.On("SessionActive", ctx, valid.SessionID, mock.AnythingOfType("time.Time")).Return(false, errors.New("db down")).Once()
+		_, err := svc.ValidateAccessToken(ctx, sign("test-secret", jwt.SigningMethodHS256, valid))
+		assert.EqualError(t, err, "db down")
+	})
+}
+
+func TestSessionService_Refresh(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	raw := "refresh-token"
+
+	t.Run("Rotates Token", func(t *testing.T) {
+		tokenRepo := new(MockRefreshTokenRepository)
+		userRepo := new(MockUserRepository)
+		svc := newTestSessionService(t, tokenRepo, userRepo)
+
+		current := &domain.RefreshToken{ID: uuid.New(), UserID: userID, SessionID: uuid.New(), ExpiresAt: time.Now().Add(time.Hour)}
+		tokenRepo.On("GetByHash", ctx, hashRefreshToken(raw)).Return(current, nil).Once()
+		userRepo.On("GetByID", ctx, userID).Return(&domain.User{ID: userID}, nil).Once()
+		var successor *uuid.UUID
+		tokenRepo.On("Revoke", ctx, current.ID, mock.AnythingOfType("time.Time"), mock.AnythingOfType("*uuid.UUID")).
+			Run(func(args mock.Arguments) { successor = args.Get(3).(*uuid.UUID) }).
+			Return(true, nil).Once()
+		tokenRepo.On("Create", ctx, mock.MatchedBy(func(tok *domain.RefreshToken) bool {
+			return successor != nil && tok.ID == *successor && tok.SessionID == current.SessionID
+		})).Return(nil).Once()
+
+		pair, err := svc.Refresh(ctx, raw)
+		assert.NoError(t, err)
+		assert.NotEqual(t, raw, pair.RefreshToken)
+		tokenRepo.AssertExpectations(t)
+	})
+
+	t.Run("Concurrent Refresh Loses Race", func(t *testing.T) {
+		tokenRepo := new(MockRefreshTokenRepository)
+		userRepo := new(MockUserRepository)
+		svc := newTestSessionService(t, tokenRepo, userRepo)
+
+		// Both refreshes read the token unrevoked; the other one revoked it first.
+		current := &domain.RefreshToken{ID: uuid.New(), UserID: userID, ExpiresAt: time.Now().Add(time.Hour)}
+		tokenRepo.On("GetByHash", ctx, hashRefreshToken(raw)).Return(current, nil).Once()
+		userRepo.On("GetByID", ctx, userID).Return(&domain.User{ID: userID}, nil).Once()
+		tokenRepo.On("Revoke", ctx, current.ID, mock.AnythingOfType("time.Time"), mock.AnythingOfType("*uuid.UUID")).Return(false, nil).Once()
+		tokenRepo.On("RevokeAllForUser", ctx, userID).Return(nil).Once()
+
+		_, err := svc.Refresh(ctx, raw)
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+		tokenRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		tokenRepo.AssertExpectations(t)
+	})
+
+	t.Run("Reuse Revokes Family", func(t *testing.T) {
+		tokenRepo := new(MockRefreshTokenRepository)
+		svc := newTestSessionService(t, tokenRepo, nil)
+
+		revokedAt := time.Now().Add(-time.Minute)
+		successor := uuid.New()
+		current := &domain.RefreshToken{ID: uuid.New(), UserID: userID, ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt, ReplacedByID: &successor}
+		tokenRepo.On("GetByHash", ctx, hashRefreshToken(raw)).Return(current, nil).Once()
+		tokenRepo.On("RevokeAllForUser", ctx, userID).Return(nil).Once()
+
+		_, err := svc.Refresh(ctx, raw)
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+		tokenRepo.AssertExpectations(t)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		tokenRepo := new(MockRefreshTokenRepository)
+		svc := newTestSessionService(t, tokenRepo, nil)
+
+		current := &domain.RefreshToken{ID: uuid.New(), UserID: userID, ExpiresAt: time.Now().Add(-time.Minute)}
+		tokenRepo.On("GetByHash", ctx, hashRefreshToken(raw)).Return(current, nil).Once()
+
+		_, err := svc.Refresh(ctx, raw)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		tokenRepo := new(MockRefreshTokenRepository)
+		svc := newTestSessionService(t, tokenRepo, nil)
+
+		tokenRepo.On("GetByHash", ctx, hashRefreshToken(raw)).Return(nil, errors.New("not found")).Once()
+
+		_, err := svc.Refresh(ctx, raw)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
+
+func TestSessionService_Revoke(t *testing.T) {
+	ctx := context.Background()
+	raw := "refresh-token"
+
+	tokenRepo := new(MockRefreshTokenRepository)
+	svc := newTestSessionService(t, tokenRepo, nil)
+
+	current := &domain.RefreshToken{ID: uuid.New(), ExpiresAt: time.Now().Add(time.Hour)}
+	tokenRepo.On("GetByHash", ctx, hashRefreshToken(raw)).Return(current, nil).Once()
+	tokenRepo.On("Revoke", ctx, current.ID, mock.AnythingOfType("time.Time"), (*uuid.UUID)(nil)).Return(true, nil).Once()
+
+	assert.NoError(t, svc.Revoke(ctx, raw))
+	tokenRepo.AssertExpectations(t)
+}
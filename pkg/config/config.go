@@ -2,15 +2,23 @@ package config
 
 import (
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	App      AppConfig      `mapstructure:"app"`
-	Logger   LoggerConfig   `mapstructure:"logger"`
-	Database DatabaseConfig `mapstructure:"database"`
+	Server      ServerConfig      `mapstructure:"server"`
+	App         AppConfig         `mapstructure:"app"`
+	Logger      LoggerConfig      `mapstructure:"logger"`
+	Database    DatabaseConfig    `mapstructure:"database"`
+	Auth        AuthConfig        `mapstructure:"auth"`
+	Secrets     SecretsConfig     `mapstructure:"secrets"`
+	Leases      LeasesConfig      `mapstructure:"leases"`
+	Connectors  ConnectorsConfig  `mapstructure:"connectors"`
+	Catalog     CatalogConfig     `mapstructure:"catalog"`
+	Mail        MailConfig        `mapstructure:"mail"`
+	Invitations InvitationsConfig `mapstructure:"invitations"`
 }
 
 type DatabaseConfig struct {
@@ -20,11 +28,16 @@ type DatabaseConfig struct {
 	Password string `mapstructure:"password"`
 	DBName   string `mapstructure:"dbname"`
 	SSLMode  string `mapstructure:"sslmode"`
+	// SchemaCheck is what startup does when the database drifts from the
+	// models: "warn" (default) logs it, "fail" aborts, "off" skips the check.
+	SchemaCheck string `mapstructure:"schema_check"`
 }
 
 type ServerConfig struct {
 	Port string `mapstructure:"port"`
 	Mode string `mapstructure:"mode"`
+	// TrustedProxies may set the client address through X-Forwarded-For.
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 type AppConfig struct {
@@ -32,6 +45,58 @@ type AppConfig struct {
 	Version string `mapstructure:"version"`
 }
 
+type AuthConfig struct {
+	JWTSecret       string        `mapstructure:"jwt_secret"`
+	Issuer          string        `mapstructure:"issuer"`
+	AccessTokenTTL  time.Duration `mapstructure:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `mapstructure:"refresh_token_ttl"`
+}
+
+type SecretsConfig struct {
+	KeyProvider       string `mapstructure:"key_provider"`
+	MasterKeys        string `mapstructure:"master_keys"`
+	MasterKeyFile     string `mapstructure:"master_key_file"`
+	CurrentKeyVersion string `mapstructure:"current_key_version"`
+}
+
+type LeasesConfig struct {
+	ReadOnlyTTL    time.Duration `mapstructure:"read_only_ttl"`
+	ReadWriteTTL   time.Duration `mapstructure:"read_write_ttl"`
+	ExpiryInterval time.Duration `mapstructure:"expiry_interval"`
+}
+
+type ConnectorsConfig struct {
+	// AllowPrivateNetworks lets connection tests reach loopback, private and
+	// link-local addresses, for deployments whose resources live on them.
+	AllowPrivateNetworks bool `mapstructure:"allow_private_networks"`
+}
+
+type CatalogConfig struct {
+	// OperatorOrganizationID is the organization whose admins may change the
+	// resource type catalog shared by every tenant. Empty, nobody may.
+	OperatorOrganizationID string `mapstructure:"operator_organization_id"`
+}
+
+type MailConfig struct {
+	// Backend is "smtp" or "file"; file writes emails to OutboxDir.
+	Backend      string `mapstructure:"backend"`
+	From         string `mapstructure:"from"`
+	BaseURL      string `mapstructure:"base_url"`
+	SMTPHost     string `mapstructure:"smtp_host"`
+	SMTPPort     int    `mapstructure:"smtp_port"`
+	SMTPUsername string `mapstructure:"smtp_username"`
+	SMTPPassword string `mapstructure:"smtp_password"`
+	OutboxDir    string `mapstructure:"outbox_dir"`
+	// MaxAttempts and RetryBackoff govern redelivery of failed sends.
+	MaxAttempts      int           `mapstructure:"max_attempts"`
+	RetryBackoff     time.Duration `mapstructure:"retry_backoff"`
+	DeliveryInterval time.Duration `mapstructure:"delivery_interval"`
+}
+
+type InvitationsConfig struct {
+	ExpiryInterval time.Duration `mapstructure:"expiry_interval"`
+}
+
 type LoggerConfig struct {
 	Level    string `mapstructure:"level"`
 	Encoding string `mapstructure:"encoding"`